@@ -28,11 +28,12 @@ type projectConfig struct {
 }
 
 type agentConfigFile struct {
-	SupervisorURL         string          `json:"supervisor_url"`
-	AuthToken             string          `json:"auth_token"`
-	OpencodePort          int             `json:"opencode_port"`
-	AuthReportIntervalSec int             `json:"auth_report_interval_sec"`
-	Projects              []projectConfig `json:"projects"`
+	SupervisorURL           string          `json:"supervisor_url"`
+	AuthToken               string          `json:"auth_token"`
+	OpencodePort            int             `json:"opencode_port"`
+	AuthReportIntervalSec   int             `json:"auth_report_interval_sec"`
+	ProgressPollIntervalSec int             `json:"progress_poll_interval_sec,omitempty"`
+	Projects                []projectConfig `json:"projects"`
 }
 
 type channelMap struct {
@@ -103,7 +104,11 @@ type supervisorConfigFile struct {
 		CheckIntervalSeconds int `json:"check_interval_seconds"`
 	} `json:"policies"`
 	Dependencies map[string]interface{} `json:"dependencies"`
-	Security     struct {
+	TaskQueue    struct {
+		PollIntervalSeconds int `json:"poll_interval_seconds"`
+		MaxSessionsPerNode  int `json:"max_sessions_per_node"`
+	} `json:"task_queue"`
+	Security struct {
 		TLS struct {
 			Enabled  bool   `json:"enabled"`
 			CertPath string `json:"cert_path"`
@@ -410,6 +415,8 @@ func defaultSupervisorConfig() supervisorConfigFile {
 	cfg.Policies.CheckIntervalSeconds = 30
 
 	cfg.Dependencies = map[string]interface{}{}
+	cfg.TaskQueue.PollIntervalSeconds = 15
+	cfg.TaskQueue.MaxSessionsPerNode = 4
 
 	cfg.Security.TLS.Enabled = false
 	cfg.Security.TokenRotation.Enabled = false
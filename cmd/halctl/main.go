@@ -5,7 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/Bldg-7/hal-o-swarm/internal/halctl"
 )
@@ -75,6 +78,12 @@ func main() {
 		handleAuth(client, args[1:])
 	case "agentmd":
 		handleAgentMd(client, args[1:])
+	case "deps":
+		handleDeps(client, args[1:])
+	case "tasks":
+		handleTasks(client, args[1:])
+	case "tokens":
+		handleTokens(client, args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", args[0])
 		os.Exit(1)
@@ -83,7 +92,7 @@ func main() {
 
 func handleSessions(client *halctl.HTTPClient, args []string) {
 	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: sessions command requires subcommand (list, get, logs)\n")
+		fmt.Fprintf(os.Stderr, "Error: sessions command requires subcommand (list, get, logs, history)\n")
 		os.Exit(1)
 	}
 
@@ -132,6 +141,22 @@ func handleSessions(client *halctl.HTTPClient, args []string) {
 			printEventsTable(events)
 		}
 
+	case "history":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: sessions history requires session id\n")
+			os.Exit(1)
+		}
+		history, err := halctl.GetSessionHistory(client, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(history)
+		} else {
+			printSessionHistory(history)
+		}
+
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown sessions subcommand %q\n", args[0])
 		os.Exit(1)
@@ -140,7 +165,7 @@ func handleSessions(client *halctl.HTTPClient, args []string) {
 
 func handleNodes(client *halctl.HTTPClient, args []string) {
 	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: nodes command requires subcommand (list, get)\n")
+		fmt.Fprintf(os.Stderr, "Error: nodes command requires subcommand (list, get, cordon, uncordon, drain, remove, revoke, join-token)\n")
 		os.Exit(1)
 	}
 
@@ -173,6 +198,106 @@ func handleNodes(client *halctl.HTTPClient, args []string) {
 			printNodeTable(node)
 		}
 
+	case "cordon", "uncordon", "drain":
+		fs := flag.NewFlagSet("nodes "+args[0], flag.ExitOnError)
+		timeout := fs.Duration("timeout", 0, "How long to wait for sessions to end (drain only; default 10m)")
+		handover := fs.Bool("handover", false, "Move running sessions to other nodes instead of waiting (drain only)")
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: nodes %s requires node id\n", args[0])
+			os.Exit(1)
+		}
+		fs.Parse(args[2:])
+
+		var (
+			node *halctl.NodeJSON
+			err  error
+		)
+		switch args[0] {
+		case "cordon":
+			node, err = halctl.CordonNode(client, args[1])
+		case "uncordon":
+			node, err = halctl.UncordonNode(client, args[1])
+		default:
+			node, err = halctl.DrainNode(client, args[1], halctl.DrainRequest{
+				TimeoutSeconds: int(timeout.Seconds()),
+				Handover:       *handover,
+			})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(node)
+		} else {
+			fmt.Printf("Node %s is %s\n", node.ID, node.Lifecycle)
+		}
+
+	case "remove":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: nodes remove requires node id\n")
+			os.Exit(1)
+		}
+		removed, err := halctl.RemoveNode(client, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(removed)
+		} else {
+			fmt.Printf("Removed node %s and dropped %d sessions from the live view (history kept)\n", removed.NodeID, len(removed.PurgedSessions))
+		}
+
+	case "revoke":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: nodes revoke requires node id\n")
+			os.Exit(1)
+		}
+		revoked, err := halctl.RevokeNode(client, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(revoked)
+		} else if revoked.Disconnected {
+			fmt.Printf("Revoked node %s and disconnected it\n", revoked.NodeID)
+		} else {
+			fmt.Printf("Revoked node %s\n", revoked.NodeID)
+		}
+
+	case "join-token":
+		fs := flag.NewFlagSet("nodes join-token", flag.ExitOnError)
+		nodeID := fs.String("node", "", "Only let this node enroll with the token")
+		ttl := fs.Duration("ttl", 0, "How long the token stays valid (default: the supervisor's join_token_ttl_seconds)")
+		fs.Parse(args[1:])
+
+		token, err := halctl.CreateJoinToken(client, halctl.JoinTokenRequest{
+			NodeID:     *nodeID,
+			TTLSeconds: int(ttl.Seconds()),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(token)
+		} else {
+			fmt.Printf("Join token: %s\n", token.Token)
+			fmt.Printf("Expires:    %s\n", token.ExpiresAt.Local().Format(time.RFC3339))
+			if token.NodeID != "" {
+				fmt.Printf("Node:       %s\n", token.NodeID)
+			}
+			if token.CAFingerprint != "" {
+				fmt.Printf("CA:         %s\n", token.CAFingerprint)
+			}
+			fmt.Println("Set it as join_token in the agent config; it can be used once.")
+			if token.CAFingerprint != "" {
+				fmt.Println("Set the CA fingerprint as ca_fingerprint so the agent can verify the supervisor.")
+			}
+		}
+
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown nodes subcommand %q\n", args[0])
 		os.Exit(1)
@@ -181,10 +306,19 @@ func handleNodes(client *halctl.HTTPClient, args []string) {
 
 func handleCost(client *halctl.HTTPClient, args []string) {
 	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: cost command requires subcommand (today, week, month)\n")
+		fmt.Fprintf(os.Stderr, "Error: cost command requires subcommand (today, week, month, export, rates)\n")
 		os.Exit(1)
 	}
 
+	switch args[0] {
+	case "export":
+		handleCostExport(client, args[1:])
+		return
+	case "rates":
+		handleCostRates(client, args[1:])
+		return
+	}
+
 	var cost *halctl.CostSummary
 	var err error
 
@@ -212,6 +346,104 @@ func handleCost(client *halctl.HTTPClient, args []string) {
 	}
 }
 
+func handleCostExport(client *halctl.HTTPClient, args []string) {
+	fs := flag.NewFlagSet("cost export", flag.ExitOnError)
+	from := fs.String("from", "", "Start date, inclusive (YYYY-MM-DD)")
+	to := fs.String("to", "", "End date, inclusive (YYYY-MM-DD)")
+	granularity := fs.String("granularity", "daily", "Period grouping: daily, weekly or monthly")
+	groupBy := fs.String("group-by", "provider", "Grouping: provider, model, project or node (project and node use session usage by day)")
+	output := fs.String("output", "", "Write the export to this file instead of stdout")
+	fs.Parse(args)
+
+	opts := halctl.CostRangeOptions{
+		From:        *from,
+		To:          *to,
+		Granularity: *granularity,
+		GroupBy:     *groupBy,
+	}
+
+	var data []byte
+	if *format == "json" {
+		report, err := halctl.GetCostRange(client, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data, err = json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data = append(data, '\n')
+	} else {
+		csv, err := halctl.ExportCostCSV(client, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data = csv
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+}
+
+func handleCostRates(client *halctl.HTTPClient, args []string) {
+	if len(args) > 0 && args[0] == "validate" {
+		fs := flag.NewFlagSet("cost rates validate", flag.ExitOnError)
+		file := fs.String("file", "", "Catalog file to validate (default: the supervisor's configured catalog)")
+		fs.Parse(args[1:])
+
+		var data []byte
+		if *file != "" {
+			raw, err := os.ReadFile(*file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: read %s: %v\n", *file, err)
+				os.Exit(1)
+			}
+			data = raw
+		}
+
+		result, err := halctl.ValidatePricingCatalog(client, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(result)
+		} else {
+			printPricingValidation(result)
+		}
+		if !result.Valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fs := flag.NewFlagSet("cost rates", flag.ExitOnError)
+	model := fs.String("model", "", "Show only the rate in effect for this model")
+	provider := fs.String("provider", "", "Provider to resolve the model rate for")
+	date := fs.String("date", "", "Resolve the rate in effect on this date (YYYY-MM-DD)")
+	fs.Parse(args)
+
+	entries, err := halctl.ListPricingRates(client, *model, *provider, *date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *format == "json" {
+		printJSON(entries)
+	} else {
+		printPricingTable(entries)
+	}
+}
+
 func handleEnv(client *halctl.HTTPClient, args []string) {
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: env command requires subcommand (status, check, provision)\n")
@@ -359,6 +591,205 @@ func handleAgentMd(client *halctl.HTTPClient, args []string) {
 	}
 }
 
+func handleDeps(client *halctl.HTTPClient, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: deps command requires subcommand (graph, complete)\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "graph":
+		statuses, err := halctl.GetDependencyGraph(client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(statuses)
+		} else {
+			printDependencyTable(statuses)
+		}
+
+	case "complete":
+		fs := flag.NewFlagSet("deps complete", flag.ExitOnError)
+		milestone := fs.String("milestone", "", "Milestone name")
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: deps complete requires project name\n")
+			os.Exit(1)
+		}
+		fs.Parse(args[2:])
+		result, err := halctl.CompleteMilestone(client, args[1], *milestone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(result)
+		} else if len(result.Started) == 0 {
+			fmt.Printf("Milestone recorded for %s; no dependents started\n", args[1])
+		} else {
+			fmt.Printf("Milestone recorded for %s; started %s\n", args[1], strings.Join(result.Started, ", "))
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown deps subcommand %q\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func handleTasks(client *halctl.HTTPClient, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: tasks command requires subcommand (add, list, get, cancel)\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("tasks add", flag.ExitOnError)
+		priority := fs.Int("priority", 0, "Task priority; higher runs first")
+		deadline := fs.String("deadline", "", "Deadline (RFC3339)")
+		model := fs.String("model", "", "Model as provider/model")
+		if len(args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: tasks add requires project and prompt\n")
+			os.Exit(1)
+		}
+		fs.Parse(args[3:])
+
+		req := halctl.TaskRequest{Project: args[1], Prompt: args[2], Priority: *priority, Model: *model}
+		if *deadline != "" {
+			parsed, err := time.Parse(time.RFC3339, *deadline)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --deadline %q: %v\n", *deadline, err)
+				os.Exit(1)
+			}
+			req.Deadline = &parsed
+		}
+
+		task, err := halctl.AddTask(client, req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(task)
+		} else {
+			fmt.Printf("Queued task %s for %s at position %d\n", task.ID, task.Project, task.Position)
+		}
+
+	case "list":
+		fs := flag.NewFlagSet("tasks list", flag.ExitOnError)
+		project := fs.String("project", "", "Only list tasks for this project")
+		status := fs.String("status", "", "Only list tasks with this status (queued, dispatched, failed, cancelled, expired)")
+		fs.Parse(args[1:])
+
+		tasks, err := halctl.ListTasks(client, *project, *status)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(tasks)
+		} else {
+			printTasksTable(tasks)
+		}
+
+	case "get", "cancel":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: tasks %s requires task ID\n", args[0])
+			os.Exit(1)
+		}
+		var (
+			task *halctl.TaskJSON
+			err  error
+		)
+		if args[0] == "get" {
+			task, err = halctl.GetTask(client, args[1])
+		} else {
+			task, err = halctl.CancelTask(client, args[1])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(task)
+		} else {
+			printTasksTable([]halctl.TaskJSON{*task})
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown tasks subcommand %q\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func handleTokens(client *halctl.HTTPClient, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: tokens command requires subcommand (create, list, revoke)\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("tokens create", flag.ExitOnError)
+		scopes := fs.String("scopes", "", "Comma-separated scopes: sessions:read, sessions:control, credentials:push, env:provision, admin")
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: tokens create requires a token name\n")
+			os.Exit(1)
+		}
+		fs.Parse(args[2:])
+
+		token, err := halctl.CreateAPIToken(client, halctl.APITokenRequest{
+			Name:   args[1],
+			Scopes: strings.Split(*scopes, ","),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(token)
+		} else {
+			fmt.Printf("Token:  %s\n", token.Token)
+			fmt.Printf("Name:   %s\n", token.Name)
+			fmt.Printf("Scopes: %s\n", strings.Join(token.Scopes, ","))
+			fmt.Println("Store it now; it cannot be shown again.")
+		}
+
+	case "list":
+		tokens, err := halctl.ListAPITokens(client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(tokens)
+		} else {
+			printAPITokensTable(tokens)
+		}
+
+	case "revoke":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: tokens revoke requires a token name\n")
+			os.Exit(1)
+		}
+		token, err := halctl.RevokeAPIToken(client, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *format == "json" {
+			printJSON(token)
+		} else {
+			fmt.Printf("Revoked token %s\n", token.Name)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown tokens subcommand %q\n", args[0])
+		os.Exit(1)
+	}
+}
+
 func printJSON(data interface{}) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
@@ -367,10 +798,10 @@ func printJSON(data interface{}) {
 
 func printSessionsTable(sessions []halctl.SessionJSON) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tNODE_ID\tPROJECT\tSTATUS\tTOKENS\tCOST\tSTARTED_AT")
+	fmt.Fprintln(w, "ID\tNODE_ID\tPROJECT\tSTATUS\tTOKENS\tCONTEXT\tCOST\tSTARTED_AT")
 	for _, s := range sessions {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%.4f\t%s\n",
-			s.ID, s.NodeID, s.Project, s.Status, s.Tokens, s.Cost, s.StartedAt.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%.4f\t%s\n",
+			s.ID, s.NodeID, s.Project, s.Status, s.Tokens, formatContextPercent(s), s.Cost, s.StartedAt.Format("2006-01-02 15:04:05"))
 	}
 	w.Flush()
 }
@@ -382,12 +813,52 @@ func printSessionTable(session *halctl.SessionJSON) {
 	fmt.Fprintf(w, "NODE_ID\t%s\n", session.NodeID)
 	fmt.Fprintf(w, "PROJECT\t%s\n", session.Project)
 	fmt.Fprintf(w, "STATUS\t%s\n", session.Status)
+	fmt.Fprintf(w, "MODEL\t%s\n", session.Model)
 	fmt.Fprintf(w, "TOKENS\t%d\n", session.Tokens)
+	if session.ContextWindow > 0 {
+		fmt.Fprintf(w, "CONTEXT\t%d / %d (%s)\n", session.ContextTokens, session.ContextWindow, formatContextPercent(*session))
+	} else {
+		fmt.Fprintf(w, "CONTEXT\t%d\n", session.ContextTokens)
+	}
 	fmt.Fprintf(w, "COST\t%.4f\n", session.Cost)
 	fmt.Fprintf(w, "STARTED_AT\t%s\n", session.StartedAt.Format("2006-01-02 15:04:05"))
 	w.Flush()
 }
 
+// printSessionHistory prints the lineage, oldest session first, then every
+// status change across it.
+func printSessionHistory(history *halctl.SessionHistoryJSON) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION_ID\tPARENT\tNODE_ID\tSTATUS\tSTARTED_AT")
+	for _, s := range history.Chain {
+		marker := ""
+		if s.ID == history.SessionID {
+			marker = " *"
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n",
+			s.ID, marker, valueOrDash(s.ParentID), s.NodeID, s.Status, s.StartedAt.Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AT\tSESSION_ID\tFROM\tTO\tCAUSE\tDETAIL")
+	for _, tr := range history.Transitions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			tr.At.Format("2006-01-02 15:04:05"), tr.SessionID, valueOrDash(tr.From), tr.To, tr.Cause, valueOrDash(tr.Detail))
+	}
+	w.Flush()
+}
+
+// formatContextPercent renders context utilization, or "-" when the
+// session's model window is unknown.
+func formatContextPercent(s halctl.SessionJSON) string {
+	if s.ContextWindow <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", int(s.ContextPercent))
+}
+
 func printEventsTable(events []halctl.EventJSON) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "ID\tSESSION_ID\tTYPE\tTIMESTAMP")
@@ -400,12 +871,13 @@ func printEventsTable(events []halctl.EventJSON) {
 
 func printNodesTable(nodes []halctl.NodeJSON) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tHOSTNAME\tSTATUS\tLAST_HEARTBEAT\tCONNECTED_AT")
+	fmt.Fprintln(w, "ID\tHOSTNAME\tSTATUS\tLIFECYCLE\tLAST_HEARTBEAT\tCONNECTED_AT\tLABELS")
 	for _, n := range nodes {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			n.ID, n.Hostname, n.Status,
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			n.ID, n.Hostname, n.Status, valueOrDash(n.Lifecycle),
 			n.LastHeartbeat.Format("2006-01-02 15:04:05"),
-			n.ConnectedAt.Format("2006-01-02 15:04:05"))
+			n.ConnectedAt.Format("2006-01-02 15:04:05"),
+			valueOrDash(formatLabels(n.Labels)))
 	}
 	w.Flush()
 }
@@ -416,11 +888,54 @@ func printNodeTable(node *halctl.NodeJSON) {
 	fmt.Fprintf(w, "ID\t%s\n", node.ID)
 	fmt.Fprintf(w, "HOSTNAME\t%s\n", node.Hostname)
 	fmt.Fprintf(w, "STATUS\t%s\n", node.Status)
+	fmt.Fprintf(w, "LIFECYCLE\t%s\n", valueOrDash(node.Lifecycle))
 	fmt.Fprintf(w, "LAST_HEARTBEAT\t%s\n", node.LastHeartbeat.Format("2006-01-02 15:04:05"))
 	fmt.Fprintf(w, "CONNECTED_AT\t%s\n", node.ConnectedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "PROJECTS\t%s\n", joinOrDash(node.Projects))
+	fmt.Fprintf(w, "LABELS\t%s\n", valueOrDash(formatLabels(node.Labels)))
+	if inv := node.Inventory; inv != nil {
+		fmt.Fprintf(w, "OS\t%s\n", valueOrDash(strings.TrimSpace(inv.OS+" "+inv.Arch)))
+		fmt.Fprintf(w, "KERNEL\t%s\n", valueOrDash(inv.Kernel))
+		fmt.Fprintf(w, "CPUS\t%d\n", inv.CPUCount)
+		fmt.Fprintf(w, "AGENT_VERSION\t%s\n", valueOrDash(inv.AgentVersion))
+		fmt.Fprintf(w, "TOOLS\t%s\n", valueOrDash(formatLabels(inv.Tools)))
+	}
+	if tel := node.Telemetry; tel != nil {
+		fmt.Fprintf(w, "CPU\t%.0f%%\n", tel.CPUPercent)
+		fmt.Fprintf(w, "LOAD\t%.2f %.2f %.2f\n", tel.Load1, tel.Load5, tel.Load15)
+		fmt.Fprintf(w, "MEMORY\t%.0f%% (%s available of %s)\n", tel.MemoryPercent, formatBytes(tel.MemoryAvailableBytes), formatBytes(tel.MemoryTotalBytes))
+		for _, disk := range tel.Disks {
+			fmt.Fprintf(w, "DISK %s\t%s free of %s (%s)\n", disk.Project, formatBytes(disk.FreeBytes), formatBytes(disk.TotalBytes), disk.Path)
+		}
+		fmt.Fprintf(w, "REPORTED_AT\t%s\n", tel.ReportedAt.Format("2006-01-02 15:04:05"))
+	}
 	w.Flush()
 }
 
+// formatBytes renders a byte count in binary units, e.g. "3.2 GiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func printCostTable(cost *halctl.CostSummary) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "FIELD\tVALUE")
@@ -428,9 +943,126 @@ func printCostTable(cost *halctl.CostSummary) {
 	fmt.Fprintf(w, "TOTAL_COST\t%.4f\n", cost.TotalCost)
 	fmt.Fprintf(w, "SESSION_COUNT\t%d\n", cost.SessionCount)
 	fmt.Fprintf(w, "TOKENS_USED\t%d\n", cost.TokensUsed)
+	if cost.Forecast != nil {
+		fmt.Fprintf(w, "PROJECTED_WEEK\t%.4f\n", cost.Forecast.EndOfWeek.Projected())
+		fmt.Fprintf(w, "PROJECTED_MONTH\t%.4f\n", cost.Forecast.EndOfMonth.Projected())
+	}
 	w.Flush()
 }
 
+func printDependencyTable(statuses []halctl.DependencyStatusJSON) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tSTATE\tDEPENDS_ON\tWAITING_ON\tLAST_MILESTONE\tAUTO_START\tACTIVE")
+	for _, s := range statuses {
+		state := "waiting"
+		switch {
+		case s.Ready:
+			state = "ready"
+		case s.TriggeredAt != nil:
+			state = "started"
+		}
+		milestone := "-"
+		if s.LastMilestone != nil {
+			milestone = s.LastMilestone.CompletedAt.Format("2006-01-02 15:04:05")
+			if s.LastMilestone.Milestone != "" {
+				milestone = s.LastMilestone.Milestone + " (" + milestone + ")"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%d\n",
+			s.Project, state, joinOrDash(s.DependsOn), joinOrDash(s.WaitingOn),
+			milestone, s.AutoStart, s.ActiveSessions)
+	}
+	w.Flush()
+}
+
+func printAPITokensTable(tokens []halctl.APITokenJSON) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPES\tSTATUS\tLAST_USED\tCREATED_AT")
+	for _, t := range tokens {
+		status := "active"
+		if t.RevokedAt != nil {
+			status = "revoked"
+		}
+		lastUsed := "-"
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			t.Name, strings.Join(t.Scopes, ","), status, lastUsed, t.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+}
+
+func printTasksTable(tasks []halctl.TaskJSON) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPROJECT\tSTATUS\tPOSITION\tPRIORITY\tDEADLINE\tMODEL\tSESSION\tCREATED_AT")
+	for _, t := range tasks {
+		position := "-"
+		if t.Position > 0 {
+			position = fmt.Sprintf("%d", t.Position)
+		}
+		deadline := "-"
+		if t.Deadline != nil {
+			deadline = t.Deadline.Format("2006-01-02 15:04:05")
+		}
+		model := t.Model
+		if model == "" {
+			model = "-"
+		}
+		session := t.SessionID
+		if session == "" {
+			session = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			t.ID, t.Project, t.Status, position, t.Priority, deadline, model, session,
+			t.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+func printPricingTable(entries []halctl.PricingEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPROVIDER\tEFFECTIVE_FROM\tINPUT\tOUTPUT\tCACHE_READ\tCACHE_WRITE")
+	for _, e := range entries {
+		provider := e.Provider
+		if provider == "" {
+			provider = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.4f\t%.4f\t%.4f\t%.4f\n",
+			e.Model, provider, e.EffectiveFrom, e.Input, e.Output, e.CacheRead, e.CacheWrite)
+	}
+	w.Flush()
+}
+
+func printPricingValidation(result *halctl.PricingValidation) {
+	status := "valid"
+	if !result.Valid {
+		status = "invalid"
+	}
+	fmt.Printf("%s: %s (%d entries)\n", result.Source, status, result.Entries)
+	for _, issue := range result.Issues {
+		if issue.Model != "" {
+			fmt.Printf("  rates[%d] (%s): %s\n", issue.Index, issue.Model, issue.Message)
+		} else {
+			fmt.Printf("  rates[%d]: %s\n", issue.Index, issue.Message)
+		}
+	}
+}
+
 func printEnvCheckTable(result *halctl.EnvCheckResult) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "FIELD\tVALUE")
@@ -488,13 +1120,29 @@ Commands:
   sessions list                    List all sessions
   sessions get <id>                Get session details
   sessions logs <id>               Get session logs/events
+  sessions history <id>            Show a session's lineage and status changes
   
   nodes list                       List all nodes
   nodes get <id>                   Get node details
+  nodes cordon <id>                Stop placing new sessions on a node
+  nodes uncordon <id>              Place new sessions on a node again
+  nodes drain <id> [--timeout D] [--handover]
+                                   Cordon a node and wait for (or move) its sessions
+  nodes remove <id>                Unregister an offline node and end its sessions
+  nodes revoke <id>                Disconnect a node and block its credential
+  nodes join-token [--node ID] [--ttl D]
+                                   Create a one-time token an agent enrolls with
   
   cost today                       Get today's cost
   cost week                        Get week's cost
   cost month                       Get month's cost
+  cost export [--from DATE] [--to DATE] [--granularity daily|weekly|monthly]
+              [--group-by provider|model|project|node] [--output FILE]
+                                   Export a date-range cost report (CSV, or JSON with --format json)
+  cost rates [--model M] [--provider P] [--date DATE]
+                                   List the pricing catalog or resolve a model's rate
+  cost rates validate [--file FILE]
+                                   Validate a pricing catalog (default: the supervisor's)
   
   env status <project>             Get environment status
   env check <project>              Check environment
@@ -506,6 +1154,24 @@ Commands:
   agentmd diff <project>           Show AGENT.md diff
   agentmd sync <project>           Sync AGENT.md
 
+  deps graph                       Show the project dependency graph and readiness
+  deps complete <project> [--milestone NAME]
+                                   Record a completed milestone and start ready dependents
+
+  tasks add <project> <prompt> [--priority N] [--deadline RFC3339] [--model provider/model]
+                                   Queue a task; it starts once the project is free, or
+                                   expires if its deadline passes first
+  tasks list [--project P] [--status S]
+                                   List tasks in dispatch order with queue positions
+  tasks get <id>                   Get task details
+  tasks cancel <id>                Remove a queued task
+
+  tokens create <name> --scopes S[,S...]
+                                   Create a named API token (sessions:read, sessions:control,
+                                   credentials:push, env:provision, admin)
+  tokens list                      List API tokens, including revoked ones
+  tokens revoke <name>             Stop a token from authenticating
+
   config [supervisor|agent|cli]    Interactive local config setup
   
   help                             Show this help message
@@ -514,6 +1180,10 @@ Examples:
   halctl -auth-token mytoken sessions list
   halctl -format json nodes list
   halctl env status my-project
+  halctl cost export --from 2026-02-01 --to 2026-02-28 --group-by project
+  halctl deps graph
+  halctl tasks add my-project "Fix the flaky build" --priority 10
+  halctl tokens create grafana --scopes sessions:read
   halctl config
   halctl config supervisor
   halctl config agent
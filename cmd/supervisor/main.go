@@ -56,18 +56,90 @@ func main() {
 
 	registry := supervisor.NewNodeRegistry(db, logger)
 	tracker := supervisor.NewSessionTracker(db, logger)
+	tracker.SetModelCatalog(supervisor.NewModelCatalog(cfg.Models))
+	srv.Hub().ConfigureSessionTracker(tracker)
 	dispatcher := supervisor.NewCommandDispatcher(db, registry, tracker, srv.Hub(), logger)
+	dispatcher.SetPlacement(cfg.Placement)
 	audit := supervisor.NewAuditLogger(db, logger)
 
 	srv.SetAuditLogger(audit)
 	srv.SetRegistry(registry)
 
+	costs := supervisor.NewCostAggregator(cfg.Cost, db, tracker, logger)
+	srv.SetCostAggregator(costs)
+
+	pipeline, err := supervisor.NewEventPipeline(db, logger, nil)
+	if err != nil {
+		logger.Error("failed to create event pipeline", zap.Error(err))
+		os.Exit(1)
+	}
+	// Supervisor-emitted events are stored for the events API and forwarded
+	// to notifiers such as the Discord alerts channel once they start.
+	events := supervisor.NewEventFanout(pipeline)
+
+	if cfg.Cost.Budgets.Enabled {
+		budgets := supervisor.NewBudgetEnforcer(cfg.Cost.Budgets, costs, tracker, events, logger)
+		if err := budgets.SetAlertStore(db); err != nil {
+			logger.Error("failed to load budget alerts", zap.Error(err))
+			os.Exit(1)
+		}
+		dispatcher.SetBudgetGate(budgets)
+		srv.SetBudgetEnforcer(budgets)
+		logger.Info("cost budgets enabled", zap.Bool("block_on_exceed", cfg.Cost.Budgets.BlockOnExceed))
+	}
+
+	var deps *supervisor.DependencyScheduler
+	if len(cfg.Dependencies) > 0 {
+		graph, err := supervisor.NewDependencyGraph(cfg.Dependencies)
+		if err != nil {
+			logger.Error("invalid dependency graph", zap.Error(err))
+			os.Exit(1)
+		}
+		deps = supervisor.NewDependencyScheduler(graph, cfg.Dependencies, tracker, dispatcher, db, logger)
+		if err := deps.LoadMilestones(); err != nil {
+			logger.Error("failed to load project milestones", zap.Error(err))
+			os.Exit(1)
+		}
+		srv.Hub().ConfigureDependencyScheduler(deps)
+		logger.Info("dependency scheduling enabled", zap.Int("projects", len(graph.Projects())))
+	}
+
+	tasks := supervisor.NewTaskQueue(cfg.TaskQueue, db, tracker, registry, dispatcher, logger)
+	srv.SetTaskQueue(tasks)
+	srv.SetNodeMaintenance(supervisor.NewNodeMaintenance(db, registry, tracker, dispatcher, logger))
+	srv.SetNodeEnrollment(supervisor.NewNodeEnrollment(cfg.Security.Enrollment, db, registry, logger))
+
+	tlsConfig, err := supervisor.LoadTLSConfig(cfg.Security.TLS)
+	if err != nil {
+		logger.Error("failed to load TLS certificate", zap.Error(err))
+		os.Exit(1)
+	}
+	srv.SetTLSConfig(tlsConfig)
+	if cfg.Security.MTLS.Enabled {
+		nodeCA, err := supervisor.LoadOrCreateNodeCA(cfg.Security.MTLS, logger)
+		if err != nil {
+			logger.Error("failed to load node CA", zap.Error(err))
+			os.Exit(1)
+		}
+		srv.SetNodeCA(nodeCA)
+		logger.Info("agent mTLS enabled", zap.String("ca_fingerprint", nodeCA.Fingerprint()))
+	}
+
+	taskTracker := supervisor.NewTaskTracker(db, tracker, logger)
+	tracker.SetTaskTracker(taskTracker)
+	srv.Hub().ConfigureTaskTracker(taskTracker)
+
 	supervisor.InitMetrics()
 	logger.Info("metrics initialized")
 
 	if cfg.Server.HTTPPort > 0 {
 		api := supervisor.NewHTTPAPI(registry, tracker, dispatcher, db, cfg.Server.AuthToken, logger)
 		api.SetAuditLogger(audit)
+		api.SetAPITokenStore(supervisor.NewAPITokenStore(db, logger))
+		api.SetTaskTracker(taskTracker)
+		if deps != nil {
+			api.SetDependencyScheduler(deps)
+		}
 		srv.SetHTTPAPI(api)
 		logger.Info("http api configured", zap.Int("http_port", cfg.Server.HTTPPort))
 	}
@@ -92,6 +164,19 @@ func main() {
 		} else if startErr := bot.Start(); startErr != nil {
 			logger.Error("failed to start discord bot", zap.Error(startErr))
 		} else {
+			bot.SetCostAggregator(costs)
+			bot.SetTaskQueue(tasks)
+			bot.SetTaskTracker(taskTracker)
+			perms := supervisor.NewDiscordPermissions(cfg.Channels.Discord.Permissions)
+			if !perms.Configured() {
+				logger.Warn("channels.discord.permissions grants no roles; every discord command will be refused")
+			}
+			bot.SetPermissions(perms)
+			bot.SetAuditLogger(audit)
+			if alerts := cfg.Channels.Discord.Channels.Alerts; alerts != "" {
+				bot.SetAlertChannel(alerts)
+				events.Add(bot)
+			}
 			discordBot = bot
 			logger.Info("discord bot started")
 		}
@@ -115,6 +200,7 @@ func main() {
 		logger.Error("error during shutdown", zap.Error(err))
 		os.Exit(1)
 	}
+	pipeline.Close()
 
 	logger.Info("supervisor exited cleanly")
 	os.Exit(0)
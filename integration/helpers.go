@@ -588,6 +588,12 @@ func (a *agentHarness) handleMessage(env *shared.Envelope) error {
 			result.Status = supervisor.CommandStatusFailure
 			result.Error = err.Error()
 		}
+	case supervisor.CommandTypeCompactSession:
+		sessionID := agent.SessionID(readStringArg(cmd.Args, "session_id"))
+		if err := a.adapter.CompactSession(ctx, sessionID, readStringArg(cmd.Args, "provider_id"), readStringArg(cmd.Args, "model_id")); err != nil {
+			result.Status = supervisor.CommandStatusFailure
+			result.Error = err.Error()
+		}
 	case supervisor.CommandTypeSessionStatus:
 		sessionID := agent.SessionID(readStringArg(cmd.Args, "session_id"))
 		status, err := a.adapter.SessionStatus(ctx, sessionID)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -28,6 +29,11 @@ type Agent struct {
 	authReporter       *AuthReporter
 	oauthExecutor      *OAuthTriggerExecutor
 	authReporterCancel context.CancelFunc
+	progressWatcher    *ProgressWatcher
+	progressCancel     context.CancelFunc
+	telemetry          *TelemetryReporter
+	telemetryCancel    context.CancelFunc
+	renewalCancel      context.CancelFunc
 }
 
 // NewAgent creates a new Agent instance with the given config.
@@ -83,11 +89,45 @@ func (a *Agent) Start(ctx context.Context) error {
 	}
 	a.opencodeAdapter = realAdapter
 
+	opencodeStatusCommand := resolveStatusCommand(ToolOpencode, a.cfg.ToolPaths.Opencode, logger)
+	claudeStatusCommand := resolveStatusCommand(ToolClaudeCode, a.cfg.ToolPaths.Claude, logger)
+	codexStatusCommand := resolveStatusCommand(ToolCodex, a.cfg.ToolPaths.Codex, logger)
+
+	projectDirs := make([]ProjectDir, 0, len(a.cfg.Projects))
+	for _, project := range a.cfg.Projects {
+		projectDirs = append(projectDirs, ProjectDir{Project: project.Name, Directory: project.Directory})
+	}
+	collector := NewTelemetryCollector(nil, DefaultToolProbes(opencodeStatusCommand, claudeStatusCommand, codexStatusCommand), projectDirs)
+
+	credentials := NewNodeCredentials(a.cfg.SupervisorURL, a.cfg.AuthToken, a.cfg.JoinToken, a.cfg.CredentialPath, nodeID, logger)
+	nodeTLS, err := NewNodeTLS(filepath.Dir(a.cfg.CredentialPath), a.cfg.CAFingerprint, logger)
+	if err != nil {
+		return fmt.Errorf("load node certificate: %w", err)
+	}
+	credentials.SetNodeTLS(nodeTLS)
 	a.wsClient = NewWSClient(
 		a.cfg.SupervisorURL,
 		a.cfg.AuthToken,
 		logger,
 		WithNodeID(nodeID),
+		WithTokenSource(credentials.Token),
+		WithTLSConfig(nodeTLS.ClientConfig()),
+		WithSnapshotProvider(a.snapshot),
+		WithOnConnectHook(func() error {
+			// Usage is reported right away rather than an interval after
+			// reconnecting; a failed send is retried on the next tick.
+			if err := a.telemetry.Report(context.Background()); err != nil {
+				logger.Debug("initial heartbeat not sent", zap.Error(err))
+			}
+			return nil
+		}),
+	)
+	a.telemetry = NewTelemetryReporter(
+		collector,
+		time.Duration(a.cfg.TelemetryIntervalSec)*time.Second,
+		time.Duration(a.cfg.InventoryIntervalSec)*time.Second,
+		a.wsClient,
+		logger,
 	)
 
 	if err := RegisterSessionCommandHandlers(a.wsClient, a.opencodeAdapter, logger); err != nil {
@@ -103,9 +143,6 @@ func (a *Agent) Start(ctx context.Context) error {
 	}
 
 	authRunner := NewAuthCommandRunner(10*time.Second, logger)
-	opencodeStatusCommand := resolveStatusCommand(ToolOpencode, a.cfg.ToolPaths.Opencode, logger)
-	claudeStatusCommand := resolveStatusCommand(ToolClaudeCode, a.cfg.ToolPaths.Claude, logger)
-	codexStatusCommand := resolveStatusCommand(ToolCodex, a.cfg.ToolPaths.Codex, logger)
 
 	a.oauthExecutor = NewOAuthTriggerExecutor(authRunner, logger)
 	if err := RegisterOAuthTriggerHandler(a.wsClient, a.oauthExecutor); err != nil {
@@ -126,6 +163,20 @@ func (a *Agent) Start(ctx context.Context) error {
 	a.authReporterCancel = reporterCancel
 	go a.authReporter.Start(reporterCtx)
 
+	progressInterval := time.Duration(a.cfg.ProgressPollIntervalSec) * time.Second
+	a.progressWatcher = NewProgressWatcher(a.registry, progressInterval, a.wsClient, logger)
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	a.progressCancel = progressCancel
+	go a.progressWatcher.Start(progressCtx)
+
+	telemetryCtx, telemetryCancel := context.WithCancel(ctx)
+	a.telemetryCancel = telemetryCancel
+	go a.telemetry.Start(telemetryCtx)
+
+	renewalCtx, renewalCancel := context.WithCancel(ctx)
+	a.renewalCancel = renewalCancel
+	go credentials.StartCertificateRenewal(renewalCtx, certRenewCheckInterval)
+
 	a.wsClient.Connect(ctx)
 
 	a.running = true
@@ -136,6 +187,23 @@ func (a *Agent) Start(ctx context.Context) error {
 	return nil
 }
 
+// snapshot describes this node for the supervisor on every connect.
+func (a *Agent) snapshot() *StateSnapshot {
+	projects := make([]string, 0, len(a.cfg.Projects))
+	for _, project := range a.cfg.Projects {
+		projects = append(projects, project.Name)
+	}
+	return &StateSnapshot{
+		NodeInfo: shared.NodeInfo{
+			Hostname:  nodeIdentifier(),
+			Projects:  projects,
+			Labels:    a.cfg.Labels,
+			Inventory: a.telemetry.Inventory(context.Background()),
+		},
+		Sessions: []SessionSnapshot{},
+	}
+}
+
 func nodeIdentifier() string {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -194,6 +262,15 @@ func (a *Agent) Stop(ctx context.Context) error {
 	if a.authReporterCancel != nil {
 		a.authReporterCancel()
 	}
+	if a.progressCancel != nil {
+		a.progressCancel()
+	}
+	if a.telemetryCancel != nil {
+		a.telemetryCancel()
+	}
+	if a.renewalCancel != nil {
+		a.renewalCancel()
+	}
 
 	if a.wsClient != nil {
 		if err := a.wsClient.Close(); err != nil {
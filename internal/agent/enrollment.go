@@ -0,0 +1,294 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	enrollTimeout = 15 * time.Second
+
+	// certRenewCheckInterval is how often the node certificate's expiry is
+	// checked.
+	certRenewCheckInterval = time.Hour
+)
+
+// NodeCredentials supplies the token the agent connects with. An enrolled
+// node uses the credential saved at its credential path; otherwise a join
+// token is exchanged for one on first use, and without a join token the
+// shared auth token is used. With NodeTLS set, enrolling over TLS also
+// obtains a node certificate, which is renewed before it expires.
+type NodeCredentials struct {
+	supervisorURL string
+	sharedToken   string
+	joinToken     string
+	path          string
+	nodeID        string
+	client        *http.Client
+	nodeTLS       *NodeTLS
+	logger        *zap.Logger
+
+	mu         sync.Mutex
+	credential string
+}
+
+func NewNodeCredentials(supervisorURL, sharedToken, joinToken, credentialPath, nodeID string, logger *zap.Logger) *NodeCredentials {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &NodeCredentials{
+		supervisorURL: supervisorURL,
+		sharedToken:   sharedToken,
+		joinToken:     joinToken,
+		path:          credentialPath,
+		nodeID:        nodeID,
+		client:        &http.Client{Timeout: enrollTimeout},
+		logger:        logger,
+	}
+}
+
+// SetNodeTLS requests a node certificate at enrollment and verifies the
+// supervisor against the CA nodeTLS pins. It must be called before Token.
+func (n *NodeCredentials) SetNodeTLS(nodeTLS *NodeTLS) {
+	n.nodeTLS = nodeTLS
+	n.client = &http.Client{
+		Timeout: enrollTimeout,
+		// Each request dials afresh so it presents the current certificate,
+		// not whichever one a kept-alive connection was opened with.
+		Transport: &http.Transport{TLSClientConfig: nodeTLS.ClientConfig(), DisableKeepAlives: true},
+	}
+}
+
+// Token returns the token to connect with, enrolling if needed.
+func (n *NodeCredentials) Token(ctx context.Context) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.credential != "" {
+		return n.credential, nil
+	}
+
+	if n.path != "" {
+		data, err := os.ReadFile(n.path)
+		if err == nil && strings.TrimSpace(string(data)) != "" {
+			n.credential = strings.TrimSpace(string(data))
+			return n.credential, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("read node credential: %w", err)
+		}
+	}
+
+	if n.joinToken == "" {
+		return n.sharedToken, nil
+	}
+
+	credential, err := n.enroll(ctx)
+	if err != nil {
+		return "", err
+	}
+	if n.path != "" {
+		if err := writeCredential(n.path, credential); err != nil {
+			// The join token is spent, so keep the credential for this run
+			// even though it will not survive a restart.
+			n.logger.Error("node credential not saved", zap.String("path", n.path), zap.Error(err))
+		}
+	}
+	n.credential = credential
+	n.logger.Info("node enrolled with supervisor", zap.String("node_id", n.nodeID))
+	return credential, nil
+}
+
+func (n *NodeCredentials) enroll(ctx context.Context) (string, error) {
+	endpoint, err := enrollURL(n.supervisorURL)
+	if err != nil {
+		return "", err
+	}
+
+	request := map[string]string{"join_token": n.joinToken, "node_id": n.nodeID}
+	var keyPEM []byte
+	if n.nodeTLS != nil && strings.HasPrefix(endpoint, "https:") {
+		var csrPEM []byte
+		if csrPEM, keyPEM, err = n.nodeTLS.NewCSR(n.nodeID); err != nil {
+			return "", fmt.Errorf("enroll: %w", err)
+		}
+		request["csr"] = string(csrPEM)
+	}
+
+	resp, err := n.post(ctx, endpoint, "", request)
+	if err != nil {
+		return "", fmt.Errorf("enroll: %w", err)
+	}
+	if resp.Credential == "" {
+		return "", fmt.Errorf("enroll: supervisor returned no credential")
+	}
+	if resp.Certificate != "" {
+		if err := n.nodeTLS.Install([]byte(resp.Certificate), keyPEM, []byte(resp.CACertificate)); err != nil {
+			// The join token is spent, so the credential is kept either way;
+			// without a certificate the node needs a new join token and its
+			// credential file removed to enroll again.
+			n.logger.Error("node certificate not installed", zap.Error(err))
+		}
+	}
+	return resp.Credential, nil
+}
+
+// RenewCertificate replaces the node certificate with a fresh one,
+// authenticating with the current certificate and node credential.
+func (n *NodeCredentials) RenewCertificate(ctx context.Context) error {
+	nodeTLS := n.nodeTLS
+	if nodeTLS == nil || !nodeTLS.HasCertificate() {
+		return fmt.Errorf("renew certificate: no node certificate")
+	}
+
+	endpoint, err := supervisorEndpoint(n.supervisorURL, "renew")
+	if err != nil {
+		return err
+	}
+	token, err := n.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("renew certificate: %w", err)
+	}
+	csrPEM, keyPEM, err := nodeTLS.NewCSR(n.nodeID)
+	if err != nil {
+		return fmt.Errorf("renew certificate: %w", err)
+	}
+
+	resp, err := n.post(ctx, endpoint, token, map[string]string{"csr": string(csrPEM)})
+	if err != nil {
+		return fmt.Errorf("renew certificate: %w", err)
+	}
+	if err := nodeTLS.Install([]byte(resp.Certificate), keyPEM, []byte(resp.CACertificate)); err != nil {
+		return fmt.Errorf("renew certificate: %w", err)
+	}
+	return nil
+}
+
+// StartCertificateRenewal renews the node certificate once it is in the
+// last third of its validity, checking every interval until ctx is done.
+func (n *NodeCredentials) StartCertificateRenewal(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = certRenewCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n.nodeTLS != nil && n.nodeTLS.NeedsRenewal(time.Now()) {
+			if err := n.RenewCertificate(ctx); err != nil {
+				n.logger.Warn("node certificate renewal failed", zap.Error(err))
+			} else {
+				n.logger.Info("node certificate renewed")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type enrollResult struct {
+	Credential    string `json:"credential"`
+	Certificate   string `json:"certificate"`
+	CACertificate string `json:"ca_certificate"`
+}
+
+func (n *NodeCredentials) post(ctx context.Context, endpoint, token string, request map[string]string) (enrollResult, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return enrollResult{}, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return enrollResult{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Node-ID", n.nodeID)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return enrollResult{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return enrollResult{}, fmt.Errorf("read response: %w", err)
+	}
+
+	var parsed struct {
+		Data  enrollResult `json:"data"`
+		Error string       `json:"error"`
+	}
+	_ = json.Unmarshal(data, &parsed)
+	if resp.StatusCode != http.StatusOK {
+		if parsed.Error != "" {
+			return enrollResult{}, fmt.Errorf("%s (HTTP %d)", parsed.Error, resp.StatusCode)
+		}
+		return enrollResult{}, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return parsed.Data, nil
+}
+
+// enrollURL derives the enrollment endpoint from the supervisor WebSocket
+// URL: ws://host:8420 enrolls at http://host:8420/enroll and
+// wss://host/ws/agent at https://host/ws/enroll.
+func enrollURL(supervisorURL string) (string, error) {
+	return supervisorEndpoint(supervisorURL, "enroll")
+}
+
+// supervisorEndpoint derives the URL of an HTTP endpoint served beside the
+// supervisor WebSocket endpoint.
+func supervisorEndpoint(supervisorURL, name string) (string, error) {
+	u, err := url.Parse(supervisorURL)
+	if err != nil {
+		return "", fmt.Errorf("parse supervisor url: %w", err)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("unsupported supervisor url scheme %q", u.Scheme)
+	}
+	u.Path = path.Join(path.Dir("/"+strings.TrimPrefix(u.Path, "/")), name)
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+func writeCredential(credentialPath, credential string) error {
+	if err := os.MkdirAll(filepath.Dir(credentialPath), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(credentialPath, []byte(credential+"\n"), 0o600)
+}
+
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnrollURL(t *testing.T) {
+	tests := map[string]string{
+		"ws://10.0.0.5:8420":                 "http://10.0.0.5:8420/enroll",
+		"ws://10.0.0.5:8420/":                "http://10.0.0.5:8420/enroll",
+		"wss://swarm.example.com/ws/agent":   "https://swarm.example.com/ws/enroll",
+		"ws://host:8421/ws/agent?token=abc":  "http://host:8421/ws/enroll",
+		"https://swarm.example.com/ws/agent": "https://swarm.example.com/ws/enroll",
+	}
+	for in, want := range tests {
+		got, err := enrollURL(in)
+		if err != nil || got != want {
+			t.Errorf("enrollURL(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := enrollURL("ftp://host"); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+	if got, _ := supervisorEndpoint("wss://swarm.example.com:8420", "renew"); got != "https://swarm.example.com:8420/renew" {
+		t.Errorf("renew endpoint = %q", got)
+	}
+}
+
+func TestNodeCredentialsEnrollsOnce(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/enroll" || req["join_token"] != "join-123" || req["node_id"] != "node-a" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid join token","code":"AUTH_REQUIRED"}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"node_id":"node-a","credential":"cred-abc"}}`))
+	}))
+	defer server.Close()
+
+	supervisorURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	path := filepath.Join(t.TempDir(), "state", "node.credential")
+
+	creds := NewNodeCredentials(supervisorURL, "shared", "join-123", path, "node-a", nil)
+	for i := 0; i < 2; i++ {
+		token, err := creds.Token(context.Background())
+		if err != nil || token != "cred-abc" {
+			t.Fatalf("token = %q, %v", token, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected one enrollment, got %d", calls.Load())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("credential not saved: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// After a restart the saved credential is used and the spent join token
+	// is not sent again.
+	restarted := NewNodeCredentials(supervisorURL, "shared", "join-123", path, "node-a", nil)
+	if token, err := restarted.Token(context.Background()); err != nil || token != "cred-abc" {
+		t.Fatalf("token after restart = %q, %v", token, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected no further enrollment, got %d calls", calls.Load())
+	}
+
+	refused := NewNodeCredentials(supervisorURL, "shared", "wrong", filepath.Join(t.TempDir(), "node.credential"), "node-a", nil)
+	if _, err := refused.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid join token") {
+		t.Fatalf("expected the refusal to be reported, got %v", err)
+	}
+}
+
+func TestNodeCredentialsSharedToken(t *testing.T) {
+	creds := NewNodeCredentials("ws://127.0.0.1:1", "shared", "", filepath.Join(t.TempDir(), "node.credential"), "node-a", nil)
+	if token, err := creds.Token(context.Background()); err != nil || token != "shared" {
+		t.Fatalf("token = %q, %v", token, err)
+	}
+}
@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	caCertFileName   = "ca.pem"
+	nodeCertFileName = "node.pem"
+)
+
+// NodeTLS holds the node certificate an agent presents for mTLS and the
+// supervisor CA it pins. Before a CA is pinned the supervisor's chain must
+// contain the CA with the configured fingerprint; with neither, the system
+// roots are trusted as for any TLS server.
+type NodeTLS struct {
+	dir         string
+	fingerprint string
+	logger      *zap.Logger
+
+	mu   sync.RWMutex
+	ca   *x509.Certificate
+	cert *tls.Certificate
+}
+
+// NewNodeTLS loads a pinned CA and node certificate saved in dir, if any.
+func NewNodeTLS(dir, caFingerprint string, logger *zap.Logger) (*NodeTLS, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	t := &NodeTLS{dir: dir, fingerprint: caFingerprint, logger: logger}
+
+	caPEM, err := os.ReadFile(filepath.Join(dir, caCertFileName))
+	switch {
+	case err == nil:
+		if t.ca, err = parseCertificatePEM(caPEM); err != nil {
+			return nil, fmt.Errorf("load pinned CA: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("load pinned CA: %w", err)
+	}
+
+	nodePEM, err := os.ReadFile(filepath.Join(dir, nodeCertFileName))
+	switch {
+	case err == nil:
+		cert, err := tls.X509KeyPair(nodePEM, nodePEM)
+		if err != nil {
+			return nil, fmt.Errorf("load node certificate: %w", err)
+		}
+		t.cert = &cert
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("load node certificate: %w", err)
+	}
+	return t, nil
+}
+
+// ClientConfig returns a TLS config that presents the current node
+// certificate and verifies the supervisor against the pinned CA. Both are
+// read at each handshake, so a renewed certificate is used on reconnect.
+func (t *NodeTLS) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The chain is verified in VerifyConnection against the pinned CA
+		// instead of the system roots.
+		InsecureSkipVerify:   true,
+		VerifyConnection:     t.verifyServer,
+		GetClientCertificate: t.clientCertificate,
+	}
+}
+
+func (t *NodeTLS) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("supervisor sent no certificate")
+	}
+
+	t.mu.RLock()
+	pinned := t.ca
+	t.mu.RUnlock()
+
+	var roots *x509.CertPool
+	switch {
+	case pinned != nil:
+		roots = x509.NewCertPool()
+		roots.AddCert(pinned)
+	case t.fingerprint != "":
+		for _, cert := range cs.PeerCertificates {
+			if certificateFingerprint(cert) == t.fingerprint && cert.IsCA {
+				roots = x509.NewCertPool()
+				roots.AddCert(cert)
+				break
+			}
+		}
+		if roots == nil {
+			return fmt.Errorf("supervisor CA does not match ca_fingerprint")
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       cs.ServerName,
+	})
+	if err != nil {
+		return fmt.Errorf("verify supervisor certificate: %w", err)
+	}
+	return nil
+}
+
+func (t *NodeTLS) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return t.cert, nil
+}
+
+// HasCertificate reports whether a node certificate has been issued.
+func (t *NodeTLS) HasCertificate() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cert != nil
+}
+
+// NeedsRenewal reports whether the node certificate is in the last third of
+// its validity.
+func (t *NodeTLS) NeedsRenewal(now time.Time) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.cert == nil || t.cert.Leaf == nil {
+		return false
+	}
+	leaf := t.cert.Leaf
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	return now.After(leaf.NotAfter.Add(-lifetime / 3))
+}
+
+// NewCSR generates a key pair for nodeID, returning a certificate request
+// for the supervisor to sign and the PEM-encoded private key.
+func (t *NodeTLS) NewCSR(nodeID string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate node key: %w", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: nodeID},
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create csr: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode node key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
+
+// Install checks a certificate issued for keyPEM against the supervisor CA,
+// saves both and pins the CA. A pinned CA cannot be replaced.
+func (t *NodeTLS) Install(certPEM, keyPEM, caPEM []byte) error {
+	ca, err := parseCertificatePEM(caPEM)
+	if err != nil {
+		return fmt.Errorf("parse supervisor CA: %w", err)
+	}
+
+	t.mu.RLock()
+	pinned := t.ca
+	t.mu.RUnlock()
+	switch {
+	case pinned != nil && !pinned.Equal(ca):
+		return fmt.Errorf("supervisor CA differs from the pinned CA")
+	case pinned == nil && t.fingerprint != "" && certificateFingerprint(ca) != t.fingerprint:
+		return fmt.Errorf("supervisor CA does not match ca_fingerprint")
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("node certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("node certificate: %w", err)
+	}
+
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return fmt.Errorf("save node certificate: %w", err)
+	}
+	if pinned == nil {
+		if err := writeFileAtomic(filepath.Join(t.dir, caCertFileName), caPEM, 0o644); err != nil {
+			return fmt.Errorf("save supervisor CA: %w", err)
+		}
+	}
+	// The key and certificate share one file so they are replaced together.
+	nodePEM := append(append([]byte{}, certPEM...), keyPEM...)
+	if err := writeFileAtomic(filepath.Join(t.dir, nodeCertFileName), nodePEM, 0o600); err != nil {
+		return fmt.Errorf("save node certificate: %w", err)
+	}
+
+	t.mu.Lock()
+	t.ca = ca
+	t.cert = &cert
+	t.mu.Unlock()
+	t.logger.Info("node certificate installed", zap.Time("not_after", cert.Leaf.NotAfter))
+	return nil
+}
+
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("not a PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func certificateFingerprint(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(digest[:])
+}
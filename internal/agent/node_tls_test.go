@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *testCA) sign(t *testing.T, csrPEM []byte, notBefore, notAfter time.Time) []byte {
+	t.Helper()
+	block, _ := pem.Decode(csrPEM)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatalf("parse csr: %v", err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("sign csr: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNodeTLSInstall(t *testing.T) {
+	ca := newTestCA(t)
+	dir := filepath.Join(t.TempDir(), "state")
+
+	nodeTLS, err := NewNodeTLS(dir, certificateFingerprint(ca.cert), nil)
+	if err != nil {
+		t.Fatalf("new node tls: %v", err)
+	}
+	if nodeTLS.HasCertificate() || nodeTLS.NeedsRenewal(time.Now()) {
+		t.Fatal("expected no certificate before enrollment")
+	}
+
+	csrPEM, keyPEM, err := nodeTLS.NewCSR("node-a")
+	if err != nil {
+		t.Fatalf("csr: %v", err)
+	}
+	now := time.Now()
+	certPEM := ca.sign(t, csrPEM, now.Add(-time.Minute), now.Add(30*time.Hour))
+	if err := nodeTLS.Install(certPEM, keyPEM, ca.pem); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if !nodeTLS.HasCertificate() {
+		t.Fatal("expected the certificate to be installed")
+	}
+	if nodeTLS.NeedsRenewal(now) || !nodeTLS.NeedsRenewal(now.Add(21*time.Hour)) {
+		t.Fatal("expected renewal only in the last third of the validity")
+	}
+
+	info, err := os.Stat(filepath.Join(dir, nodeCertFileName))
+	if err != nil {
+		t.Fatalf("node certificate not saved: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := NewNodeTLS(dir, "", nil)
+	if err != nil || !reloaded.HasCertificate() {
+		t.Fatalf("reload: %v", err)
+	}
+
+	// A different CA cannot replace the pinned one.
+	other := newTestCA(t)
+	csrPEM, keyPEM, _ = reloaded.NewCSR("node-a")
+	err = reloaded.Install(other.sign(t, csrPEM, now, now.Add(time.Hour)), keyPEM, other.pem)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Fatalf("expected the pinned CA to be kept, got %v", err)
+	}
+}
+
+func TestNodeTLSInstallChecksFingerprint(t *testing.T) {
+	ca := newTestCA(t)
+	nodeTLS, _ := NewNodeTLS(t.TempDir(), strings.Repeat("0", 64), nil)
+
+	csrPEM, keyPEM, _ := nodeTLS.NewCSR("node-a")
+	certPEM := ca.sign(t, csrPEM, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	if err := nodeTLS.Install(certPEM, keyPEM, ca.pem); err == nil || !strings.Contains(err.Error(), "ca_fingerprint") {
+		t.Fatalf("expected a fingerprint mismatch, got %v", err)
+	}
+
+	// A certificate that does not match the key is refused as well.
+	nodeTLS, _ = NewNodeTLS(t.TempDir(), "", nil)
+	_, otherKey, _ := nodeTLS.NewCSR("node-a")
+	if err := nodeTLS.Install(certPEM, otherKey, ca.pem); err == nil {
+		t.Fatal("expected a mismatched key to be refused")
+	}
+	if nodeTLS.HasCertificate() {
+		t.Fatal("a refused certificate must not be installed")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/sst/opencode-sdk-go"
@@ -45,6 +46,7 @@ type OpencodeAdapter interface {
 	CreateSession(ctx context.Context, project, prompt string) (SessionID, error)
 	PromptSession(ctx context.Context, sessionID SessionID, message string) error
 	KillSession(ctx context.Context, sessionID SessionID) error
+	CompactSession(ctx context.Context, sessionID SessionID, providerID, modelID string) error
 	SessionStatus(ctx context.Context, sessionID SessionID) (SessionStatus, error)
 	SubscribeEvents(ctx context.Context) (<-chan Event, error)
 }
@@ -62,6 +64,8 @@ type sessionService interface {
 	Abort(ctx context.Context, id string, body opencode.SessionAbortParams, opts ...option.RequestOption) (*bool, error)
 	Delete(ctx context.Context, id string, body opencode.SessionDeleteParams, opts ...option.RequestOption) (*bool, error)
 	Get(ctx context.Context, id string, query opencode.SessionGetParams, opts ...option.RequestOption) (*opencode.Session, error)
+	Messages(ctx context.Context, id string, query opencode.SessionMessagesParams, opts ...option.RequestOption) (*[]opencode.SessionMessagesResponse, error)
+	Summarize(ctx context.Context, id string, params opencode.SessionSummarizeParams, opts ...option.RequestOption) (*bool, error)
 }
 
 type eventStream interface {
@@ -198,6 +202,24 @@ func (a *RealAdapter) ListSessions(ctx context.Context) ([]SessionInfo, error) {
 }
 
 func (a *RealAdapter) CreateSession(ctx context.Context, project, prompt string) (SessionID, error) {
+	return a.CreateSessionWithModel(ctx, project, prompt, "")
+}
+
+// CreateSessionWithModel creates a session whose first prompt runs on model,
+// given as "provider/model". An empty model uses opencode's default.
+func (a *RealAdapter) CreateSessionWithModel(ctx context.Context, project, prompt, model string) (SessionID, error) {
+	var promptModel *opencode.SessionPromptParamsModel
+	if model != "" {
+		providerID, modelID, ok := strings.Cut(model, "/")
+		if !ok || providerID == "" || modelID == "" {
+			return "", fmt.Errorf("model %q must be provider/model", model)
+		}
+		promptModel = &opencode.SessionPromptParamsModel{
+			ProviderID: opencode.F(providerID),
+			ModelID:    opencode.F(modelID),
+		}
+	}
+
 	client, directory, err := a.clientForProject(project)
 	if err != nil {
 		return "", err
@@ -212,7 +234,7 @@ func (a *RealAdapter) CreateSession(ctx context.Context, project, prompt string)
 	a.recordSession(sessionID, project, SessionStatusRunning)
 
 	if prompt != "" {
-		if err := a.PromptSession(ctx, sessionID, prompt); err != nil {
+		if err := a.prompt(ctx, sessionID, prompt, promptModel); err != nil {
 			return "", err
 		}
 	}
@@ -221,12 +243,16 @@ func (a *RealAdapter) CreateSession(ctx context.Context, project, prompt string)
 }
 
 func (a *RealAdapter) PromptSession(ctx context.Context, sessionID SessionID, message string) error {
+	return a.prompt(ctx, sessionID, message, nil)
+}
+
+func (a *RealAdapter) prompt(ctx context.Context, sessionID SessionID, message string, model *opencode.SessionPromptParamsModel) error {
 	project, client, directory, err := a.clientForSession(sessionID)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.SessionService().Prompt(ctx, string(sessionID), opencode.SessionPromptParams{
+	params := opencode.SessionPromptParams{
 		Directory: opencode.F(directory),
 		Parts: opencode.F([]opencode.SessionPromptParamsPartUnion{
 			opencode.TextPartInputParam{
@@ -234,8 +260,12 @@ func (a *RealAdapter) PromptSession(ctx context.Context, sessionID SessionID, me
 				Text: opencode.F(message),
 			},
 		}),
-	})
-	if err != nil {
+	}
+	if model != nil {
+		params.Model = opencode.F(*model)
+	}
+
+	if _, err := client.SessionService().Prompt(ctx, string(sessionID), params); err != nil {
 		return mapAdapterError(err)
 	}
 
@@ -264,6 +294,46 @@ func (a *RealAdapter) KillSession(ctx context.Context, sessionID SessionID) erro
 	return nil
 }
 
+// CompactSession summarizes the session through opencode so its history is
+// replaced by a summary. Without providerID and modelID it uses the model of
+// the session's most recent assistant message.
+func (a *RealAdapter) CompactSession(ctx context.Context, sessionID SessionID, providerID, modelID string) error {
+	project, client, directory, err := a.clientForSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if providerID == "" || modelID == "" {
+		messages, err := client.SessionService().Messages(ctx, string(sessionID), opencode.SessionMessagesParams{Directory: opencode.F(directory)})
+		if err != nil {
+			return mapAdapterError(err)
+		}
+		if messages != nil {
+			for i := len(*messages) - 1; i >= 0; i-- {
+				info := (*messages)[i].Info
+				if info.ProviderID != "" && info.ModelID != "" {
+					providerID, modelID = info.ProviderID, info.ModelID
+					break
+				}
+			}
+		}
+		if providerID == "" || modelID == "" {
+			return fmt.Errorf("%w: no model found for session %s", ErrNonRecoverable, sessionID)
+		}
+	}
+
+	if _, err := client.SessionService().Summarize(ctx, string(sessionID), opencode.SessionSummarizeParams{
+		ProviderID: opencode.F(providerID),
+		ModelID:    opencode.F(modelID),
+		Directory:  opencode.F(directory),
+	}); err != nil {
+		return mapAdapterError(err)
+	}
+
+	a.recordSession(sessionID, project, SessionStatusCompacted)
+	return nil
+}
+
 func (a *RealAdapter) SessionStatus(ctx context.Context, sessionID SessionID) (SessionStatus, error) {
 	project, client, directory, err := a.clientForSession(sessionID)
 	if err != nil {
@@ -2,7 +2,10 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -85,3 +88,89 @@ func TestOpencodeAdapterSSEDisconnect(t *testing.T) {
 		t.Fatalf("expected ErrRecoverable, got %v", err)
 	}
 }
+
+func TestRealAdapterCompactSessionUsesLatestModel(t *testing.T) {
+	var summarizeBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/session/ses-1/message":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[
+				{"info": {"id": "m1", "role": "user", "sessionID": "ses-1", "time": {"created": 1}}, "parts": []},
+				{"info": {"id": "m2", "role": "assistant", "sessionID": "ses-1", "providerID": "anthropic", "modelID": "claude-sonnet-4", "time": {"created": 2}}, "parts": []},
+				{"info": {"id": "m3", "role": "user", "sessionID": "ses-1", "time": {"created": 3}}, "parts": []}
+			]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/session/ses-1/summarize":
+			if err := json.NewDecoder(r.Body).Decode(&summarizeBody); err != nil {
+				t.Errorf("decode summarize body: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`true`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adapter := NewOpencodeAdapter(server.URL, "")
+	adapter.RegisterProjectClient("proj-a", "/work/proj-a", server.URL)
+	adapter.recordSession("ses-1", "proj-a", SessionStatusRunning)
+
+	if err := adapter.CompactSession(context.Background(), "ses-1", "", ""); err != nil {
+		t.Fatalf("CompactSession failed: %v", err)
+	}
+	if summarizeBody["providerID"] != "anthropic" || summarizeBody["modelID"] != "claude-sonnet-4" {
+		t.Fatalf("expected latest assistant model in summarize request, got %v", summarizeBody)
+	}
+
+	adapter.mu.RLock()
+	status := adapter.sessionStatuses["ses-1"]
+	adapter.mu.RUnlock()
+	if status != SessionStatusCompacted {
+		t.Fatalf("expected compacted status, got %q", status)
+	}
+}
+
+func TestRealAdapterCreateSessionWithModel(t *testing.T) {
+	var promptBody struct {
+		Model struct {
+			ProviderID string `json:"providerID"`
+			ModelID    string `json:"modelID"`
+		} `json:"model"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/session":
+			w.Write([]byte(`{"id": "ses-1", "directory": "/work/proj-a", "projectID": "p", "title": "t", "version": "1", "time": {"created": 1, "updated": 1}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/session/ses-1/message":
+			if err := json.NewDecoder(r.Body).Decode(&promptBody); err != nil {
+				t.Errorf("decode prompt body: %v", err)
+			}
+			w.Write([]byte(`{"info": {}, "parts": []}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adapter := NewOpencodeAdapter(server.URL, "")
+	adapter.RegisterProjectClient("proj-a", "/work/proj-a", server.URL)
+
+	if _, err := adapter.CreateSessionWithModel(context.Background(), "proj-a", "start", "claude-sonnet-4"); err == nil {
+		t.Fatal("expected error for model without provider")
+	}
+
+	sessionID, err := adapter.CreateSessionWithModel(context.Background(), "proj-a", "start", "anthropic/claude-sonnet-4")
+	if err != nil {
+		t.Fatalf("CreateSessionWithModel failed: %v", err)
+	}
+	if sessionID != "ses-1" {
+		t.Fatalf("unexpected session id %q", sessionID)
+	}
+	if promptBody.Model.ProviderID != "anthropic" || promptBody.Model.ModelID != "claude-sonnet-4" {
+		t.Fatalf("expected requested model in prompt, got %+v", promptBody.Model)
+	}
+}
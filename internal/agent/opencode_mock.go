@@ -120,6 +120,22 @@ func (m *MockOpencodeAdapter) KillSession(ctx context.Context, sessionID Session
 	return nil
 }
 
+func (m *MockOpencodeAdapter) CompactSession(ctx context.Context, sessionID SessionID, providerID, modelID string) error {
+	_ = ctx
+	_ = providerID
+	_ = modelID
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	sess.Status = SessionStatusCompacted
+	m.sessions[sessionID] = sess
+	m.events <- Event{Type: "session.compacted", SessionID: sessionID}
+	return nil
+}
+
 func (m *MockOpencodeAdapter) SessionStatus(ctx context.Context, sessionID SessionID) (SessionStatus, error) {
 	_ = ctx
 	m.mu.RLock()
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Progress event types emitted from a project's .context files.
+const (
+	EventTypeTaskCompleted    = "task.completed"
+	EventTypeTaskUpdated      = "task.updated"
+	EventTypeMilestoneReached = "milestone.reached"
+)
+
+const maxTaskNameLength = 200
+
+var (
+	checklistPattern = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+)$`)
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	milestonePattern = regexp.MustCompile(`(?i)^milestone\b\s*[\w.]*\s*(?:[:\-–—]\s*)?(.*)$`)
+	statusPattern    = regexp.MustCompile(`(?i)^\s*(?:[-*]\s+)?\**status\**\s*:\s*\**\s*(.+?)\s*\**\s*$`)
+	taskLinePattern  = regexp.MustCompile(`(?i)^\s*(?:[-*]\s+)?\**(?:current\s+)?task\**\s*:\s*\**\s*(.+?)\s*\**\s*$`)
+	criteriaPattern  = regexp.MustCompile(`(?i)^(?:acceptance\s+criteria|definition\s+of\s+done)\s*:?$`)
+	doneMarkers      = []string{"✅", "[x]", "[X]", "(done)", "(complete)", "(completed)"}
+)
+
+// ProgressReport is what PROGRESS.md says has been finished.
+type ProgressReport struct {
+	CompletedTasks    []string
+	ReachedMilestones []string
+}
+
+// ParseProgress reads the PROGRESS.md convention:
+//
+//	## Milestone 1: Interfaces
+//	Status: done
+//	- [x] Define HAL interfaces
+//	- [ ] Publish AIDL
+//
+// Checked checklist items are completed tasks. A "Milestone" heading is
+// reached when its section has a Status of done, complete, completed or
+// reached, or when the heading itself carries a ✅, [x] or (done) marker.
+func ParseProgress(data []byte) ProgressReport {
+	report := ProgressReport{}
+	milestone := ""
+	reached := false
+
+	flush := func() {
+		if milestone != "" && reached {
+			report.ReachedMilestones = append(report.ReachedMilestones, milestone)
+		}
+		milestone, reached = "", false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			title, marked := stripDoneMarkers(m[2])
+			if mm := milestonePattern.FindStringSubmatch(title); mm != nil {
+				milestone = strings.TrimSpace(mm[1])
+				if milestone == "" {
+					milestone = title
+				}
+				reached = marked
+			}
+			continue
+		}
+
+		if m := checklistPattern.FindStringSubmatch(line); m != nil {
+			if m[1] != " " {
+				report.CompletedTasks = append(report.CompletedTasks, truncateTaskName(m[2]))
+			}
+			continue
+		}
+
+		if milestone != "" {
+			if m := statusPattern.FindStringSubmatch(line); m != nil {
+				reached = isDoneStatus(m[1])
+			}
+		}
+	}
+	flush()
+
+	return report
+}
+
+// ParseCurrentTask returns the task named in CURRENT_TASK.md: a "Task:" line
+// if there is one, otherwise the first heading, otherwise the first
+// non-empty line.
+func ParseCurrentTask(data []byte) string {
+	heading, first := "", ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if m := taskLinePattern.FindStringSubmatch(line); m != nil {
+			return truncateTaskName(m[1])
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			if heading == "" {
+				heading = m[2]
+			}
+			continue
+		}
+		if first == "" {
+			first = line
+		}
+	}
+
+	if heading != "" {
+		if m := taskLinePattern.FindStringSubmatch(heading); m != nil {
+			return truncateTaskName(m[1])
+		}
+		return truncateTaskName(heading)
+	}
+	return truncateTaskName(first)
+}
+
+// ParseAcceptanceCriteria returns the body of CURRENT_TASK.md's "Acceptance
+// Criteria" (or "Definition of Done") section, up to the next heading of the
+// same or a higher level.
+func ParseAcceptanceCriteria(data []byte) string {
+	level := 0
+	lines := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			if level > 0 && len(m[1]) <= level {
+				break
+			}
+			if level == 0 && criteriaPattern.MatchString(strings.TrimSpace(m[2])) {
+				level = len(m[1])
+				continue
+			}
+		}
+		if level > 0 {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func stripDoneMarkers(title string) (string, bool) {
+	marked := false
+	for _, marker := range doneMarkers {
+		if strings.Contains(title, marker) {
+			title = strings.ReplaceAll(title, marker, "")
+			marked = true
+		}
+	}
+	return strings.TrimSpace(title), marked
+}
+
+func isDoneStatus(status string) bool {
+	status, marked := stripDoneMarkers(status)
+	if marked {
+		return true
+	}
+	switch strings.ToLower(strings.Trim(status, " .*_")) {
+	case "done", "complete", "completed", "reached":
+		return true
+	default:
+		return false
+	}
+}
+
+func truncateTaskName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > maxTaskNameLength {
+		name = strings.TrimSpace(name[:maxTaskNameLength])
+	}
+	return name
+}
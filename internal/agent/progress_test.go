@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
+	"go.uber.org/zap"
+)
+
+const testProgress = `# Progress
+
+## Milestone 1: Interfaces
+Status: done
+- [x] Define HAL interfaces
+- [X] Publish AIDL
+
+## Milestone 2 - Launcher ✅
+- [x] Home screen
+
+## Milestone: ROM
+**Status**: in progress
+- [ ] Build image
+`
+
+func TestParseProgress(t *testing.T) {
+	report := ParseProgress([]byte(testProgress))
+
+	wantTasks := []string{"Define HAL interfaces", "Publish AIDL", "Home screen"}
+	if !reflect.DeepEqual(report.CompletedTasks, wantTasks) {
+		t.Fatalf("unexpected tasks %v", report.CompletedTasks)
+	}
+	wantMilestones := []string{"Interfaces", "Launcher"}
+	if !reflect.DeepEqual(report.ReachedMilestones, wantMilestones) {
+		t.Fatalf("unexpected milestones %v", report.ReachedMilestones)
+	}
+
+	if report := ParseProgress(nil); len(report.CompletedTasks) != 0 || len(report.ReachedMilestones) != 0 {
+		t.Fatalf("expected empty report, got %+v", report)
+	}
+}
+
+func TestParseCurrentTask(t *testing.T) {
+	cases := map[string]string{
+		"# Current Task: Wire the HAL bridge\n\nNotes...":        "Wire the HAL bridge",
+		"# Sprint 4\n\n**Task**: Fix boot loop\n":                "Fix boot loop",
+		"## Implement launcher grid\nStop point: adapter.kt:120": "Implement launcher grid",
+		"\n\nRefactor settings screen\n":                         "Refactor settings screen",
+		"":                                                       "",
+	}
+	for input, want := range cases {
+		if got := ParseCurrentTask([]byte(input)); got != want {
+			t.Errorf("ParseCurrentTask(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseAcceptanceCriteria(t *testing.T) {
+	data := "# Current Task: Wire the HAL bridge\n\n## Acceptance Criteria\n- Boots on device\n- `make test` passes\n\n### Notes\nnested stays\n\n## Stop point\nadapter.kt:120\n"
+	want := "- Boots on device\n- `make test` passes\n\n### Notes\nnested stays"
+	if got := ParseAcceptanceCriteria([]byte(data)); got != want {
+		t.Fatalf("ParseAcceptanceCriteria = %q, want %q", got, want)
+	}
+	if got := ParseAcceptanceCriteria([]byte("# Task\n## Definition of Done:\nShipped\n")); got != "Shipped" {
+		t.Fatalf("expected definition of done section, got %q", got)
+	}
+	if got := ParseAcceptanceCriteria([]byte("# Task\nno criteria\n")); got != "" {
+		t.Fatalf("expected no criteria, got %q", got)
+	}
+}
+
+type progressEventSender struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func (s *progressEventSender) SendEnvelope(env *shared.Envelope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if env.Type != string(shared.MessageTypeEvent) {
+		return errors.New("unexpected envelope type " + env.Type)
+	}
+	var event Event
+	if err := json.Unmarshal(env.Payload, &event); err != nil {
+		return err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *progressEventSender) take() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events
+	s.events = nil
+	return events
+}
+
+func TestProgressWatcherEmitsChanges(t *testing.T) {
+	dir := t.TempDir()
+	contextDir := filepath.Join(dir, ".context")
+	if err := os.MkdirAll(contextDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeContextFile := func(name, content string, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(contextDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		// Distinct mtimes so coarse filesystem clocks still register changes.
+		stamp := time.Now().Add(-age)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatalf("chtimes %s: %v", name, err)
+		}
+	}
+
+	writeContextFile("PROGRESS.md", "## Milestone: Interfaces\n- [x] Define HAL interfaces\n- [ ] Publish AIDL\n", time.Hour)
+	writeContextFile("CURRENT_TASK.md", "# Publish AIDL\n", time.Hour)
+
+	registry, err := NewProjectRegistry([]struct {
+		Name      string `json:"name"`
+		Directory string `json:"directory"`
+	}{{Name: "ai-os-interfaces", Directory: dir}})
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+
+	sender := &progressEventSender{}
+	watcher := NewProgressWatcher(registry, time.Second, sender, zap.NewNop())
+
+	// The first scan only reports the current task.
+	watcher.Poll()
+	events := sender.take()
+	if len(events) != 1 || events[0].Type != EventTypeTaskUpdated {
+		t.Fatalf("expected baseline task.updated, got %+v", events)
+	}
+	var payload ProgressEventPayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil || payload.Project != "ai-os-interfaces" || payload.Task != "Publish AIDL" {
+		t.Fatalf("unexpected payload %s (%v)", events[0].Payload, err)
+	}
+
+	watcher.Poll()
+	if events := sender.take(); len(events) != 0 {
+		t.Fatalf("expected no events for unchanged files, got %+v", events)
+	}
+
+	// Sends that fail leave the state untouched so the next poll retries.
+	writeContextFile("PROGRESS.md", "## Milestone: Interfaces\nStatus: complete\n- [x] Define HAL interfaces\n- [x] Publish AIDL\n", 0)
+	writeContextFile("CURRENT_TASK.md", "# Start L0 bring-up\n", 0)
+	sender.mu.Lock()
+	sender.err = errors.New("not connected")
+	sender.mu.Unlock()
+	watcher.Poll()
+
+	sender.mu.Lock()
+	sender.err = nil
+	sender.mu.Unlock()
+	watcher.Poll()
+
+	events = sender.take()
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	wantTypes := []string{EventTypeTaskCompleted, EventTypeMilestoneReached, EventTypeTaskUpdated}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("unexpected event types %v", types)
+	}
+	if err := json.Unmarshal(events[1].Payload, &payload); err != nil || payload.Milestone != "Interfaces" || payload.CurrentTask != "Start L0 bring-up" {
+		t.Fatalf("unexpected milestone payload %s (%v)", events[1].Payload, err)
+	}
+
+	// New acceptance criteria for the same task are reported too.
+	writeContextFile("CURRENT_TASK.md", "# Start L0 bring-up\n## Acceptance Criteria\n- Kernel boots\n", -time.Minute)
+	watcher.Poll()
+	events = sender.take()
+	if len(events) != 1 || events[0].Type != EventTypeTaskUpdated {
+		t.Fatalf("expected task.updated for new criteria, got %+v", events)
+	}
+	payload = ProgressEventPayload{}
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil || payload.AcceptanceCriteria != "- Kernel boots" {
+		t.Fatalf("unexpected criteria payload %s (%v)", events[0].Payload, err)
+	}
+}
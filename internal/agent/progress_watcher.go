@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
+	"go.uber.org/zap"
+)
+
+const defaultProgressPollInterval = 10 * time.Second
+
+// ProgressEventPayload is the payload of task and milestone events.
+type ProgressEventPayload struct {
+	Project     string `json:"project"`
+	Task        string `json:"task,omitempty"`
+	Milestone   string `json:"milestone,omitempty"`
+	CurrentTask string `json:"current_task,omitempty"`
+
+	// AcceptanceCriteria is only set on task.updated events.
+	AcceptanceCriteria string `json:"acceptance_criteria,omitempty"`
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type progressState struct {
+	progress    fileStamp
+	currentTask fileStamp
+
+	task       string
+	criteria   string
+	completed  map[string]bool
+	milestones map[string]bool
+}
+
+// ProgressWatcher polls each project's .context/PROGRESS.md and
+// CURRENT_TASK.md and emits task and milestone events when they change.
+// The first scan of a project only records a baseline, so restarting the
+// agent does not replay work finished earlier; the current task is still
+// reported so the supervisor learns it.
+type ProgressWatcher struct {
+	registry *ProjectRegistry
+	interval time.Duration
+	sender   envelopeSender
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	states map[string]*progressState
+}
+
+func NewProgressWatcher(registry *ProjectRegistry, interval time.Duration, sender envelopeSender, logger *zap.Logger) *ProgressWatcher {
+	if interval <= 0 {
+		interval = defaultProgressPollInterval
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ProgressWatcher{
+		registry: registry,
+		interval: interval,
+		sender:   sender,
+		logger:   logger,
+		states:   make(map[string]*progressState),
+	}
+}
+
+func (w *ProgressWatcher) Start(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	w.Poll()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Poll()
+		}
+	}
+}
+
+// Poll scans every project once.
+func (w *ProgressWatcher) Poll() {
+	if w.registry == nil {
+		return
+	}
+	for _, project := range w.registry.ListProjects() {
+		if err := w.scanProject(project); err != nil {
+			w.logger.Debug("progress scan failed",
+				zap.String("project", project.Name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// scanProject emits events for what changed since the last successful scan.
+// State only advances once every event was sent, so events that could not be
+// delivered are retried on the next poll.
+func (w *ProgressWatcher) scanProject(project *ProjectInfo) error {
+	contextDir := filepath.Join(project.Directory, ".context")
+	progressData, progressStamp, err := readStampedFile(filepath.Join(contextDir, "PROGRESS.md"))
+	if err != nil {
+		return err
+	}
+	taskData, taskStamp, err := readStampedFile(filepath.Join(contextDir, "CURRENT_TASK.md"))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous, initialized := w.states[project.Name]
+	if initialized && previous.progress == progressStamp && previous.currentTask == taskStamp {
+		return nil
+	}
+
+	report := ParseProgress(progressData)
+	next := &progressState{
+		progress:    progressStamp,
+		currentTask: taskStamp,
+		task:        ParseCurrentTask(taskData),
+		criteria:    ParseAcceptanceCriteria(taskData),
+		completed:   toSet(report.CompletedTasks),
+		milestones:  toSet(report.ReachedMilestones),
+	}
+
+	events := make([]Event, 0)
+	if initialized {
+		for _, task := range report.CompletedTasks {
+			if !previous.completed[task] {
+				events = append(events, progressEvent(EventTypeTaskCompleted, ProgressEventPayload{Project: project.Name, Task: task, CurrentTask: next.task}))
+			}
+		}
+		for _, milestone := range report.ReachedMilestones {
+			if !previous.milestones[milestone] {
+				events = append(events, progressEvent(EventTypeMilestoneReached, ProgressEventPayload{Project: project.Name, Milestone: milestone, CurrentTask: next.task}))
+			}
+		}
+	}
+	if next.task != "" && (!initialized || next.task != previous.task || next.criteria != previous.criteria) {
+		events = append(events, progressEvent(EventTypeTaskUpdated, ProgressEventPayload{
+			Project:            project.Name,
+			Task:               next.task,
+			CurrentTask:        next.task,
+			AcceptanceCriteria: next.criteria,
+		}))
+	}
+
+	for _, event := range events {
+		if err := w.send(event); err != nil {
+			return fmt.Errorf("send %s: %w", event.Type, err)
+		}
+	}
+
+	w.states[project.Name] = next
+	return nil
+}
+
+func (w *ProgressWatcher) send(event Event) error {
+	if w.sender == nil {
+		return fmt.Errorf("progress event sender is required")
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal progress event: %w", err)
+	}
+
+	return w.sender.SendEnvelope(&shared.Envelope{
+		Version:   shared.ProtocolVersion,
+		Type:      string(shared.MessageTypeEvent),
+		Timestamp: time.Now().UTC().Unix(),
+		Payload:   payload,
+	})
+}
+
+func progressEvent(eventType string, payload ProgressEventPayload) Event {
+	data, _ := json.Marshal(payload)
+	return Event{Type: eventType, Payload: data}
+}
+
+// readStampedFile reads path, treating a missing file as empty.
+func readStampedFile(path string) ([]byte, fileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fileStamp{}, nil
+	}
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	return data, fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -58,6 +58,7 @@ func RegisterSessionCommandHandlers(client *WSClient, adapter OpencodeAdapter, l
 	client.RegisterCommandHandler("prompt_session", handler)
 	client.RegisterCommandHandler("kill_session", handler)
 	client.RegisterCommandHandler("restart_session", handler)
+	client.RegisterCommandHandler("compact_session", handler)
 	client.RegisterCommandHandler("session_status", handler)
 	client.RegisterCommandHandler("env_check", handler)
 	client.RegisterCommandHandler("env_provision", handler)
@@ -120,6 +121,12 @@ func HandleSessionCommand(adapter OpencodeAdapter, sender commandResultSender, l
 	}
 }
 
+// modelSessionCreator is implemented by adapters that can start a session on
+// a requested model.
+type modelSessionCreator interface {
+	CreateSessionWithModel(ctx context.Context, project, prompt, model string) (SessionID, error)
+}
+
 func executeSessionCommand(ctx context.Context, adapter OpencodeAdapter, cmd sessionCommand, result *sessionCommandResult) error {
 	if result == nil {
 		return fmt.Errorf("command result is required")
@@ -128,7 +135,19 @@ func executeSessionCommand(ctx context.Context, adapter OpencodeAdapter, cmd ses
 	switch cmd.Type {
 	case "create_session":
 		prompt := readStringArg(cmd.Args, "prompt")
-		sessionID, err := adapter.CreateSession(ctx, cmd.Target.Project, prompt)
+		var (
+			sessionID SessionID
+			err       error
+		)
+		if model := readStringArg(cmd.Args, "model"); model != "" {
+			creator, ok := adapter.(modelSessionCreator)
+			if !ok {
+				return fmt.Errorf("model selection is not supported by this adapter")
+			}
+			sessionID, err = creator.CreateSessionWithModel(ctx, cmd.Target.Project, prompt, model)
+		} else {
+			sessionID, err = adapter.CreateSession(ctx, cmd.Target.Project, prompt)
+		}
 		if err != nil {
 			return err
 		}
@@ -150,6 +169,12 @@ func executeSessionCommand(ctx context.Context, adapter OpencodeAdapter, cmd ses
 			return fmt.Errorf("session_id is required")
 		}
 		return adapter.KillSession(ctx, sessionID)
+	case "compact_session":
+		sessionID := SessionID(readStringArg(cmd.Args, "session_id"))
+		if sessionID == "" {
+			return fmt.Errorf("session_id is required")
+		}
+		return adapter.CompactSession(ctx, sessionID, readStringArg(cmd.Args, "provider_id"), readStringArg(cmd.Args, "model_id"))
 	case "session_status":
 		sessionID := SessionID(readStringArg(cmd.Args, "session_id"))
 		if sessionID == "" {
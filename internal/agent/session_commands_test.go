@@ -88,6 +88,25 @@ func TestHandleSessionCommandLifecycle(t *testing.T) {
 		t.Fatalf("expected status output %q, got %q", SessionStatusRunning, statusResult.Output)
 	}
 
+	compact := makeCommandEnvelope(t, map[string]interface{}{
+		"command_id": "cmd-compact",
+		"type":       "compact_session",
+		"target":     map[string]interface{}{"project": "proj-a"},
+		"args": map[string]interface{}{
+			"session_id": createResult.Output,
+		},
+	})
+	if err := handler(ctx, compact); err != nil {
+		t.Fatalf("compact_session handler returned error: %v", err)
+	}
+	compactResult := decodeCommandResult(t, sender.lastEnvelope(t))
+	if compactResult.Status != commandStatusSuccess {
+		t.Fatalf("expected compact_session success, got %s (%s)", compactResult.Status, compactResult.Error)
+	}
+	if got, _ := adapter.SessionStatus(ctx, SessionID(createResult.Output)); got != SessionStatusCompacted {
+		t.Fatalf("expected compacted session, got %q", got)
+	}
+
 	restart := makeCommandEnvelope(t, map[string]interface{}{
 		"command_id": "cmd-restart",
 		"type":       "restart_session",
@@ -180,3 +199,23 @@ func decodeCommandResult(t *testing.T, env *shared.Envelope) sessionCommandResul
 	}
 	return result
 }
+
+func TestHandleSessionCommandRejectsModelWithoutSupport(t *testing.T) {
+	adapter := NewMockOpencodeAdapter()
+	sender := &captureCommandResultSender{}
+	handler := HandleSessionCommand(adapter, sender, zap.NewNop())
+
+	env := makeCommandEnvelope(t, map[string]interface{}{
+		"command_id": "cmd-create-model",
+		"type":       "create_session",
+		"target":     map[string]interface{}{"project": "proj-a"},
+		"args":       map[string]interface{}{"prompt": "bootstrap", "model": "anthropic/claude-sonnet-4"},
+	})
+	if err := handler(context.Background(), env); err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	result := decodeCommandResult(t, sender.lastEnvelope(t))
+	if result.Status != commandStatusFailure {
+		t.Fatalf("expected failure for unsupported model selection, got %s", result.Status)
+	}
+}
@@ -0,0 +1,344 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
+	"go.uber.org/zap"
+)
+
+// Version is the agent version reported in the node inventory. Release builds
+// set it with -ldflags "-X github.com/Bldg-7/hal-o-swarm/internal/agent.Version=<tag>".
+var Version = "dev"
+
+const (
+	defaultTelemetryInterval = 30 * time.Second
+	defaultInventoryInterval = time.Hour
+	toolVersionTimeout       = 5 * time.Second
+)
+
+// ToolProbe names a tool and the command that prints its version.
+type ToolProbe struct {
+	Name    string
+	Command []string
+}
+
+// DefaultToolProbes returns the version probes for the coding tools, using
+// the binaries their status commands resolve to, and for git and the common
+// runtimes.
+func DefaultToolProbes(opencodeStatus, claudeStatus, codexStatus []string) []ToolProbe {
+	probes := make([]ToolProbe, 0, 7)
+	for _, tool := range []struct {
+		name   string
+		status []string
+	}{
+		{"opencode", opencodeStatus},
+		{"claude", claudeStatus},
+		{"codex", codexStatus},
+	} {
+		if len(tool.status) > 0 {
+			probes = append(probes, ToolProbe{Name: tool.name, Command: []string{tool.status[0], "--version"}})
+		}
+	}
+	return append(probes,
+		ToolProbe{Name: "git", Command: []string{"git", "--version"}},
+		ToolProbe{Name: "node", Command: []string{"node", "--version"}},
+		ToolProbe{Name: "python3", Command: []string{"python3", "--version"}},
+		ToolProbe{Name: "go", Command: []string{"go", "version"}},
+	)
+}
+
+// ProjectDir is a project directory whose filesystem's free space is
+// reported.
+type ProjectDir struct {
+	Project   string
+	Directory string
+}
+
+// TelemetryCollector gathers the node inventory and resource usage. Usage is
+// read from /proc, so CPU, load and memory stay zero on other platforms.
+type TelemetryCollector struct {
+	runner    CommandRunner
+	probes    []ToolProbe
+	projects  []ProjectDir
+	procRoot  string
+	osRelease string
+
+	mu      sync.Mutex
+	prevCPU cpuSample
+}
+
+type cpuSample struct {
+	idle  uint64
+	total uint64
+}
+
+func NewTelemetryCollector(runner CommandRunner, probes []ToolProbe, projects []ProjectDir) *TelemetryCollector {
+	if runner == nil {
+		runner = &ExecCommandRunner{}
+	}
+	return &TelemetryCollector{
+		runner:    runner,
+		probes:    probes,
+		projects:  projects,
+		procRoot:  "/proc",
+		osRelease: "/etc/os-release",
+	}
+}
+
+// Inventory describes the platform and probes each tool's version. Tools that
+// fail to run are left out.
+func (c *TelemetryCollector) Inventory(ctx context.Context) shared.NodeInventory {
+	inventory := shared.NodeInventory{
+		OS:           c.osName(),
+		Kernel:       c.kernel(ctx),
+		Arch:         runtime.GOARCH,
+		CPUCount:     runtime.NumCPU(),
+		AgentVersion: Version,
+		Tools:        make(map[string]string),
+	}
+	for _, probe := range c.probes {
+		if len(probe.Command) == 0 {
+			continue
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+		out, err := c.runner.Run(probeCtx, probe.Command[0], probe.Command[1:]...)
+		cancel()
+		if err != nil || out == "" {
+			continue
+		}
+		inventory.Tools[probe.Name] = parseVersionString(out)
+	}
+	return inventory
+}
+
+// Sample reads current resource usage. CPU usage is measured since the
+// previous sample, so the first one reports zero.
+func (c *TelemetryCollector) Sample() shared.HeartbeatPayload {
+	var payload shared.HeartbeatPayload
+
+	if sample, ok := c.readCPU(); ok {
+		c.mu.Lock()
+		prev := c.prevCPU
+		c.prevCPU = sample
+		c.mu.Unlock()
+		if prev.total > 0 && sample.total > prev.total {
+			busy := float64((sample.total - prev.total) - (sample.idle - prev.idle))
+			payload.CPUPercent = 100 * busy / float64(sample.total-prev.total)
+		}
+	}
+
+	if fields := c.readFields("loadavg"); len(fields) >= 3 {
+		payload.Load1, _ = strconv.ParseFloat(fields[0], 64)
+		payload.Load5, _ = strconv.ParseFloat(fields[1], 64)
+		payload.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	}
+
+	if total, available, ok := c.readMemory(); ok {
+		payload.MemoryTotalBytes = total
+		payload.MemoryAvailableBytes = available
+		if total > 0 {
+			payload.MemoryPercent = 100 * float64(total-available) / float64(total)
+		}
+	}
+
+	for _, project := range c.projects {
+		free, total, err := diskSpace(project.Directory)
+		if err != nil {
+			continue
+		}
+		payload.Disks = append(payload.Disks, shared.DiskUsage{
+			Project:    project.Project,
+			Path:       project.Directory,
+			FreeBytes:  free,
+			TotalBytes: total,
+		})
+	}
+
+	return payload
+}
+
+func (c *TelemetryCollector) osName() string {
+	data, err := os.ReadFile(c.osRelease)
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if value, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+				return strings.Trim(value, `"`)
+			}
+		}
+	}
+	return runtime.GOOS
+}
+
+func (c *TelemetryCollector) kernel(ctx context.Context) string {
+	if data, err := os.ReadFile(filepath.Join(c.procRoot, "sys", "kernel", "osrelease")); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	out, err := c.runner.Run(ctx, "uname", "-r")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+func (c *TelemetryCollector) readFields(name string) []string {
+	data, err := os.ReadFile(filepath.Join(c.procRoot, name))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// readCPU sums the aggregate "cpu" line of /proc/stat; idle includes iowait.
+func (c *TelemetryCollector) readCPU() (cpuSample, bool) {
+	file, err := os.Open(filepath.Join(c.procRoot, "stat"))
+	if err != nil {
+		return cpuSample{}, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return cpuSample{}, false
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuSample{}, false
+	}
+
+	var sample cpuSample
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuSample{}, false
+		}
+		sample.total += value
+		if i == 3 || i == 4 {
+			sample.idle += value
+		}
+	}
+	return sample, true
+}
+
+func (c *TelemetryCollector) readMemory() (total, available uint64, ok bool) {
+	data, err := os.ReadFile(filepath.Join(c.procRoot, "meminfo"))
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+		}
+	}
+	return total, available, total > 0
+}
+
+// TelemetryReporter sends a heartbeat with the node's resource usage and
+// inventory every interval. The inventory is probed again every
+// inventoryInterval, since probing runs each tool.
+type TelemetryReporter struct {
+	collector         *TelemetryCollector
+	interval          time.Duration
+	inventoryInterval time.Duration
+	sender            envelopeSender
+	logger            *zap.Logger
+
+	mu          sync.Mutex
+	inventory   *shared.NodeInventory
+	inventoryAt time.Time
+}
+
+func NewTelemetryReporter(collector *TelemetryCollector, interval, inventoryInterval time.Duration, sender envelopeSender, logger *zap.Logger) *TelemetryReporter {
+	if interval <= 0 {
+		interval = defaultTelemetryInterval
+	}
+	if inventoryInterval <= 0 {
+		inventoryInterval = defaultInventoryInterval
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &TelemetryReporter{
+		collector:         collector,
+		interval:          interval,
+		inventoryInterval: inventoryInterval,
+		sender:            sender,
+		logger:            logger,
+	}
+}
+
+func (r *TelemetryReporter) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Report(ctx); err != nil {
+				r.logger.Debug("heartbeat not sent", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Inventory returns the node inventory, probing it when it is missing or
+// older than the inventory interval.
+func (r *TelemetryReporter) Inventory(ctx context.Context) *shared.NodeInventory {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.inventory == nil || time.Since(r.inventoryAt) >= r.inventoryInterval {
+		inventory := r.collector.Inventory(ctx)
+		r.inventory = &inventory
+		r.inventoryAt = time.Now()
+	}
+	return r.inventory
+}
+
+// Report sends one heartbeat now.
+func (r *TelemetryReporter) Report(ctx context.Context) error {
+	if r.sender == nil {
+		return fmt.Errorf("telemetry sender is required")
+	}
+
+	payload := r.collector.Sample()
+	payload.Inventory = r.Inventory(ctx)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal heartbeat: %w", err)
+	}
+
+	return r.sender.SendEnvelope(&shared.Envelope{
+		Version:   shared.ProtocolVersion,
+		Type:      string(shared.MessageTypeHeartbeat),
+		Timestamp: time.Now().UTC().Unix(),
+		Payload:   data,
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "errors"
+
+// diskSpace is not supported on this platform.
+func diskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk space is not supported on this platform")
+}
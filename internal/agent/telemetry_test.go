@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
+)
+
+func writeProcFiles(t *testing.T, root, stat string) {
+	t.Helper()
+	writeFile(t, filepath.Join(root, "stat"), stat)
+	writeFile(t, filepath.Join(root, "loadavg"), "1.50 0.75 0.25 2/345 6789\n")
+	writeFile(t, filepath.Join(root, "meminfo"), "MemTotal:       8000000 kB\nMemFree:         500000 kB\nMemAvailable:   2000000 kB\n")
+	writeFile(t, filepath.Join(root, "sys", "kernel", "osrelease"), "6.8.0-45-generic\n")
+}
+
+func newTestCollector(t *testing.T) (*TelemetryCollector, string) {
+	t.Helper()
+	root := t.TempDir()
+	projectDir := t.TempDir()
+	writeProcFiles(t, root, "cpu  100 0 100 800 0 0 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0 0 0\n")
+	writeFile(t, filepath.Join(root, "os-release"), "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\n")
+
+	runner := newMockRunner()
+	runner.responses["git --version"] = "git version 2.43.0"
+	runner.responses["/opt/bin/claude --version"] = "1.0.51 (Claude Code)"
+	runner.responses["go version"] = "go version go1.24.3 linux/amd64"
+
+	probes := DefaultToolProbes(nil, []string{"/opt/bin/claude", "auth", "status"}, nil)
+	collector := NewTelemetryCollector(runner, probes, []ProjectDir{{Project: "proj-a", Directory: projectDir}})
+	collector.procRoot = root
+	collector.osRelease = filepath.Join(root, "os-release")
+	return collector, root
+}
+
+func TestTelemetryCollectorInventory(t *testing.T) {
+	collector, _ := newTestCollector(t)
+
+	inventory := collector.Inventory(context.Background())
+	if inventory.OS != "Ubuntu 24.04.1 LTS" || inventory.Kernel != "6.8.0-45-generic" || inventory.Arch != runtime.GOARCH {
+		t.Fatalf("unexpected platform %+v", inventory)
+	}
+	if inventory.AgentVersion != Version || inventory.CPUCount != runtime.NumCPU() {
+		t.Fatalf("unexpected agent info %+v", inventory)
+	}
+	want := map[string]string{"claude": "1.0.51", "git": "2.43.0", "go": "1.24.3"}
+	if len(inventory.Tools) != len(want) {
+		t.Fatalf("expected tools %v, got %v", want, inventory.Tools)
+	}
+	for tool, version := range want {
+		if inventory.Tools[tool] != version {
+			t.Errorf("expected %s %s, got %q", tool, version, inventory.Tools[tool])
+		}
+	}
+}
+
+func TestTelemetryCollectorSample(t *testing.T) {
+	collector, root := newTestCollector(t)
+
+	first := collector.Sample()
+	if first.CPUPercent != 0 {
+		t.Fatalf("expected no CPU usage from the first sample, got %v", first.CPUPercent)
+	}
+	if first.Load1 != 1.5 || first.Load5 != 0.75 || first.Load15 != 0.25 {
+		t.Fatalf("unexpected load %+v", first)
+	}
+	if first.MemoryTotalBytes != 8000000*1024 || first.MemoryAvailableBytes != 2000000*1024 || first.MemoryPercent != 75 {
+		t.Fatalf("unexpected memory %+v", first)
+	}
+	if runtime.GOOS != "windows" {
+		if len(first.Disks) != 1 || first.Disks[0].Project != "proj-a" || first.Disks[0].TotalBytes == 0 {
+			t.Fatalf("unexpected disks %+v", first.Disks)
+		}
+	}
+
+	// 100 more busy and 100 more idle jiffies: 50% busy.
+	writeFile(t, filepath.Join(root, "stat"), "cpu  150 0 150 850 50 0 0 0 0 0\n")
+	if second := collector.Sample(); second.CPUPercent != 50 {
+		t.Fatalf("expected 50%% CPU, got %v", second.CPUPercent)
+	}
+}
+
+func TestTelemetryCollectorWithoutProc(t *testing.T) {
+	collector := NewTelemetryCollector(newMockRunner(), nil, []ProjectDir{{Project: "gone", Directory: filepath.Join(t.TempDir(), "missing")}})
+	collector.procRoot = filepath.Join(t.TempDir(), "none")
+
+	sample := collector.Sample()
+	if sample.CPUPercent != 0 || sample.MemoryTotalBytes != 0 || len(sample.Disks) != 0 {
+		t.Fatalf("expected an empty sample, got %+v", sample)
+	}
+}
+
+func TestTelemetryReporterSendsHeartbeat(t *testing.T) {
+	collector, _ := newTestCollector(t)
+	capture := &captureEnvelopeSender{}
+	reporter := NewTelemetryReporter(collector, time.Minute, time.Hour, capture, nil)
+
+	if err := reporter.Report(context.Background()); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if capture.env == nil || capture.env.Type != string(shared.MessageTypeHeartbeat) {
+		t.Fatalf("expected a heartbeat envelope, got %+v", capture.env)
+	}
+	var payload shared.HeartbeatPayload
+	if err := json.Unmarshal(capture.env.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Inventory == nil || payload.Inventory.Tools["git"] != "2.43.0" || payload.Load1 != 1.5 {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+
+	// The inventory is cached until the inventory interval passes.
+	first := reporter.Inventory(context.Background())
+	if err := os.Remove(collector.osRelease); err != nil {
+		t.Fatalf("remove os-release: %v", err)
+	}
+	if again := reporter.Inventory(context.Background()); again != first {
+		t.Fatal("expected the cached inventory")
+	}
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -31,6 +32,7 @@ type SessionSnapshot struct {
 // StateSnapshot is sent on every connect/reconnect so the supervisor
 // has a full picture of this agent's state.
 type StateSnapshot struct {
+	shared.NodeInfo
 	Sessions []SessionSnapshot `json:"sessions"`
 	LastSeq  int64             `json:"last_seq"`
 }
@@ -57,11 +59,13 @@ type pendingEvent struct {
 //
 // Usage: call Connect() once, then Close() to shut down.
 type WSClient struct {
-	url       string
-	authToken string
-	nodeID    string
-	logger    *zap.Logger
-	backoff   *Backoff
+	url         string
+	authToken   string
+	tokenSource func(ctx context.Context) (string, error)
+	tlsConfig   *tls.Config
+	nodeID      string
+	logger      *zap.Logger
+	backoff     *Backoff
 
 	snapshotProvider SnapshotProvider
 	messageHandler   MessageHandler
@@ -116,6 +120,18 @@ func WithNodeID(nodeID string) WSClientOption {
 	return func(c *WSClient) { c.nodeID = nodeID }
 }
 
+// WithTokenSource supplies the auth token before every dial instead of the
+// fixed token, e.g. a per-node credential obtained by enrollment.
+func WithTokenSource(source func(ctx context.Context) (string, error)) WSClientOption {
+	return func(c *WSClient) { c.tokenSource = source }
+}
+
+// WithTLSConfig sets the TLS config used to dial a wss:// supervisor, e.g.
+// one presenting the node certificate and pinning the supervisor CA.
+func WithTLSConfig(cfg *tls.Config) WSClientOption {
+	return func(c *WSClient) { c.tlsConfig = cfg }
+}
+
 // NewWSClient creates a WebSocket client for supervisor communication.
 func NewWSClient(url, authToken string, logger *zap.Logger, opts ...WSClientOption) *WSClient {
 	c := &WSClient{
@@ -178,18 +194,33 @@ func (c *WSClient) connectLoop(ctx context.Context) {
 }
 
 func (c *WSClient) dialAndServe(ctx context.Context) error {
+	token := c.authToken
+	if c.tokenSource != nil {
+		var err error
+		if token, err = c.tokenSource(ctx); err != nil {
+			return fmt.Errorf("obtain credential: %w", err)
+		}
+	}
+
 	header := http.Header{}
-	header.Set("Authorization", "Bearer "+c.authToken)
+	header.Set("Authorization", "Bearer "+token)
 	if c.nodeID != "" {
 		header.Set("X-Node-ID", c.nodeID)
 	}
 
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  c.tlsConfig,
 	}
 
-	conn, _, err := dialer.DialContext(ctx, c.url, header)
+	conn, resp, err := dialer.DialContext(ctx, c.url, header)
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("dial: node is revoked by the supervisor: %w", err)
+		}
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("dial: supervisor refused the node credential or certificate: %w", err)
+		}
 		return fmt.Errorf("dial: %w", err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 )
 
 type AgentConfig struct {
@@ -20,8 +21,38 @@ type AgentConfig struct {
 		Name      string `json:"name"`
 		Directory string `json:"directory"`
 	} `json:"projects"`
+
+	// Labels are free-form key/value pairs (e.g. "zone": "office") reported
+	// at registration, used to place and fan out commands.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ProgressPollIntervalSec is how often each project's .context/PROGRESS.md
+	// and CURRENT_TASK.md are checked for task and milestone changes.
+	ProgressPollIntervalSec int `json:"progress_poll_interval_sec"`
+
+	// TelemetryIntervalSec is how often a heartbeat carrying resource usage
+	// and the node inventory is sent; InventoryIntervalSec is how often tool
+	// versions are probed again.
+	TelemetryIntervalSec int `json:"telemetry_interval_sec"`
+	InventoryIntervalSec int `json:"inventory_interval_sec"`
+
+	// JoinToken is a one-time token exchanged with the supervisor for this
+	// node's own credential, which is saved to CredentialPath and used in
+	// place of AuthToken from then on.
+	JoinToken      string `json:"join_token,omitempty"`
+	CredentialPath string `json:"credential_path"`
+
+	// CAFingerprint is the SHA-256 fingerprint of the supervisor's node CA,
+	// printed with the join token when mTLS is enabled. It is checked on the
+	// first TLS connection; after enrollment the CA certificate and this
+	// node's certificate are saved next to CredentialPath and pinned.
+	CAFingerprint string `json:"ca_fingerprint,omitempty"`
 }
 
+// DefaultCredentialPath is where an enrolled agent keeps its node credential
+// unless credential_path is set.
+const DefaultCredentialPath = "/var/lib/hal-o-swarm/node.credential"
+
 func LoadAgentConfig(path string) (*AgentConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -44,12 +75,30 @@ func validateAgentConfig(cfg *AgentConfig) error {
 	if cfg.AuthReportIntervalSec <= 0 {
 		cfg.AuthReportIntervalSec = 30
 	}
+	if cfg.ProgressPollIntervalSec <= 0 {
+		cfg.ProgressPollIntervalSec = 10
+	}
+	if cfg.TelemetryIntervalSec <= 0 {
+		cfg.TelemetryIntervalSec = 30
+	}
+	if cfg.InventoryIntervalSec <= 0 {
+		cfg.InventoryIntervalSec = 3600
+	}
 
 	if cfg.SupervisorURL == "" {
 		return fmt.Errorf("validation error: supervisor_url is required")
 	}
-	if cfg.AuthToken == "" {
-		return fmt.Errorf("validation error: auth_token is required")
+	if cfg.CredentialPath == "" {
+		cfg.CredentialPath = DefaultCredentialPath
+	}
+	if cfg.AuthToken == "" && cfg.JoinToken == "" {
+		if _, err := os.Stat(cfg.CredentialPath); err != nil {
+			return fmt.Errorf("validation error: auth_token or join_token is required")
+		}
+	}
+	cfg.CAFingerprint = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(cfg.CAFingerprint), ":", ""))
+	if cfg.CAFingerprint != "" && len(cfg.CAFingerprint) != 64 {
+		return fmt.Errorf("validation error: ca_fingerprint must be a SHA-256 fingerprint (64 hex digits)")
 	}
 	if cfg.OpencodePort <= 0 || cfg.OpencodePort > 65535 {
 		return fmt.Errorf("validation error: opencode_port must be between 1 and 65535, got %d", cfg.OpencodePort)
@@ -62,5 +111,13 @@ func validateAgentConfig(cfg *AgentConfig) error {
 			return fmt.Errorf("validation error: projects[%d].directory is required", i)
 		}
 	}
+	for key, value := range cfg.Labels {
+		if key == "" || strings.ContainsAny(key, "=,") {
+			return fmt.Errorf("validation error: labels key %q must be non-empty and contain no '=' or ','", key)
+		}
+		if strings.Contains(value, ",") {
+			return fmt.Errorf("validation error: labels.%s must not contain ','", key)
+		}
+	}
 	return nil
 }
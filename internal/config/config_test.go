@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,6 +34,9 @@ func TestLoadAgentConfigExample(t *testing.T) {
 	if len(cfg.Projects) == 0 {
 		t.Error("expected projects array to be non-empty")
 	}
+	if cfg.Labels["zone"] != "office" {
+		t.Errorf("expected zone label, got %v", cfg.Labels)
+	}
 }
 
 func TestLoadEnvManifestExample(t *testing.T) {
@@ -124,7 +128,7 @@ func TestAgentConfigValidationMissingAuthToken(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for missing auth token, got nil")
 	}
-	if err.Error() != "validation error: auth_token is required" {
+	if err.Error() != "validation error: auth_token or join_token is required" {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
@@ -159,6 +163,73 @@ func TestAgentConfigValidationEmptyProjects(t *testing.T) {
 	}
 }
 
+func TestAgentConfigValidationLabels(t *testing.T) {
+	cfg := &AgentConfig{
+		SupervisorURL: "ws://localhost:8420",
+		AuthToken:     "token",
+		OpencodePort:  4096,
+		Labels:        map[string]string{"zone=office": "true"},
+	}
+
+	err := validateAgentConfig(cfg)
+	if err == nil || err.Error() != `validation error: labels key "zone=office" must be non-empty and contain no '=' or ','` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Labels = map[string]string{"zone": "office,lab"}
+	err = validateAgentConfig(cfg)
+	if err == nil || err.Error() != "validation error: labels.zone must not contain ','" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAgentConfigValidationJoinToken(t *testing.T) {
+	cfg := &AgentConfig{
+		SupervisorURL: "ws://localhost:8420",
+		JoinToken:     "join",
+		OpencodePort:  4096,
+	}
+	if err := validateAgentConfig(cfg); err != nil {
+		t.Fatalf("unexpected error with a join token: %v", err)
+	}
+	if cfg.CredentialPath != DefaultCredentialPath {
+		t.Errorf("expected default credential path, got %q", cfg.CredentialPath)
+	}
+
+	// A saved credential is enough once the join token is removed.
+	cfg.JoinToken = ""
+	cfg.CredentialPath = filepath.Join(t.TempDir(), "node.credential")
+	if err := validateAgentConfig(cfg); err == nil {
+		t.Fatal("expected error without any token")
+	}
+	if err := os.WriteFile(cfg.CredentialPath, []byte("cred\n"), 0o600); err != nil {
+		t.Fatalf("write credential: %v", err)
+	}
+	if err := validateAgentConfig(cfg); err != nil {
+		t.Errorf("unexpected error with a saved credential: %v", err)
+	}
+}
+
+func TestAgentConfigValidationCAFingerprint(t *testing.T) {
+	cfg := &AgentConfig{
+		SupervisorURL: "wss://swarm.example.com:8420",
+		JoinToken:     "join",
+		OpencodePort:  4096,
+		CAFingerprint: "AB:" + strings.Repeat("cd", 31),
+	}
+	if err := validateAgentConfig(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CAFingerprint != "ab"+strings.Repeat("cd", 31) {
+		t.Errorf("expected a normalized fingerprint, got %q", cfg.CAFingerprint)
+	}
+
+	cfg.CAFingerprint = "abc"
+	if err := validateAgentConfig(cfg); err == nil {
+		t.Fatal("expected error for a short fingerprint")
+	}
+}
+
 func TestEnvManifestValidationMissingVersion(t *testing.T) {
 	manifest := &EnvManifest{
 		Version: "",
@@ -673,3 +744,314 @@ func TestSupervisorCredentialConfigRejectsNegativeVersion(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestSupervisorBudgetConfigDefaults(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	cfg.Cost.Budgets.Enabled = true
+	cfg.Cost.Budgets.Projects = map[string]BudgetLimits{
+		"proj-a": {DailyUSD: 5},
+	}
+
+	if err := validateSupervisorConfig(cfg); err != nil {
+		t.Fatalf("expected valid budget config, got error: %v", err)
+	}
+	if cfg.Cost.Budgets.CheckIntervalSec != 60 {
+		t.Errorf("expected default check interval 60, got %d", cfg.Cost.Budgets.CheckIntervalSec)
+	}
+	if len(cfg.Cost.Budgets.AlertThresholds) != 3 {
+		t.Errorf("expected default alert thresholds, got %v", cfg.Cost.Budgets.AlertThresholds)
+	}
+}
+
+func TestSupervisorBudgetConfigRejectsNegativeLimit(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	cfg.Cost.Budgets.Projects = map[string]BudgetLimits{
+		"proj-a": {WeeklyUSD: -1},
+	}
+
+	err := validateSupervisorConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for negative budget limit, got nil")
+	}
+	if err.Error() != "validation error: cost.budgets.projects.proj-a limits must be >= 0" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorCostProviderExportDirOnlyForGoogle(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	cfg.Cost.Providers.Google.ExportDir = "/tmp/billing"
+
+	if err := validateSupervisorConfig(cfg); err != nil {
+		t.Fatalf("expected google export_dir without api key to be valid, got %v", err)
+	}
+
+	cfg.Cost.Providers.OpenAI.APIKey = "sk-openai-test"
+	cfg.Cost.Providers.OpenAI.ExportDir = "/tmp/billing"
+	err := validateSupervisorConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for export_dir on openai, got nil")
+	}
+	if err.Error() != "validation error: cost.providers.openai.export_dir is only supported for google" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorCostProviderCustomRequiresType(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	cfg.Cost.Providers.Custom = map[string]CostProviderConfig{
+		"litellm": {APIKey: "key"},
+	}
+
+	err := validateSupervisorConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for custom provider without type, got nil")
+	}
+	if err.Error() != "validation error: cost.providers.custom.litellm.type is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorCostProviderLocalRequiresRates(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	enabled := true
+	cfg.Cost.Providers.Local.Enabled = &enabled
+
+	err := validateSupervisorConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for local provider without rates, got nil")
+	}
+	if err.Error() != "validation error: cost.providers.local requires pricing_file or model_rates" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorCostProviderRejectsNegativeCacheRate(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	cfg.Cost.Providers.Anthropic.APIKey = "sk-ant-admin-test"
+	cfg.Cost.Providers.Anthropic.ModelRates = map[string]ModelRate{
+		"claude-sonnet-4": {Input: 0.003, Output: 0.015, CacheRead: -0.1},
+	}
+
+	err := validateSupervisorConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for negative cache_read rate, got nil")
+	}
+	if err.Error() != "validation error: cost.providers.anthropic.model_rates.claude-sonnet-4 rates must be >= 0" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorModelsValidation(t *testing.T) {
+	newConfig := func() *SupervisorConfig {
+		cfg := &SupervisorConfig{}
+		cfg.Server.Port = 8420
+		cfg.Server.AuthToken = "token"
+		cfg.Server.HeartbeatIntervalSec = 30
+		cfg.Server.HeartbeatTimeoutCount = 3
+		return cfg
+	}
+
+	cfg := newConfig()
+	cfg.Models = map[string]ModelInfo{"llama-3-70b": {ContextWindow: 8192, CompactionHeadroom: 1024}}
+	if err := validateSupervisorConfig(cfg); err != nil {
+		t.Fatalf("expected valid models config, got %v", err)
+	}
+
+	cfg = newConfig()
+	cfg.Models = map[string]ModelInfo{"llama-3-70b": {ContextWindow: 8192, CompactionHeadroom: 8192}}
+	err := validateSupervisorConfig(cfg)
+	if err == nil || err.Error() != "validation error: models.llama-3-70b.compaction_headroom must be between 0 and context_window, got 8192" {
+		t.Errorf("unexpected error for headroom >= window: %v", err)
+	}
+
+	cfg = newConfig()
+	cfg.Models = map[string]ModelInfo{"llama-3-70b": {}}
+	if err := validateSupervisorConfig(cfg); err == nil {
+		t.Error("expected error for missing context_window")
+	}
+
+	cfg = newConfig()
+	cfg.Policies.RestartOnCompaction.ContextPercent = 120
+	err = validateSupervisorConfig(cfg)
+	if err == nil || err.Error() != "validation error: policies.restart_on_compaction.context_percent must be between 0 and 100, got 120" {
+		t.Errorf("unexpected error for context_percent: %v", err)
+	}
+}
+
+func TestSupervisorCompactOnContextDefaults(t *testing.T) {
+	cfg := &SupervisorConfig{}
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+
+	if err := validateSupervisorConfig(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	policy := cfg.Policies.CompactOnContext
+	if policy.ContextPercent != 70 || policy.CooldownSeconds != 600 || policy.MaxRetries != 2 || policy.RetryResetSeconds != 3600 {
+		t.Fatalf("unexpected compact_on_context defaults %+v", policy)
+	}
+
+	cfg.Policies.CompactOnContext.ContextPercent = 150
+	err := validateSupervisorConfig(cfg)
+	if err == nil || err.Error() != "validation error: policies.compact_on_context.context_percent must be between 0 and 100, got 150" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSupervisorDependenciesConfig(t *testing.T) {
+	var cfg SupervisorConfig
+	data := `{"dependencies": {
+		"ai-os-rom": {"depends_on": ["ai-os-l0", "ai-os-l1"], "prompt": "Build the ROM"},
+		"ai-os-l0": {"depends_on": ["ai-os-interfaces"], "auto_start": false}
+	}}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	rom := cfg.Dependencies["ai-os-rom"]
+	if len(rom.DependsOn) != 2 || rom.Prompt != "Build the ROM" || !rom.AutoStartEnabled() {
+		t.Fatalf("unexpected ai-os-rom dependency %+v", rom)
+	}
+	if cfg.Dependencies["ai-os-l0"].AutoStartEnabled() {
+		t.Fatal("expected auto_start false for ai-os-l0")
+	}
+
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	if err := validateSupervisorConfig(&cfg); err != nil {
+		t.Fatalf("expected valid dependencies, got %v", err)
+	}
+
+	cfg.Dependencies["ai-os-l1"] = ProjectDependency{DependsOn: []string{"ai-os-l1"}}
+	err := validateSupervisorConfig(&cfg)
+	if err == nil || err.Error() != "validation error: dependencies.ai-os-l1 must not depend on itself" {
+		t.Errorf("unexpected error for self dependency: %v", err)
+	}
+}
+
+func TestSupervisorTaskQueueConfig(t *testing.T) {
+	var cfg SupervisorConfig
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	if err := validateSupervisorConfig(&cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.TaskQueue.PollIntervalSec != 15 || cfg.TaskQueue.MaxSessionsPerNode != 4 {
+		t.Fatalf("unexpected task queue defaults %+v", cfg.TaskQueue)
+	}
+
+	cfg.TaskQueue.MaxSessionsPerNode = -1
+	err := validateSupervisorConfig(&cfg)
+	if err == nil || err.Error() != "validation error: task_queue.max_sessions_per_node must not be negative, got -1" {
+		t.Errorf("unexpected error for negative max sessions: %v", err)
+	}
+}
+
+func TestSupervisorEnrollmentConfigDefaults(t *testing.T) {
+	var cfg SupervisorConfig
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	if err := validateSupervisorConfig(&cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.Security.Enrollment.JoinTokenTTLSeconds != 3600 || cfg.Security.Enrollment.RequireNodeCredentials {
+		t.Fatalf("unexpected enrollment defaults %+v", cfg.Security.Enrollment)
+	}
+	mtls := cfg.Security.MTLS
+	if mtls.Enabled || mtls.CADir != "/var/lib/hal-o-swarm/ca" || mtls.CertValidityHours != 720 || len(mtls.ServerNames) == 0 {
+		t.Fatalf("unexpected mtls defaults %+v", mtls)
+	}
+}
+
+func TestSupervisorPlacementConfig(t *testing.T) {
+	var cfg SupervisorConfig
+	cfg.Server.Port = 8420
+	cfg.Server.AuthToken = "token"
+	cfg.Server.HeartbeatIntervalSec = 30
+	cfg.Server.HeartbeatTimeoutCount = 3
+	if err := validateSupervisorConfig(&cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.Placement.Strategy != PlacementLeastLoaded {
+		t.Fatalf("expected least_loaded default, got %q", cfg.Placement.Strategy)
+	}
+
+	cfg.Placement.Strategy = "random"
+	err := validateSupervisorConfig(&cfg)
+	if err == nil || err.Error() != `validation error: placement.strategy must be one of least_loaded, spread or pinned, got "random"` {
+		t.Errorf("unexpected error for unknown strategy: %v", err)
+	}
+
+	cfg.Placement.Strategy = PlacementPinned
+	cfg.Placement.Pins = map[string]string{"ai-os-l1": ""}
+	err = validateSupervisorConfig(&cfg)
+	if err == nil || err.Error() != "validation error: placement.pins entries must name a project and a node" {
+		t.Errorf("unexpected error for empty pin: %v", err)
+	}
+}
+
+func TestSupervisorDiscordPermissionsValidation(t *testing.T) {
+	newConfig := func() SupervisorConfig {
+		var cfg SupervisorConfig
+		cfg.Server.Port = 8420
+		cfg.Server.AuthToken = "token"
+		cfg.Server.HeartbeatIntervalSec = 30
+		cfg.Server.HeartbeatTimeoutCount = 3
+		return cfg
+	}
+
+	cfg := newConfig()
+	cfg.Channels.Discord.Permissions = DiscordPermissionsConfig{
+		Users:       map[string]string{"111": DiscordRoleAdmin},
+		Roles:       map[string]string{"222": DiscordRoleOperator},
+		DefaultRole: DiscordRoleViewer,
+	}
+	if err := validateSupervisorConfig(&cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	for _, perms := range []DiscordPermissionsConfig{
+		{Users: map[string]string{"111": "root"}},
+		{Roles: map[string]string{"222": ""}},
+		{DefaultRole: "everyone"},
+	} {
+		cfg := newConfig()
+		cfg.Channels.Discord.Permissions = perms
+		err := validateSupervisorConfig(&cfg)
+		if err == nil || !strings.Contains(err.Error(), "channels.discord.permissions") {
+			t.Fatalf("expected %+v to be refused, got %v", perms, err)
+		}
+	}
+}
@@ -38,6 +38,7 @@ type SupervisorConfig struct {
 				DevLog   string `json:"dev-log"`
 				BuildLog string `json:"build-log"`
 			} `json:"channels"`
+			Permissions DiscordPermissionsConfig `json:"permissions"`
 		} `json:"discord"`
 		Slack struct {
 			BotToken string `json:"bot_token"`
@@ -54,9 +55,64 @@ type SupervisorConfig struct {
 	Cost         CostConfig                   `json:"cost"`
 	Routes       []interface{}                `json:"routes"`
 	Policies     PolicyConfig                 `json:"policies"`
-	Dependencies interface{}                  `json:"dependencies"`
+	Dependencies map[string]ProjectDependency `json:"dependencies"`
+	TaskQueue    TaskQueueConfig              `json:"task_queue"`
+	Placement    PlacementConfig              `json:"placement"`
 	Security     SecurityConfig               `json:"security"`
 	Credentials  CredentialDistributionConfig `json:"credentials"`
+
+	// Models overrides or extends the built-in model metadata catalog, keyed
+	// by model name.
+	Models map[string]ModelInfo `json:"models,omitempty"`
+}
+
+// ProjectDependency lists the projects that must complete a milestone before
+// a project is scheduled. Prompt seeds the session the scheduler starts once
+// the project becomes ready; AutoStart set to false only reports readiness.
+type ProjectDependency struct {
+	DependsOn []string `json:"depends_on"`
+	Prompt    string   `json:"prompt,omitempty"`
+	AutoStart *bool    `json:"auto_start,omitempty"`
+}
+
+// AutoStartEnabled reports whether the scheduler may start sessions for the
+// project. It defaults to true.
+func (d ProjectDependency) AutoStartEnabled() bool {
+	return d.AutoStart == nil || *d.AutoStart
+}
+
+// TaskQueueConfig controls how queued tasks are dispatched. A task is only
+// started while its project has no active session and the node serving the
+// project runs fewer than MaxSessionsPerNode active sessions.
+type TaskQueueConfig struct {
+	PollIntervalSec    int `json:"poll_interval_seconds"`
+	MaxSessionsPerNode int `json:"max_sessions_per_node"`
+}
+
+// Placement strategies choose among the online nodes that can serve a
+// project.
+const (
+	PlacementLeastLoaded = "least_loaded"
+	PlacementSpread      = "spread"
+	PlacementPinned      = "pinned"
+)
+
+// PlacementConfig controls which node runs a command for a project that is
+// checked out on several nodes. Strategy defaults to least_loaded. Pins maps
+// a project to the node the pinned strategy sends it to; unpinned projects
+// are placed least-loaded. Labels lists the node labels a project requires.
+type PlacementConfig struct {
+	Strategy string                       `json:"strategy"`
+	Pins     map[string]string            `json:"pins,omitempty"`
+	Labels   map[string]map[string]string `json:"labels,omitempty"`
+}
+
+// ModelInfo describes a model's context window. CompactionHeadroom is the
+// number of tokens the agent keeps free before it auto-compacts, so
+// compaction happens at ContextWindow - CompactionHeadroom.
+type ModelInfo struct {
+	ContextWindow      int `json:"context_window"`
+	CompactionHeadroom int `json:"compaction_headroom,omitempty"`
 }
 
 type SecurityConfig struct {
@@ -64,6 +120,8 @@ type SecurityConfig struct {
 	OriginAllowlist []string            `json:"origin_allowlist"`
 	TokenRotation   TokenRotationConfig `json:"token_rotation"`
 	Audit           AuditConfig         `json:"audit"`
+	Enrollment      EnrollmentConfig    `json:"enrollment"`
+	MTLS            MTLSConfig          `json:"mtls"`
 }
 
 type TLSConfig struct {
@@ -72,6 +130,46 @@ type TLSConfig struct {
 	KeyPath  string `json:"key_path"`
 }
 
+// EnrollmentConfig controls per-node agent credentials. Agents exchange a
+// one-time join token for their own credential; RequireNodeCredentials
+// refuses agents still using server.auth_token. JoinTokenTTLSeconds is how
+// long a join token stays valid when none is given.
+type EnrollmentConfig struct {
+	RequireNodeCredentials bool `json:"require_node_credentials"`
+	JoinTokenTTLSeconds    int  `json:"join_token_ttl_seconds"`
+}
+
+// MTLSConfig runs an internal certificate authority kept in CADir. It issues
+// node certificates at enrollment and the WebSocket port's server
+// certificate, valid for the names in ServerNames, and the WebSocket port
+// then requires a node certificate from every agent. Certificates are valid
+// for CertValidityHours and are renewed in the last third of that.
+type MTLSConfig struct {
+	Enabled           bool     `json:"enabled"`
+	CADir             string   `json:"ca_dir"`
+	ServerNames       []string `json:"server_names"`
+	CertValidityHours int      `json:"cert_validity_hours"`
+}
+
+// Supervisor roles that Discord users can be granted. A viewer can run the
+// read-only commands, an operator can also control sessions and the task
+// queue, and an admin can run every command.
+const (
+	DiscordRoleViewer   = "viewer"
+	DiscordRoleOperator = "operator"
+	DiscordRoleAdmin    = "admin"
+)
+
+// DiscordPermissionsConfig maps Discord user IDs and guild role IDs to
+// supervisor roles. A user holds the highest role granted to their ID or to
+// any of their guild roles, and DefaultRole otherwise; with no DefaultRole,
+// unmapped users cannot run any command.
+type DiscordPermissionsConfig struct {
+	Users       map[string]string `json:"users"`
+	Roles       map[string]string `json:"roles"`
+	DefaultRole string            `json:"default_role"`
+}
+
 type TokenRotationConfig struct {
 	Enabled              bool `json:"enabled"`
 	CheckIntervalSeconds int  `json:"check_interval_seconds"`
@@ -88,25 +186,67 @@ type CostConfig struct {
 	RequestTimeoutSec   int           `json:"request_timeout_seconds"`
 	MaxRetries          int           `json:"max_retries"`
 	BackoffBaseMS       int           `json:"backoff_base_ms"`
+	Budgets             BudgetConfig  `json:"budgets"`
+
+	// PricingCatalog is a JSON file of model rates with effective dates.
+	// Catalog rates take precedence over providers' model_rates for usage on
+	// or after each entry's effective_from date.
+	PricingCatalog string `json:"pricing_catalog,omitempty"`
+}
+
+type BudgetConfig struct {
+	Enabled          bool                    `json:"enabled"`
+	CheckIntervalSec int                     `json:"check_interval_seconds"`
+	AlertThresholds  []float64               `json:"alert_thresholds"`
+	BlockOnExceed    bool                    `json:"block_on_exceed"`
+	Global           BudgetLimits            `json:"global"`
+	Projects         map[string]BudgetLimits `json:"projects"`
+}
+
+type BudgetLimits struct {
+	DailyUSD   float64 `json:"daily_usd"`
+	WeeklyUSD  float64 `json:"weekly_usd"`
+	MonthlyUSD float64 `json:"monthly_usd"`
 }
 
 type CostProviders struct {
-	Anthropic CostProviderConfig `json:"anthropic"`
-	OpenAI    CostProviderConfig `json:"openai"`
+	Anthropic  CostProviderConfig `json:"anthropic"`
+	OpenAI     CostProviderConfig `json:"openai"`
+	Google     CostProviderConfig `json:"google"`
+	OpenRouter CostProviderConfig `json:"openrouter"`
+	Local      CostProviderConfig `json:"local"`
+
+	// Custom holds additional provider instances keyed by name. Each entry
+	// selects a registered provider implementation through Type.
+	Custom map[string]CostProviderConfig `json:"custom,omitempty"`
 }
 
 type CostProviderConfig struct {
+	Type        string               `json:"type,omitempty"`
 	APIKey      string               `json:"api_key"`
 	Enabled     *bool                `json:"enabled,omitempty"`
 	ModelRates  map[string]ModelRate `json:"model_rates"`
 	BaseURL     string               `json:"base_url,omitempty"`
 	AdminAPIKey string               `json:"admin_api_key,omitempty"`
 	OrgAPIKey   string               `json:"org_api_key,omitempty"`
+
+	// ExportDir is a directory of Cloud Billing export files (JSON, NDJSON or
+	// CSV) to ingest instead of calling an API. Only used by the google provider.
+	ExportDir string `json:"export_dir,omitempty"`
+	// SKUModels maps billing SKU IDs or descriptions to model names.
+	SKUModels map[string]string `json:"sku_models,omitempty"`
+	// PricingFile is a JSON file of per-model rates used by the local
+	// provider to price agent-reported tokens for self-hosted models.
+	PricingFile string `json:"pricing_file,omitempty"`
 }
 
+// ModelRate holds per-1K-token prices. Cache rates are optional; prompt-cache
+// tokens are priced at the input rate when they are left unset.
 type ModelRate struct {
-	Input  float64 `json:"input"`
-	Output float64 `json:"output"`
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
 }
 
 func (p CostProviderConfig) EffectiveAPIKey() string {
@@ -123,14 +263,15 @@ func (p CostProviderConfig) IsEnabled() bool {
 	if p.Enabled != nil {
 		return *p.Enabled
 	}
-	return p.EffectiveAPIKey() != ""
+	return p.EffectiveAPIKey() != "" || p.ExportDir != "" || p.PricingFile != ""
 }
 
 type PolicyConfig struct {
-	ResumeOnIdle        IdlePolicyConfig       `json:"resume_on_idle"`
-	RestartOnCompaction CompactionPolicyConfig `json:"restart_on_compaction"`
-	KillOnCost          CostPolicyConfig       `json:"kill_on_cost"`
-	CheckIntervalSec    int                    `json:"check_interval_seconds"`
+	ResumeOnIdle        IdlePolicyConfig              `json:"resume_on_idle"`
+	RestartOnCompaction CompactionPolicyConfig        `json:"restart_on_compaction"`
+	CompactOnContext    ContextCompactionPolicyConfig `json:"compact_on_context"`
+	KillOnCost          CostPolicyConfig              `json:"kill_on_cost"`
+	CheckIntervalSec    int                           `json:"check_interval_seconds"`
 }
 
 type IdlePolicyConfig struct {
@@ -145,6 +286,22 @@ type CompactionPolicyConfig struct {
 	TokenThreshold    int  `json:"token_threshold"`
 	MaxRetries        int  `json:"max_retries"`
 	RetryResetSeconds int  `json:"retry_reset_seconds"`
+
+	// ContextPercent, when set, triggers the restart once a session uses this
+	// percentage of its model's context window. Sessions whose model is not
+	// in the catalog still use TokenThreshold.
+	ContextPercent float64 `json:"context_percent,omitempty"`
+}
+
+// ContextCompactionPolicyConfig summarizes a session once it uses
+// ContextPercent of its model's context window, ahead of the agent's own
+// compaction. CooldownSeconds spaces out compactions of the same session.
+type ContextCompactionPolicyConfig struct {
+	Enabled           bool    `json:"enabled"`
+	ContextPercent    float64 `json:"context_percent"`
+	CooldownSeconds   int     `json:"cooldown_seconds"`
+	MaxRetries        int     `json:"max_retries"`
+	RetryResetSeconds int     `json:"retry_reset_seconds"`
 }
 
 type CostPolicyConfig struct {
@@ -162,6 +319,10 @@ const (
 	defaultCompactionTokenThreshold = 180000
 	defaultCompactionMaxRetries     = 2
 	defaultCompactionRetryResetSec  = 3600
+	defaultCompactContextPercent    = 70.0
+	defaultCompactCooldownSec       = 600
+	defaultCompactMaxRetries        = 2
+	defaultCompactRetryResetSec     = 3600
 	defaultKillCostThresholdUSD     = 10.0
 	defaultKillMaxRetries           = 1
 	defaultKillRetryResetSec        = 86400
@@ -169,9 +330,15 @@ const (
 	defaultCostRequestTimeoutSec    = 15
 	defaultCostMaxRetries           = 3
 	defaultCostBackoffBaseMS        = 500
+	defaultBudgetCheckIntervalSec   = 60
+	defaultTaskQueuePollIntervalSec = 15
+	defaultTaskQueueMaxSessions     = 4
 
 	defaultTokenRotationCheckIntervalSec = 300
 	defaultAuditRetentionDays            = 90
+	defaultJoinTokenTTLSeconds           = 3600
+	defaultMTLSCADir                     = "/var/lib/hal-o-swarm/ca"
+	defaultMTLSCertValidityHours         = 720
 )
 
 func LoadSupervisorConfig(path string) (*SupervisorConfig, error) {
@@ -224,10 +391,34 @@ func validateSupervisorConfig(cfg *SupervisorConfig) error {
 		cfg.Cost.BackoffBaseMS = defaultCostBackoffBaseMS
 	}
 
-	if err := validateCostProviderConfig("anthropic", cfg.Cost.Providers.Anthropic); err != nil {
-		return err
+	builtinProviders := []struct {
+		name string
+		cfg  CostProviderConfig
+	}{
+		{"anthropic", cfg.Cost.Providers.Anthropic},
+		{"openai", cfg.Cost.Providers.OpenAI},
+		{"google", cfg.Cost.Providers.Google},
+		{"openrouter", cfg.Cost.Providers.OpenRouter},
+		{"local", cfg.Cost.Providers.Local},
+	}
+	for _, provider := range builtinProviders {
+		if err := validateCostProviderConfig("cost.providers."+provider.name, provider.name, provider.cfg); err != nil {
+			return err
+		}
 	}
-	if err := validateCostProviderConfig("openai", cfg.Cost.Providers.OpenAI); err != nil {
+	for name, providerCfg := range cfg.Cost.Providers.Custom {
+		if name == "" {
+			return fmt.Errorf("validation error: cost.providers.custom keys must not be empty")
+		}
+		path := "cost.providers.custom." + name
+		if providerCfg.Type == "" {
+			return fmt.Errorf("validation error: %s.type is required", path)
+		}
+		if err := validateCostProviderConfig(path, providerCfg.Type, providerCfg); err != nil {
+			return err
+		}
+	}
+	if err := validateBudgetConfig(&cfg.Cost.Budgets); err != nil {
 		return err
 	}
 
@@ -255,6 +446,13 @@ func validateSupervisorConfig(cfg *SupervisorConfig) error {
 	if cfg.Policies.RestartOnCompaction.RetryResetSeconds <= 0 {
 		return fmt.Errorf("validation error: policies.restart_on_compaction.retry_reset_seconds must be positive, got %d", cfg.Policies.RestartOnCompaction.RetryResetSeconds)
 	}
+	if pct := cfg.Policies.RestartOnCompaction.ContextPercent; pct < 0 || pct > 100 {
+		return fmt.Errorf("validation error: policies.restart_on_compaction.context_percent must be between 0 and 100, got %g", pct)
+	}
+
+	if pct := cfg.Policies.CompactOnContext.ContextPercent; pct > 100 {
+		return fmt.Errorf("validation error: policies.compact_on_context.context_percent must be between 0 and 100, got %g", pct)
+	}
 
 	if cfg.Policies.KillOnCost.CostThresholdUSD <= 0 {
 		return fmt.Errorf("validation error: policies.kill_on_cost.cost_threshold_usd must be positive, got %f", cfg.Policies.KillOnCost.CostThresholdUSD)
@@ -280,31 +478,154 @@ func validateSupervisorConfig(cfg *SupervisorConfig) error {
 	if err := validateCredentialDistributionConfig(&cfg.Credentials); err != nil {
 		return err
 	}
+	if err := validateDiscordPermissionsConfig(&cfg.Channels.Discord.Permissions); err != nil {
+		return err
+	}
+
+	for model, info := range cfg.Models {
+		if model == "" {
+			return fmt.Errorf("validation error: models keys must not be empty")
+		}
+		if info.ContextWindow <= 0 {
+			return fmt.Errorf("validation error: models.%s.context_window must be positive, got %d", model, info.ContextWindow)
+		}
+		if info.CompactionHeadroom < 0 || info.CompactionHeadroom >= info.ContextWindow {
+			return fmt.Errorf("validation error: models.%s.compaction_headroom must be between 0 and context_window, got %d", model, info.CompactionHeadroom)
+		}
+	}
+
+	if cfg.TaskQueue.PollIntervalSec < 0 {
+		return fmt.Errorf("validation error: task_queue.poll_interval_seconds must not be negative, got %d", cfg.TaskQueue.PollIntervalSec)
+	}
+	if cfg.TaskQueue.PollIntervalSec == 0 {
+		cfg.TaskQueue.PollIntervalSec = defaultTaskQueuePollIntervalSec
+	}
+	if cfg.TaskQueue.MaxSessionsPerNode < 0 {
+		return fmt.Errorf("validation error: task_queue.max_sessions_per_node must not be negative, got %d", cfg.TaskQueue.MaxSessionsPerNode)
+	}
+	if cfg.TaskQueue.MaxSessionsPerNode == 0 {
+		cfg.TaskQueue.MaxSessionsPerNode = defaultTaskQueueMaxSessions
+	}
+
+	switch cfg.Placement.Strategy {
+	case "":
+		cfg.Placement.Strategy = PlacementLeastLoaded
+	case PlacementLeastLoaded, PlacementSpread, PlacementPinned:
+	default:
+		return fmt.Errorf("validation error: placement.strategy must be one of %s, %s or %s, got %q",
+			PlacementLeastLoaded, PlacementSpread, PlacementPinned, cfg.Placement.Strategy)
+	}
+	for project, nodeID := range cfg.Placement.Pins {
+		if project == "" || nodeID == "" {
+			return fmt.Errorf("validation error: placement.pins entries must name a project and a node")
+		}
+	}
+	for project := range cfg.Placement.Labels {
+		if project == "" {
+			return fmt.Errorf("validation error: placement.labels keys must not be empty")
+		}
+	}
+
+	for project, dep := range cfg.Dependencies {
+		if project == "" {
+			return fmt.Errorf("validation error: dependencies keys must not be empty")
+		}
+		for _, upstream := range dep.DependsOn {
+			if upstream == "" {
+				return fmt.Errorf("validation error: dependencies.%s.depends_on entries must not be empty", project)
+			}
+			if upstream == project {
+				return fmt.Errorf("validation error: dependencies.%s must not depend on itself", project)
+			}
+		}
+	}
 
 	return nil
 }
 
-func validateCostProviderConfig(provider string, cfg CostProviderConfig) error {
+// validateCostProviderConfig checks a provider entry at path whose
+// implementation is kind. Kinds not built in are registered at runtime, so
+// only the generic checks apply to them.
+func validateCostProviderConfig(path, kind string, cfg CostProviderConfig) error {
 	if !cfg.IsEnabled() {
 		return nil
 	}
 
-	if cfg.EffectiveAPIKey() == "" {
-		return fmt.Errorf("validation error: cost.providers.%s.api_key is required when provider is enabled", provider)
+	switch kind {
+	case "anthropic", "openai", "openrouter":
+		if cfg.EffectiveAPIKey() == "" {
+			return fmt.Errorf("validation error: %s.api_key is required when provider is enabled", path)
+		}
+	case "google":
+		if cfg.EffectiveAPIKey() == "" && cfg.ExportDir == "" {
+			return fmt.Errorf("validation error: %s.api_key is required when provider is enabled", path)
+		}
+	case "local":
+		if cfg.PricingFile == "" && len(cfg.ModelRates) == 0 {
+			return fmt.Errorf("validation error: %s requires pricing_file or model_rates", path)
+		}
+	}
+
+	if cfg.ExportDir != "" && kind != "google" {
+		return fmt.Errorf("validation error: %s.export_dir is only supported for google", path)
+	}
+	if cfg.PricingFile != "" && kind != "local" {
+		return fmt.Errorf("validation error: %s.pricing_file is only supported for local", path)
+	}
+	for sku, model := range cfg.SKUModels {
+		if sku == "" || model == "" {
+			return fmt.Errorf("validation error: %s.sku_models entries must not be empty", path)
+		}
 	}
 
 	for model, rates := range cfg.ModelRates {
 		if model == "" {
-			return fmt.Errorf("validation error: cost.providers.%s.model_rates keys must not be empty", provider)
+			return fmt.Errorf("validation error: %s.model_rates keys must not be empty", path)
 		}
-		if rates.Input < 0 || rates.Output < 0 {
-			return fmt.Errorf("validation error: cost.providers.%s.model_rates.%s rates must be >= 0", provider, model)
+		if rates.Input < 0 || rates.Output < 0 || rates.CacheRead < 0 || rates.CacheWrite < 0 {
+			return fmt.Errorf("validation error: %s.model_rates.%s rates must be >= 0", path, model)
 		}
 	}
 
 	return nil
 }
 
+func validateBudgetConfig(cfg *BudgetConfig) error {
+	if cfg.CheckIntervalSec <= 0 {
+		cfg.CheckIntervalSec = defaultBudgetCheckIntervalSec
+	}
+	if len(cfg.AlertThresholds) == 0 {
+		cfg.AlertThresholds = []float64{50, 80, 100}
+	}
+
+	for _, threshold := range cfg.AlertThresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("validation error: cost.budgets.alert_thresholds must be positive, got %f", threshold)
+		}
+	}
+
+	if err := validateBudgetLimits("cost.budgets.global", cfg.Global); err != nil {
+		return err
+	}
+	for project, limits := range cfg.Projects {
+		if project == "" {
+			return fmt.Errorf("validation error: cost.budgets.projects keys must not be empty")
+		}
+		if err := validateBudgetLimits("cost.budgets.projects."+project, limits); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateBudgetLimits(path string, limits BudgetLimits) error {
+	if limits.DailyUSD < 0 || limits.WeeklyUSD < 0 || limits.MonthlyUSD < 0 {
+		return fmt.Errorf("validation error: %s limits must be >= 0", path)
+	}
+	return nil
+}
+
 func validateCredentialDistributionConfig(cfg *CredentialDistributionConfig) error {
 	// If credentials section is not provided, it's valid (optional)
 	if cfg == nil {
@@ -335,6 +656,31 @@ func validateCredentialDistributionConfig(cfg *CredentialDistributionConfig) err
 	return nil
 }
 
+func validateDiscordPermissionsConfig(cfg *DiscordPermissionsConfig) error {
+	for id, role := range cfg.Users {
+		if !validDiscordRole(role) {
+			return fmt.Errorf("validation error: channels.discord.permissions.users.%s must be viewer, operator or admin, got %q", id, role)
+		}
+	}
+	for id, role := range cfg.Roles {
+		if !validDiscordRole(role) {
+			return fmt.Errorf("validation error: channels.discord.permissions.roles.%s must be viewer, operator or admin, got %q", id, role)
+		}
+	}
+	if cfg.DefaultRole != "" && !validDiscordRole(cfg.DefaultRole) {
+		return fmt.Errorf("validation error: channels.discord.permissions.default_role must be viewer, operator or admin, got %q", cfg.DefaultRole)
+	}
+	return nil
+}
+
+func validDiscordRole(role string) bool {
+	switch role {
+	case DiscordRoleViewer, DiscordRoleOperator, DiscordRoleAdmin:
+		return true
+	}
+	return false
+}
+
 func (cfg *SupervisorConfig) applyPolicyDefaults() {
 	if cfg.Policies.CheckIntervalSec <= 0 {
 		cfg.Policies.CheckIntervalSec = defaultPolicyCheckIntervalSec
@@ -360,6 +706,19 @@ func (cfg *SupervisorConfig) applyPolicyDefaults() {
 		cfg.Policies.RestartOnCompaction.RetryResetSeconds = defaultCompactionRetryResetSec
 	}
 
+	if cfg.Policies.CompactOnContext.ContextPercent <= 0 {
+		cfg.Policies.CompactOnContext.ContextPercent = defaultCompactContextPercent
+	}
+	if cfg.Policies.CompactOnContext.CooldownSeconds <= 0 {
+		cfg.Policies.CompactOnContext.CooldownSeconds = defaultCompactCooldownSec
+	}
+	if cfg.Policies.CompactOnContext.MaxRetries <= 0 {
+		cfg.Policies.CompactOnContext.MaxRetries = defaultCompactMaxRetries
+	}
+	if cfg.Policies.CompactOnContext.RetryResetSeconds <= 0 {
+		cfg.Policies.CompactOnContext.RetryResetSeconds = defaultCompactRetryResetSec
+	}
+
 	if cfg.Policies.KillOnCost.CostThresholdUSD <= 0 {
 		cfg.Policies.KillOnCost.CostThresholdUSD = defaultKillCostThresholdUSD
 	}
@@ -378,4 +737,19 @@ func (cfg *SupervisorConfig) applySecurityDefaults() {
 	if cfg.Security.Audit.RetentionDays <= 0 {
 		cfg.Security.Audit.RetentionDays = defaultAuditRetentionDays
 	}
+	if cfg.Security.Enrollment.JoinTokenTTLSeconds <= 0 {
+		cfg.Security.Enrollment.JoinTokenTTLSeconds = defaultJoinTokenTTLSeconds
+	}
+	if cfg.Security.MTLS.CADir == "" {
+		cfg.Security.MTLS.CADir = defaultMTLSCADir
+	}
+	if cfg.Security.MTLS.CertValidityHours <= 0 {
+		cfg.Security.MTLS.CertValidityHours = defaultMTLSCertValidityHours
+	}
+	if len(cfg.Security.MTLS.ServerNames) == 0 {
+		cfg.Security.MTLS.ServerNames = []string{"localhost", "127.0.0.1"}
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			cfg.Security.MTLS.ServerNames = append([]string{hostname}, cfg.Security.MTLS.ServerNames...)
+		}
+	}
 }
@@ -105,6 +105,32 @@ func (c *HTTPClient) Post(path string, payload interface{}) ([]byte, error) {
 	return body, nil
 }
 
+// Delete performs a DELETE request to the API
+func (c *HTTPClient) Delete(path string) ([]byte, error) {
+	req, err := http.NewRequest("DELETE", c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to supervisor at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
 // setAuthHeader adds the Bearer token to the request
 func (c *HTTPClient) setAuthHeader(req *http.Request) {
 	if c.authToken != "" {
@@ -157,6 +157,34 @@ func TestGetSession(t *testing.T) {
 	}
 }
 
+func TestGetSessionHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/sessions/s2/history" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		history := SessionHistoryJSON{
+			SessionID:   "s2",
+			Chain:       []SessionJSON{{ID: "s1"}, {ID: "s2", ParentID: "s1"}},
+			Transitions: []SessionTransitionJSON{{SessionID: "s2", To: "running", Cause: "command", Detail: "restart_session"}},
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: history})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	history, err := GetSessionHistory(client, "s2")
+	if err != nil {
+		t.Fatalf("GetSessionHistory failed: %v", err)
+	}
+	if len(history.Chain) != 2 || history.Chain[1].ParentID != "s1" || history.Transitions[0].Cause != "command" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if _, err := GetSessionHistory(client, ""); err == nil {
+		t.Fatal("expected error for empty session id")
+	}
+}
+
 func TestGetSessionEmptyID(t *testing.T) {
 	client := NewHTTPClient("http://localhost", "test-token")
 	_, err := GetSession(client, "")
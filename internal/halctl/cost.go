@@ -1,10 +1,38 @@
 package halctl
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
 type CostSummary struct {
 	Period       string  `json:"period"`
 	TotalCost    float64 `json:"total_cost"`
 	SessionCount int     `json:"session_count"`
 	TokensUsed   int     `json:"tokens_used"`
+
+	Forecast *CostForecast `json:"forecast,omitempty"`
+}
+
+type CostForecast struct {
+	Method     string         `json:"method"`
+	EndOfWeek  ForecastWindow `json:"end_of_week"`
+	EndOfMonth ForecastWindow `json:"end_of_month"`
+}
+
+type ForecastWindow struct {
+	SpentUSD             float64 `json:"spent_usd"`
+	ProjectedUSD         float64 `json:"projected_usd"`
+	ProjectedSessionsUSD float64 `json:"projected_sessions_usd"`
+}
+
+// Projected returns the higher of the provider and session based projections.
+func (w ForecastWindow) Projected() float64 {
+	if w.ProjectedSessionsUSD > w.ProjectedUSD {
+		return w.ProjectedSessionsUSD
+	}
+	return w.ProjectedUSD
 }
 
 func GetCostToday(client *HTTPClient) (*CostSummary, error) {
@@ -48,3 +76,156 @@ func GetCostMonth(client *HTTPClient) (*CostSummary, error) {
 
 	return &cost, nil
 }
+
+// CostRangeOptions selects a date-range cost report. Empty fields use the
+// supervisor defaults: the last 30 days, daily, grouped by provider.
+type CostRangeOptions struct {
+	From        string
+	To          string
+	Granularity string
+	GroupBy     string
+}
+
+type CostRangeReport struct {
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Granularity  string         `json:"granularity"`
+	GroupBy      string         `json:"group_by"`
+	TotalTokens  int64          `json:"total_tokens"`
+	TotalCostUSD float64        `json:"total_cost_usd"`
+	Rows         []CostRangeRow `json:"rows"`
+}
+
+type CostRangeRow struct {
+	Period           string  `json:"period"`
+	Group            string  `json:"group"`
+	Provider         string  `json:"provider,omitempty"`
+	Tokens           int64   `json:"tokens"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (o CostRangeOptions) query(format string) string {
+	values := url.Values{}
+	if o.From != "" {
+		values.Set("from", o.From)
+	}
+	if o.To != "" {
+		values.Set("to", o.To)
+	}
+	if o.Granularity != "" {
+		values.Set("granularity", o.Granularity)
+	}
+	if o.GroupBy != "" {
+		values.Set("group_by", o.GroupBy)
+	}
+	values.Set("format", format)
+	return values.Encode()
+}
+
+func GetCostRange(client *HTTPClient, opts CostRangeOptions) (*CostRangeReport, error) {
+	body, err := client.Get("/api/v1/cost/export?" + opts.query("json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var report CostRangeReport
+	if err := ParseResponse(body, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// ExportCostCSV returns the raw CSV export for the range.
+func ExportCostCSV(client *HTTPClient, opts CostRangeOptions) ([]byte, error) {
+	return client.Get("/api/v1/cost/export?" + opts.query("csv"))
+}
+
+type PricingEntry struct {
+	Model         string  `json:"model"`
+	Provider      string  `json:"provider,omitempty"`
+	EffectiveFrom string  `json:"effective_from"`
+	Input         float64 `json:"input"`
+	Output        float64 `json:"output"`
+	CacheRead     float64 `json:"cache_read,omitempty"`
+	CacheWrite    float64 `json:"cache_write,omitempty"`
+}
+
+type PricingIssue struct {
+	Index   int    `json:"index"`
+	Model   string `json:"model,omitempty"`
+	Message string `json:"message"`
+}
+
+type PricingValidation struct {
+	Valid   bool           `json:"valid"`
+	Source  string         `json:"source"`
+	Entries int            `json:"entries"`
+	Issues  []PricingIssue `json:"issues"`
+}
+
+// ListPricingRates returns the supervisor's pricing catalog. When model is
+// set, only the rate in effect on date (YYYY-MM-DD, default today) is
+// returned.
+func ListPricingRates(client *HTTPClient, model, provider, date string) ([]PricingEntry, error) {
+	values := url.Values{}
+	if model != "" {
+		values.Set("model", model)
+	}
+	if provider != "" {
+		values.Set("provider", provider)
+	}
+	if date != "" {
+		values.Set("date", date)
+	}
+	path := "/api/v1/cost/rates"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	body, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if model != "" {
+		var entry PricingEntry
+		if err := ParseResponse(body, &entry); err != nil {
+			return nil, err
+		}
+		return []PricingEntry{entry}, nil
+	}
+
+	var entries []PricingEntry
+	if err := ParseResponse(body, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ValidatePricingCatalog asks the supervisor to validate catalog, or its own
+// configured catalog file when catalog is nil.
+func ValidatePricingCatalog(client *HTTPClient, catalog []byte) (*PricingValidation, error) {
+	var payload interface{}
+	if catalog != nil {
+		if !json.Valid(catalog) {
+			return nil, fmt.Errorf("pricing catalog is not valid JSON")
+		}
+		payload = json.RawMessage(catalog)
+	}
+
+	body, err := client.Post("/api/v1/cost/rates/validate", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result PricingValidation
+	if err := ParseResponse(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,125 @@
+package halctl
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetCostRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/cost/export" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("format") != "json" || query.Get("from") != "2026-02-01" || query.Get("group_by") != "project" || query.Has("to") {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: CostRangeReport{
+			From:         "2026-02-01",
+			GroupBy:      "project",
+			TotalCostUSD: 3,
+			Rows:         []CostRangeRow{{Period: "2026-02-01", Group: "proj-a", CostUSD: 3}},
+		}})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	report, err := GetCostRange(client, CostRangeOptions{From: "2026-02-01", GroupBy: "project"})
+	if err != nil {
+		t.Fatalf("GetCostRange failed: %v", err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Group != "proj-a" || report.TotalCostUSD != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestExportCostCSV(t *testing.T) {
+	const csv = "period,provider,tokens\n2026-02-01,openai,10\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "csv" || r.URL.Query().Get("granularity") != "weekly" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(csv))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	body, err := ExportCostCSV(client, CostRangeOptions{Granularity: "weekly"})
+	if err != nil {
+		t.Fatalf("ExportCostCSV failed: %v", err)
+	}
+	if string(body) != csv {
+		t.Fatalf("unexpected csv %q", body)
+	}
+}
+
+func TestListPricingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/cost/rates" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("model") == "" {
+			json.NewEncoder(w).Encode(APIResponse{Data: []PricingEntry{
+				{Model: "gpt-4o", EffectiveFrom: "2024-05-13", Input: 0.005, Output: 0.015},
+				{Model: "gpt-4o", EffectiveFrom: "2024-08-06", Input: 0.0025, Output: 0.01},
+			}})
+			return
+		}
+		if r.URL.Query().Get("date") != "2024-07-01" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: PricingEntry{Model: "gpt-4o", EffectiveFrom: "2024-05-13", Input: 0.005}})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	entries, err := ListPricingRates(client, "", "", "")
+	if err != nil {
+		t.Fatalf("ListPricingRates failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+
+	entries, err = ListPricingRates(client, "gpt-4o", "", "2024-07-01")
+	if err != nil {
+		t.Fatalf("ListPricingRates with model failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].EffectiveFrom != "2024-05-13" {
+		t.Fatalf("unexpected resolved rate %+v", entries)
+	}
+}
+
+func TestValidatePricingCatalog(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(APIResponse{Data: PricingValidation{
+			Valid:  false,
+			Source: "request",
+			Issues: []PricingIssue{{Index: 0, Model: "gpt-4o", Message: "input or output rate is required"}},
+		}})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	catalog := []byte(`{"rates":[{"model":"gpt-4o","effective_from":"2024-01-01"}]}`)
+	result, err := ValidatePricingCatalog(client, catalog)
+	if err != nil {
+		t.Fatalf("ValidatePricingCatalog failed: %v", err)
+	}
+	if result.Valid || len(result.Issues) != 1 {
+		t.Fatalf("unexpected validation %+v", result)
+	}
+	if string(received) != string(catalog) {
+		t.Fatalf("expected catalog posted verbatim, got %s", received)
+	}
+
+	if _, err := ValidatePricingCatalog(client, []byte("{not json")); err == nil {
+		t.Fatal("expected error for malformed catalog")
+	}
+}
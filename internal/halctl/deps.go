@@ -0,0 +1,67 @@
+package halctl
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type MilestoneJSON struct {
+	Project     string    `json:"project"`
+	Milestone   string    `json:"milestone,omitempty"`
+	SessionID   string    `json:"session_id,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// DependencyStatusJSON is one project in the supervisor's dependency graph.
+type DependencyStatusJSON struct {
+	Project        string         `json:"project"`
+	DependsOn      []string       `json:"depends_on"`
+	Dependents     []string       `json:"dependents"`
+	WaitingOn      []string       `json:"waiting_on"`
+	Ready          bool           `json:"ready"`
+	AutoStart      bool           `json:"auto_start"`
+	ActiveSessions int            `json:"active_sessions"`
+	LastMilestone  *MilestoneJSON `json:"last_milestone,omitempty"`
+	TriggeredAt    *time.Time     `json:"triggered_at,omitempty"`
+}
+
+type MilestoneResult struct {
+	Milestone MilestoneJSON `json:"milestone"`
+	Started   []string      `json:"started"`
+}
+
+func GetDependencyGraph(client *HTTPClient) ([]DependencyStatusJSON, error) {
+	body, err := client.Get("/api/v1/deps")
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []DependencyStatusJSON
+	if err := ParseResponse(body, &statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// CompleteMilestone records a milestone for project and returns the
+// dependents the supervisor started as a result.
+func CompleteMilestone(client *HTTPClient, project, milestone string) (*MilestoneResult, error) {
+	if project == "" {
+		return nil, fmt.Errorf("project is required")
+	}
+
+	payload := map[string]string{"milestone": milestone}
+	body, err := client.Post("/api/v1/deps/"+url.PathEscape(project)+"/milestones", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result MilestoneResult
+	if err := ParseResponse(body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
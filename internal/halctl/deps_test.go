@@ -0,0 +1,58 @@
+package halctl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetDependencyGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/deps" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: []DependencyStatusJSON{
+			{Project: "ai-os-interfaces", Dependents: []string{"ai-os-l0"}, Ready: true, AutoStart: true},
+			{Project: "ai-os-l0", DependsOn: []string{"ai-os-interfaces"}, WaitingOn: []string{"ai-os-interfaces"}, AutoStart: true},
+		}})
+	}))
+	defer server.Close()
+
+	statuses, err := GetDependencyGraph(NewHTTPClient(server.URL, "test-token"))
+	if err != nil {
+		t.Fatalf("GetDependencyGraph failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[1].Ready || statuses[1].WaitingOn[0] != "ai-os-interfaces" {
+		t.Fatalf("unexpected graph %+v", statuses)
+	}
+}
+
+func TestCompleteMilestone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/deps/ai-os-interfaces/milestones" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req["milestone"] != "v1" {
+			t.Errorf("unexpected body %v (%v)", req, err)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: MilestoneResult{
+			Milestone: MilestoneJSON{Project: "ai-os-interfaces", Milestone: "v1"},
+			Started:   []string{"ai-os-l0"},
+		}})
+	}))
+	defer server.Close()
+
+	result, err := CompleteMilestone(NewHTTPClient(server.URL, "test-token"), "ai-os-interfaces", "v1")
+	if err != nil {
+		t.Fatalf("CompleteMilestone failed: %v", err)
+	}
+	if len(result.Started) != 1 || result.Started[0] != "ai-os-l0" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if _, err := CompleteMilestone(NewHTTPClient(server.URL, "test-token"), "", ""); err == nil {
+		t.Fatal("expected error for empty project")
+	}
+}
@@ -2,15 +2,52 @@ package halctl
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 )
 
 type NodeJSON struct {
-	ID            string    `json:"id"`
-	Hostname      string    `json:"hostname"`
-	Status        string    `json:"status"`
-	LastHeartbeat time.Time `json:"last_heartbeat"`
-	ConnectedAt   time.Time `json:"connected_at"`
+	ID            string             `json:"id"`
+	Hostname      string             `json:"hostname"`
+	Status        string             `json:"status"`
+	Lifecycle     string             `json:"lifecycle"`
+	LastHeartbeat time.Time          `json:"last_heartbeat"`
+	ConnectedAt   time.Time          `json:"connected_at"`
+	Projects      []string           `json:"projects,omitempty"`
+	Labels        map[string]string  `json:"labels,omitempty"`
+	Inventory     *NodeInventoryJSON `json:"inventory,omitempty"`
+	Telemetry     *NodeTelemetryJSON `json:"telemetry,omitempty"`
+}
+
+// NodeInventoryJSON is a node's platform and tool versions.
+type NodeInventoryJSON struct {
+	OS           string            `json:"os,omitempty"`
+	Kernel       string            `json:"kernel,omitempty"`
+	Arch         string            `json:"arch,omitempty"`
+	CPUCount     int               `json:"cpu_count,omitempty"`
+	AgentVersion string            `json:"agent_version,omitempty"`
+	Tools        map[string]string `json:"tools,omitempty"`
+}
+
+// NodeTelemetryJSON is the resource usage a node last reported.
+type NodeTelemetryJSON struct {
+	CPUPercent           float64         `json:"cpu_percent"`
+	MemoryPercent        float64         `json:"memory_percent"`
+	Load1                float64         `json:"load1"`
+	Load5                float64         `json:"load5"`
+	Load15               float64         `json:"load15"`
+	MemoryTotalBytes     uint64          `json:"memory_total_bytes"`
+	MemoryAvailableBytes uint64          `json:"memory_available_bytes"`
+	Disks                []DiskUsageJSON `json:"disks,omitempty"`
+	ReportedAt           time.Time       `json:"reported_at"`
+}
+
+// DiskUsageJSON is the space on the filesystem holding a project directory.
+type DiskUsageJSON struct {
+	Project    string `json:"project"`
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	TotalBytes uint64 `json:"total_bytes"`
 }
 
 func ListNodes(client *HTTPClient) ([]NodeJSON, error) {
@@ -44,3 +81,122 @@ func GetNode(client *HTTPClient, id string) (*NodeJSON, error) {
 
 	return &node, nil
 }
+
+// DrainRequest asks the supervisor to drain a node. A zero timeout uses the
+// supervisor default.
+type DrainRequest struct {
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty"`
+	Handover       bool `json:"handover,omitempty"`
+}
+
+// RemovedNodeJSON reports a removed node and the sessions dropped from the
+// live view with it; their history is kept.
+type RemovedNodeJSON struct {
+	NodeID         string   `json:"node_id"`
+	PurgedSessions []string `json:"purged_sessions"`
+}
+
+func CordonNode(client *HTTPClient, id string) (*NodeJSON, error) {
+	return postNodeAction(client, id, "cordon", nil)
+}
+
+func UncordonNode(client *HTTPClient, id string) (*NodeJSON, error) {
+	return postNodeAction(client, id, "uncordon", nil)
+}
+
+func DrainNode(client *HTTPClient, id string, req DrainRequest) (*NodeJSON, error) {
+	return postNodeAction(client, id, "drain", req)
+}
+
+func RemoveNode(client *HTTPClient, id string) (*RemovedNodeJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("node id is required")
+	}
+
+	body, err := client.Delete("/api/v1/nodes/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var removed RemovedNodeJSON
+	if err := ParseResponse(body, &removed); err != nil {
+		return nil, err
+	}
+
+	return &removed, nil
+}
+
+// RevokedNodeJSON reports a revoked node and whether it was disconnected.
+type RevokedNodeJSON struct {
+	NodeID       string    `json:"node_id"`
+	RevokedAt    time.Time `json:"revoked_at"`
+	Disconnected bool      `json:"disconnected"`
+}
+
+// JoinTokenRequest asks for a join token. NodeID binds the token to one
+// node; a zero TTL uses the supervisor default.
+type JoinTokenRequest struct {
+	NodeID     string `json:"node_id,omitempty"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+// JoinTokenJSON is a one-time token an agent exchanges for its node
+// credential. CAFingerprint is set when the supervisor runs the node CA.
+type JoinTokenJSON struct {
+	Token         string    `json:"token"`
+	NodeID        string    `json:"node_id,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CAFingerprint string    `json:"ca_fingerprint,omitempty"`
+}
+
+// RevokeNode discards a node's credential, disconnects it and blocks it from
+// reconnecting.
+func RevokeNode(client *HTTPClient, id string) (*RevokedNodeJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("node id is required")
+	}
+
+	body, err := client.Post("/api/v1/nodes/"+url.PathEscape(id)+"/revoke", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked RevokedNodeJSON
+	if err := ParseResponse(body, &revoked); err != nil {
+		return nil, err
+	}
+
+	return &revoked, nil
+}
+
+func CreateJoinToken(client *HTTPClient, req JoinTokenRequest) (*JoinTokenJSON, error) {
+	body, err := client.Post("/api/v1/nodes/join-tokens", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var token JoinTokenJSON
+	if err := ParseResponse(body, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func postNodeAction(client *HTTPClient, id, action string, payload interface{}) (*NodeJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("node id is required")
+	}
+
+	body, err := client.Post("/api/v1/nodes/"+url.PathEscape(id)+"/"+action, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var node NodeJSON
+	if err := ParseResponse(body, &node); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
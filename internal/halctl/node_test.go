@@ -0,0 +1,99 @@
+package halctl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNodeLifecycleCommands(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/nodes/n-1/cordon":
+			json.NewEncoder(w).Encode(APIResponse{Data: NodeJSON{ID: "n-1", Lifecycle: "cordoned"}})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/nodes/n-1/drain":
+			var req DrainRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TimeoutSeconds != 300 || !req.Handover {
+				t.Errorf("unexpected drain body %+v (%v)", req, err)
+			}
+			json.NewEncoder(w).Encode(APIResponse{Data: NodeJSON{ID: "n-1", Lifecycle: "draining"}})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/nodes/n-1":
+			json.NewEncoder(w).Encode(APIResponse{Data: RemovedNodeJSON{NodeID: "n-1", PurgedSessions: []string{"s-1"}}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	node, err := CordonNode(client, "n-1")
+	if err != nil || node.Lifecycle != "cordoned" {
+		t.Fatalf("unexpected cordon result %+v (%v)", node, err)
+	}
+	node, err = DrainNode(client, "n-1", DrainRequest{TimeoutSeconds: 300, Handover: true})
+	if err != nil || node.Lifecycle != "draining" {
+		t.Fatalf("unexpected drain result %+v (%v)", node, err)
+	}
+	removed, err := RemoveNode(client, "n-1")
+	if err != nil || len(removed.PurgedSessions) != 1 {
+		t.Fatalf("unexpected remove result %+v (%v)", removed, err)
+	}
+
+	if _, err := UncordonNode(client, ""); err == nil {
+		t.Fatal("expected error for empty node id")
+	}
+}
+
+func TestGetNodeInventory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"id":"n-1","hostname":"host-1","status":"online",
+			"inventory":{"os":"Debian 12","kernel":"6.1.0","arch":"arm64","cpu_count":4,"agent_version":"v1.4.0","tools":{"git":"2.39.5"}},
+			"telemetry":{"cpu_percent":12.5,"load1":0.75,"memory_total_bytes":4294967296,"disks":[{"project":"proj-a","path":"/srv/proj-a","free_bytes":1024,"total_bytes":2048}]}}}`))
+	}))
+	defer server.Close()
+
+	node, err := GetNode(NewHTTPClient(server.URL, "test-token"), "n-1")
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if node.Inventory == nil || node.Inventory.AgentVersion != "v1.4.0" || node.Inventory.Tools["git"] != "2.39.5" {
+		t.Fatalf("unexpected inventory %+v", node.Inventory)
+	}
+	if node.Telemetry == nil || node.Telemetry.Load1 != 0.75 || len(node.Telemetry.Disks) != 1 || node.Telemetry.Disks[0].FreeBytes != 1024 {
+		t.Fatalf("unexpected telemetry %+v", node.Telemetry)
+	}
+}
+
+func TestNodeEnrollmentCommands(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/nodes/n-1/revoke":
+			json.NewEncoder(w).Encode(APIResponse{Data: RevokedNodeJSON{NodeID: "n-1", Disconnected: true}})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/nodes/join-tokens":
+			var req JoinTokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.NodeID != "n-2" || req.TTLSeconds != 900 {
+				t.Errorf("unexpected join token body %+v (%v)", req, err)
+			}
+			json.NewEncoder(w).Encode(APIResponse{Data: JoinTokenJSON{Token: "abc", NodeID: "n-2", CAFingerprint: "ab12"}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	revoked, err := RevokeNode(client, "n-1")
+	if err != nil || !revoked.Disconnected {
+		t.Fatalf("unexpected revoke result %+v (%v)", revoked, err)
+	}
+	token, err := CreateJoinToken(client, JoinTokenRequest{NodeID: "n-2", TTLSeconds: 900})
+	if err != nil || token.Token != "abc" || token.CAFingerprint != "ab12" {
+		t.Fatalf("unexpected join token %+v (%v)", token, err)
+	}
+	if _, err := RevokeNode(client, ""); err == nil {
+		t.Fatal("expected error for empty node id")
+	}
+}
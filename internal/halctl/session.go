@@ -7,13 +7,34 @@ import (
 )
 
 type SessionJSON struct {
-	ID        string    `json:"id"`
-	NodeID    string    `json:"node_id"`
-	Project   string    `json:"project"`
-	Status    string    `json:"status"`
-	Tokens    int       `json:"tokens"`
-	Cost      float64   `json:"cost"`
-	StartedAt time.Time `json:"started_at"`
+	ID             string    `json:"id"`
+	NodeID         string    `json:"node_id"`
+	Project        string    `json:"project"`
+	Status         string    `json:"status"`
+	Model          string    `json:"model,omitempty"`
+	Tokens         int       `json:"tokens"`
+	ContextTokens  int       `json:"context_tokens"`
+	ContextWindow  int       `json:"context_window,omitempty"`
+	ContextPercent float64   `json:"context_percent,omitempty"`
+	Cost           float64   `json:"cost"`
+	StartedAt      time.Time `json:"started_at"`
+	ParentID       string    `json:"parent_session_id,omitempty"`
+}
+
+type SessionTransitionJSON struct {
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Cause     string    `json:"cause"`
+	Detail    string    `json:"detail,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+type SessionHistoryJSON struct {
+	SessionID   string                  `json:"session_id"`
+	Chain       []SessionJSON           `json:"chain"`
+	Transitions []SessionTransitionJSON `json:"transitions"`
 }
 
 type EventJSON struct {
@@ -76,6 +97,24 @@ func GetSession(client *HTTPClient, id string) (*SessionJSON, error) {
 	return &session, nil
 }
 
+func GetSessionHistory(client *HTTPClient, id string) (*SessionHistoryJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("session id is required")
+	}
+
+	body, err := client.Get("/api/v1/sessions/" + id + "/history")
+	if err != nil {
+		return nil, err
+	}
+
+	var history SessionHistoryJSON
+	if err := ParseResponse(body, &history); err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
 func GetSessionLogs(client *HTTPClient, id string, limit int) ([]EventJSON, error) {
 	if id == "" {
 		return nil, fmt.Errorf("session id is required")
@@ -0,0 +1,120 @@
+package halctl
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TaskJSON is a task in the supervisor's queue. Position is only set while
+// the task is queued.
+type TaskJSON struct {
+	ID           string     `json:"id"`
+	Project      string     `json:"project"`
+	Prompt       string     `json:"prompt"`
+	Priority     int        `json:"priority"`
+	Deadline     *time.Time `json:"deadline,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	Status       string     `json:"status"`
+	Position     int        `json:"position,omitempty"`
+	NodeID       string     `json:"node_id,omitempty"`
+	SessionID    string     `json:"session_id,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Attempts     int        `json:"attempts"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty"`
+}
+
+// TaskRequest is the body of POST /api/v1/tasks.
+type TaskRequest struct {
+	Project  string     `json:"project"`
+	Prompt   string     `json:"prompt"`
+	Priority int        `json:"priority,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Model    string     `json:"model,omitempty"`
+}
+
+func AddTask(client *HTTPClient, req TaskRequest) (*TaskJSON, error) {
+	if req.Project == "" {
+		return nil, fmt.Errorf("project is required")
+	}
+	if req.Prompt == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+
+	body, err := client.Post("/api/v1/tasks", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var task TaskJSON
+	if err := ParseResponse(body, &task); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// ListTasks lists tasks in dispatch order, optionally filtered by project
+// and status.
+func ListTasks(client *HTTPClient, project, status string) ([]TaskJSON, error) {
+	query := url.Values{}
+	if project != "" {
+		query.Set("project", project)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	path := "/api/v1/tasks"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	body, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []TaskJSON
+	if err := ParseResponse(body, &tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func GetTask(client *HTTPClient, id string) (*TaskJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("task id is required")
+	}
+
+	body, err := client.Get("/api/v1/tasks/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var task TaskJSON
+	if err := ParseResponse(body, &task); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+func CancelTask(client *HTTPClient, id string) (*TaskJSON, error) {
+	if id == "" {
+		return nil, fmt.Errorf("task id is required")
+	}
+
+	body, err := client.Delete("/api/v1/tasks/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var task TaskJSON
+	if err := ParseResponse(body, &task); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
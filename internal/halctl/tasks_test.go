@@ -0,0 +1,70 @@
+package halctl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/tasks" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req TaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Project != "alpha" || req.Priority != 2 || req.Model != "anthropic/claude-sonnet-4" {
+			t.Errorf("unexpected body %+v (%v)", req, err)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Data: TaskJSON{ID: "task-1", Project: req.Project, Status: "queued", Position: 3}})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	task, err := AddTask(client, TaskRequest{Project: "alpha", Prompt: "write docs", Priority: 2, Model: "anthropic/claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if task.ID != "task-1" || task.Position != 3 {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	if _, err := AddTask(client, TaskRequest{Project: "alpha"}); err == nil {
+		t.Fatal("expected error for empty prompt")
+	}
+}
+
+func TestListAndCancelTasks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/tasks":
+			if r.URL.Query().Get("project") != "alpha" || r.URL.Query().Get("status") != "queued" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(APIResponse{Data: []TaskJSON{{ID: "task-1", Status: "queued", Position: 1}}})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/tasks/task-1":
+			json.NewEncoder(w).Encode(APIResponse{Data: TaskJSON{ID: "task-1", Status: "cancelled"}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	tasks, err := ListTasks(client, "alpha", "queued")
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Position != 1 {
+		t.Fatalf("unexpected tasks %+v", tasks)
+	}
+
+	task, err := CancelTask(client, "task-1")
+	if err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	if task.Status != "cancelled" {
+		t.Fatalf("unexpected status %q", task.Status)
+	}
+}
@@ -0,0 +1,78 @@
+package halctl
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// APITokenJSON is a named API token. Token is only returned when the token
+// is created.
+type APITokenJSON struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// APITokenRequest is the body of POST /api/v1/tokens.
+type APITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func CreateAPIToken(client *HTTPClient, req APITokenRequest) (*APITokenJSON, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("token name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	body, err := client.Post("/api/v1/tokens", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var token APITokenJSON
+	if err := ParseResponse(body, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func ListAPITokens(client *HTTPClient) ([]APITokenJSON, error) {
+	body, err := client.Get("/api/v1/tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []APITokenJSON
+	if err := ParseResponse(body, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken stops the named token from authenticating.
+func RevokeAPIToken(client *HTTPClient, name string) (*APITokenJSON, error) {
+	if name == "" {
+		return nil, fmt.Errorf("token name is required")
+	}
+
+	body, err := client.Delete("/api/v1/tokens/" + url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var token APITokenJSON
+	if err := ParseResponse(body, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
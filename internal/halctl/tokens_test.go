@@ -0,0 +1,52 @@
+package halctl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPITokenCommands(t *testing.T) {
+	revokedAt := time.Now().UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/tokens":
+			var req APITokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name != "dashboard" || len(req.Scopes) != 1 || req.Scopes[0] != "sessions:read" {
+				t.Errorf("unexpected create body %+v (%v)", req, err)
+			}
+			json.NewEncoder(w).Encode(APIResponse{Data: APITokenJSON{Name: "dashboard", Scopes: req.Scopes, Token: "hal_abc"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/tokens":
+			json.NewEncoder(w).Encode(APIResponse{Data: []APITokenJSON{{Name: "dashboard", Scopes: []string{"sessions:read"}}}})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/tokens/dashboard":
+			json.NewEncoder(w).Encode(APIResponse{Data: APITokenJSON{Name: "dashboard", RevokedAt: &revokedAt}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token")
+	created, err := CreateAPIToken(client, APITokenRequest{Name: "dashboard", Scopes: []string{"sessions:read"}})
+	if err != nil || created.Token != "hal_abc" {
+		t.Fatalf("unexpected create result %+v (%v)", created, err)
+	}
+	tokens, err := ListAPITokens(client)
+	if err != nil || len(tokens) != 1 || tokens[0].Token != "" {
+		t.Fatalf("unexpected list result %+v (%v)", tokens, err)
+	}
+	revoked, err := RevokeAPIToken(client, "dashboard")
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("unexpected revoke result %+v (%v)", revoked, err)
+	}
+
+	if _, err := CreateAPIToken(client, APITokenRequest{Name: "ops"}); err == nil {
+		t.Fatal("expected an error without scopes")
+	}
+	if _, err := RevokeAPIToken(client, ""); err == nil {
+		t.Fatal("expected an error without a name")
+	}
+}
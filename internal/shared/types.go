@@ -26,3 +26,46 @@ const (
 	MessageTypeAuthState      MessageType = "auth_state"
 	MessageTypeConfigUpdate   MessageType = "config_update"
 )
+
+// HeartbeatPayload is the optional body of a heartbeat message: the agent's
+// current resource usage and, when set, its inventory.
+type HeartbeatPayload struct {
+	CPUPercent           float64        `json:"cpu_percent"`
+	MemoryPercent        float64        `json:"memory_percent"`
+	Load1                float64        `json:"load1,omitempty"`
+	Load5                float64        `json:"load5,omitempty"`
+	Load15               float64        `json:"load15,omitempty"`
+	MemoryTotalBytes     uint64         `json:"memory_total_bytes,omitempty"`
+	MemoryAvailableBytes uint64         `json:"memory_available_bytes,omitempty"`
+	Disks                []DiskUsage    `json:"disks,omitempty"`
+	Inventory            *NodeInventory `json:"inventory,omitempty"`
+}
+
+// DiskUsage is the space on the filesystem holding a project's directory.
+type DiskUsage struct {
+	Project    string `json:"project"`
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	TotalBytes uint64 `json:"total_bytes"`
+}
+
+// NodeInventory describes a node's platform and the versions of the agent
+// and of the tools it can run. Tools maps a tool name to its version and
+// leaves out tools that are not installed.
+type NodeInventory struct {
+	OS           string            `json:"os,omitempty"`
+	Kernel       string            `json:"kernel,omitempty"`
+	Arch         string            `json:"arch,omitempty"`
+	CPUCount     int               `json:"cpu_count,omitempty"`
+	AgentVersion string            `json:"agent_version,omitempty"`
+	Tools        map[string]string `json:"tools,omitempty"`
+}
+
+// NodeInfo describes a node in its register message: the host, the projects
+// it has checked out, the free-form labels from its config and its inventory.
+type NodeInfo struct {
+	Hostname  string            `json:"hostname,omitempty"`
+	Projects  []string          `json:"projects,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Inventory *NodeInventory    `json:"inventory,omitempty"`
+}
@@ -0,0 +1,11 @@
+-- Separate input, output and prompt-cache token counts for costs and sessions
+
+ALTER TABLE costs ADD COLUMN input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE costs ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE costs ADD COLUMN cache_read_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE costs ADD COLUMN cache_write_tokens INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE sessions ADD COLUMN input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN cache_read_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN cache_write_tokens INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- Milestones completed by projects, used for dependency-aware scheduling
+
+CREATE TABLE IF NOT EXISTS project_milestones (
+    project TEXT NOT NULL,
+    milestone TEXT NOT NULL DEFAULT '',
+    session_id TEXT,
+    completed_at DATETIME NOT NULL,
+    PRIMARY KEY (project, milestone)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_milestones_completed ON project_milestones(completed_at);
@@ -0,0 +1,19 @@
+-- Queued work waiting for a project to become free
+
+CREATE TABLE IF NOT EXISTS task_queue (
+    id TEXT PRIMARY KEY,
+    project TEXT NOT NULL,
+    prompt TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    deadline DATETIME,
+    model TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'queued',
+    node_id TEXT,
+    session_id TEXT,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    dispatched_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_queue_status ON task_queue(status, project);
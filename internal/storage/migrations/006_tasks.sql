@@ -0,0 +1,25 @@
+-- Tasks that span several sessions (restarts, compactions, handovers)
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id TEXT PRIMARY KEY,
+    project TEXT NOT NULL,
+    title TEXT NOT NULL,
+    acceptance_criteria TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT 'open',
+    outcome TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_project_state ON tasks(project, state);
+
+CREATE TABLE IF NOT EXISTS task_sessions (
+    task_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    linked_at DATETIME NOT NULL,
+    PRIMARY KEY (task_id, session_id),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_sessions_session ON task_sessions(session_id);
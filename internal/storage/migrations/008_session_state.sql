@@ -0,0 +1,7 @@
+-- Keep the rest of a tracked session's state across supervisor restarts
+
+ALTER TABLE sessions ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN current_task TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN compaction_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN context_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN last_activity DATETIME;
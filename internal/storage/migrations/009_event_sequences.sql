@@ -0,0 +1,13 @@
+-- Per-agent event sequence state, so a supervisor restart resumes where it
+-- left off instead of replaying or re-applying events
+
+ALTER TABLE events ADD COLUMN agent_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_events_agent_seq ON events(agent_id, seq);
+
+CREATE TABLE IF NOT EXISTS agent_sequences (
+    agent_id TEXT PRIMARY KEY,
+    last_seq INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL
+);
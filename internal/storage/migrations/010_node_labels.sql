@@ -0,0 +1,3 @@
+-- Free-form labels agents report at registration, as a JSON object
+
+ALTER TABLE nodes ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
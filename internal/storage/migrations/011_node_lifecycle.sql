@@ -0,0 +1,3 @@
+-- Maintenance state of a node: active, cordoned, draining or drained
+
+ALTER TABLE nodes ADD COLUMN lifecycle TEXT NOT NULL DEFAULT 'active';
@@ -0,0 +1,3 @@
+-- Platform, agent and tool versions agents report, as a JSON object
+
+ALTER TABLE nodes ADD COLUMN inventory TEXT NOT NULL DEFAULT '{}';
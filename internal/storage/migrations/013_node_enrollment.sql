@@ -0,0 +1,19 @@
+-- One-time join tokens agents exchange for a per-node credential, and the
+-- credentials themselves. Only SHA-256 hashes are stored. A revoked node
+-- keeps its row so it cannot reconnect or enroll again until it is removed.
+
+CREATE TABLE IF NOT EXISTS join_tokens (
+    token_hash TEXT PRIMARY KEY,
+    node_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    used_by TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS node_credentials (
+    node_id TEXT PRIMARY KEY,
+    credential_hash TEXT NOT NULL DEFAULT '',
+    enrolled_at DATETIME,
+    revoked_at DATETIME
+);
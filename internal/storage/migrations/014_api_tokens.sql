@@ -0,0 +1,12 @@
+-- Named HTTP API tokens with scopes. Only SHA-256 hashes of tokens are
+-- stored. A revoked token keeps its row, and its name, until a token of the
+-- same name is created again.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    name TEXT PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
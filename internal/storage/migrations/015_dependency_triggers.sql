@@ -0,0 +1,8 @@
+-- When each dependent project was last started by the dependency scheduler.
+-- A dependent is started again only once every project it depends on has
+-- completed a milestone after this time.
+
+CREATE TABLE IF NOT EXISTS dependency_triggers (
+    project TEXT PRIMARY KEY,
+    triggered_at DATETIME NOT NULL
+);
@@ -0,0 +1,25 @@
+-- Token usage each session reported per UTC day, so spend is attributed to
+-- the day it was incurred rather than the day the session started. Usage
+-- recorded before this table existed is attributed to the start day.
+
+CREATE TABLE IF NOT EXISTS session_usage (
+    session_id TEXT NOT NULL,
+    date TEXT NOT NULL,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_write_tokens INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (session_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_usage_date ON session_usage(date);
+
+INSERT OR IGNORE INTO session_usage (
+    session_id, date, tokens,
+    input_tokens, output_tokens, cache_read_tokens, cache_write_tokens
+)
+SELECT id, substr(started_at, 1, 10), COALESCE(tokens, 0),
+    input_tokens, output_tokens, cache_read_tokens, cache_write_tokens
+FROM sessions
+WHERE COALESCE(tokens, 0) + input_tokens + output_tokens + cache_read_tokens + cache_write_tokens > 0;
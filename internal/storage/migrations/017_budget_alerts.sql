@@ -0,0 +1,9 @@
+-- The highest budget alert sent for each budget and period, so a restarted
+-- supervisor does not repeat alerts. Rows are dropped once their period
+-- resets.
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    alert_key TEXT PRIMARY KEY,
+    value REAL NOT NULL,
+    resets_at DATETIME NOT NULL
+);
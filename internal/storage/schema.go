@@ -11,13 +11,17 @@ type Event struct {
 }
 
 type Session struct {
-	ID        string
-	NodeID    string
-	Project   string
-	Status    string
-	Tokens    int
-	Cost      float64
-	StartedAt string
+	ID               string
+	NodeID           string
+	Project          string
+	Status           string
+	Tokens           int
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+	Cost             float64
+	StartedAt        string
 }
 
 type Node struct {
@@ -29,12 +33,16 @@ type Node struct {
 }
 
 type Cost struct {
-	ID       string
-	Provider string
-	Model    string
-	Date     string
-	Tokens   int
-	CostUSD  float64
+	ID               string
+	Provider         string
+	Model            string
+	Date             string
+	Tokens           int
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+	CostUSD          float64
 }
 
 type CommandIdempotency struct {
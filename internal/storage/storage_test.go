@@ -60,8 +60,8 @@ func TestMigrateIdempotent(t *testing.T) {
 		t.Fatalf("failed to query migrations: %v", err)
 	}
 
-	if count != 2 {
-		t.Errorf("expected 2 migration records, got %d", count)
+	if count != 17 {
+		t.Errorf("expected 17 migration records, got %d", count)
 	}
 }
 
@@ -79,6 +79,7 @@ func (c *AgentConn) handleEnvelope(env *shared.Envelope) {
 	c.mu.Unlock()
 
 	if env.Type == string(shared.MessageTypeHeartbeat) {
+		c.hub.recordHeartbeat(c.agentID, env.Payload)
 		return
 	}
 
@@ -88,6 +89,7 @@ func (c *AgentConn) handleEnvelope(env *shared.Envelope) {
 	}
 
 	if env.Type == string(shared.MessageTypeRegister) {
+		c.hub.recordNodeInfo(c.agentID, env.Payload)
 		return
 	}
 
@@ -96,6 +98,11 @@ func (c *AgentConn) handleEnvelope(env *shared.Envelope) {
 		return
 	}
 
+	if env.Type == string(shared.MessageTypeEvent) {
+		c.hub.ingestSessionEvent(env.Payload)
+		return
+	}
+
 	if env.Type == string(shared.MessageTypeCommandResult) {
 		c.hub.handleCommandResultEnvelope(env)
 	}
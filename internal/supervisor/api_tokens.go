@@ -0,0 +1,297 @@
+package supervisor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// APIScope is a permission granted to an HTTP API token.
+type APIScope string
+
+const (
+	ScopeSessionsRead    APIScope = "sessions:read"
+	ScopeSessionsControl APIScope = "sessions:control"
+	ScopeCredentialsPush APIScope = "credentials:push"
+	ScopeEnvProvision    APIScope = "env:provision"
+	// ScopeAdmin grants every other scope, plus node lifecycle and token
+	// management.
+	ScopeAdmin APIScope = "admin"
+)
+
+// APIScopes lists every scope a token can be granted.
+var APIScopes = []APIScope{ScopeSessionsRead, ScopeSessionsControl, ScopeCredentialsPush, ScopeEnvProvision, ScopeAdmin}
+
+const (
+	apiTokenPrefix = "hal_"
+	// apiTokenTouchInterval limits how often last_used_at is written for a
+	// busy token.
+	apiTokenTouchInterval = time.Minute
+
+	// Audit log actions for token management.
+	auditActionAPITokenCreate = "api_token_create"
+	auditActionAPITokenRevoke = "api_token_revoke"
+)
+
+var (
+	// ErrInvalidAPIToken is returned for an unknown or revoked API token.
+	ErrInvalidAPIToken = errors.New("invalid api token")
+	// ErrAPITokenNotFound is returned when no active token has the name.
+	ErrAPITokenNotFound = errors.New("api token not found")
+	// ErrAPITokenExists is returned when an active token already has the name.
+	ErrAPITokenExists = errors.New("api token already exists")
+	// ErrInvalidAPITokenName is returned for a token name that is empty, too
+	// long or has characters other than letters, digits, '.', '_' and '-'.
+	ErrInvalidAPITokenName = errors.New("token name must be 1-64 letters, digits, '.', '_' or '-'")
+
+	apiTokenNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+// APIToken is a named HTTP API token. Token is only available when the token
+// is created.
+type APIToken struct {
+	Name       string     `json:"name"`
+	Scopes     []APIScope `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// HasScope reports whether the token grants scope, directly or through
+// admin.
+func (t APIToken) HasScope(scope APIScope) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAPIScopes validates scope names, dropping duplicates.
+func ParseAPIScopes(names []string) ([]APIScope, error) {
+	seen := make(map[APIScope]bool, len(names))
+	scopes := make([]APIScope, 0, len(names))
+	for _, name := range names {
+		scope := APIScope(strings.TrimSpace(name))
+		if scope == "" || seen[scope] {
+			continue
+		}
+		known := false
+		for _, candidate := range APIScopes {
+			if scope == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i] < scopes[j] })
+	return scopes, nil
+}
+
+// APITokenStore issues, authenticates and revokes named HTTP API tokens.
+// Only hashes of tokens are stored.
+type APITokenStore struct {
+	db     *sql.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewAPITokenStore(db *sql.DB, logger *zap.Logger) *APITokenStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &APITokenStore{db: db, logger: logger, now: time.Now}
+}
+
+// Create issues a token named name with scopes. The name of a revoked token
+// can be reused.
+func (s *APITokenStore) Create(name string, scopes []APIScope) (APIToken, error) {
+	if !apiTokenNamePattern.MatchString(name) {
+		return APIToken{}, ErrInvalidAPITokenName
+	}
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	scopes, err := ParseAPIScopes(names)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	secret, err := randomSecret()
+	if err != nil {
+		return APIToken{}, fmt.Errorf("create api token %s: %w", name, err)
+	}
+	token := APIToken{
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: s.now().UTC(),
+		Token:     apiTokenPrefix + secret,
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO api_tokens (name, token_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			token_hash = excluded.token_hash,
+			scopes = excluded.scopes,
+			created_at = excluded.created_at,
+			last_used_at = NULL,
+			revoked_at = NULL
+		WHERE api_tokens.revoked_at IS NOT NULL
+	`, name, hashSecret(token.Token), joinScopes(scopes), token.CreatedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return APIToken{}, fmt.Errorf("create api token %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return APIToken{}, ErrAPITokenExists
+	}
+
+	s.logger.Info("api token created", zap.String("name", name), zap.String("scopes", joinScopes(scopes)))
+	return token, nil
+}
+
+// List returns every token, revoked ones included, by name.
+func (s *APITokenStore) List() ([]APIToken, error) {
+	rows, err := s.db.Query(`
+		SELECT name, scopes, created_at, last_used_at, revoked_at
+		FROM api_tokens
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list api tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Revoke stops the named token from authenticating.
+func (s *APITokenStore) Revoke(name string) (APIToken, error) {
+	revokedAt := s.now().UTC()
+	result, err := s.db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE name = ? AND revoked_at IS NULL`,
+		revokedAt.Format(time.RFC3339Nano), name)
+	if err != nil {
+		return APIToken{}, fmt.Errorf("revoke api token %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return APIToken{}, ErrAPITokenNotFound
+	}
+
+	row := s.db.QueryRow(`SELECT name, scopes, created_at, last_used_at, revoked_at FROM api_tokens WHERE name = ?`, name)
+	token, err := scanAPIToken(row)
+	if err != nil {
+		return APIToken{}, fmt.Errorf("revoke api token %s: %w", name, err)
+	}
+	s.logger.Warn("api token revoked", zap.String("name", name))
+	return token, nil
+}
+
+// Authenticate returns the active token matching secret and records its use.
+func (s *APITokenStore) Authenticate(secret string) (APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return APIToken{}, ErrInvalidAPIToken
+	}
+
+	row := s.db.QueryRow(`
+		SELECT name, scopes, created_at, last_used_at, revoked_at
+		FROM api_tokens
+		WHERE token_hash = ?
+	`, hashSecret(secret))
+	token, err := scanAPIToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIToken{}, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return APIToken{}, fmt.Errorf("authenticate api token: %w", err)
+	}
+	if token.RevokedAt != nil {
+		return APIToken{}, ErrInvalidAPIToken
+	}
+
+	now := s.now().UTC()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if _, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE name = ?`, now.Format(time.RFC3339Nano), token.Name); err != nil {
+			s.logger.Warn("api token last use not recorded", zap.String("name", token.Name), zap.Error(err))
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
+
+type apiTokenScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIToken(row apiTokenScanner) (APIToken, error) {
+	var (
+		token      APIToken
+		scopes     string
+		createdAt  string
+		lastUsedAt sql.NullString
+		revokedAt  sql.NullString
+	)
+	if err := row.Scan(&token.Name, &scopes, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return APIToken{}, err
+	}
+
+	var err error
+	if token.CreatedAt, err = parseSQLiteTimestamp(createdAt); err != nil {
+		return APIToken{}, err
+	}
+	for _, ts := range []struct {
+		value sql.NullString
+		dest  **time.Time
+	}{{lastUsedAt, &token.LastUsedAt}, {revokedAt, &token.RevokedAt}} {
+		if !ts.value.Valid {
+			continue
+		}
+		parsed, err := parseSQLiteTimestamp(ts.value.String)
+		if err != nil {
+			return APIToken{}, err
+		}
+		*ts.dest = &parsed
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope != "" {
+			token.Scopes = append(token.Scopes, APIScope(scope))
+		}
+	}
+	return token, nil
+}
+
+func joinScopes(scopes []APIScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
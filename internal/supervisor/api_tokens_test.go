@@ -0,0 +1,186 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPITokenStore(t *testing.T) {
+	store := NewAPITokenStore(setupSupervisorTestDB(t), zap.NewNop())
+
+	token, err := store.Create("dashboard", []APIScope{ScopeSessionsRead, ScopeSessionsRead})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(token.Token, apiTokenPrefix) || len(token.Scopes) != 1 {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	if _, err := store.Create("dashboard", []APIScope{ScopeAdmin}); !errors.Is(err, ErrAPITokenExists) {
+		t.Fatalf("expected ErrAPITokenExists, got %v", err)
+	}
+	if _, err := store.Create("bad name", []APIScope{ScopeAdmin}); !errors.Is(err, ErrInvalidAPITokenName) {
+		t.Fatalf("expected ErrInvalidAPITokenName, got %v", err)
+	}
+	if _, err := store.Create("ops", []APIScope{"sessions:write"}); err == nil {
+		t.Fatal("expected an unknown scope to be refused")
+	}
+
+	authed, err := store.Authenticate(token.Token)
+	if err != nil || authed.Name != "dashboard" || authed.LastUsedAt == nil {
+		t.Fatalf("authenticate: %+v, %v", authed, err)
+	}
+	if !authed.HasScope(ScopeSessionsRead) || authed.HasScope(ScopeSessionsControl) {
+		t.Fatalf("unexpected scopes %v", authed.Scopes)
+	}
+	if _, err := store.Authenticate(token.Token + "x"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected ErrInvalidAPIToken, got %v", err)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 1 || list[0].Token != "" {
+		t.Fatalf("list must not expose tokens: %+v, %v", list, err)
+	}
+
+	revoked, err := store.Revoke("dashboard")
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("revoke: %+v, %v", revoked, err)
+	}
+	if _, err := store.Authenticate(token.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected a revoked token to be refused, got %v", err)
+	}
+	if _, err := store.Revoke("dashboard"); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("expected ErrAPITokenNotFound, got %v", err)
+	}
+
+	// The name of a revoked token can be reused for a new token.
+	reissued, err := store.Create("dashboard", []APIScope{ScopeAdmin})
+	if err != nil {
+		t.Fatalf("reissue: %v", err)
+	}
+	if _, err := store.Authenticate(token.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatal("the old token must stay invalid after the name is reused")
+	}
+	if authed, err := store.Authenticate(reissued.Token); err != nil || !authed.HasScope(ScopeEnvProvision) {
+		t.Fatalf("expected admin to grant every scope: %+v, %v", authed, err)
+	}
+}
+
+func TestHTTPAPITokenScopes(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	seedNode(t, registry, "node-cred", "host-cred")
+
+	var dispatcher *CommandDispatcher
+	transport := &mockCommandTransport{}
+	transport.onSend = func(nodeID string, cmd Command) {
+		go func(cmdID string) {
+			time.Sleep(10 * time.Millisecond)
+			dispatcher.HandleCommandResult(CommandResult{CommandID: cmdID, Status: CommandStatusSuccess, Timestamp: time.Now().UTC()})
+		}(cmd.CommandID)
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+
+	api := NewHTTPAPI(registry, tracker, dispatcher, db, testAuthToken, logger)
+	audit := NewAuditLogger(db, logger)
+	api.SetAuditLogger(audit)
+	api.SetAPITokenStore(NewAPITokenStore(db, logger))
+	handler := api.Handler()
+
+	do := func(token, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authRequest(method, path, body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	create := func(name string, scopes ...string) string {
+		t.Helper()
+		body, _ := json.Marshal(createAPITokenRequest{Name: name, Scopes: scopes})
+		w := do(testAuthToken, "POST", "/api/v1/tokens", string(body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s: %d %s", name, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data APIToken `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Data.Token
+	}
+
+	reader := create("dashboard", "sessions:read")
+	pusher := create("ci", "credentials:push")
+
+	if w := do(testAuthToken, "POST", "/api/v1/tokens", `{"name":"dashboard","scopes":["admin"]}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate name, got %d", w.Code)
+	}
+	if w := do(testAuthToken, "POST", "/api/v1/tokens", `{"name":"x","scopes":["root"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown scope, got %d", w.Code)
+	}
+
+	tests := []struct {
+		token, method, path, body string
+		want                      int
+	}{
+		{reader, "GET", "/api/v1/sessions", "", http.StatusOK},
+		{reader, "GET", "/api/v1/nodes", "", http.StatusOK},
+		{reader, "POST", "/api/v1/nodes/node-cred/cordon", "", http.StatusForbidden},
+		{reader, "GET", "/api/v1/tokens", "", http.StatusForbidden},
+		{reader, "POST", "/api/v1/commands", `{"type":"kill_session","target":"p","args":{"session_id":"s"}}`, http.StatusForbidden},
+		{reader, "POST", "/api/v1/commands", `{"type":"env_provision","target":"p"}`, http.StatusForbidden},
+		{reader, "POST", "/api/v1/commands/credentials/push", `{"target_node":"node-cred","env_vars":{"K":"v"},"version":1}`, http.StatusForbidden},
+		{pusher, "GET", "/api/v1/sessions", "", http.StatusForbidden},
+		{pusher, "POST", "/api/v1/commands/credentials/push", `{"target_node":"node-cred","env_vars":{"K":"v"},"version":1}`, http.StatusOK},
+		{"hal_unknown", "GET", "/api/v1/sessions", "", http.StatusUnauthorized},
+		{testAuthToken, "GET", "/api/v1/tokens", "", http.StatusOK},
+	}
+	for _, tc := range tests {
+		if w := do(tc.token, tc.method, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	entries, err := audit.QueryByActor("token:ci", 10)
+	if err != nil || len(entries) != 1 || entries[0].Action != string(CommandTypeCredentialPush) {
+		t.Fatalf("expected the push audited under the token name: %+v, %v", entries, err)
+	}
+
+	operator := create("oncall", "sessions:control")
+	if w := do(operator, "POST", "/api/v1/commands", `{"type":"kill_session","target":"proj-a","args":{"session_id":"s-9"}}`); w.Code != http.StatusOK {
+		t.Fatalf("kill: %d %s", w.Code, w.Body.String())
+	}
+	var actor, target string
+	if err := db.QueryRow(`SELECT actor, target FROM audit_log WHERE action = ?`, string(CommandTypeKillSession)).Scan(&actor, &target); err != nil {
+		t.Fatalf("expected the kill in audit_log: %v", err)
+	}
+	if actor != "token:oncall" || target != "proj-a" {
+		t.Fatalf("unexpected audit row actor=%q target=%q", actor, target)
+	}
+
+	if w := do(testAuthToken, "DELETE", "/api/v1/tokens/dashboard", ""); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body.String())
+	}
+	for _, action := range []string{auditActionAPITokenCreate, auditActionAPITokenRevoke} {
+		var actor, target string
+		err := db.QueryRow(`SELECT actor, target FROM audit_log WHERE action = ? AND target = 'dashboard'`, action).Scan(&actor, &target)
+		if err != nil || actor != "api" {
+			t.Fatalf("expected %s of dashboard audited as api, got %q (%v)", action, actor, err)
+		}
+	}
+	if w := do(reader, "GET", "/api/v1/sessions", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revocation, got %d", w.Code)
+	}
+	if w := do(testAuthToken, "DELETE", "/api/v1/tokens/dashboard", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a revoked token, got %d", w.Code)
+	}
+}
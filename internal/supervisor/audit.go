@@ -71,6 +71,31 @@ func (a *AuditLogger) LogCommand(cmd Command, result *CommandResult, actor strin
 	}
 }
 
+// LogAction records an administrative action that is not an agent command,
+// such as creating an API token.
+func (a *AuditLogger) LogAction(action, target string, args map[string]interface{}, actor, ipAddr string) {
+	if a.db == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		ID:        uuid.NewString(),
+		Timestamp: time.Now().UTC(),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Args:      SanitizeArgs(args),
+		Result:    string(CommandStatusSuccess),
+		IPAddress: ipAddr,
+	}
+	if err := a.insertEntry(entry); err != nil {
+		a.logger.Warn("failed to write audit log entry",
+			zap.String("action", entry.Action),
+			zap.Error(err),
+		)
+	}
+}
+
 func (a *AuditLogger) insertEntry(entry AuditEntry) error {
 	_, err := a.db.Exec(`
 		INSERT INTO audit_log (id, timestamp, actor, action, target, args, result, error, duration_ms, ip_address)
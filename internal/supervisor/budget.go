@@ -0,0 +1,591 @@
+package supervisor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+const budgetEnforcerAgentID = "budget-enforcer"
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDaily   BudgetPeriod = "daily"
+	BudgetPeriodWeekly  BudgetPeriod = "weekly"
+	BudgetPeriodMonthly BudgetPeriod = "monthly"
+)
+
+const (
+	BudgetScopeGlobal  = "global"
+	BudgetScopeProject = "project"
+)
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+type BudgetStatus struct {
+	Scope       string       `json:"scope"`
+	Project     string       `json:"project,omitempty"`
+	Period      BudgetPeriod `json:"period"`
+	LimitUSD    float64      `json:"limit_usd"`
+	SpentUSD    float64      `json:"spent_usd"`
+	Percent     float64      `json:"percent"`
+	Exceeded    bool         `json:"exceeded"`
+	PeriodStart time.Time    `json:"period_start"`
+	ResetsAt    time.Time    `json:"resets_at"`
+
+	// ProjectedUSD is the forecast spend at the end of the period. It is only
+	// set for weekly and monthly budgets when a forecast is available.
+	ProjectedUSD float64 `json:"projected_usd,omitempty"`
+}
+
+// BudgetEnforcer evaluates org-wide and per-project spend against the
+// configured daily/weekly/monthly limits. Spend is taken from provider-reported
+// cost buckets and from live session estimates, whichever is higher, so that
+// lagging provider reports do not hide a runaway session. Session estimates
+// are attributed to the day their usage was reported.
+type BudgetEnforcer struct {
+	cfg     config.BudgetConfig
+	costs   *CostAggregator
+	tracker interface{ GetAllSessions() []TrackedSession }
+	events  interface {
+		ProcessEvent(agentID string, event Event) error
+	}
+	logger *zap.Logger
+	now    func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	statuses []BudgetStatus
+	// alerted holds the highest alert sent per budget and period until the
+	// period resets; alertStore, when set, keeps it across restarts.
+	alerted    map[string]budgetAlertState
+	alertStore *sql.DB
+
+	eventMu  sync.Mutex
+	eventSeq uint64
+}
+
+func NewBudgetEnforcer(
+	cfg config.BudgetConfig,
+	costs *CostAggregator,
+	tracker interface{ GetAllSessions() []TrackedSession },
+	events interface {
+		ProcessEvent(agentID string, event Event) error
+	},
+	logger *zap.Logger,
+) *BudgetEnforcer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.CheckIntervalSec <= 0 {
+		cfg.CheckIntervalSec = 60
+	}
+	if len(cfg.AlertThresholds) == 0 {
+		cfg.AlertThresholds = []float64{50, 80, 100}
+	}
+	thresholds := append([]float64(nil), cfg.AlertThresholds...)
+	sort.Float64s(thresholds)
+	cfg.AlertThresholds = thresholds
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &BudgetEnforcer{
+		cfg:     cfg,
+		costs:   costs,
+		tracker: tracker,
+		events:  events,
+		logger:  logger,
+		now:     func() time.Time { return time.Now().UTC() },
+		ctx:     ctx,
+		cancel:  cancel,
+		alerted: make(map[string]budgetAlertState),
+	}
+}
+
+// budgetAlertState is the highest threshold (or projection) alerted for a
+// budget period, kept until the period resets.
+type budgetAlertState struct {
+	value    float64
+	resetsAt time.Time
+}
+
+// SetAlertStore persists sent alerts in db and loads those of periods that
+// have not reset yet, so a restart does not repeat them.
+func (b *BudgetEnforcer) SetAlertStore(db *sql.DB) error {
+	rows, err := db.Query(`SELECT alert_key, value, resets_at FROM budget_alerts`)
+	if err != nil {
+		return fmt.Errorf("load budget alerts: %w", err)
+	}
+	defer rows.Close()
+
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for rows.Next() {
+		var (
+			key      string
+			value    float64
+			resetsAt string
+		)
+		if err := rows.Scan(&key, &value, &resetsAt); err != nil {
+			return fmt.Errorf("load budget alerts: scan: %w", err)
+		}
+		resets, err := parseSQLiteTimestamp(resetsAt)
+		if err != nil || !now.Before(resets) {
+			continue
+		}
+		b.alerted[key] = budgetAlertState{value: value, resetsAt: resets}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load budget alerts: %w", err)
+	}
+	b.alertStore = db
+	return nil
+}
+
+// markAlerted records an alert for key. Callers must hold b.mu.
+func (b *BudgetEnforcer) markAlerted(key string, value float64, resetsAt time.Time) {
+	b.alerted[key] = budgetAlertState{value: value, resetsAt: resetsAt}
+	if b.alertStore == nil {
+		return
+	}
+	if _, err := b.alertStore.Exec(`
+		INSERT INTO budget_alerts (alert_key, value, resets_at) VALUES (?, ?, ?)
+		ON CONFLICT(alert_key) DO UPDATE SET value = excluded.value, resets_at = excluded.resets_at
+	`, key, value, resetsAt.UTC().Format(time.RFC3339)); err != nil {
+		b.logger.Warn("persist budget alert failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// pruneAlerted forgets alerts of periods that have reset.
+func (b *BudgetEnforcer) pruneAlerted(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, state := range b.alerted {
+		if !now.Before(state.resetsAt) {
+			delete(b.alerted, key)
+		}
+	}
+	if b.alertStore == nil {
+		return
+	}
+	if _, err := b.alertStore.Exec(`DELETE FROM budget_alerts WHERE resets_at <= ?`, now.UTC().Format(time.RFC3339)); err != nil {
+		b.logger.Warn("prune budget alerts failed", zap.Error(err))
+	}
+}
+
+func (b *BudgetEnforcer) Start() {
+	b.startOnce.Do(func() {
+		ticker := time.NewTicker(time.Duration(b.cfg.CheckIntervalSec) * time.Second)
+
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			defer ticker.Stop()
+
+			b.Evaluate()
+			for {
+				select {
+				case <-b.ctx.Done():
+					return
+				case <-ticker.C:
+					b.Evaluate()
+				}
+			}
+		}()
+	})
+}
+
+func (b *BudgetEnforcer) Stop() {
+	b.stopOnce.Do(func() {
+		b.cancel()
+
+		done := make(chan struct{})
+		go func() {
+			b.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(250 * time.Millisecond):
+		}
+	})
+}
+
+// Evaluate recomputes every configured budget, emits alerts for thresholds
+// crossed since the last evaluation and caches the result for Statuses.
+func (b *BudgetEnforcer) Evaluate() []BudgetStatus {
+	b.pruneAlerted(b.now())
+	statuses := b.computeStatuses("", true)
+	b.applyForecast(statuses)
+
+	b.mu.Lock()
+	b.statuses = statuses
+	b.mu.Unlock()
+
+	for _, status := range statuses {
+		b.checkThresholds(status)
+		b.checkForecast(status)
+	}
+
+	return statuses
+}
+
+// applyForecast fills ProjectedUSD on weekly and monthly statuses. Global
+// projections take the higher of the provider and session based forecasts,
+// mirroring how actual global spend is computed.
+func (b *BudgetEnforcer) applyForecast(statuses []BudgetStatus) {
+	if b.costs == nil {
+		return
+	}
+
+	forecast, err := b.costs.Forecast()
+	if err != nil {
+		b.logger.Warn("budget forecast failed", zap.Error(err))
+		return
+	}
+
+	for i := range statuses {
+		var window ForecastWindow
+		switch statuses[i].Period {
+		case BudgetPeriodWeekly:
+			window = forecast.EndOfWeek
+		case BudgetPeriodMonthly:
+			window = forecast.EndOfMonth
+		default:
+			continue
+		}
+
+		if statuses[i].Scope == BudgetScopeGlobal {
+			statuses[i].ProjectedUSD = window.ProjectedUSD
+			if window.ProjectedSessionsUSD > statuses[i].ProjectedUSD {
+				statuses[i].ProjectedUSD = window.ProjectedSessionsUSD
+			}
+			continue
+		}
+
+		for _, entry := range window.ByProject {
+			if entry.Project == statuses[i].Project {
+				statuses[i].ProjectedUSD = entry.ProjectedUSD
+				break
+			}
+		}
+	}
+}
+
+// Statuses returns the most recent evaluation, computing one if none exists.
+func (b *BudgetEnforcer) Statuses() []BudgetStatus {
+	b.mu.Lock()
+	cached := b.statuses
+	b.mu.Unlock()
+
+	if cached == nil {
+		return b.Evaluate()
+	}
+
+	out := make([]BudgetStatus, len(cached))
+	copy(out, cached)
+	return out
+}
+
+// Allow reports whether a new session may be created for project. It returns
+// an error wrapping ErrBudgetExceeded when blocking is enabled and either the
+// global or the project budget is exhausted for any period.
+func (b *BudgetEnforcer) Allow(project string) error {
+	if b == nil || !b.cfg.Enabled || !b.cfg.BlockOnExceed {
+		return nil
+	}
+
+	for _, status := range b.computeStatuses(project, false) {
+		if !status.Exceeded {
+			continue
+		}
+		scope := status.Scope
+		if status.Project != "" {
+			scope = "project " + status.Project
+		}
+		return fmt.Errorf("%w: %s %s budget spent $%.2f of $%.2f (resets %s)",
+			ErrBudgetExceeded,
+			scope,
+			status.Period,
+			status.SpentUSD,
+			status.LimitUSD,
+			status.ResetsAt.Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
+
+// computeStatuses evaluates the global budget plus either every configured
+// project (allProjects) or only the given project.
+func (b *BudgetEnforcer) computeStatuses(project string, allProjects bool) []BudgetStatus {
+	now := b.now().UTC()
+	sessions := []TrackedSession{}
+	if b.tracker != nil {
+		sessions = b.tracker.GetAllSessions()
+	}
+
+	statuses := make([]BudgetStatus, 0)
+	for _, period := range []BudgetPeriod{BudgetPeriodDaily, BudgetPeriodWeekly, BudgetPeriodMonthly} {
+		start, end := budgetPeriodWindow(now, period)
+		sessionSpend := b.projectSpend(start, end, sessions)
+
+		if limit := budgetLimitFor(b.cfg.Global, period); limit > 0 {
+			spent := b.globalSpend(start, sessionSpend)
+			statuses = append(statuses, newBudgetStatus(BudgetScopeGlobal, "", period, limit, spent, start, end))
+		}
+
+		projects := make([]string, 0)
+		if allProjects {
+			for name := range b.cfg.Projects {
+				projects = append(projects, name)
+			}
+			sort.Strings(projects)
+		} else if project != "" {
+			projects = append(projects, project)
+		}
+
+		for _, name := range projects {
+			limits, ok := b.cfg.Projects[name]
+			if !ok {
+				continue
+			}
+			limit := budgetLimitFor(limits, period)
+			if limit <= 0 {
+				continue
+			}
+			spent := sessionSpend[name]
+			statuses = append(statuses, newBudgetStatus(BudgetScopeProject, name, period, limit, spent, start, end))
+		}
+	}
+
+	return statuses
+}
+
+// projectSpend returns session spend per project in the period from start to
+// end. With a cost aggregator, usage is priced on the day it was reported,
+// so sessions started before the period count for what they used in it.
+// Without one, each session's estimate counts in the period it started.
+func (b *BudgetEnforcer) projectSpend(start, end time.Time, sessions []TrackedSession) map[string]float64 {
+	if b.costs != nil {
+		spend, err := b.costs.ProjectSpend(start, end)
+		if err == nil {
+			return spend
+		}
+		b.logger.Warn("budget session spend lookup failed", zap.Error(err))
+	}
+
+	spend := map[string]float64{}
+	for _, session := range sessions {
+		if session.StartedAt.Before(start) || !session.StartedAt.Before(end) {
+			continue
+		}
+		spend[session.Project] += session.SessionCost
+	}
+	return spend
+}
+
+func (b *BudgetEnforcer) globalSpend(start time.Time, projectSpend map[string]float64) float64 {
+	sessionSpend := 0.0
+	for _, spent := range projectSpend {
+		sessionSpend += spent
+	}
+
+	if b.costs == nil {
+		return sessionSpend
+	}
+
+	providerSpend, err := b.costs.SpendSince(start)
+	if err != nil {
+		b.logger.Warn("budget provider spend lookup failed", zap.Error(err))
+		return sessionSpend
+	}
+
+	if providerSpend > sessionSpend {
+		return providerSpend
+	}
+	return sessionSpend
+}
+
+func (b *BudgetEnforcer) checkThresholds(status BudgetStatus) {
+	key := fmt.Sprintf("%s|%s|%s|%s", status.Scope, status.Project, status.Period, status.PeriodStart.Format("2006-01-02"))
+
+	crossed := 0.0
+	for _, threshold := range b.cfg.AlertThresholds {
+		if status.Percent >= threshold {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	if crossed <= b.alerted[key].value {
+		b.mu.Unlock()
+		return
+	}
+	b.markAlerted(key, crossed, status.ResetsAt)
+	b.mu.Unlock()
+
+	b.logger.Warn("budget threshold crossed",
+		zap.String("scope", status.Scope),
+		zap.String("project", status.Project),
+		zap.String("period", string(status.Period)),
+		zap.Float64("threshold_percent", crossed),
+		zap.Float64("spent_usd", status.SpentUSD),
+		zap.Float64("limit_usd", status.LimitUSD),
+	)
+
+	payload := map[string]interface{}{
+		"scope":             status.Scope,
+		"period":            string(status.Period),
+		"threshold_percent": crossed,
+		"percent":           status.Percent,
+		"spent_usd":         status.SpentUSD,
+		"limit_usd":         status.LimitUSD,
+		"exceeded":          status.Exceeded,
+		"resets_at":         status.ResetsAt,
+	}
+	if status.Project != "" {
+		payload["project"] = status.Project
+	}
+	_ = b.emitBudgetEvent("budget.alert", payload)
+}
+
+// checkForecast emits a single budget.forecast_alert per budget and period
+// when the projected end-of-period spend exceeds the limit before the actual
+// spend does.
+func (b *BudgetEnforcer) checkForecast(status BudgetStatus) {
+	if status.Exceeded || status.ProjectedUSD < status.LimitUSD || status.ProjectedUSD == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("forecast|%s|%s|%s|%s", status.Scope, status.Project, status.Period, status.PeriodStart.Format("2006-01-02"))
+
+	b.mu.Lock()
+	if _, done := b.alerted[key]; done {
+		b.mu.Unlock()
+		return
+	}
+	b.markAlerted(key, status.ProjectedUSD, status.ResetsAt)
+	b.mu.Unlock()
+
+	b.logger.Warn("budget projected to be exceeded",
+		zap.String("scope", status.Scope),
+		zap.String("project", status.Project),
+		zap.String("period", string(status.Period)),
+		zap.Float64("projected_usd", status.ProjectedUSD),
+		zap.Float64("limit_usd", status.LimitUSD),
+	)
+
+	payload := map[string]interface{}{
+		"scope":         status.Scope,
+		"period":        string(status.Period),
+		"spent_usd":     status.SpentUSD,
+		"projected_usd": status.ProjectedUSD,
+		"limit_usd":     status.LimitUSD,
+		"resets_at":     status.ResetsAt,
+	}
+	if status.Project != "" {
+		payload["project"] = status.Project
+	}
+	_ = b.emitBudgetEvent("budget.forecast_alert", payload)
+}
+
+func (b *BudgetEnforcer) emitBudgetEvent(eventType string, payload map[string]interface{}) error {
+	if b.events == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", eventType, err)
+	}
+
+	b.eventMu.Lock()
+	if b.eventSeq == 0 {
+		// Continue after events emitted before a supervisor restart.
+		if source, ok := b.events.(sequenceSource); ok {
+			b.eventSeq = source.LastSequence(budgetEnforcerAgentID)
+		}
+	}
+	b.eventSeq++
+	seq := b.eventSeq
+	b.eventMu.Unlock()
+
+	return b.events.ProcessEvent(budgetEnforcerAgentID, Event{
+		ID:        fmt.Sprintf("budget-%d", seq),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: b.now().UTC(),
+		Seq:       seq,
+	})
+}
+
+func newBudgetStatus(scope, project string, period BudgetPeriod, limit, spent float64, start, end time.Time) BudgetStatus {
+	percent := 0.0
+	if limit > 0 {
+		percent = spent / limit * 100
+	}
+	return BudgetStatus{
+		Scope:       scope,
+		Project:     project,
+		Period:      period,
+		LimitUSD:    limit,
+		SpentUSD:    spent,
+		Percent:     percent,
+		Exceeded:    spent >= limit,
+		PeriodStart: start,
+		ResetsAt:    end,
+	}
+}
+
+func budgetLimitFor(limits config.BudgetLimits, period BudgetPeriod) float64 {
+	switch period {
+	case BudgetPeriodDaily:
+		return limits.DailyUSD
+	case BudgetPeriodWeekly:
+		return limits.WeeklyUSD
+	case BudgetPeriodMonthly:
+		return limits.MonthlyUSD
+	default:
+		return 0
+	}
+}
+
+// budgetPeriodWindow returns the calendar window (UTC) containing now. Weeks
+// start on Monday so budgets reset at the same point every week.
+func budgetPeriodWindow(now time.Time, period BudgetPeriod) (time.Time, time.Time) {
+	now = now.UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case BudgetPeriodWeekly:
+		offset := (int(startOfDay.Weekday()) + 6) % 7
+		start := startOfDay.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	case BudgetPeriodMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return startOfDay, startOfDay.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,372 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestBudgetPeriodWindow(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2026, 2, 18, 15, 30, 0, 0, time.UTC)
+
+	start, end := budgetPeriodWindow(now, BudgetPeriodDaily)
+	if !start.Equal(time.Date(2026, 2, 18, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 2, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected daily window %s - %s", start, end)
+	}
+
+	start, end = budgetPeriodWindow(now, BudgetPeriodWeekly)
+	if !start.Equal(time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected weekly window %s - %s", start, end)
+	}
+
+	start, end = budgetPeriodWindow(now, BudgetPeriodMonthly)
+	if !start.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected monthly window %s - %s", start, end)
+	}
+
+	sunday := time.Date(2026, 2, 22, 23, 0, 0, 0, time.UTC)
+	start, _ = budgetPeriodWindow(sunday, BudgetPeriodWeekly)
+	if !start.Equal(time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected sunday to belong to week starting monday, got %s", start)
+	}
+}
+
+func TestBudgetEnforcerThresholdAlerts(t *testing.T) {
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-1", Project: "proj-a", SessionCost: 6, StartedAt: now.Add(-time.Hour)},
+	}}
+	events := &policyTestEvents{}
+
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:         true,
+		AlertThresholds: []float64{50, 80, 100},
+		Projects: map[string]config.BudgetLimits{
+			"proj-a": {DailyUSD: 10},
+		},
+	}, nil, tracker, events, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	statuses := enforcer.Evaluate()
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 budget status, got %d", len(statuses))
+	}
+	if statuses[0].Percent != 60 || statuses[0].Exceeded {
+		t.Fatalf("unexpected status %+v", statuses[0])
+	}
+	if got := budgetAlertThresholds(t, events); len(got) != 1 || got[0] != 50 {
+		t.Fatalf("expected single 50%% alert, got %v", got)
+	}
+
+	enforcer.Evaluate()
+	if got := budgetAlertThresholds(t, events); len(got) != 1 {
+		t.Fatalf("expected no duplicate alert, got %v", got)
+	}
+
+	tracker.mu.Lock()
+	tracker.sessions[0].SessionCost = 10.5
+	tracker.mu.Unlock()
+
+	statuses = enforcer.Evaluate()
+	if !statuses[0].Exceeded {
+		t.Fatalf("expected budget to be exceeded, got %+v", statuses[0])
+	}
+	if got := budgetAlertThresholds(t, events); len(got) != 2 || got[1] != 100 {
+		t.Fatalf("expected escalation straight to 100%% alert, got %v", got)
+	}
+
+	enforcer.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	tracker.mu.Lock()
+	tracker.sessions[0].StartedAt = now.AddDate(0, 0, 1)
+	tracker.mu.Unlock()
+
+	enforcer.Evaluate()
+	if got := budgetAlertThresholds(t, events); len(got) != 3 || got[2] != 100 {
+		t.Fatalf("expected alert to fire again in new period, got %v", got)
+	}
+}
+
+func TestBudgetEnforcerAlertsSurviveRestart(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-1", Project: "proj-a", SessionCost: 6, StartedAt: now.Add(-time.Hour)},
+	}}
+	cfg := config.BudgetConfig{
+		Enabled:         true,
+		AlertThresholds: []float64{50},
+		Projects:        map[string]config.BudgetLimits{"proj-a": {DailyUSD: 10}},
+	}
+	newEnforcer := func(events *policyTestEvents, at time.Time) *BudgetEnforcer {
+		enforcer := NewBudgetEnforcer(cfg, nil, tracker, events, zap.NewNop())
+		enforcer.now = func() time.Time { return at }
+		if err := enforcer.SetAlertStore(db); err != nil {
+			t.Fatalf("set alert store: %v", err)
+		}
+		return enforcer
+	}
+
+	first := &policyTestEvents{}
+	newEnforcer(first, now).Evaluate()
+	if got := budgetAlertThresholds(t, first); len(got) != 1 {
+		t.Fatalf("expected one alert, got %v", got)
+	}
+
+	restarted := &policyTestEvents{}
+	newEnforcer(restarted, now.Add(time.Hour)).Evaluate()
+	if got := budgetAlertThresholds(t, restarted); len(got) != 0 {
+		t.Fatalf("expected no repeated alert after a restart, got %v", got)
+	}
+
+	nextDay := &policyTestEvents{}
+	tracker.mu.Lock()
+	tracker.sessions[0].StartedAt = now.AddDate(0, 0, 1)
+	tracker.mu.Unlock()
+	enforcer := newEnforcer(nextDay, now.AddDate(0, 0, 1))
+	enforcer.Evaluate()
+	if got := budgetAlertThresholds(t, nextDay); len(got) != 1 {
+		t.Fatalf("expected the alert again in a new period, got %v", got)
+	}
+
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM budget_alerts`).Scan(&rows); err != nil {
+		t.Fatalf("count alerts: %v", err)
+	}
+	enforcer.mu.Lock()
+	kept := len(enforcer.alerted)
+	enforcer.mu.Unlock()
+	if rows != 1 || kept != 1 {
+		t.Fatalf("expected the past period's alert dropped, got %d stored and %d in memory", rows, kept)
+	}
+}
+
+func TestBudgetEnforcerAllow(t *testing.T) {
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-1", Project: "proj-a", SessionCost: 12, StartedAt: now.Add(-time.Hour)},
+		{SessionID: "s-2", Project: "proj-b", SessionCost: 1, StartedAt: now.Add(-time.Hour)},
+		{SessionID: "s-3", Project: "proj-b", SessionCost: 50, StartedAt: now.AddDate(0, 0, -3)},
+	}}
+
+	cfg := config.BudgetConfig{
+		Enabled:       true,
+		BlockOnExceed: true,
+		Projects: map[string]config.BudgetLimits{
+			"proj-a": {DailyUSD: 10},
+			"proj-b": {DailyUSD: 10},
+		},
+	}
+	enforcer := NewBudgetEnforcer(cfg, nil, tracker, nil, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	err := enforcer.Allow("proj-a")
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded for proj-a, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "proj-a daily") {
+		t.Fatalf("expected error to name project and period, got %q", err.Error())
+	}
+	if err := enforcer.Allow("proj-b"); err != nil {
+		t.Fatalf("expected proj-b to be allowed (older spend outside daily window), got %v", err)
+	}
+	if err := enforcer.Allow("unbudgeted"); err != nil {
+		t.Fatalf("expected project without budget to be allowed, got %v", err)
+	}
+
+	cfg.BlockOnExceed = false
+	observeOnly := NewBudgetEnforcer(cfg, nil, tracker, nil, zap.NewNop())
+	observeOnly.now = func() time.Time { return now }
+	if err := observeOnly.Allow("proj-a"); err != nil {
+		t.Fatalf("expected no blocking when block_on_exceed is off, got %v", err)
+	}
+}
+
+func TestBudgetEnforcerGlobalUsesProviderSpend(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+
+	if _, err := db.Exec(`
+		INSERT INTO costs (id, provider, model, date, tokens, cost_usd)
+		VALUES
+			('anthropic|claude-sonnet-4|2026-02-18', 'anthropic', 'claude-sonnet-4', '2026-02-18', 1000, 40),
+			('openai|gpt-4|2026-02-10', 'openai', 'gpt-4', '2026-02-10', 2000, 30)
+	`); err != nil {
+		t.Fatalf("insert costs: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:       true,
+		BlockOnExceed: true,
+		Global:        config.BudgetLimits{DailyUSD: 50, MonthlyUSD: 60},
+	}, agg, tracker, nil, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	statuses := enforcer.Evaluate()
+	if len(statuses) != 2 {
+		t.Fatalf("expected daily and monthly global statuses, got %+v", statuses)
+	}
+	if statuses[0].Period != BudgetPeriodDaily || statuses[0].SpentUSD != 40 {
+		t.Fatalf("unexpected daily status %+v", statuses[0])
+	}
+	if statuses[1].Period != BudgetPeriodMonthly || statuses[1].SpentUSD != 70 || !statuses[1].Exceeded {
+		t.Fatalf("unexpected monthly status %+v", statuses[1])
+	}
+
+	if err := enforcer.Allow(""); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected global budget to block, got %v", err)
+	}
+}
+
+func TestBudgetEnforcerAttributesSpendByUsageDate(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Now().UTC()
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", now.Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	// A long-running session started two months ago with usage from then,
+	// and reports more usage today.
+	if err := tracker.AddSession(TrackedSession{
+		SessionID:  "s-1",
+		NodeID:     "n-1",
+		Project:    "proj-a",
+		Model:      "gpt-4",
+		TokenUsage: TokenUsage{Prompt: 1000, Total: 1000},
+		StartedAt:  now.AddDate(0, -2, 0),
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-1", "m-1", "", TokenUsage{Prompt: 2000, Total: 2000}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{Providers: config.CostProviders{
+		OpenAI: config.CostProviderConfig{
+			APIKey:     "sk-org-test",
+			ModelRates: map[string]config.ModelRate{"gpt-4": {Input: 1}},
+		},
+	}}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:  true,
+		Global:   config.BudgetLimits{MonthlyUSD: 10},
+		Projects: map[string]config.BudgetLimits{"proj-a": {DailyUSD: 10}},
+	}, agg, tracker, nil, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	for _, status := range enforcer.Evaluate() {
+		if status.SpentUSD != 2 {
+			t.Fatalf("expected only today's usage to count, got %+v", status)
+		}
+	}
+}
+
+func TestBudgetEnforcerChargesUnpricedModelsTheirReportedCost(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Now().UTC()
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", now.Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{
+		SessionID:   "s-1",
+		NodeID:      "n-1",
+		Project:     "proj-a",
+		Model:       "llama-3-70b",
+		TokenUsage:  TokenUsage{Prompt: 1000, Total: 1000},
+		SessionCost: 12,
+		StartedAt:   now,
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:       true,
+		BlockOnExceed: true,
+		Projects:      map[string]config.BudgetLimits{"proj-a": {DailyUSD: 10}},
+	}, agg, tracker, nil, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	statuses := enforcer.Evaluate()
+	if len(statuses) != 1 || statuses[0].SpentUSD != 12 || !statuses[0].Exceeded {
+		t.Fatalf("expected the reported cost counted for an unpriced model, got %+v", statuses)
+	}
+	if err := enforcer.Allow("proj-a"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected the unpriced project blocked, got %v", err)
+	}
+}
+
+func TestDispatcherBudgetGateBlocksCreateSession(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	transport := &mockCommandTransport{}
+	dispatcher := NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+
+	now := time.Now().UTC()
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:       true,
+		BlockOnExceed: true,
+		Projects:      map[string]config.BudgetLimits{"proj-a": {DailyUSD: 1}},
+	}, nil, &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-1", Project: "proj-a", SessionCost: 2, StartedAt: now},
+	}}, nil, logger)
+	dispatcher.SetBudgetGate(enforcer)
+
+	result, err := dispatcher.DispatchCommand(context.Background(), Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: "proj-a"},
+	})
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	if result.Status != CommandStatusFailure || !strings.Contains(result.Error, "budget exceeded") {
+		t.Fatalf("expected budget failure, got %+v", result)
+	}
+
+	transport.mu.Lock()
+	calls := transport.calls
+	transport.mu.Unlock()
+	if calls != 0 {
+		t.Fatalf("expected no command to reach transport, got %d", calls)
+	}
+}
+
+func budgetAlertThresholds(t *testing.T, events *policyTestEvents) []float64 {
+	t.Helper()
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	out := make([]float64, 0)
+	for _, event := range events.events {
+		if event.Type != "budget.alert" {
+			continue
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(event.Data, &payload); err != nil {
+			t.Fatalf("unmarshal budget alert: %v", err)
+		}
+		threshold, _ := payload["threshold_percent"].(float64)
+		out = append(out, threshold)
+	}
+	return out
+}
@@ -8,9 +8,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
 	"github.com/Bldg-7/hal-o-swarm/internal/shared"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -62,6 +65,11 @@ func (t *hubCommandTransport) Send(nodeID string, cmd Command) error {
 	}
 }
 
+// budgetGate decides whether new sessions may be created for a project.
+type budgetGate interface {
+	Allow(project string) error
+}
+
 type CommandDispatcher struct {
 	db       *sql.DB
 	registry *NodeRegistry
@@ -69,6 +77,10 @@ type CommandDispatcher struct {
 	logger   *zap.Logger
 
 	transport commandTransport
+	budget    budgetGate
+
+	placement       PlacementStrategy
+	placementLabels map[string]map[string]string
 
 	pendingMu sync.Mutex
 	pending   map[string]chan *CommandResult
@@ -85,6 +97,7 @@ func NewCommandDispatcher(db *sql.DB, registry *NodeRegistry, tracker *SessionTr
 		tracker:   tracker,
 		logger:    logger,
 		transport: &hubCommandTransport{hub: hub},
+		placement: leastLoadedStrategy{},
 		pending:   make(map[string]chan *CommandResult),
 	}
 
@@ -106,10 +119,33 @@ func NewCommandDispatcherWithTransport(db *sql.DB, registry *NodeRegistry, track
 		tracker:   tracker,
 		logger:    logger,
 		transport: transport,
+		placement: leastLoadedStrategy{},
 		pending:   make(map[string]chan *CommandResult),
 	}
 }
 
+// SetBudgetGate installs a check that can refuse create_session commands while
+// a project's (or the global) budget is exhausted.
+func (d *CommandDispatcher) SetBudgetGate(gate budgetGate) {
+	d.budget = gate
+}
+
+// AllowCreateSession reports whether the budget gate lets a new session
+// start for project. It returns nil when no gate is installed.
+func (d *CommandDispatcher) AllowCreateSession(project string) error {
+	if d.budget == nil {
+		return nil
+	}
+	return d.budget.Allow(project)
+}
+
+// SetPlacement installs the strategy and per-project label requirements
+// used to pick a node for commands that name neither a node nor a session.
+func (d *CommandDispatcher) SetPlacement(cfg config.PlacementConfig) {
+	d.placement = NewPlacementStrategy(cfg)
+	d.placementLabels = cfg.Labels
+}
+
 func (d *CommandDispatcher) DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error) {
 	normalizedType, err := ParseCommandIntent(string(cmd.Type))
 	if err != nil {
@@ -123,6 +159,31 @@ func (d *CommandDispatcher) DispatchCommand(ctx context.Context, cmd Command) (*
 		return nil, fmt.Errorf("invalid command_id %q: %w", cmd.CommandID, err)
 	}
 
+	if cmd.Type == CommandTypeCreateSession {
+		if err := d.AllowCreateSession(cmd.Target.Project); err != nil {
+			d.logger.Warn("create_session blocked by budget",
+				zap.String("command_id", cmd.CommandID),
+				zap.String("project", cmd.Target.Project),
+				zap.Error(err),
+			)
+			return &CommandResult{
+				CommandID: cmd.CommandID,
+				Status:    CommandStatusFailure,
+				Error:     err.Error(),
+				Timestamp: time.Now().UTC(),
+			}, nil
+		}
+	}
+
+	if session, ok := d.commandSession(cmd); ok && !session.Status.AllowsCommand(cmd.Type) {
+		return &CommandResult{
+			CommandID: cmd.CommandID,
+			Status:    CommandStatusFailure,
+			Error:     fmt.Sprintf("session %s is %s: %s not allowed", session.SessionID, session.Status, cmd.Type),
+			Timestamp: time.Now().UTC(),
+		}, nil
+	}
+
 	if cmd.IdempotencyKey != "" {
 		key, err := d.idempotencyHash(cmd)
 		if err != nil {
@@ -146,7 +207,11 @@ func (d *CommandDispatcher) DispatchCommand(ctx context.Context, cmd Command) (*
 }
 
 func (d *CommandDispatcher) dispatchToTarget(ctx context.Context, cmd Command) (*CommandResult, error) {
-	nodeID, err := d.resolveTarget(cmd)
+	if len(cmd.Target.Labels) > 0 {
+		return d.fanOut(cmd), nil
+	}
+
+	nodeID, placement, err := d.resolveTarget(cmd)
 	if err != nil {
 		return &CommandResult{
 			CommandID: cmd.CommandID,
@@ -156,23 +221,282 @@ func (d *CommandDispatcher) dispatchToTarget(ctx context.Context, cmd Command) (
 		}, nil
 	}
 
+	prior, tracked := d.beginSessionCommand(cmd)
 	result, err := d.sendCommand(nodeID, cmd)
+	if err == nil && result == nil {
+		err = fmt.Errorf("empty command result for command %s", cmd.CommandID)
+	}
 	if err != nil {
+		if tracked {
+			d.endSessionCommand(cmd, prior, false)
+		}
 		return nil, err
 	}
-	if result == nil {
-		return nil, fmt.Errorf("empty command result for command %s", cmd.CommandID)
-	}
 	if result.CommandID == "" {
 		result.CommandID = cmd.CommandID
 	}
 	if result.Timestamp.IsZero() {
 		result.Timestamp = time.Now().UTC()
 	}
+	result.NodeID = nodeID
+	result.Placement = placement
+	if tracked {
+		d.endSessionCommand(cmd, prior, result.Status == CommandStatusSuccess)
+	}
+	d.recordLineage(cmd, nodeID, result)
 
 	return result, nil
 }
 
+// fanOut sends cmd to every node its label selector matches, each under its
+// own command ID, and folds the per-node results into one.
+func (d *CommandDispatcher) fanOut(cmd Command) *CommandResult {
+	failed := func(msg string) *CommandResult {
+		return &CommandResult{
+			CommandID: cmd.CommandID,
+			Status:    CommandStatusFailure,
+			Error:     msg,
+			Timestamp: time.Now().UTC(),
+		}
+	}
+	selector := formatLabels(cmd.Target.Labels)
+	if cmd.Target.NodeID != "" {
+		return failed("target node_id and labels are mutually exclusive")
+	}
+	nodes := d.selectNodes(cmd.Target, cmd.Type == CommandTypeCreateSession)
+	if len(nodes) == 0 {
+		if cmd.Target.Project != "" {
+			return failed(fmt.Sprintf("no online node serving %s matches labels %s", cmd.Target.Project, selector))
+		}
+		return failed("no online node matches labels " + selector)
+	}
+
+	results := make([]CommandResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, nodeID := range nodes {
+		wg.Add(1)
+		go func(i int, nodeID string) {
+			defer wg.Done()
+			sub := cmd
+			sub.CommandID = uuid.NewString()
+			sub.Target = CommandTarget{Project: cmd.Target.Project, NodeID: nodeID}
+
+			result, err := d.sendCommand(nodeID, sub)
+			if err == nil && result == nil {
+				err = fmt.Errorf("empty command result for command %s", sub.CommandID)
+			}
+			if err != nil {
+				result = &CommandResult{CommandID: sub.CommandID, Status: CommandStatusFailure, Error: err.Error()}
+			}
+			if result.CommandID == "" {
+				result.CommandID = sub.CommandID
+			}
+			if result.Timestamp.IsZero() {
+				result.Timestamp = time.Now().UTC()
+			}
+			result.NodeID = nodeID
+			result.Placement = "matches labels " + selector
+			results[i] = *result
+		}(i, nodeID)
+	}
+	wg.Wait()
+
+	return aggregateResults(cmd.CommandID, selector, results)
+}
+
+// selectNodes returns the IDs of the online nodes carrying every label in
+// target.Labels and, when target.Project is set, serving that project.
+// schedulableOnly leaves out cordoned and draining nodes.
+func (d *CommandDispatcher) selectNodes(target CommandTarget, schedulableOnly bool) []string {
+	if d.registry == nil {
+		return nil
+	}
+
+	serves := make(map[string]bool)
+	if target.Project != "" && d.tracker != nil {
+		for _, session := range d.tracker.GetAllSessions() {
+			if session.Project == target.Project {
+				serves[session.NodeID] = true
+			}
+		}
+	}
+
+	ids := make([]string, 0)
+	for _, node := range d.registry.ListNodes() {
+		if node.Status != NodeStatusOnline || !labelsMatch(node.Labels, target.Labels) {
+			continue
+		}
+		if schedulableOnly && !node.Schedulable() {
+			continue
+		}
+		if target.Project != "" && !serves[node.ID] {
+			found := false
+			for _, project := range node.Projects {
+				if project == target.Project {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// aggregateResults folds per-node results into one: success when every node
+// succeeded, timeout when the rest only timed out, failure otherwise. Outputs
+// and errors are prefixed with their node.
+func aggregateResults(commandID, selector string, results []CommandResult) *CommandResult {
+	status := CommandStatusSuccess
+	outputs := make([]string, 0, len(results))
+	errs := make([]string, 0)
+	for _, result := range results {
+		switch result.Status {
+		case CommandStatusSuccess:
+		case CommandStatusTimeout:
+			if status == CommandStatusSuccess {
+				status = CommandStatusTimeout
+			}
+		default:
+			status = CommandStatusFailure
+		}
+		if result.Output != "" {
+			outputs = append(outputs, result.NodeID+": "+result.Output)
+		}
+		if result.Error != "" {
+			errs = append(errs, result.NodeID+": "+result.Error)
+		}
+	}
+
+	return &CommandResult{
+		CommandID: commandID,
+		Status:    status,
+		Output:    strings.Join(outputs, "\n"),
+		Error:     strings.Join(errs, "; "),
+		Timestamp: time.Now().UTC(),
+		Placement: fmt.Sprintf("%d nodes match labels %s", len(results), selector),
+		Results:   results,
+	}
+}
+
+// Session statuses held while a command runs, and reached when it succeeds.
+var (
+	commandInFlightStatus = map[CommandType]SessionStatus{
+		CommandTypeRestartSession: SessionStatusRestarting,
+		CommandTypeHandover:       SessionStatusHandover,
+	}
+	commandDoneStatus = map[CommandType]SessionStatus{
+		CommandTypeRestartSession: SessionStatusKilled,
+		CommandTypeHandover:       SessionStatusCompleted,
+		CommandTypeKillSession:    SessionStatusKilled,
+	}
+)
+
+// commandSession returns the tracked session a command names, if any.
+func (d *CommandDispatcher) commandSession(cmd Command) (TrackedSession, bool) {
+	if d.tracker == nil {
+		return TrackedSession{}, false
+	}
+	sessionID, _ := cmd.Args["session_id"].(string)
+	if sessionID == "" {
+		return TrackedSession{}, false
+	}
+	session, err := d.tracker.GetSession(sessionID)
+	if err != nil {
+		return TrackedSession{}, false
+	}
+	return session, true
+}
+
+// beginSessionCommand moves the command's session into the status it holds
+// while the command runs, returning the session as it was before.
+func (d *CommandDispatcher) beginSessionCommand(cmd Command) (TrackedSession, bool) {
+	session, ok := d.commandSession(cmd)
+	if !ok {
+		return TrackedSession{}, false
+	}
+	if status, ok := commandInFlightStatus[cmd.Type]; ok {
+		d.setSessionStatus(session.SessionID, status, commandTransitionReason(cmd))
+	}
+	return session, true
+}
+
+// endSessionCommand moves the command's session to its final status when the
+// command succeeded, or back to where it was when it failed.
+func (d *CommandDispatcher) endSessionCommand(cmd Command, prior TrackedSession, succeeded bool) {
+	reason := commandTransitionReason(cmd)
+	if succeeded {
+		if status, ok := commandDoneStatus[cmd.Type]; ok {
+			d.setSessionStatus(prior.SessionID, status, reason)
+		}
+		return
+	}
+	if status, ok := commandInFlightStatus[cmd.Type]; ok {
+		if current, err := d.tracker.GetSession(prior.SessionID); err == nil && current.Status == status {
+			d.setSessionStatus(prior.SessionID, prior.Status, reason)
+		}
+	}
+}
+
+func (d *CommandDispatcher) setSessionStatus(sessionID string, status SessionStatus, reason TransitionReason) {
+	if err := d.tracker.UpdateSessionWithReason(sessionID, map[string]interface{}{"status": string(status)}, reason); err != nil {
+		d.logger.Warn("session status change rejected",
+			zap.String("session_id", sessionID),
+			zap.String("status", string(status)),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordLineage tracks the session a successful restart started as the
+// replacement of the session it was asked to restart.
+func (d *CommandDispatcher) recordLineage(cmd Command, nodeID string, result *CommandResult) {
+	if d.tracker == nil || cmd.Type != CommandTypeRestartSession || result.Status != CommandStatusSuccess {
+		return
+	}
+	parentID, _ := cmd.Args["session_id"].(string)
+	sessionID := strings.TrimSpace(result.Output)
+	if parentID == "" || sessionID == "" || sessionID == parentID {
+		return
+	}
+	parent, err := d.tracker.GetSession(parentID)
+	if err != nil {
+		return
+	}
+
+	reason := commandTransitionReason(cmd)
+	if _, err := d.tracker.GetSession(sessionID); err == nil {
+		err = d.tracker.UpdateSessionWithReason(sessionID, map[string]interface{}{"parent_session_id": parentID}, reason)
+		if err != nil {
+			d.logger.Warn("record session lineage failed", zap.String("session_id", sessionID), zap.Error(err))
+		}
+		return
+	}
+	if err := d.tracker.AddSessionWithReason(TrackedSession{
+		SessionID:       sessionID,
+		NodeID:          nodeID,
+		Project:         parent.Project,
+		Model:           parent.Model,
+		CurrentTask:     parent.CurrentTask,
+		ParentSessionID: parentID,
+	}, reason); err != nil {
+		d.logger.Warn("record session lineage failed", zap.String("session_id", sessionID), zap.Error(err))
+	}
+}
+
+// commandTransitionReason attributes a command's session changes to the
+// policy that issued it, or to the command itself.
+func commandTransitionReason(cmd Command) TransitionReason {
+	if policy, _ := cmd.Args["policy"].(string); policy != "" {
+		return TransitionReason{Cause: TransitionCausePolicy, Detail: policy}
+	}
+	return TransitionReason{Cause: TransitionCauseCommand, Detail: string(cmd.Type)}
+}
+
 func (d *CommandDispatcher) checkIdempotency(key string) (*CommandResult, bool) {
 	if d.db == nil {
 		return nil, false
@@ -246,58 +570,166 @@ func (d *CommandDispatcher) cacheResult(key string, result *CommandResult) error
 	return nil
 }
 
-func (d *CommandDispatcher) resolveTarget(cmd Command) (nodeID string, err error) {
+// resolveTarget picks the node a command runs on and says why. Commands
+// naming a node or a tracked session go to that node; others are placed by
+// the placement strategy among the online nodes serving the project.
+func (d *CommandDispatcher) resolveTarget(cmd Command) (nodeID, reason string, err error) {
 	if d.registry == nil {
-		return "", fmt.Errorf("node registry unavailable")
+		return "", "", fmt.Errorf("node registry unavailable")
 	}
 
 	if cmd.Target.NodeID != "" {
 		node, err := d.registry.GetNode(cmd.Target.NodeID)
 		if err != nil {
 			if errors.Is(err, ErrNodeNotFound) {
-				return "", fmt.Errorf("target node not found: %s", cmd.Target.NodeID)
+				return "", "", fmt.Errorf("target node not found: %s", cmd.Target.NodeID)
 			}
-			return "", fmt.Errorf("resolve node %s: %w", cmd.Target.NodeID, err)
+			return "", "", fmt.Errorf("resolve node %s: %w", cmd.Target.NodeID, err)
 		}
 		if node.Status != NodeStatusOnline {
-			return "", fmt.Errorf("target node offline: %s", cmd.Target.NodeID)
+			return "", "", fmt.Errorf("target node offline: %s", cmd.Target.NodeID)
+		}
+		if cmd.Type == CommandTypeCreateSession && !node.Schedulable() {
+			return "", "", fmt.Errorf("target node %s is %s", node.ID, node.Lifecycle)
+		}
+		return node.ID, "requested node", nil
+	}
+
+	if cmd.Target.Project == "" {
+		return "", "", fmt.Errorf("target project or node_id is required")
+	}
+
+	if session, ok := d.commandSession(cmd); ok && session.NodeID != "" {
+		node, err := d.registry.GetNode(session.NodeID)
+		if err == nil && node.Status == NodeStatusOnline {
+			return node.ID, fmt.Sprintf("session %s runs on %s", session.SessionID, node.ID), nil
+		}
+	}
+
+	candidates, excluded := d.placementCandidates(cmd)
+	if len(candidates) == 0 {
+		if len(excluded) > 0 {
+			return "", "", fmt.Errorf("no online node found for project: %s (%s)", cmd.Target.Project, strings.Join(excluded, "; "))
 		}
-		return node.ID, nil
+		return "", "", fmt.Errorf("no online node found for project: %s", cmd.Target.Project)
 	}
 
+	chosen, reason, err := d.placement.Place(cmd.Target.Project, candidates)
+	if err != nil {
+		return "", "", err
+	}
+	return chosen.Node.ID, reason, nil
+}
+
+// PlaceWithCapacity picks the node a create_session for cmd's project would
+// be placed on, leaving out nodes that already run maxSessions sessions.
+// load holds session counts the caller knows of but the tracker may not yet,
+// such as sessions dispatched earlier in the same pass.
+func (d *CommandDispatcher) PlaceWithCapacity(cmd Command, load map[string]int, maxSessions int) (nodeID, reason string, err error) {
+	if d.registry == nil {
+		return "", "", fmt.Errorf("node registry unavailable")
+	}
 	if cmd.Target.Project == "" {
-		return "", fmt.Errorf("target project or node_id is required")
+		return "", "", fmt.Errorf("target project is required")
+	}
+
+	all, excluded := d.placementCandidates(cmd)
+	candidates := make([]PlacementCandidate, 0, len(all))
+	for _, candidate := range all {
+		if load[candidate.Node.ID] > candidate.ActiveSessions {
+			candidate.ActiveSessions = load[candidate.Node.ID]
+		}
+		if candidate.ActiveSessions >= maxSessions {
+			excluded = append(excluded, fmt.Sprintf("%s is at capacity", candidate.Node.ID))
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		if len(excluded) > 0 {
+			return "", "", fmt.Errorf("no node with capacity for project: %s (%s)", cmd.Target.Project, strings.Join(excluded, "; "))
+		}
+		return "", "", fmt.Errorf("no online node found for project: %s", cmd.Target.Project)
 	}
 
+	chosen, reason, err := d.placement.Place(cmd.Target.Project, candidates)
+	if err != nil {
+		return "", "", err
+	}
+	return chosen.Node.ID, reason, nil
+}
+
+// placementCandidates lists the online nodes that have the command's project
+// checked out or run its sessions, with their session load. Nodes lacking the
+// project's required labels, or reporting the command's tool as not
+// authenticated, are left out and described in excluded.
+func (d *CommandDispatcher) placementCandidates(cmd Command) (candidates []PlacementCandidate, excluded []string) {
+	project := cmd.Target.Project
+	tool, _ := cmd.Args["tool"].(string)
+	required := d.placementLabels[project]
+
+	serves := make(map[string]bool)
+	active := make(map[string]int)
+	projectActive := make(map[string]int)
 	if d.tracker != nil {
-		sessions := d.tracker.GetAllSessions()
-		for _, session := range sessions {
-			if session.Project != cmd.Target.Project {
-				continue
+		for _, session := range d.tracker.GetAllSessions() {
+			if session.Project == project {
+				serves[session.NodeID] = true
 			}
-
-			node, err := d.registry.GetNode(session.NodeID)
-			if err != nil {
+			if !session.Status.Active() {
 				continue
 			}
-			if node.Status == NodeStatusOnline {
-				return node.ID, nil
+			active[session.NodeID]++
+			if session.Project == project {
+				projectActive[session.NodeID]++
 			}
 		}
 	}
 
-	for _, node := range d.registry.ListNodes() {
+	nodes := d.registry.ListNodes()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	for _, node := range nodes {
 		if node.Status != NodeStatusOnline {
 			continue
 		}
-		for _, project := range node.Projects {
-			if project == cmd.Target.Project {
-				return node.ID, nil
+		if !serves[node.ID] {
+			for _, p := range node.Projects {
+				if p == project {
+					serves[node.ID] = true
+					break
+				}
 			}
 		}
-	}
+		if !serves[node.ID] {
+			continue
+		}
+		if cmd.Type == CommandTypeCreateSession && !node.Schedulable() {
+			excluded = append(excluded, fmt.Sprintf("%s is %s", node.ID, node.Lifecycle))
+			continue
+		}
+		if !labelsMatch(node.Labels, required) {
+			excluded = append(excluded, fmt.Sprintf("%s lacks labels %s", node.ID, formatLabels(required)))
+			continue
+		}
 
-	return "", fmt.Errorf("no online node found for project: %s", cmd.Target.Project)
+		candidate := PlacementCandidate{
+			Node:            node,
+			ActiveSessions:  active[node.ID],
+			ProjectSessions: projectActive[node.ID],
+		}
+		if tool != "" {
+			state, ok := node.AuthStates[tool]
+			switch {
+			case !ok:
+				candidate.AuthUnknown = true
+			case state.Status != authStatusAuthenticated:
+				excluded = append(excluded, fmt.Sprintf("%s has %s %s", node.ID, tool, state.Status))
+				continue
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, excluded
 }
 
 func (d *CommandDispatcher) sendCommand(nodeID string, cmd Command) (*CommandResult, error) {
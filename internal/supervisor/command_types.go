@@ -14,6 +14,7 @@ const (
 	CommandTypePromptSession  CommandType = "prompt_session"
 	CommandTypeKillSession    CommandType = "kill_session"
 	CommandTypeRestartSession CommandType = "restart_session"
+	CommandTypeCompactSession CommandType = "compact_session"
 	CommandTypeSessionStatus  CommandType = "session_status"
 	CommandTypeHandover       CommandType = "handover"
 	CommandTypeCredentialPush CommandType = "credential_push"
@@ -35,11 +36,18 @@ const (
 const (
 	DefaultCommandTimeout  = 30 * time.Second
 	HandoverCommandTimeout = 60 * time.Second
+	// CompactCommandTimeout allows for the LLM call that summarizes a
+	// session's history.
+	CompactCommandTimeout = 10 * time.Minute
 )
 
+// CommandTarget names where a command runs: one node, or a project placed
+// on a node. Labels is a selector that fans the command out to every online
+// node carrying all of them (and serving Project, when one is given).
 type CommandTarget struct {
-	Project string `json:"project,omitempty"`
-	NodeID  string `json:"node_id,omitempty"`
+	Project string            `json:"project,omitempty"`
+	NodeID  string            `json:"node_id,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
 }
 
 type Command struct {
@@ -57,6 +65,34 @@ type CommandResult struct {
 	Output    string        `json:"output,omitempty"`
 	Error     string        `json:"error,omitempty"`
 	Timestamp time.Time     `json:"timestamp"`
+
+	// NodeID is the node the command ran on and Placement why it was chosen.
+	NodeID    string `json:"node_id,omitempty"`
+	Placement string `json:"placement,omitempty"`
+
+	// Results holds the per-node results of a command fanned out by label.
+	Results []CommandResult `json:"results,omitempty"`
+}
+
+// ParseLabelSelector parses a selector of the form "zone=office,gpu=false".
+func ParseLabelSelector(selector string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(term, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label selector term %q: want key=value", term)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("label selector is empty")
+	}
+	return labels, nil
 }
 
 func ParseCommandIntent(intent string) (CommandType, error) {
@@ -70,6 +106,8 @@ func ParseCommandIntent(intent string) (CommandType, error) {
 		return CommandTypeKillSession, nil
 	case string(CommandTypeRestartSession), "restart", "/restart":
 		return CommandTypeRestartSession, nil
+	case string(CommandTypeCompactSession), "compact", "/compact":
+		return CommandTypeCompactSession, nil
 	case string(CommandTypeSessionStatus), "status", "/status":
 		return CommandTypeSessionStatus, nil
 	case string(CommandTypeHandover), "/handover":
@@ -97,6 +135,7 @@ func IsSupportedCommandType(commandType CommandType) bool {
 		CommandTypePromptSession,
 		CommandTypeKillSession,
 		CommandTypeRestartSession,
+		CommandTypeCompactSession,
 		CommandTypeSessionStatus,
 		CommandTypeHandover,
 		CommandTypeCredentialPush,
@@ -115,8 +154,11 @@ func (c Command) EffectiveTimeout() time.Duration {
 	if c.Timeout > 0 {
 		return c.Timeout
 	}
-	if c.Type == CommandTypeHandover {
+	switch c.Type {
+	case CommandTypeHandover:
 		return HandoverCommandTimeout
+	case CommandTypeCompactSession:
+		return CompactCommandTimeout
 	}
 	return DefaultCommandTimeout
 }
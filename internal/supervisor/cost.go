@@ -5,10 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,19 +17,33 @@ import (
 	"go.uber.org/zap"
 )
 
-const (
-	providerAnthropic = "anthropic"
-	providerOpenAI    = "openai"
-)
-
 type CostReport struct {
-	Period            string          `json:"period"`
-	TotalTokens       int64           `json:"total_tokens"`
-	TotalCostUSD      float64         `json:"total_cost_usd"`
+	Period       string  `json:"period"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	TokenBreakdown
+
 	ByProvider        []CostBreakdown `json:"by_provider"`
 	ByModel           []CostBreakdown `json:"by_model"`
 	ByProject         []ProjectCost   `json:"by_project"`
 	DegradedProviders []string        `json:"degraded_providers"`
+	Forecast          *CostForecast   `json:"forecast,omitempty"`
+}
+
+// TokenBreakdown splits a token total by billing class. Totals can exceed
+// the sum of the split when a source only reported totals.
+type TokenBreakdown struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+}
+
+func (b *TokenBreakdown) add(other TokenBreakdown) {
+	b.InputTokens += other.InputTokens
+	b.OutputTokens += other.OutputTokens
+	b.CacheReadTokens += other.CacheReadTokens
+	b.CacheWriteTokens += other.CacheWriteTokens
 }
 
 type CostBreakdown struct {
@@ -38,21 +51,14 @@ type CostBreakdown struct {
 	Model    string  `json:"model,omitempty"`
 	Tokens   int64   `json:"tokens"`
 	CostUSD  float64 `json:"cost_usd"`
+	TokenBreakdown
 }
 
 type ProjectCost struct {
 	Project string  `json:"project"`
 	Tokens  int64   `json:"tokens"`
 	CostUSD float64 `json:"cost_usd"`
-}
-
-type usageRow struct {
-	Provider string
-	Model    string
-	Project  string
-	Date     string
-	Tokens   int64
-	CostUSD  float64
+	TokenBreakdown
 }
 
 type CostAggregator struct {
@@ -65,11 +71,21 @@ type CostAggregator struct {
 	now        func() time.Time
 	sleep      func(time.Duration)
 
-	mu       sync.RWMutex
-	running  bool
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
-	degraded map[string]string
+	mu        sync.RWMutex
+	running   bool
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	degraded  map[string]string
+	providers []CostProvider
+	pricing   *PricingCatalog
+
+	// writeMu serializes database writes from concurrent provider polls;
+	// SQLite rejects overlapping writers with SQLITE_BUSY.
+	writeMu sync.Mutex
+
+	// unpriced holds models already logged as having no rate.
+	unpricedMu sync.Mutex
+	unpriced   map[string]bool
 }
 
 func NewCostAggregator(cfg config.CostConfig, db *sql.DB, tracker *SessionTracker, logger *zap.Logger) *CostAggregator {
@@ -89,7 +105,7 @@ func NewCostAggregator(cfg config.CostConfig, db *sql.DB, tracker *SessionTracke
 		cfg.BackoffBaseMS = 500
 	}
 
-	return &CostAggregator{
+	c := &CostAggregator{
 		cfg:        cfg,
 		db:         db,
 		tracker:    tracker,
@@ -98,7 +114,127 @@ func NewCostAggregator(cfg config.CostConfig, db *sql.DB, tracker *SessionTracke
 		now:        func() time.Time { return time.Now().UTC() },
 		sleep:      time.Sleep,
 		degraded:   make(map[string]string),
+		unpriced:   make(map[string]bool),
+	}
+
+	c.reloadPricingCatalog()
+
+	deps := CostProviderDeps{
+		HTTPClient: c.httpClient,
+		Sessions:   c.trackedSessions,
+		DailyUsage: c.dailyUsage,
+		RateAt:     c.catalogRate,
+		Logger:     logger,
+	}
+	for _, entry := range configuredCostProviders(cfg.Providers) {
+		factory, ok := lookupCostProvider(entry.kind)
+		if !ok {
+			c.setDegraded(entry.name, fmt.Sprintf("unknown provider type %q", entry.kind))
+			logger.Warn("unknown cost provider type", zap.String("provider", entry.name), zap.String("type", entry.kind))
+			continue
+		}
+		provider, err := factory(entry.name, entry.cfg, deps)
+		if err != nil {
+			c.setDegraded(entry.name, err.Error())
+			logger.Warn("cost provider init failed", zap.String("provider", entry.name), zap.Error(err))
+			continue
+		}
+		c.providers = append(c.providers, provider)
+	}
+
+	return c
+}
+
+// AddProvider registers an additional provider instance at runtime. Providers
+// added later lose model-rate ties to those configured earlier.
+func (c *CostAggregator) AddProvider(provider CostProvider) {
+	if provider == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers = append(c.providers, provider)
+}
+
+// Providers returns the names of the active providers in priority order.
+func (c *CostAggregator) Providers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
 	}
+	return names
+}
+
+func (c *CostAggregator) providerList() []CostProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]CostProvider, len(c.providers))
+	copy(out, c.providers)
+	return out
+}
+
+// PricingCatalog returns the loaded pricing catalog, or nil when none is
+// configured.
+func (c *CostAggregator) PricingCatalog() *PricingCatalog {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pricing
+}
+
+// PricingCatalogPath returns the configured catalog file path.
+func (c *CostAggregator) PricingCatalogPath() string {
+	return c.cfg.PricingCatalog
+}
+
+// reloadPricingCatalog re-reads the catalog file. A catalog that fails to
+// load or validate is logged and the previously loaded one stays in use.
+func (c *CostAggregator) reloadPricingCatalog() {
+	if c.cfg.PricingCatalog == "" {
+		return
+	}
+	catalog, err := LoadPricingCatalog(c.cfg.PricingCatalog)
+	if err != nil {
+		c.logger.Warn("pricing catalog not loaded", zap.String("path", c.cfg.PricingCatalog), zap.Error(err))
+		return
+	}
+	c.mu.Lock()
+	c.pricing = catalog
+	c.mu.Unlock()
+}
+
+func (c *CostAggregator) catalogRate(provider, model string, at time.Time) (config.ModelRate, bool) {
+	entry, ok := c.PricingCatalog().RateAt(provider, model, at)
+	if !ok {
+		return config.ModelRate{}, false
+	}
+	return entry.ModelRate, true
+}
+
+// rateAt returns the rate for model on the date of at: the catalog rate in
+// effect then, or the provider's configured rate when the catalog has none.
+func (c *CostAggregator) rateAt(provider CostProvider, model string, at time.Time) (config.ModelRate, bool) {
+	if rate, ok := c.catalogRate(provider.Name(), model, at); ok {
+		return rate, true
+	}
+	return provider.ModelRate(model)
+}
+
+func (c *CostAggregator) trackedSessions() []TrackedSession {
+	if c.tracker == nil {
+		return nil
+	}
+	return c.tracker.GetAllSessions()
+}
+
+func (c *CostAggregator) dailyUsage(start, end time.Time) ([]SessionDayUsage, error) {
+	if c.tracker == nil {
+		return nil, nil
+	}
+	return c.tracker.DailyUsage(start, end)
 }
 
 func (c *CostAggregator) Start(parent context.Context) {
@@ -165,55 +301,63 @@ func (c *CostAggregator) PollOnce(ctx context.Context) {
 }
 
 func (c *CostAggregator) pollCycle(ctx context.Context) {
-	providers := map[string]config.CostProviderConfig{
-		providerAnthropic: c.cfg.Providers.Anthropic,
-		providerOpenAI:    c.cfg.Providers.OpenAI,
-	}
+	c.reloadPricingCatalog()
 
 	var wg sync.WaitGroup
-	for provider, providerCfg := range providers {
-		if !providerCfg.IsEnabled() {
-			continue
-		}
+	for _, provider := range c.providerList() {
 		wg.Add(1)
-		go func(provider string, providerCfg config.CostProviderConfig) {
+		go func(provider CostProvider) {
 			defer wg.Done()
-			c.pollProvider(ctx, provider, providerCfg)
-		}(provider, providerCfg)
+			c.pollProvider(ctx, provider)
+		}(provider)
 	}
 	wg.Wait()
 }
 
-func (c *CostAggregator) pollProvider(ctx context.Context, provider string, providerCfg config.CostProviderConfig) {
-	rows, err := c.fetchProviderWithRetry(ctx, provider, providerCfg)
+func (c *CostAggregator) pollProvider(ctx context.Context, provider CostProvider) {
+	name := provider.Name()
+
+	rows, err := c.fetchProviderWithRetry(ctx, provider)
 	if err != nil {
-		c.setDegraded(provider, err.Error())
+		c.setDegraded(name, err.Error())
 		c.logger.Warn("cost provider polling degraded",
-			zap.String("provider", provider),
+			zap.String("provider", name),
 			zap.String("reason", err.Error()),
 		)
+		c.writeMu.Lock()
 		c.applySessionEstimateFallback(provider)
+		c.writeMu.Unlock()
 		return
 	}
 
-	c.clearDegraded(provider)
-	if err := c.persistDailyBuckets(rows); err != nil {
-		c.logger.Warn("failed to persist cost buckets", zap.String("provider", provider), zap.Error(err))
+	c.clearDegraded(name)
+	c.writeMu.Lock()
+	err = c.persistDailyBuckets(rows)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.logger.Warn("failed to persist cost buckets", zap.String("provider", name), zap.Error(err))
 		return
 	}
 }
 
-func (c *CostAggregator) fetchProviderWithRetry(ctx context.Context, provider string, providerCfg config.CostProviderConfig) ([]usageRow, error) {
-	var lastErr error
+func (c *CostAggregator) fetchProviderWithRetry(ctx context.Context, provider CostProvider) ([]UsageRecord, error) {
+	if err := provider.Health(ctx); err != nil {
+		return nil, fmt.Errorf("health check: %w", err)
+	}
 
+	now := c.now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	var lastErr error
 	for attempt := 0; attempt <= c.cfg.MaxRetries; attempt++ {
-		rows, retryable, err := c.fetchProviderUsage(ctx, provider, providerCfg)
+		rows, err := provider.FetchUsage(ctx, start, end)
 		if err == nil {
 			return rows, nil
 		}
 
 		lastErr = err
-		if !retryable || attempt == c.cfg.MaxRetries {
+		if !isRetryable(err) || attempt == c.cfg.MaxRetries {
 			break
 		}
 
@@ -229,200 +373,12 @@ func (c *CostAggregator) fetchProviderWithRetry(ctx context.Context, provider st
 	return nil, lastErr
 }
 
-func (c *CostAggregator) fetchProviderUsage(ctx context.Context, provider string, providerCfg config.CostProviderConfig) ([]usageRow, bool, error) {
-	endpoint, query := c.providerRequest(provider, providerCfg)
-	if endpoint == "" {
-		return nil, false, fmt.Errorf("provider endpoint is empty")
-	}
-
-	if len(query) > 0 {
-		if strings.Contains(endpoint, "?") {
-			endpoint += "&" + query.Encode()
-		} else {
-			endpoint += "?" + query.Encode()
-		}
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return nil, false, fmt.Errorf("build request: %w", err)
-	}
-
-	apiKey := providerCfg.EffectiveAPIKey()
-	switch provider {
-	case providerAnthropic:
-		req.Header.Set("x-api-key", apiKey)
-		req.Header.Set("anthropic-version", "2023-06-01")
-	case providerOpenAI:
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, true, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, true, fmt.Errorf("read response: %w", readErr)
-	}
-
-	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-		return nil, true, fmt.Errorf("provider returned status %d", resp.StatusCode)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, false, fmt.Errorf("provider returned status %d", resp.StatusCode)
-	}
-
-	parsedRows, parseErr := parseProviderUsage(provider, body)
-	if parseErr != nil {
-		return nil, false, parseErr
-	}
-
-	return parsedRows, false, nil
-}
-
-func (c *CostAggregator) providerRequest(provider string, providerCfg config.CostProviderConfig) (string, url.Values) {
-	now := c.now().UTC()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	end := start.Add(24 * time.Hour)
-
-	if providerCfg.BaseURL != "" {
-		return providerCfg.BaseURL, nil
-	}
-
-	query := url.Values{}
-	switch provider {
-	case providerAnthropic:
-		query.Set("starting_at", start.Format(time.RFC3339))
-		query.Set("ending_at", end.Format(time.RFC3339))
-		query.Set("bucket_width", "1d")
-		query.Add("group_by[]", "model")
-		return "https://api.anthropic.com/v1/organizations/usage_report/messages", query
-	case providerOpenAI:
-		query.Set("start_date", start.Format("2006-01-02"))
-		query.Set("end_date", end.Format("2006-01-02"))
-		query.Set("bucket_width", "1d")
-		query.Add("group_by[]", "model")
-		query.Add("group_by[]", "project_id")
-		return "https://api.openai.com/v1/organization/usage/completions", query
-	default:
-		return "", nil
-	}
-}
-
-func parseProviderUsage(provider string, body []byte) ([]usageRow, error) {
-	switch provider {
-	case providerAnthropic:
-		return parseAnthropicUsage(body)
-	case providerOpenAI:
-		return parseOpenAIUsage(body)
-	default:
-		return nil, fmt.Errorf("unsupported provider %q", provider)
-	}
-}
-
-func parseAnthropicUsage(body []byte) ([]usageRow, error) {
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(body, &raw); err != nil {
-		return nil, fmt.Errorf("decode anthropic response: %w", err)
-	}
-
-	var rows []usageRow
-	if data, ok := raw["usage"]; ok {
-		var usage []struct {
-			Date    string  `json:"date"`
-			Model   string  `json:"model"`
-			Tokens  int64   `json:"tokens"`
-			CostUSD float64 `json:"cost_usd"`
-		}
-		if err := json.Unmarshal(data, &usage); err != nil {
-			return nil, fmt.Errorf("decode anthropic usage: %w", err)
-		}
-		for _, row := range usage {
-			rows = append(rows, usageRow{
-				Provider: providerAnthropic,
-				Model:    row.Model,
-				Date:     normalizeDate(row.Date),
-				Tokens:   row.Tokens,
-				CostUSD:  row.CostUSD,
-			})
-		}
-		return rows, nil
-	}
-
-	if data, ok := raw["data"]; ok {
-		var usage []map[string]interface{}
-		if err := json.Unmarshal(data, &usage); err != nil {
-			return nil, fmt.Errorf("decode anthropic data: %w", err)
-		}
-		for _, item := range usage {
-			tokens := asInt64(item["tokens"])
-			if tokens == 0 {
-				tokens = asInt64(item["total_tokens"])
-			}
-			cost := asFloat64(item["cost_usd"])
-			if cost == 0 {
-				cost = asFloat64(item["total_cost"])
-			}
-			rows = append(rows, usageRow{
-				Provider: providerAnthropic,
-				Model:    asString(item["model"]),
-				Date:     normalizeDate(asString(item["date"]), asString(item["time"]), asString(item["timestamp"]), asString(item["created_at"])),
-				Tokens:   tokens,
-				CostUSD:  cost,
-			})
-		}
-	}
-
-	return rows, nil
-}
-
-func parseOpenAIUsage(body []byte) ([]usageRow, error) {
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(body, &raw); err != nil {
-		return nil, fmt.Errorf("decode openai response: %w", err)
-	}
-
-	data, ok := raw["data"]
-	if !ok {
-		return nil, nil
-	}
-
-	var usage []map[string]interface{}
-	if err := json.Unmarshal(data, &usage); err != nil {
-		return nil, fmt.Errorf("decode openai data: %w", err)
-	}
-
-	rows := make([]usageRow, 0, len(usage))
-	for _, item := range usage {
-		model := asString(item["model"])
-		project := asString(item["project"], item["project_id"])
-		tokens := asInt64(item["tokens"])
-		if tokens == 0 {
-			tokens = asInt64(item["total_tokens"], item["input_tokens"])
-		}
-		cost := asFloat64(item["cost_usd"], item["cost"])
-		rows = append(rows, usageRow{
-			Provider: providerOpenAI,
-			Model:    model,
-			Project:  project,
-			Date:     normalizeDate(asString(item["date"]), asString(item["time"]), asString(item["timestamp"]), asString(item["start_time"])),
-			Tokens:   tokens,
-			CostUSD:  cost,
-		})
-	}
-
-	return rows, nil
-}
-
-func (c *CostAggregator) persistDailyBuckets(rows []usageRow) error {
+func (c *CostAggregator) persistDailyBuckets(rows []UsageRecord) error {
 	if c.db == nil || len(rows) == 0 {
 		return nil
 	}
 
-	combined := map[string]usageRow{}
+	combined := map[string]UsageRecord{}
 	for _, row := range rows {
 		if row.Date == "" {
 			row.Date = c.now().Format("2006-01-02")
@@ -436,6 +392,10 @@ func (c *CostAggregator) persistDailyBuckets(rows []usageRow) error {
 		current.Model = row.Model
 		current.Date = row.Date
 		current.Tokens += row.Tokens
+		current.InputTokens += row.InputTokens
+		current.OutputTokens += row.OutputTokens
+		current.CacheReadTokens += row.CacheReadTokens
+		current.CacheWriteTokens += row.CacheWriteTokens
 		current.CostUSD += row.CostUSD
 		combined[key] = current
 	}
@@ -447,13 +407,21 @@ func (c *CostAggregator) persistDailyBuckets(rows []usageRow) error {
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO costs (id, provider, model, date, tokens, cost_usd)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO costs (
+			id, provider, model, date, tokens,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+			cost_usd
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			provider = excluded.provider,
 			model = excluded.model,
 			date = excluded.date,
 			tokens = excluded.tokens,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			cache_read_tokens = excluded.cache_read_tokens,
+			cache_write_tokens = excluded.cache_write_tokens,
 			cost_usd = excluded.cost_usd
 	`)
 	if err != nil {
@@ -463,7 +431,11 @@ func (c *CostAggregator) persistDailyBuckets(rows []usageRow) error {
 
 	for _, row := range combined {
 		id := row.Provider + "|" + row.Model + "|" + row.Date
-		if _, err := stmt.Exec(id, row.Provider, row.Model, row.Date, row.Tokens, row.CostUSD); err != nil {
+		if _, err := stmt.Exec(
+			id, row.Provider, row.Model, row.Date, row.Tokens,
+			row.InputTokens, row.OutputTokens, row.CacheReadTokens, row.CacheWriteTokens,
+			row.CostUSD,
+		); err != nil {
 			return fmt.Errorf("upsert cost row %s: %w", id, err)
 		}
 	}
@@ -475,33 +447,36 @@ func (c *CostAggregator) persistDailyBuckets(rows []usageRow) error {
 	return nil
 }
 
-func (c *CostAggregator) applySessionEstimateFallback(provider string) {
+func (c *CostAggregator) applySessionEstimateFallback(provider CostProvider) {
 	if c.tracker == nil {
 		return
 	}
 
-	sessions := c.tracker.GetAllSessions()
-	for _, session := range sessions {
-		sessionProvider, ok := c.providerForModel(session.Model)
-		if !ok || sessionProvider != provider {
+	// Price each day's usage at the rate in effect that day, so a rate
+	// change mid-session applies only to usage reported after it.
+	days, err := c.tracker.DailyUsage(time.Time{}, c.now().UTC().AddDate(0, 0, 1))
+	if err != nil {
+		c.logger.Warn("failed to read session usage for fallback estimate", zap.String("provider", provider.Name()), zap.Error(err))
+		return
+	}
+	estimates := map[string]float64{}
+	for _, day := range days {
+		at, err := time.Parse("2006-01-02", day.Date)
+		if err != nil {
 			continue
 		}
+		rate, _ := c.rateAt(provider, day.Model, at)
+		estimates[day.SessionID] += priceTokenUsage(rate, day.Usage)
+	}
 
-		rate := c.modelRate(provider, session.Model)
-		if rate.Input == 0 && rate.Output == 0 {
+	sessions := c.tracker.GetAllSessions()
+	for _, session := range sessions {
+		owner, ok := c.providerForModel(session.Model)
+		if !ok || owner.Name() != provider.Name() {
 			continue
 		}
 
-		ratePerK := rate.Input
-		if rate.Output > 0 {
-			if ratePerK > 0 {
-				ratePerK = (rate.Input + rate.Output) / 2
-			} else {
-				ratePerK = rate.Output
-			}
-		}
-
-		estimate := (float64(session.TokenUsage.Total) / 1000) * ratePerK
+		estimate := estimates[session.SessionID]
 		if estimate <= 0 {
 			continue
 		}
@@ -514,28 +489,27 @@ func (c *CostAggregator) applySessionEstimateFallback(provider string) {
 	}
 }
 
-func (c *CostAggregator) providerForModel(model string) (string, bool) {
+// providerForModel returns the first provider, in priority order, that has a
+// rate for model. Models only priced in the catalog belong to the provider
+// named by their catalog entries.
+func (c *CostAggregator) providerForModel(model string) (CostProvider, bool) {
 	if model == "" {
-		return "", false
-	}
-	if _, ok := c.cfg.Providers.Anthropic.ModelRates[model]; ok {
-		return providerAnthropic, true
+		return nil, false
 	}
-	if _, ok := c.cfg.Providers.OpenAI.ModelRates[model]; ok {
-		return providerOpenAI, true
+	providers := c.providerList()
+	for _, provider := range providers {
+		if _, ok := provider.ModelRate(model); ok {
+			return provider, true
+		}
 	}
-	return "", false
-}
-
-func (c *CostAggregator) modelRate(provider string, model string) config.ModelRate {
-	switch provider {
-	case providerAnthropic:
-		return c.cfg.Providers.Anthropic.ModelRates[model]
-	case providerOpenAI:
-		return c.cfg.Providers.OpenAI.ModelRates[model]
-	default:
-		return config.ModelRate{}
+	if name, ok := c.PricingCatalog().providerFor(model); ok {
+		for _, provider := range providers {
+			if provider.Name() == name {
+				return provider, true
+			}
+		}
 	}
+	return nil, false
 }
 
 func (c *CostAggregator) Report(period string) (CostReport, error) {
@@ -558,21 +532,34 @@ func (c *CostAggregator) Report(period string) (CostReport, error) {
 		for _, row := range modelRows {
 			report.TotalTokens += row.Tokens
 			report.TotalCostUSD += row.CostUSD
+			report.TokenBreakdown.add(row.TokenBreakdown)
 		}
 	}
 
 	if c.tracker != nil {
-		report.ByProject = c.projectTotals(start)
+		byProject, err := c.projectTotals(start)
+		if err != nil {
+			return CostReport{}, err
+		}
+		report.ByProject = byProject
 	}
 
 	report.DegradedProviders = c.DegradedProviders()
 
+	forecast, err := c.Forecast()
+	if err != nil {
+		c.logger.Warn("cost forecast failed", zap.Error(err))
+	} else {
+		report.Forecast = forecast
+	}
+
 	return report, nil
 }
 
 func (c *CostAggregator) queryProviderTotals(start time.Time) ([]CostBreakdown, error) {
 	rows, err := c.db.Query(`
-		SELECT provider, SUM(tokens), SUM(cost_usd)
+		SELECT provider, SUM(tokens), SUM(cost_usd),
+			SUM(input_tokens), SUM(output_tokens), SUM(cache_read_tokens), SUM(cache_write_tokens)
 		FROM costs
 		WHERE date >= ?
 		GROUP BY provider
@@ -586,7 +573,10 @@ func (c *CostAggregator) queryProviderTotals(start time.Time) ([]CostBreakdown,
 	result := make([]CostBreakdown, 0)
 	for rows.Next() {
 		var row CostBreakdown
-		if err := rows.Scan(&row.Provider, &row.Tokens, &row.CostUSD); err != nil {
+		if err := rows.Scan(
+			&row.Provider, &row.Tokens, &row.CostUSD,
+			&row.InputTokens, &row.OutputTokens, &row.CacheReadTokens, &row.CacheWriteTokens,
+		); err != nil {
 			return nil, fmt.Errorf("scan provider total: %w", err)
 		}
 		result = append(result, row)
@@ -597,7 +587,8 @@ func (c *CostAggregator) queryProviderTotals(start time.Time) ([]CostBreakdown,
 
 func (c *CostAggregator) queryModelTotals(start time.Time) ([]CostBreakdown, error) {
 	rows, err := c.db.Query(`
-		SELECT provider, model, SUM(tokens), SUM(cost_usd)
+		SELECT provider, model, SUM(tokens), SUM(cost_usd),
+			SUM(input_tokens), SUM(output_tokens), SUM(cache_read_tokens), SUM(cache_write_tokens)
 		FROM costs
 		WHERE date >= ?
 		GROUP BY provider, model
@@ -611,7 +602,10 @@ func (c *CostAggregator) queryModelTotals(start time.Time) ([]CostBreakdown, err
 	result := make([]CostBreakdown, 0)
 	for rows.Next() {
 		var row CostBreakdown
-		if err := rows.Scan(&row.Provider, &row.Model, &row.Tokens, &row.CostUSD); err != nil {
+		if err := rows.Scan(
+			&row.Provider, &row.Model, &row.Tokens, &row.CostUSD,
+			&row.InputTokens, &row.OutputTokens, &row.CacheReadTokens, &row.CacheWriteTokens,
+		); err != nil {
 			return nil, fmt.Errorf("scan model total: %w", err)
 		}
 		result = append(result, row)
@@ -620,24 +614,131 @@ func (c *CostAggregator) queryModelTotals(start time.Time) ([]CostBreakdown, err
 	return result, rows.Err()
 }
 
-func (c *CostAggregator) projectTotals(start time.Time) []ProjectCost {
-	totals := map[string]ProjectCost{}
-	for _, session := range c.tracker.GetAllSessions() {
-		if session.StartedAt.Before(start) {
+// SpendSince returns the provider-reported spend recorded on or after start.
+func (c *CostAggregator) SpendSince(start time.Time) (float64, error) {
+	if c.db == nil {
+		return 0, nil
+	}
+
+	var total sql.NullFloat64
+	if err := c.db.QueryRow(`
+		SELECT SUM(cost_usd)
+		FROM costs
+		WHERE date >= ?
+	`, start.UTC().Format("2006-01-02")).Scan(&total); err != nil {
+		return 0, fmt.Errorf("query spend since %s: %w", start.Format("2006-01-02"), err)
+	}
+
+	return total.Float64, nil
+}
+
+// sessionDaySpend is a session's usage on one UTC day, priced at the rate in
+// effect that day.
+type sessionDaySpend struct {
+	SessionDayUsage
+	Day     time.Time
+	CostUSD float64
+}
+
+// sessionSpendByDay prices the usage sessions reported from start up to end
+// on the day it was reported, so a session running across several days or
+// periods is charged to each of them. Usage of a model no provider prices is
+// charged its share of the session's reported cost instead.
+func (c *CostAggregator) sessionSpendByDay(start, end time.Time) ([]sessionDaySpend, error) {
+	if c.tracker == nil {
+		return nil, nil
+	}
+	usage, err := c.tracker.DailyUsage(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]sessionDaySpend, 0, len(usage))
+	for _, u := range usage {
+		day, err := time.Parse("2006-01-02", u.Date)
+		if err != nil {
 			continue
 		}
-		item := totals[session.Project]
-		item.Project = session.Project
-		item.Tokens += int64(session.TokenUsage.Total)
-		item.CostUSD += session.SessionCost
-		totals[session.Project] = item
+		cost, ok := c.priceUsageOn(u.Model, day, u.Usage)
+		if !ok {
+			c.noteUnpriced(u.Model)
+			cost = u.reportedCostShare()
+		}
+		out = append(out, sessionDaySpend{
+			SessionDayUsage: u,
+			Day:             day,
+			CostUSD:         cost,
+		})
+	}
+	return out, nil
+}
+
+// priceUsageOn prices usage of model at the rate in effect on day. It
+// reports false when no provider or catalog entry prices the model.
+func (c *CostAggregator) priceUsageOn(model string, day time.Time, usage TokenUsage) (float64, bool) {
+	provider, ok := c.providerForModel(model)
+	if !ok {
+		return 0, false
+	}
+	rate, ok := c.rateAt(provider, model, day)
+	if !ok {
+		return 0, false
+	}
+	return priceTokenUsage(rate, usage), true
+}
+
+// noteUnpriced logs the first time usage of model cannot be priced.
+func (c *CostAggregator) noteUnpriced(model string) {
+	c.unpricedMu.Lock()
+	seen := c.unpriced[model]
+	c.unpriced[model] = true
+	c.unpricedMu.Unlock()
+	if !seen {
+		c.logger.Warn("no rate for model; using session reported cost", zap.String("model", model))
+	}
+}
+
+// ProjectSpend returns estimated session spend per project from start up to
+// end, attributed to the day the usage was reported.
+func (c *CostAggregator) ProjectSpend(start, end time.Time) (map[string]float64, error) {
+	days, err := c.sessionSpendByDay(start, end)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]float64{}
+	for _, day := range days {
+		out[day.Project] += day.CostUSD
+	}
+	return out, nil
+}
+
+// projectTotals sums the usage each project's sessions reported from start
+// through today, priced at the rate of the day it was reported.
+func (c *CostAggregator) projectTotals(start time.Time) ([]ProjectCost, error) {
+	days, err := c.sessionSpendByDay(start, startOfUTCDay(c.now()).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]ProjectCost{}
+	for _, day := range days {
+		item := totals[day.Project]
+		item.Project = day.Project
+		item.Tokens += int64(day.Usage.Total)
+		item.InputTokens += int64(day.Usage.Prompt)
+		item.OutputTokens += int64(day.Usage.Completion)
+		item.CacheReadTokens += int64(day.Usage.CacheRead)
+		item.CacheWriteTokens += int64(day.Usage.CacheWrite)
+		item.CostUSD += day.CostUSD
+		totals[day.Project] = item
 	}
 
 	out := make([]ProjectCost, 0, len(totals))
 	for _, value := range totals {
 		out = append(out, value)
 	}
-	return out
+	sort.Slice(out, func(i, j int) bool { return out[i].Project < out[j].Project })
+	return out, nil
 }
 
 func (c *CostAggregator) DegradedProviders() []string {
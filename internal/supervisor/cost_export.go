@@ -0,0 +1,290 @@
+package supervisor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CostGranularity string
+
+const (
+	CostGranularityDaily   CostGranularity = "daily"
+	CostGranularityWeekly  CostGranularity = "weekly"
+	CostGranularityMonthly CostGranularity = "monthly"
+)
+
+type CostGroupBy string
+
+const (
+	CostGroupByProvider CostGroupBy = "provider"
+	CostGroupByModel    CostGroupBy = "model"
+	CostGroupByProject  CostGroupBy = "project"
+	CostGroupByNode     CostGroupBy = "node"
+)
+
+const costRangeMaxDays = 366
+
+// CostRangeQuery selects usage between two UTC dates, both inclusive.
+type CostRangeQuery struct {
+	From        time.Time
+	To          time.Time
+	Granularity CostGranularity
+	GroupBy     CostGroupBy
+}
+
+// CostRangeReport buckets usage by period and group. Provider and model
+// groups come from provider-reported cost buckets; project and node groups
+// come from the usage sessions reported each day, priced at that day's rate.
+type CostRangeReport struct {
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Granularity  string         `json:"granularity"`
+	GroupBy      string         `json:"group_by"`
+	TotalTokens  int64          `json:"total_tokens"`
+	TotalCostUSD float64        `json:"total_cost_usd"`
+	Rows         []CostRangeRow `json:"rows"`
+}
+
+type CostRangeRow struct {
+	Period   string  `json:"period"`
+	Group    string  `json:"group"`
+	Provider string  `json:"provider,omitempty"`
+	Tokens   int64   `json:"tokens"`
+	CostUSD  float64 `json:"cost_usd"`
+	TokenBreakdown
+}
+
+// ParseCostRangeQuery builds a query from raw from/to/granularity/group_by
+// values. Dates are YYYY-MM-DD; to defaults to today and from to 29 days
+// before to, matching the "month" report window.
+func ParseCostRangeQuery(now time.Time, from, to, granularity, groupBy string) (CostRangeQuery, error) {
+	query := CostRangeQuery{
+		To:          startOfUTCDay(now),
+		Granularity: CostGranularityDaily,
+		GroupBy:     CostGroupByProvider,
+	}
+
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return CostRangeQuery{}, fmt.Errorf("invalid to date %q: expected YYYY-MM-DD", to)
+		}
+		query.To = parsed
+	}
+	query.From = query.To.AddDate(0, 0, -29)
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return CostRangeQuery{}, fmt.Errorf("invalid from date %q: expected YYYY-MM-DD", from)
+		}
+		query.From = parsed
+	}
+
+	if query.From.After(query.To) {
+		return CostRangeQuery{}, fmt.Errorf("from %s is after to %s", query.From.Format("2006-01-02"), query.To.Format("2006-01-02"))
+	}
+	if query.To.Sub(query.From) > costRangeMaxDays*24*time.Hour {
+		return CostRangeQuery{}, fmt.Errorf("range exceeds %d days", costRangeMaxDays)
+	}
+
+	switch CostGranularity(strings.ToLower(granularity)) {
+	case "", CostGranularityDaily:
+	case CostGranularityWeekly:
+		query.Granularity = CostGranularityWeekly
+	case CostGranularityMonthly:
+		query.Granularity = CostGranularityMonthly
+	default:
+		return CostRangeQuery{}, fmt.Errorf("invalid granularity %q: expected daily, weekly or monthly", granularity)
+	}
+
+	switch CostGroupBy(strings.ToLower(groupBy)) {
+	case "", CostGroupByProvider:
+	case CostGroupByModel:
+		query.GroupBy = CostGroupByModel
+	case CostGroupByProject:
+		query.GroupBy = CostGroupByProject
+	case CostGroupByNode:
+		query.GroupBy = CostGroupByNode
+	default:
+		return CostRangeQuery{}, fmt.Errorf("invalid group_by %q: expected provider, model, project or node", groupBy)
+	}
+
+	return query, nil
+}
+
+// RangeReport aggregates usage for an arbitrary date range.
+func (c *CostAggregator) RangeReport(query CostRangeQuery) (CostRangeReport, error) {
+	from := startOfUTCDay(query.From)
+	end := startOfUTCDay(query.To).AddDate(0, 0, 1)
+
+	report := CostRangeReport{
+		From:        from.Format("2006-01-02"),
+		To:          end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Granularity: string(query.Granularity),
+		GroupBy:     string(query.GroupBy),
+		Rows:        make([]CostRangeRow, 0),
+	}
+
+	rows := map[string]CostRangeRow{}
+	add := func(day time.Time, group, provider string, tokens int64, split TokenBreakdown, cost float64) {
+		period := costPeriodStart(day, query.Granularity).Format("2006-01-02")
+		key := period + "|" + provider + "|" + group
+		row := rows[key]
+		row.Period = period
+		row.Group = group
+		row.Provider = provider
+		row.Tokens += tokens
+		row.CostUSD += cost
+		row.TokenBreakdown.add(split)
+		rows[key] = row
+	}
+
+	switch query.GroupBy {
+	case CostGroupByProject, CostGroupByNode:
+		days, err := c.sessionSpendByDay(from, end)
+		if err != nil {
+			return CostRangeReport{}, err
+		}
+		for _, day := range days {
+			group := day.Project
+			if query.GroupBy == CostGroupByNode {
+				group = day.NodeID
+			}
+			usage := day.Usage
+			add(day.Day, group, "", int64(usage.Total), TokenBreakdown{
+				InputTokens:      int64(usage.Prompt),
+				OutputTokens:     int64(usage.Completion),
+				CacheReadTokens:  int64(usage.CacheRead),
+				CacheWriteTokens: int64(usage.CacheWrite),
+			}, day.CostUSD)
+		}
+	default:
+		if c.db != nil {
+			buckets, err := c.queryDailyBuckets(from, end)
+			if err != nil {
+				return CostRangeReport{}, err
+			}
+			for _, bucket := range buckets {
+				day, err := time.Parse("2006-01-02", bucket.Date)
+				if err != nil {
+					continue
+				}
+				group, provider := bucket.Provider, ""
+				if query.GroupBy == CostGroupByModel {
+					group, provider = bucket.Model, bucket.Provider
+				}
+				add(day, group, provider, bucket.Tokens, TokenBreakdown{
+					InputTokens:      bucket.InputTokens,
+					OutputTokens:     bucket.OutputTokens,
+					CacheReadTokens:  bucket.CacheReadTokens,
+					CacheWriteTokens: bucket.CacheWriteTokens,
+				}, bucket.CostUSD)
+			}
+		}
+	}
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, row)
+		report.TotalTokens += row.Tokens
+		report.TotalCostUSD += row.CostUSD
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Group < b.Group
+	})
+
+	return report, nil
+}
+
+func (c *CostAggregator) queryDailyBuckets(from, end time.Time) ([]UsageRecord, error) {
+	rows, err := c.db.Query(`
+		SELECT provider, model, substr(date, 1, 10) AS day,
+			SUM(tokens), SUM(input_tokens), SUM(output_tokens),
+			SUM(cache_read_tokens), SUM(cache_write_tokens), SUM(cost_usd)
+		FROM costs
+		WHERE date >= ? AND date < ?
+		GROUP BY provider, model, day
+	`, from.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("query daily cost buckets: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]UsageRecord, 0)
+	for rows.Next() {
+		var row UsageRecord
+		if err := rows.Scan(
+			&row.Provider, &row.Model, &row.Date,
+			&row.Tokens, &row.InputTokens, &row.OutputTokens,
+			&row.CacheReadTokens, &row.CacheWriteTokens, &row.CostUSD,
+		); err != nil {
+			return nil, fmt.Errorf("scan daily cost bucket: %w", err)
+		}
+		out = append(out, row)
+	}
+
+	return out, rows.Err()
+}
+
+// costPeriodStart returns the first day of the granularity period containing
+// day. Weeks and months use the same calendar windows as budgets.
+func costPeriodStart(day time.Time, granularity CostGranularity) time.Time {
+	switch granularity {
+	case CostGranularityWeekly:
+		start, _ := budgetPeriodWindow(day, BudgetPeriodWeekly)
+		return start
+	case CostGranularityMonthly:
+		start, _ := budgetPeriodWindow(day, BudgetPeriodMonthly)
+		return start
+	default:
+		return startOfUTCDay(day)
+	}
+}
+
+// WriteCostCSV writes report rows as CSV with a header. Model reports carry
+// an extra provider column so identically named models stay distinguishable.
+func WriteCostCSV(w io.Writer, report CostRangeReport) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"period"}
+	if report.GroupBy == string(CostGroupByModel) {
+		header = append(header, "provider")
+	}
+	header = append(header, report.GroupBy, "tokens", "input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "cost_usd")
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+
+	for _, row := range report.Rows {
+		record := []string{row.Period}
+		if report.GroupBy == string(CostGroupByModel) {
+			record = append(record, row.Provider)
+		}
+		record = append(record,
+			row.Group,
+			strconv.FormatInt(row.Tokens, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheReadTokens, 10),
+			strconv.FormatInt(row.CacheWriteTokens, 10),
+			strconv.FormatFloat(row.CostUSD, 'f', 6, 64),
+		)
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
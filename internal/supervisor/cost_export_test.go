@@ -0,0 +1,252 @@
+package supervisor
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestParseCostRangeQuery(t *testing.T) {
+	now := time.Date(2026, 2, 18, 15, 0, 0, 0, time.UTC)
+
+	query, err := ParseCostRangeQuery(now, "", "", "", "")
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	if !query.To.Equal(time.Date(2026, 2, 18, 0, 0, 0, 0, time.UTC)) || !query.From.Equal(time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected default range %s - %s", query.From, query.To)
+	}
+	if query.Granularity != CostGranularityDaily || query.GroupBy != CostGroupByProvider {
+		t.Fatalf("unexpected defaults %+v", query)
+	}
+
+	query, err = ParseCostRangeQuery(now, "2026-02-01", "2026-02-28", "Weekly", "project")
+	if err != nil {
+		t.Fatalf("explicit: %v", err)
+	}
+	if query.Granularity != CostGranularityWeekly || query.GroupBy != CostGroupByProject {
+		t.Fatalf("unexpected parsed query %+v", query)
+	}
+
+	invalid := [][4]string{
+		{"2026-02-10", "2026-02-01", "", ""},
+		{"02/01/2026", "", "", ""},
+		{"", "", "hourly", ""},
+		{"", "", "", "team"},
+		{"2024-01-01", "2026-01-01", "", ""},
+	}
+	for _, args := range invalid {
+		if _, err := ParseCostRangeQuery(now, args[0], args[1], args[2], args[3]); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestCostRangeReportByProviderAndModel(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	seedRangeCosts(t, db)
+
+	agg := NewCostAggregator(config.CostConfig{}, db, nil, zap.NewNop())
+
+	report, err := agg.RangeReport(CostRangeQuery{
+		From:        time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+		Granularity: CostGranularityWeekly,
+		GroupBy:     CostGroupByProvider,
+	})
+	if err != nil {
+		t.Fatalf("range report: %v", err)
+	}
+	// 2026-02-02 and 2026-02-04 share the week of Mon 2 Feb; 2026-02-10 falls
+	// in the week of Mon 9 Feb; 2026-03-01 is outside the range.
+	if len(report.Rows) != 2 || report.Rows[1].Period != "2026-02-09" {
+		t.Fatalf("expected 3 rows, got %+v", report.Rows)
+	}
+	first := report.Rows[0]
+	if first.Period != "2026-02-02" || first.Group != "anthropic" || first.CostUSD != 3 || first.Tokens != 300 || first.InputTokens != 200 {
+		t.Fatalf("unexpected first row %+v", first)
+	}
+	if report.TotalCostUSD != 7 || report.From != "2026-02-01" || report.To != "2026-02-28" {
+		t.Fatalf("unexpected totals %+v", report)
+	}
+
+	byModel, err := agg.RangeReport(CostRangeQuery{
+		From:        time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+		Granularity: CostGranularityMonthly,
+		GroupBy:     CostGroupByModel,
+	})
+	if err != nil {
+		t.Fatalf("model report: %v", err)
+	}
+	if len(byModel.Rows) != 2 || byModel.Rows[0].Provider != "anthropic" || byModel.Rows[0].Group != "claude-sonnet-4" || byModel.Rows[0].Period != "2026-02-01" {
+		t.Fatalf("unexpected model rows %+v", byModel.Rows)
+	}
+}
+
+func TestCostRangeReportByProjectAndNode(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	for _, node := range []string{"n-1", "n-2"} {
+		if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, node, node, "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+			t.Fatalf("insert node: %v", err)
+		}
+	}
+	sessions := []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Model: "gpt-4", StartedAt: time.Date(2026, 1, 30, 9, 0, 0, 0, time.UTC)},
+		{SessionID: "s-2", NodeID: "n-2", Project: "proj-a", Model: "gpt-4", StartedAt: time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)},
+		{SessionID: "s-3", NodeID: "n-2", Project: "proj-b", Model: "gpt-4", StartedAt: time.Date(2026, 2, 4, 10, 0, 0, 0, time.UTC)},
+		{SessionID: "s-4", NodeID: "n-1", Project: "proj-b", Model: "gpt-4", StartedAt: time.Date(2026, 2, 27, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, session := range sessions {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+	// s-1 started before the range but reported usage inside it; s-4 ran
+	// past the end of it.
+	if _, err := db.Exec(`
+		INSERT INTO session_usage (session_id, date, tokens, input_tokens)
+		VALUES
+			('s-1', '2026-01-30', 5000, 5000),
+			('s-1', '2026-02-02', 1000, 1000),
+			('s-2', '2026-02-03', 2000, 2000),
+			('s-3', '2026-02-04', 4000, 4000),
+			('s-4', '2026-02-27', 1000, 1000),
+			('s-4', '2026-03-01', 8000, 8000)
+	`); err != nil {
+		t.Fatalf("insert session usage: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{Providers: config.CostProviders{
+		OpenAI: config.CostProviderConfig{
+			APIKey:     "sk-org-test",
+			ModelRates: map[string]config.ModelRate{"gpt-4": {Input: 1}},
+		},
+	}}, db, tracker, zap.NewNop())
+	rangeQuery := CostRangeQuery{
+		From:        time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+		Granularity: CostGranularityMonthly,
+		GroupBy:     CostGroupByProject,
+	}
+
+	report, err := agg.RangeReport(rangeQuery)
+	if err != nil {
+		t.Fatalf("project report: %v", err)
+	}
+	if len(report.Rows) != 2 || report.Rows[0].Group != "proj-a" || report.Rows[0].CostUSD != 3 || report.Rows[0].InputTokens != 3000 || report.Rows[1].CostUSD != 5 {
+		t.Fatalf("unexpected project rows %+v", report.Rows)
+	}
+
+	rangeQuery.GroupBy = CostGroupByNode
+	report, err = agg.RangeReport(rangeQuery)
+	if err != nil {
+		t.Fatalf("node report: %v", err)
+	}
+	if len(report.Rows) != 2 || report.Rows[0].Group != "n-1" || report.Rows[0].CostUSD != 2 || report.Rows[1].CostUSD != 6 {
+		t.Fatalf("unexpected node rows %+v", report.Rows)
+	}
+}
+
+func TestWriteCostCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCostCSV(&buf, CostRangeReport{
+		GroupBy: "model",
+		Rows: []CostRangeRow{
+			{Period: "2026-02-01", Group: "gpt-4", Provider: "openai", Tokens: 10, CostUSD: 0.5, TokenBreakdown: TokenBreakdown{InputTokens: 6, OutputTokens: 4}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+
+	want := "period,provider,model,tokens,input_tokens,output_tokens,cache_read_tokens,cache_write_tokens,cost_usd\n" +
+		"2026-02-01,openai,gpt-4,10,6,4,0,0,0.500000\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestHTTPAPICostExport(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+	seedRangeCosts(t, api.db)
+
+	agg := NewCostAggregator(config.CostConfig{}, api.db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC) }
+	api.SetCostAggregator(agg)
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/export?from=2026-02-01&to=2026-02-28&group_by=provider", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Fatalf("expected text/csv, got %q", ct)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "cost-provider-2026-02-01-2026-02-28.csv") {
+		t.Fatalf("unexpected content disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "period,provider,tokens") {
+		t.Fatalf("unexpected csv body:\n%s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/export?format=json&from=2026-02-01&to=2026-02-28&granularity=monthly", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data CostRangeReport `json:"data"`
+		Meta apiMeta         `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Meta.Total != 2 || resp.Data.TotalCostUSD != 7 {
+		t.Fatalf("unexpected json export %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost?from=2026-02-01&to=2026-02-28&granularity=monthly", ""))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"granularity":"monthly"`) {
+		t.Fatalf("expected range report from /api/v1/cost, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/export?from=2026-02-10&to=2026-02-01", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for inverted range, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/export?format=xml", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown format, got %d", rec.Code)
+	}
+}
+
+func seedRangeCosts(t *testing.T, db *sql.DB) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO costs (id, provider, model, date, tokens, input_tokens, output_tokens, cost_usd)
+		VALUES
+			('anthropic|claude-sonnet-4|2026-02-02', 'anthropic', 'claude-sonnet-4', '2026-02-02', 100, 60, 40, 1),
+			('anthropic|claude-sonnet-4|2026-02-04', 'anthropic', 'claude-sonnet-4', '2026-02-04', 200, 140, 60, 2),
+			('openai|gpt-4|2026-02-10', 'openai', 'gpt-4', '2026-02-10', 400, 300, 100, 4),
+			('openai|gpt-4|2026-03-01', 'openai', 'gpt-4', '2026-03-01', 800, 600, 200, 8)
+	`); err != nil {
+		t.Fatalf("insert costs: %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+const providerGoogle = "google"
+
+// googleCostProvider reads Cloud Billing export files from a drop directory
+// or, when no directory is configured, fetches export rows from base_url.
+type googleCostProvider struct {
+	*httpCostProvider
+}
+
+func newGoogleCostProvider(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error) {
+	return &googleCostProvider{
+		httpCostProvider: &httpCostProvider{
+			name:   name,
+			cfg:    cfg,
+			client: deps.HTTPClient,
+			parse: func(body []byte) ([]UsageRecord, error) {
+				return parseGoogleUsage(name, body, cfg.SKUModels)
+			},
+		},
+	}, nil
+}
+
+func (p *googleCostProvider) Health(ctx context.Context) error {
+	if p.cfg.ExportDir == "" {
+		if p.cfg.BaseURL == "" {
+			return fmt.Errorf("export_dir or base_url is required")
+		}
+		return p.httpCostProvider.Health(ctx)
+	}
+
+	info, err := os.Stat(p.cfg.ExportDir)
+	if err != nil {
+		return fmt.Errorf("billing export dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("billing export path %s is not a directory", p.cfg.ExportDir)
+	}
+	return nil
+}
+
+func (p *googleCostProvider) FetchUsage(ctx context.Context, start, end time.Time) ([]UsageRecord, error) {
+	if p.cfg.ExportDir == "" {
+		return p.httpCostProvider.FetchUsage(ctx, start, end)
+	}
+	return readGoogleBillingExport(p.name, p.cfg.ExportDir, p.cfg.SKUModels)
+}
+
+// googleBillingRow is one line item from a Cloud Billing export, flattened
+// from either the nested BigQuery JSON layout or the dotted CSV headers.
+type googleBillingRow struct {
+	Service        string
+	SKUID          string
+	SKUDescription string
+	UsageStart     string
+	Cost           float64
+	Currency       string
+	UsageAmount    float64
+}
+
+// readGoogleBillingExport ingests every export file in dir. Files are re-read
+// on each poll; persistDailyBuckets upserts by provider/model/date so totals
+// stay correct as long as exports are not removed mid-period.
+func readGoogleBillingExport(provider, dir string, skuModels map[string]string) ([]UsageRecord, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read google billing export dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".jsonl", ".ndjson", ".csv":
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	rows := make([]UsageRecord, 0)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read google billing export %s: %w", name, err)
+		}
+
+		var billing []googleBillingRow
+		if strings.EqualFold(filepath.Ext(name), ".csv") {
+			billing, err = parseGoogleBillingCSV(data)
+		} else {
+			billing, err = parseGoogleBillingJSON(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse google billing export %s: %w", name, err)
+		}
+
+		converted, err := googleUsageRows(provider, billing, skuModels)
+		if err != nil {
+			return nil, fmt.Errorf("google billing export %s: %w", name, err)
+		}
+		rows = append(rows, converted...)
+	}
+
+	return rows, nil
+}
+
+// parseGoogleUsage decodes an API response carrying billing export rows, as
+// returned by an export proxy configured through base_url.
+func parseGoogleUsage(provider string, body []byte, skuModels map[string]string) ([]UsageRecord, error) {
+	billing, err := parseGoogleBillingJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode google response: %w", err)
+	}
+	return googleUsageRows(provider, billing, skuModels)
+}
+
+// parseGoogleBillingJSON accepts a JSON array, an object wrapping the rows in
+// "rows" or "data", or newline-delimited JSON as written by BigQuery exports.
+func parseGoogleBillingJSON(data []byte) ([]googleBillingRow, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var items []map[string]interface{}
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("decode json array: %w", err)
+		}
+	case '{':
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			lines, lineErr := parseJSONLines(trimmed)
+			if lineErr != nil {
+				return nil, lineErr
+			}
+			items = lines
+			break
+		}
+
+		raw, ok := wrapper["rows"]
+		if !ok {
+			raw, ok = wrapper["data"]
+		}
+		if !ok {
+			var single map[string]interface{}
+			if err := json.Unmarshal(trimmed, &single); err != nil {
+				return nil, fmt.Errorf("decode json object: %w", err)
+			}
+			items = append(items, single)
+			break
+		}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("decode json rows: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected json content")
+	}
+
+	rows := make([]googleBillingRow, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, googleBillingRow{
+			Service:        googleField(item, "service", "description"),
+			SKUID:          googleField(item, "sku", "id"),
+			SKUDescription: googleField(item, "sku", "description"),
+			UsageStart:     googleField(item, "usage_start_time", ""),
+			Cost:           googleNumber(item["cost"]),
+			Currency:       googleField(item, "currency", ""),
+			UsageAmount:    googleNumber(googleNested(item, "usage", "amount")),
+		})
+	}
+
+	return rows, nil
+}
+
+func parseJSONLines(data []byte) ([]map[string]interface{}, error) {
+	items := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item map[string]interface{}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("decode json line: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan json lines: %w", err)
+	}
+	return items, nil
+}
+
+// parseGoogleBillingCSV reads a CSV export whose header uses either dotted
+// BigQuery column names (sku.description) or underscores (sku_description).
+func parseGoogleBillingCSV(data []byte) ([]googleBillingRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), ".", "_")
+		columns[key] = i
+	}
+
+	value := func(record []string, key string) string {
+		idx, ok := columns[key]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	rows := make([]googleBillingRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv record: %w", err)
+		}
+		rows = append(rows, googleBillingRow{
+			Service:        value(record, "service_description"),
+			SKUID:          value(record, "sku_id"),
+			SKUDescription: value(record, "sku_description"),
+			UsageStart:     value(record, "usage_start_time"),
+			Cost:           googleNumber(value(record, "cost")),
+			Currency:       value(record, "currency"),
+			UsageAmount:    googleNumber(value(record, "usage_amount")),
+		})
+	}
+
+	return rows, nil
+}
+
+// googleUsageRows keeps the line items that belong to Gemini/Vertex and maps
+// each SKU to a model. Exports in a currency other than USD are rejected so
+// the provider is reported as degraded rather than silently mis-costed.
+func googleUsageRows(provider string, billing []googleBillingRow, skuModels map[string]string) ([]UsageRecord, error) {
+	rows := make([]UsageRecord, 0, len(billing))
+	for _, item := range billing {
+		model, mapped := googleModelForSKU(item, skuModels)
+		if !mapped && !isGoogleAIService(item.Service) {
+			continue
+		}
+		if item.Currency != "" && !strings.EqualFold(item.Currency, "USD") {
+			return nil, fmt.Errorf("unsupported billing currency %q", item.Currency)
+		}
+		row := UsageRecord{
+			Provider: provider,
+			Model:    model,
+			Date:     normalizeDate(item.UsageStart),
+			Tokens:   int64(item.UsageAmount),
+			CostUSD:  item.Cost,
+		}
+		switch googleTokenClass(item.SKUDescription) {
+		case "cache_read":
+			row.CacheReadTokens = row.Tokens
+		case "input":
+			row.InputTokens = row.Tokens
+		case "output":
+			row.OutputTokens = row.Tokens
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// googleTokenClass infers which token class a SKU bills from its description,
+// e.g. "Gemini 1.5 Pro Cached Input Tokens" -> cache_read. Context-cache
+// storage SKUs bill token-hours rather than tokens and are left unsplit.
+func googleTokenClass(description string) string {
+	lower := strings.ToLower(description)
+	switch {
+	case strings.Contains(lower, "storage"):
+		return ""
+	case strings.Contains(lower, "cached") || strings.Contains(lower, "caching"):
+		return "cache_read"
+	case strings.Contains(lower, "output"):
+		return "output"
+	case strings.Contains(lower, "input"):
+		return "input"
+	}
+	return ""
+}
+
+func googleModelForSKU(item googleBillingRow, skuModels map[string]string) (string, bool) {
+	for sku, model := range skuModels {
+		if strings.EqualFold(sku, item.SKUID) || strings.EqualFold(sku, item.SKUDescription) {
+			return model, true
+		}
+	}
+	return googleModelFromDescription(item.SKUDescription), false
+}
+
+// googleModelFromDescription derives a model name from SKU descriptions such
+// as "Gemini 1.5 Pro Input Tokens" -> "gemini-1.5-pro".
+func googleModelFromDescription(description string) string {
+	stop := map[string]bool{
+		"input": true, "output": true, "text": true, "token": true, "tokens": true,
+		"character": true, "characters": true, "cached": true, "caching": true,
+		"image": true, "audio": true, "video": true, "for": true, "-": true,
+	}
+
+	parts := make([]string, 0)
+	for _, word := range strings.Fields(strings.ToLower(description)) {
+		if stop[word] {
+			break
+		}
+		parts = append(parts, word)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "-")
+}
+
+func isGoogleAIService(service string) bool {
+	lower := strings.ToLower(service)
+	return strings.Contains(lower, "vertex") ||
+		strings.Contains(lower, "gemini") ||
+		strings.Contains(lower, "generative language")
+}
+
+func googleField(item map[string]interface{}, key, nested string) string {
+	if nested == "" {
+		return asString(item[key])
+	}
+	if value := asString(googleNested(item, key, nested)); value != "" {
+		return value
+	}
+	return asString(item[key+"."+nested], item[key+"_"+nested])
+}
+
+func googleNested(item map[string]interface{}, key, nested string) interface{} {
+	if inner, ok := item[key].(map[string]interface{}); ok {
+		return inner[nested]
+	}
+	if value, ok := item[key+"."+nested]; ok {
+		return value
+	}
+	return item[key+"_"+nested]
+}
+
+// googleNumber accepts JSON numbers as well as the numeric strings BigQuery
+// uses for NUMERIC columns.
+func googleNumber(value interface{}) float64 {
+	if s, ok := value.(string); ok {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0
+		}
+		return parsed
+	}
+	return asFloat64(value)
+}
@@ -0,0 +1,190 @@
+package supervisor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestParseGoogleBillingJSONNested(t *testing.T) {
+	body := []byte(`[
+		{"service": {"description": "Vertex AI"}, "sku": {"id": "A1B2", "description": "Gemini 1.5 Pro Input Tokens"}, "usage_start_time": "2026-02-16T03:00:00Z", "cost": 1.25, "currency": "USD", "usage": {"amount": 250000}},
+		{"service": {"description": "Compute Engine"}, "sku": {"id": "C3D4", "description": "N2 Instance Core"}, "usage_start_time": "2026-02-16T03:00:00Z", "cost": 9.5, "currency": "USD"}
+	]`)
+
+	rows, err := parseGoogleUsage(providerGoogle, body, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected only the Vertex AI row, got %+v", rows)
+	}
+	row := rows[0]
+	if row.Provider != providerGoogle || row.Model != "gemini-1.5-pro" || row.Date != "2026-02-16" || row.Tokens != 250000 || row.CostUSD != 1.25 {
+		t.Fatalf("unexpected row %+v", row)
+	}
+	if row.InputTokens != 250000 || row.OutputTokens != 0 {
+		t.Fatalf("expected input SKU to be classed as input tokens, got %+v", row)
+	}
+}
+
+func TestGoogleTokenClass(t *testing.T) {
+	cases := map[string]string{
+		"Gemini 1.5 Pro Input Tokens":          "input",
+		"Gemini 1.5 Pro Output Tokens":         "output",
+		"Gemini 1.5 Pro Cached Input Tokens":   "cache_read",
+		"Gemini 1.5 Pro Context Cache Storage": "",
+		"Imagen Generation":                    "",
+	}
+	for description, want := range cases {
+		if got := googleTokenClass(description); got != want {
+			t.Fatalf("googleTokenClass(%q) = %q, want %q", description, got, want)
+		}
+	}
+}
+
+func TestParseGoogleBillingNDJSONWithSKUMapping(t *testing.T) {
+	body := []byte(`{"service.description": "Generative Language API", "sku.id": "F00D", "sku.description": "Flash tokens", "usage_start_time": "2026-02-17T00:00:00Z", "cost": "0.40", "usage.amount": "1000"}
+{"service.description": "Cloud Storage", "sku.id": "BEEF", "sku.description": "Standard Storage", "usage_start_time": "2026-02-17T00:00:00Z", "cost": "0.10"}
+`)
+
+	rows, err := parseGoogleUsage(providerGoogle, body, map[string]string{"f00d": "gemini-2.0-flash", "BEEF": "storage-as-model"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected mapped SKUs to be kept regardless of service, got %+v", rows)
+	}
+	if rows[0].Model != "gemini-2.0-flash" || rows[0].CostUSD != 0.40 || rows[0].Tokens != 1000 {
+		t.Fatalf("unexpected mapped row %+v", rows[0])
+	}
+}
+
+func TestParseGoogleBillingCSV(t *testing.T) {
+	data := []byte("service.description,sku.id,sku.description,usage_start_time,cost,currency,usage.amount\n" +
+		"Vertex AI,A1B2,Gemini 1.5 Flash Output Characters,2026-02-16T00:00:00Z,0.75,USD,3000\n" +
+		"BigQuery,X,Analysis,2026-02-16T00:00:00Z,5,USD,1\n")
+
+	billing, err := parseGoogleBillingCSV(data)
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	rows, err := googleUsageRows(providerGoogle, billing, nil)
+	if err != nil {
+		t.Fatalf("convert rows: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Model != "gemini-1.5-flash" || rows[0].CostUSD != 0.75 || rows[0].Tokens != 3000 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+}
+
+func TestGoogleBillingRejectsNonUSD(t *testing.T) {
+	billing := []googleBillingRow{{Service: "Vertex AI", SKUDescription: "Gemini 1.5 Pro Input Tokens", Cost: 1, Currency: "EUR"}}
+	if _, err := googleUsageRows(providerGoogle, billing, nil); err == nil || !strings.Contains(err.Error(), "EUR") {
+		t.Fatalf("expected currency error, got %v", err)
+	}
+}
+
+func TestCostAggregatorGoogleExportDir(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "export-1.json"), []byte(`{"rows": [
+		{"service": {"description": "Vertex AI"}, "sku": {"id": "A", "description": "Gemini 1.5 Pro Input Tokens"}, "usage_start_time": "2026-02-16T01:00:00Z", "cost": 1.0, "usage": {"amount": 100}},
+		{"service": {"description": "Vertex AI"}, "sku": {"id": "B", "description": "Gemini 1.5 Pro Output Tokens"}, "usage_start_time": "2026-02-16T02:00:00Z", "cost": 2.0, "usage": {"amount": 50}}
+	]}`), 0o644); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+
+	cfg := config.CostConfig{
+		MaxRetries:    1,
+		BackoffBaseMS: 1,
+		Providers: config.CostProviders{
+			Google: config.CostProviderConfig{ExportDir: dir},
+		},
+	}
+	agg := NewCostAggregator(cfg, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC) }
+
+	// Polling twice must not double count: buckets are upserted.
+	agg.PollOnce(context.Background())
+	agg.PollOnce(context.Background())
+
+	var tokens int64
+	var cost float64
+	if err := db.QueryRow(`SELECT tokens, cost_usd FROM costs WHERE id = ?`, "google|gemini-1.5-pro|2026-02-16").Scan(&tokens, &cost); err != nil {
+		t.Fatalf("query google bucket: %v", err)
+	}
+	if tokens != 150 || cost != 3.0 {
+		t.Fatalf("unexpected google bucket tokens=%d cost=%f", tokens, cost)
+	}
+	if len(agg.DegradedProviders()) != 0 {
+		t.Fatalf("expected no degraded providers, got %v", agg.DegradedProviders())
+	}
+}
+
+func TestCostAggregatorGoogleDegraded(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	cfg := config.CostConfig{
+		MaxRetries:    1,
+		BackoffBaseMS: 1,
+		Providers: config.CostProviders{
+			Google: config.CostProviderConfig{ExportDir: filepath.Join(t.TempDir(), "missing")},
+		},
+	}
+	agg := NewCostAggregator(cfg, db, tracker, zap.NewNop())
+	agg.PollOnce(context.Background())
+
+	if !slices.Contains(agg.DegradedProviders(), providerGoogle) {
+		t.Fatalf("expected google degraded for missing export dir, got %v", agg.DegradedProviders())
+	}
+}
+
+func TestCostAggregatorGoogleAPIStandIn(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"data": [{"service": {"description": "Vertex AI"}, "sku": {"id": "A", "description": "Gemini 2.0 Flash Input Tokens"}, "usage_start_time": "2026-02-16T00:00:00Z", "cost": 0.5, "usage": {"amount": 10}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.CostConfig{
+		MaxRetries:    1,
+		BackoffBaseMS: 1,
+		Providers: config.CostProviders{
+			Google: config.CostProviderConfig{APIKey: "gcp-token", BaseURL: server.URL},
+		},
+	}
+	agg := NewCostAggregator(cfg, db, tracker, zap.NewNop())
+	agg.PollOnce(context.Background())
+
+	if gotAuth != "Bearer gcp-token" {
+		t.Fatalf("expected bearer auth, got %q", gotAuth)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM costs WHERE provider = ? AND model = ?`, providerGoogle, "gemini-2.0-flash").Scan(&count); err != nil {
+		t.Fatalf("count google rows: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected google row from API stand-in, got %d", count)
+	}
+}
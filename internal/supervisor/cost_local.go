@@ -0,0 +1,136 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// localPricingCostProvider prices agent-reported session tokens with a local
+// rate table. It is meant for self-hosted models that have no billing API.
+type localPricingCostProvider struct {
+	name       string
+	cfg        config.CostProviderConfig
+	dailyUsage func(start, end time.Time) ([]SessionDayUsage, error)
+	rateAt     func(provider, model string, at time.Time) (config.ModelRate, bool)
+}
+
+type localPricingFile struct {
+	ModelRates map[string]config.ModelRate `json:"model_rates"`
+}
+
+func newLocalPricingCostProvider(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error) {
+	if deps.DailyUsage == nil {
+		return nil, fmt.Errorf("local pricing provider requires session usage data")
+	}
+	return &localPricingCostProvider{
+		name:       name,
+		cfg:        cfg,
+		dailyUsage: deps.DailyUsage,
+		rateAt:     deps.RateAt,
+	}, nil
+}
+
+func (p *localPricingCostProvider) Name() string {
+	return p.name
+}
+
+func (p *localPricingCostProvider) Health(ctx context.Context) error {
+	_ = ctx
+	_, err := p.rates()
+	return err
+}
+
+func (p *localPricingCostProvider) ModelRate(model string) (config.ModelRate, bool) {
+	rates, err := p.rates()
+	if err != nil {
+		return config.ModelRate{}, false
+	}
+	rate, ok := rates[model]
+	return rate, ok
+}
+
+// FetchUsage buckets the usage sessions reported inside the window by model
+// and day and prices each token class with its own rate via priceTokenUsage.
+// A catalog rate in effect on the day the usage was reported wins over the
+// local rate table.
+func (p *localPricingCostProvider) FetchUsage(ctx context.Context, start, end time.Time) ([]UsageRecord, error) {
+	_ = ctx
+	rates, err := p.rates()
+	if err != nil {
+		return nil, err
+	}
+	days, err := p.dailyUsage(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("read session usage: %w", err)
+	}
+
+	buckets := map[string]UsageRecord{}
+	for _, day := range days {
+		rate, ok := rates[day.Model]
+		if p.rateAt != nil {
+			if at, err := time.Parse("2006-01-02", day.Date); err == nil {
+				if catalogRate, found := p.rateAt(p.name, day.Model, at); found {
+					rate, ok = catalogRate, true
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		usage := day.Usage
+
+		key := day.Model + "|" + day.Date
+		bucket := buckets[key]
+		bucket.Provider = p.name
+		bucket.Model = day.Model
+		bucket.Date = day.Date
+		bucket.Tokens += int64(usage.Total)
+		bucket.InputTokens += int64(usage.Prompt)
+		bucket.OutputTokens += int64(usage.Completion)
+		bucket.CacheReadTokens += int64(usage.CacheRead)
+		bucket.CacheWriteTokens += int64(usage.CacheWrite)
+		bucket.CostUSD += priceTokenUsage(rate, usage)
+		buckets[key] = bucket
+	}
+
+	rows := make([]UsageRecord, 0, len(buckets))
+	for _, bucket := range buckets {
+		rows = append(rows, bucket)
+	}
+	return rows, nil
+}
+
+// rates merges the pricing file with inline model_rates; inline entries win.
+// The file is re-read on every call so edits apply without a restart.
+func (p *localPricingCostProvider) rates() (map[string]config.ModelRate, error) {
+	rates := map[string]config.ModelRate{}
+
+	if p.cfg.PricingFile != "" {
+		data, err := os.ReadFile(p.cfg.PricingFile)
+		if err != nil {
+			return nil, fmt.Errorf("read pricing file: %w", err)
+		}
+		var file localPricingFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("decode pricing file: %w", err)
+		}
+		for model, rate := range file.ModelRates {
+			rates[model] = rate
+		}
+	}
+
+	for model, rate := range p.cfg.ModelRates {
+		rates[model] = rate
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no model rates configured")
+	}
+	return rates, nil
+}
@@ -0,0 +1,68 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// newOpenRouterCostProvider polls an OpenRouter-style aggregator whose
+// activity endpoint reports per-model daily spend across upstream vendors.
+// Other aggregators with the same response shape can be used via base_url.
+func newOpenRouterCostProvider(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error) {
+	return &httpCostProvider{
+		name:   name,
+		cfg:    cfg,
+		client: deps.HTTPClient,
+		endpoint: func(start, end time.Time) (string, url.Values) {
+			_ = end
+			query := url.Values{}
+			query.Set("date", start.Format("2006-01-02"))
+			return "https://openrouter.ai/api/v1/activity", query
+		},
+		parse: func(body []byte) ([]UsageRecord, error) {
+			return parseOpenRouterUsage(name, body)
+		},
+	}, nil
+}
+
+func parseOpenRouterUsage(provider string, body []byte) ([]UsageRecord, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode openrouter response: %w", err)
+	}
+
+	data, ok := raw["data"]
+	if !ok {
+		return nil, nil
+	}
+
+	var usage []map[string]interface{}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("decode openrouter data: %w", err)
+	}
+
+	rows := make([]UsageRecord, 0, len(usage))
+	for _, item := range usage {
+		cached := asInt64(item["cached_tokens"], item["native_tokens_cached"])
+		input := asInt64(item["prompt_tokens"]) - cached
+		if input < 0 {
+			input = 0
+		}
+		rows = append(rows, withSplitTotal(UsageRecord{
+			Provider:        provider,
+			Model:           asString(item["model"], item["model_permaslug"]),
+			Date:            normalizeDate(asString(item["date"]), asString(item["created_at"])),
+			Tokens:          asInt64(item["total_tokens"], item["tokens"]),
+			InputTokens:     input,
+			OutputTokens:    asInt64(item["completion_tokens"]) + asInt64(item["reasoning_tokens"]),
+			CacheReadTokens: cached,
+			CostUSD:         asFloat64(item["usage"], item["cost_usd"], item["cost"]),
+		}))
+	}
+
+	return rows, nil
+}
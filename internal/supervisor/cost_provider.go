@@ -0,0 +1,443 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	providerAnthropic  = "anthropic"
+	providerOpenAI     = "openai"
+	providerOpenRouter = "openrouter"
+	providerLocal      = "local"
+)
+
+// UsageRecord is one provider/model/day usage bucket reported by a provider.
+// Tokens is the total; providers that only report totals leave the split
+// zero, and providers that only report the split get Tokens filled from it.
+type UsageRecord struct {
+	Provider         string
+	Model            string
+	Project          string
+	Date             string
+	Tokens           int64
+	InputTokens      int64
+	OutputTokens     int64
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+	CostUSD          float64
+}
+
+func (r UsageRecord) splitTokens() int64 {
+	return r.InputTokens + r.OutputTokens + r.CacheReadTokens + r.CacheWriteTokens
+}
+
+// CostProvider is a source of billed usage. The aggregator polls every enabled
+// provider, persists the returned daily buckets and falls back to session
+// estimates priced with ModelRate when a provider is unhealthy.
+type CostProvider interface {
+	// Name identifies the provider in cost buckets and degraded reports.
+	Name() string
+	// FetchUsage returns usage buckets for the window [start, end).
+	FetchUsage(ctx context.Context, start, end time.Time) ([]UsageRecord, error)
+	// ModelRate returns the per-1K-token rate for a model this provider bills.
+	ModelRate(model string) (config.ModelRate, bool)
+	// Health reports whether the provider is configured well enough to poll.
+	Health(ctx context.Context) error
+}
+
+// CostProviderDeps carries the shared dependencies handed to provider
+// factories.
+type CostProviderDeps struct {
+	HTTPClient *http.Client
+	Sessions   func() []TrackedSession
+	// DailyUsage returns the usage sessions reported on each UTC day from
+	// start up to end.
+	DailyUsage func(start, end time.Time) ([]SessionDayUsage, error)
+	// RateAt resolves a model's rate on a given date from the pricing
+	// catalog. It reports false when the catalog has no rate in effect.
+	RateAt func(provider, model string, at time.Time) (config.ModelRate, bool)
+	Logger *zap.Logger
+}
+
+// CostProviderFactory builds a provider instance named name from its config.
+type CostProviderFactory func(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error)
+
+var (
+	costProviderRegistryMu sync.RWMutex
+	costProviderRegistry   = map[string]CostProviderFactory{
+		providerAnthropic:  newAnthropicCostProvider,
+		providerOpenAI:     newOpenAICostProvider,
+		providerGoogle:     newGoogleCostProvider,
+		providerOpenRouter: newOpenRouterCostProvider,
+		providerLocal:      newLocalPricingCostProvider,
+	}
+)
+
+// RegisterCostProvider makes a provider type available to the cost config
+// under kind. Registering a kind twice is an error.
+func RegisterCostProvider(kind string, factory CostProviderFactory) error {
+	kind = strings.TrimSpace(kind)
+	if kind == "" {
+		return fmt.Errorf("cost provider kind is required")
+	}
+	if factory == nil {
+		return fmt.Errorf("cost provider factory is required")
+	}
+
+	costProviderRegistryMu.Lock()
+	defer costProviderRegistryMu.Unlock()
+
+	if _, exists := costProviderRegistry[kind]; exists {
+		return fmt.Errorf("cost provider %q already registered", kind)
+	}
+	costProviderRegistry[kind] = factory
+	return nil
+}
+
+// RegisteredCostProviders returns the sorted list of known provider kinds.
+func RegisteredCostProviders() []string {
+	costProviderRegistryMu.RLock()
+	defer costProviderRegistryMu.RUnlock()
+
+	kinds := make([]string, 0, len(costProviderRegistry))
+	for kind := range costProviderRegistry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func lookupCostProvider(kind string) (CostProviderFactory, bool) {
+	costProviderRegistryMu.RLock()
+	defer costProviderRegistryMu.RUnlock()
+
+	factory, ok := costProviderRegistry[kind]
+	return factory, ok
+}
+
+type configuredCostProvider struct {
+	name string
+	kind string
+	cfg  config.CostProviderConfig
+}
+
+// configuredCostProviders lists the enabled providers in a stable order:
+// built-ins first, then custom entries sorted by name. The order decides
+// which provider claims a model present in more than one rate table.
+func configuredCostProviders(providers config.CostProviders) []configuredCostProvider {
+	builtins := []configuredCostProvider{
+		{name: providerAnthropic, kind: providerAnthropic, cfg: providers.Anthropic},
+		{name: providerOpenAI, kind: providerOpenAI, cfg: providers.OpenAI},
+		{name: providerGoogle, kind: providerGoogle, cfg: providers.Google},
+		{name: providerOpenRouter, kind: providerOpenRouter, cfg: providers.OpenRouter},
+		{name: providerLocal, kind: providerLocal, cfg: providers.Local},
+	}
+
+	out := make([]configuredCostProvider, 0, len(builtins)+len(providers.Custom))
+	for _, entry := range builtins {
+		if entry.cfg.IsEnabled() {
+			out = append(out, entry)
+		}
+	}
+
+	names := make([]string, 0, len(providers.Custom))
+	for name := range providers.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg := providers.Custom[name]
+		if !cfg.IsEnabled() {
+			continue
+		}
+		out = append(out, configuredCostProvider{name: name, kind: cfg.Type, cfg: cfg})
+	}
+
+	return out
+}
+
+// retryableError marks a fetch failure that is worth retrying with backoff.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+func isRetryable(err error) bool {
+	var target *retryableError
+	return errors.As(err, &target)
+}
+
+// httpCostProvider implements CostProvider for usage APIs that answer a single
+// authenticated GET with a JSON body.
+type httpCostProvider struct {
+	name   string
+	cfg    config.CostProviderConfig
+	client *http.Client
+
+	endpoint  func(start, end time.Time) (string, url.Values)
+	authorize func(req *http.Request, apiKey string)
+	parse     func(body []byte) ([]UsageRecord, error)
+}
+
+func (p *httpCostProvider) Name() string {
+	return p.name
+}
+
+func (p *httpCostProvider) ModelRate(model string) (config.ModelRate, bool) {
+	rate, ok := p.cfg.ModelRates[model]
+	return rate, ok
+}
+
+func (p *httpCostProvider) Health(ctx context.Context) error {
+	_ = ctx
+	if p.cfg.EffectiveAPIKey() == "" {
+		return fmt.Errorf("api key is not configured")
+	}
+	return nil
+}
+
+func (p *httpCostProvider) FetchUsage(ctx context.Context, start, end time.Time) ([]UsageRecord, error) {
+	endpoint := p.cfg.BaseURL
+	var query url.Values
+	if endpoint == "" && p.endpoint != nil {
+		endpoint, query = p.endpoint(start, end)
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("provider endpoint is empty")
+	}
+
+	if len(query) > 0 {
+		if strings.Contains(endpoint, "?") {
+			endpoint += "&" + query.Encode()
+		} else {
+			endpoint += "?" + query.Encode()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if p.authorize != nil {
+		p.authorize(req, p.cfg.EffectiveAPIKey())
+	} else {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.EffectiveAPIKey())
+	}
+
+	client := p.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, retryable(fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		return nil, retryable(fmt.Errorf("read response: %w", readErr))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, retryable(fmt.Errorf("provider returned status %d", resp.StatusCode))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("provider returned status %d", resp.StatusCode)
+	}
+
+	return p.parse(body)
+}
+
+func newAnthropicCostProvider(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error) {
+	return &httpCostProvider{
+		name:   name,
+		cfg:    cfg,
+		client: deps.HTTPClient,
+		endpoint: func(start, end time.Time) (string, url.Values) {
+			query := url.Values{}
+			query.Set("starting_at", start.Format(time.RFC3339))
+			query.Set("ending_at", end.Format(time.RFC3339))
+			query.Set("bucket_width", "1d")
+			query.Add("group_by[]", "model")
+			return "https://api.anthropic.com/v1/organizations/usage_report/messages", query
+		},
+		authorize: func(req *http.Request, apiKey string) {
+			req.Header.Set("x-api-key", apiKey)
+			req.Header.Set("anthropic-version", "2023-06-01")
+		},
+		parse: func(body []byte) ([]UsageRecord, error) {
+			return parseAnthropicUsage(name, body)
+		},
+	}, nil
+}
+
+func newOpenAICostProvider(name string, cfg config.CostProviderConfig, deps CostProviderDeps) (CostProvider, error) {
+	return &httpCostProvider{
+		name:   name,
+		cfg:    cfg,
+		client: deps.HTTPClient,
+		endpoint: func(start, end time.Time) (string, url.Values) {
+			query := url.Values{}
+			query.Set("start_date", start.Format("2006-01-02"))
+			query.Set("end_date", end.Format("2006-01-02"))
+			query.Set("bucket_width", "1d")
+			query.Add("group_by[]", "model")
+			query.Add("group_by[]", "project_id")
+			return "https://api.openai.com/v1/organization/usage/completions", query
+		},
+		parse: func(body []byte) ([]UsageRecord, error) {
+			return parseOpenAIUsage(name, body)
+		},
+	}, nil
+}
+
+func parseAnthropicUsage(provider string, body []byte) ([]UsageRecord, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode anthropic response: %w", err)
+	}
+
+	var rows []UsageRecord
+	if data, ok := raw["usage"]; ok {
+		var usage []struct {
+			Date             string  `json:"date"`
+			Model            string  `json:"model"`
+			Tokens           int64   `json:"tokens"`
+			InputTokens      int64   `json:"input_tokens"`
+			OutputTokens     int64   `json:"output_tokens"`
+			CacheReadTokens  int64   `json:"cache_read_tokens"`
+			CacheWriteTokens int64   `json:"cache_write_tokens"`
+			CostUSD          float64 `json:"cost_usd"`
+		}
+		if err := json.Unmarshal(data, &usage); err != nil {
+			return nil, fmt.Errorf("decode anthropic usage: %w", err)
+		}
+		for _, row := range usage {
+			rows = append(rows, withSplitTotal(UsageRecord{
+				Provider:         provider,
+				Model:            row.Model,
+				Date:             normalizeDate(row.Date),
+				Tokens:           row.Tokens,
+				InputTokens:      row.InputTokens,
+				OutputTokens:     row.OutputTokens,
+				CacheReadTokens:  row.CacheReadTokens,
+				CacheWriteTokens: row.CacheWriteTokens,
+				CostUSD:          row.CostUSD,
+			}))
+		}
+		return rows, nil
+	}
+
+	if data, ok := raw["data"]; ok {
+		var usage []map[string]interface{}
+		if err := json.Unmarshal(data, &usage); err != nil {
+			return nil, fmt.Errorf("decode anthropic data: %w", err)
+		}
+		for _, item := range usage {
+			tokens := asInt64(item["tokens"])
+			if tokens == 0 {
+				tokens = asInt64(item["total_tokens"])
+			}
+			cost := asFloat64(item["cost_usd"])
+			if cost == 0 {
+				cost = asFloat64(item["total_cost"])
+			}
+			cacheWrite := asInt64(item["cache_creation_input_tokens"])
+			if creation, ok := item["cache_creation"].(map[string]interface{}); ok && cacheWrite == 0 {
+				cacheWrite = asInt64(creation["ephemeral_5m_input_tokens"]) + asInt64(creation["ephemeral_1h_input_tokens"])
+			}
+			rows = append(rows, withSplitTotal(UsageRecord{
+				Provider:         provider,
+				Model:            asString(item["model"]),
+				Date:             normalizeDate(asString(item["date"]), asString(item["time"]), asString(item["timestamp"]), asString(item["created_at"])),
+				Tokens:           tokens,
+				InputTokens:      asInt64(item["uncached_input_tokens"], item["input_tokens"]),
+				OutputTokens:     asInt64(item["output_tokens"]),
+				CacheReadTokens:  asInt64(item["cache_read_input_tokens"]),
+				CacheWriteTokens: cacheWrite,
+				CostUSD:          cost,
+			}))
+		}
+	}
+
+	return rows, nil
+}
+
+// withSplitTotal fills Tokens from the split when a provider omits the total.
+func withSplitTotal(row UsageRecord) UsageRecord {
+	if row.Tokens == 0 {
+		row.Tokens = row.splitTokens()
+	}
+	return row
+}
+
+func parseOpenAIUsage(provider string, body []byte) ([]UsageRecord, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode openai response: %w", err)
+	}
+
+	data, ok := raw["data"]
+	if !ok {
+		return nil, nil
+	}
+
+	var usage []map[string]interface{}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("decode openai data: %w", err)
+	}
+
+	rows := make([]UsageRecord, 0, len(usage))
+	for _, item := range usage {
+		model := asString(item["model"])
+		project := asString(item["project"], item["project_id"])
+		tokens := asInt64(item["tokens"])
+		if tokens == 0 {
+			tokens = asInt64(item["total_tokens"])
+		}
+		// OpenAI counts cached prompt tokens inside input_tokens.
+		cached := asInt64(item["input_cached_tokens"])
+		input := asInt64(item["input_tokens"]) - cached
+		if input < 0 {
+			input = 0
+		}
+		cost := asFloat64(item["cost_usd"], item["cost"])
+		rows = append(rows, withSplitTotal(UsageRecord{
+			Provider:        provider,
+			Model:           model,
+			Project:         project,
+			Date:            normalizeDate(asString(item["date"]), asString(item["time"]), asString(item["timestamp"]), asString(item["start_time"])),
+			Tokens:          tokens,
+			InputTokens:     input,
+			OutputTokens:    asInt64(item["output_tokens"]),
+			CacheReadTokens: cached,
+			CostUSD:         cost,
+		}))
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,224 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+type stubCostProvider struct {
+	name      string
+	rows      []UsageRecord
+	err       error
+	healthErr error
+	rates     map[string]config.ModelRate
+	calls     int
+}
+
+func (p *stubCostProvider) Name() string { return p.name }
+
+func (p *stubCostProvider) FetchUsage(context.Context, time.Time, time.Time) ([]UsageRecord, error) {
+	p.calls++
+	return p.rows, p.err
+}
+
+func (p *stubCostProvider) ModelRate(model string) (config.ModelRate, bool) {
+	rate, ok := p.rates[model]
+	return rate, ok
+}
+
+func (p *stubCostProvider) Health(context.Context) error { return p.healthErr }
+
+func TestRegisterCostProvider(t *testing.T) {
+	if err := RegisterCostProvider(providerAnthropic, newAnthropicCostProvider); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+	if err := RegisterCostProvider("", newAnthropicCostProvider); err == nil {
+		t.Fatal("expected empty kind to fail")
+	}
+
+	kind := "test-registry-provider"
+	stub := &stubCostProvider{rows: []UsageRecord{{Model: "m", Date: "2026-02-16", Tokens: 5, CostUSD: 0.5}}}
+	if err := RegisterCostProvider(kind, func(name string, _ config.CostProviderConfig, _ CostProviderDeps) (CostProvider, error) {
+		stub.name = name
+		for i := range stub.rows {
+			stub.rows[i].Provider = name
+		}
+		return stub, nil
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if !slices.Contains(RegisteredCostProviders(), kind) {
+		t.Fatalf("expected %s in registered providers %v", kind, RegisteredCostProviders())
+	}
+
+	db := setupSupervisorTestDB(t)
+	enabled := true
+	agg := NewCostAggregator(config.CostConfig{
+		Providers: config.CostProviders{
+			Custom: map[string]config.CostProviderConfig{
+				"self-hosted": {Type: kind, Enabled: &enabled},
+				"broken":      {Type: "does-not-exist", Enabled: &enabled},
+			},
+		},
+	}, db, NewSessionTracker(db, zap.NewNop()), zap.NewNop())
+
+	if got := agg.Providers(); len(got) != 1 || got[0] != "self-hosted" {
+		t.Fatalf("unexpected providers %v", got)
+	}
+	if !slices.Contains(agg.DegradedProviders(), "broken") {
+		t.Fatalf("expected unknown provider type to be degraded, got %v", agg.DegradedProviders())
+	}
+
+	agg.PollOnce(context.Background())
+
+	var cost float64
+	if err := db.QueryRow(`SELECT cost_usd FROM costs WHERE id = ?`, "self-hosted|m|2026-02-16").Scan(&cost); err != nil {
+		t.Fatalf("query custom provider bucket: %v", err)
+	}
+	if cost != 0.5 {
+		t.Fatalf("expected cost 0.5, got %f", cost)
+	}
+}
+
+func TestCostAggregatorRetriesOnlyRetryableErrors(t *testing.T) {
+	agg := NewCostAggregator(config.CostConfig{MaxRetries: 2, BackoffBaseMS: 1}, nil, nil, zap.NewNop())
+	agg.sleep = func(time.Duration) {}
+
+	permanent := &stubCostProvider{name: "permanent", err: errors.New("bad request")}
+	agg.AddProvider(permanent)
+	transient := &stubCostProvider{name: "transient", err: retryable(errors.New("provider returned status 503"))}
+	agg.AddProvider(transient)
+	unhealthy := &stubCostProvider{name: "unhealthy", healthErr: errors.New("api key is not configured")}
+	agg.AddProvider(unhealthy)
+
+	agg.PollOnce(context.Background())
+
+	if permanent.calls != 1 {
+		t.Fatalf("expected no retries for permanent error, got %d calls", permanent.calls)
+	}
+	if transient.calls != 3 {
+		t.Fatalf("expected 3 attempts for retryable error, got %d", transient.calls)
+	}
+	if unhealthy.calls != 0 {
+		t.Fatalf("expected unhealthy provider not to be fetched, got %d calls", unhealthy.calls)
+	}
+
+	degraded := agg.DegradedProviders()
+	for _, name := range []string{"permanent", "transient", "unhealthy"} {
+		if !slices.Contains(degraded, name) {
+			t.Fatalf("expected %s degraded, got %v", name, degraded)
+		}
+	}
+}
+
+func TestOpenRouterProvider(t *testing.T) {
+	var gotQuery, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"data": [
+			{"date": "2026-02-16", "model": "anthropic/claude-sonnet-4", "usage": 0.42, "prompt_tokens": 100, "completion_tokens": 40, "reasoning_tokens": 10},
+			{"date": "2026-02-16", "model": "openai/gpt-4.1", "usage": 0.1, "total_tokens": 70}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider, err := newOpenRouterCostProvider(providerOpenRouter, config.CostProviderConfig{APIKey: "or-key"}, CostProviderDeps{HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	httpProvider := provider.(*httpCostProvider)
+	defaultEndpoint := httpProvider.endpoint
+	httpProvider.endpoint = func(start, end time.Time) (string, url.Values) {
+		_, query := defaultEndpoint(start, end)
+		return server.URL, query
+	}
+
+	start := time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)
+	rows, err := provider.FetchUsage(context.Background(), start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if gotAuth != "Bearer or-key" || gotQuery != "date=2026-02-16" {
+		t.Fatalf("unexpected request auth=%q query=%q", gotAuth, gotQuery)
+	}
+	if len(rows) != 2 || rows[0].Tokens != 150 || rows[0].CostUSD != 0.42 || rows[1].Tokens != 70 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if rows[0].Provider != providerOpenRouter || rows[0].Model != "anthropic/claude-sonnet-4" {
+		t.Fatalf("unexpected row identity %+v", rows[0])
+	}
+}
+
+func TestLocalPricingProvider(t *testing.T) {
+	pricing := filepath.Join(t.TempDir(), "pricing.json")
+	if err := os.WriteFile(pricing, []byte(`{"model_rates": {"llama-3-70b": {"input": 0.5, "output": 1.0}, "qwen-32b": {"input": 0.2, "output": 0.2}}}`), 0o644); err != nil {
+		t.Fatalf("write pricing: %v", err)
+	}
+
+	start := time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)
+	days := []SessionDayUsage{
+		{SessionID: "a", Model: "llama-3-70b", Date: "2026-02-16", Usage: TokenUsage{Prompt: 2000, Completion: 1000, Total: 3000}},
+		{SessionID: "b", Model: "llama-3-70b", Date: "2026-02-16", Usage: TokenUsage{Total: 1000}},
+		{SessionID: "c", Model: "qwen-32b", Date: "2026-02-15", Usage: TokenUsage{Prompt: 1000, Completion: 1000, Total: 2000}},
+		{SessionID: "d", Model: "claude-sonnet-4", Date: "2026-02-16", Usage: TokenUsage{Total: 5000}},
+	}
+
+	provider, err := newLocalPricingCostProvider(providerLocal, config.CostProviderConfig{
+		PricingFile: pricing,
+		ModelRates:  map[string]config.ModelRate{"qwen-32b": {Input: 0.3, Output: 0.3}},
+	}, CostProviderDeps{DailyUsage: dailyUsageFrom(days)})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if err := provider.Health(context.Background()); err != nil {
+		t.Fatalf("health: %v", err)
+	}
+	if rate, ok := provider.ModelRate("qwen-32b"); !ok || rate.Input != 0.3 {
+		t.Fatalf("expected inline rate to override pricing file, got %+v", rate)
+	}
+
+	rows, err := provider.FetchUsage(context.Background(), start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected one llama bucket, got %+v", rows)
+	}
+	// a: 2*0.5 + 1*1.0 = 2.0; b: total only, priced at the blended rate: 0.75.
+	if rows[0].Model != "llama-3-70b" || rows[0].Tokens != 4000 || rows[0].CostUSD != 2.75 || rows[0].Date != "2026-02-16" {
+		t.Fatalf("unexpected bucket %+v", rows[0])
+	}
+	if rows[0].InputTokens != 2000 || rows[0].OutputTokens != 1000 {
+		t.Fatalf("expected token split in bucket, got %+v", rows[0])
+	}
+
+	missing, _ := newLocalPricingCostProvider(providerLocal, config.CostProviderConfig{PricingFile: filepath.Join(t.TempDir(), "nope.json")}, CostProviderDeps{DailyUsage: dailyUsageFrom(nil)})
+	if err := missing.Health(context.Background()); err == nil {
+		t.Fatal("expected health error for missing pricing file")
+	}
+}
+
+// dailyUsageFrom serves days as a tracker would, filtered to the window.
+func dailyUsageFrom(days []SessionDayUsage) func(start, end time.Time) ([]SessionDayUsage, error) {
+	return func(start, end time.Time) ([]SessionDayUsage, error) {
+		out := make([]SessionDayUsage, 0)
+		for _, day := range days {
+			if day.Date >= start.Format("2006-01-02") && day.Date < end.Format("2006-01-02") {
+				out = append(out, day)
+			}
+		}
+		return out, nil
+	}
+}
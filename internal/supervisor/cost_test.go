@@ -3,6 +3,7 @@ package supervisor
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"slices"
@@ -237,3 +238,130 @@ func TestCostReport(t *testing.T) {
 		t.Fatalf("unexpected project totals: %+v", report.ByProject)
 	}
 }
+
+func TestCostTokenSplit(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{
+					"date":                    "2026-02-16",
+					"model":                   "claude-sonnet-4",
+					"uncached_input_tokens":   1000,
+					"output_tokens":           500,
+					"cache_read_input_tokens": 4000,
+					"cache_creation":          map[string]interface{}{"ephemeral_5m_input_tokens": 200, "ephemeral_1h_input_tokens": 100},
+					"cost_usd":                0.5,
+				},
+			},
+		})
+	}))
+	defer anthropic.Close()
+
+	openai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"date": "2026-02-16", "model": "gpt-4", "input_tokens": 3000, "input_cached_tokens": 1000, "output_tokens": 700, "cost_usd": 0.2},
+			},
+		})
+	}))
+	defer openai.Close()
+
+	agg := NewCostAggregator(config.CostConfig{
+		RequestTimeoutSec: 3,
+		MaxRetries:        1,
+		BackoffBaseMS:     1,
+		Providers: config.CostProviders{
+			Anthropic: config.CostProviderConfig{APIKey: "sk-ant-admin-test", BaseURL: anthropic.URL},
+			OpenAI:    config.CostProviderConfig{APIKey: "sk-openai-test", BaseURL: openai.URL},
+		},
+	}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+	agg.PollOnce(context.Background())
+
+	report, err := agg.Report("today")
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report.ByModel) != 2 {
+		t.Fatalf("expected 2 model rows, got %+v", report.ByModel)
+	}
+
+	claude := report.ByModel[0]
+	if claude.Tokens != 5800 || claude.InputTokens != 1000 || claude.OutputTokens != 500 ||
+		claude.CacheReadTokens != 4000 || claude.CacheWriteTokens != 300 {
+		t.Fatalf("unexpected anthropic split %+v", claude)
+	}
+	gpt := report.ByModel[1]
+	if gpt.Tokens != 3700 || gpt.InputTokens != 2000 || gpt.CacheReadTokens != 1000 || gpt.OutputTokens != 700 {
+		t.Fatalf("unexpected openai split %+v", gpt)
+	}
+	if report.InputTokens != 3000 || report.OutputTokens != 1200 || report.CacheReadTokens != 5000 || report.CacheWriteTokens != 300 {
+		t.Fatalf("unexpected report split %+v", report.TokenBreakdown)
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("marshal report: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	if decoded["cache_read_tokens"] != float64(5000) {
+		t.Fatalf("expected cache_read_tokens in report json, got %v", decoded["cache_read_tokens"])
+	}
+}
+
+func TestCostFallbackPricesTokenClassesSeparately(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{
+		SessionID: "s-1",
+		NodeID:    "n-1",
+		Project:   "proj-a",
+		Model:     "claude-sonnet-4",
+		TokenUsage: TokenUsage{
+			Prompt:     1000,
+			Completion: 1000,
+			CacheRead:  10000,
+			CacheWrite: 1000,
+			Total:      13000,
+		},
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{
+		Providers: config.CostProviders{
+			Anthropic: config.CostProviderConfig{
+				APIKey: "sk-ant-admin-test",
+				ModelRates: map[string]config.ModelRate{
+					"claude-sonnet-4": {Input: 0.003, Output: 0.015, CacheRead: 0.0003, CacheWrite: 0.00375},
+				},
+			},
+		},
+	}, db, tracker, zap.NewNop())
+
+	provider, ok := agg.providerForModel("claude-sonnet-4")
+	if !ok {
+		t.Fatal("expected anthropic to own claude-sonnet-4")
+	}
+	agg.applySessionEstimateFallback(provider)
+
+	session, err := tracker.GetSession("s-1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	// 1K input * 0.003 + 1K output * 0.015 + 10K cache reads * 0.0003 + 1K cache writes * 0.00375.
+	if math.Abs(session.SessionCost-0.02475) > 1e-9 {
+		t.Fatalf("expected split-priced estimate 0.02475, got %f", session.SessionCost)
+	}
+}
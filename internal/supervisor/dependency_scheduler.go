@@ -0,0 +1,363 @@
+package supervisor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+const defaultDependencyPromptFormat = "Dependencies %s have completed a milestone. Read .context/PROGRESS.md and CURRENT_TASK.md, then start the next task."
+
+// ProjectMilestone records a milestone completed by a project.
+type ProjectMilestone struct {
+	Project     string    `json:"project"`
+	Milestone   string    `json:"milestone,omitempty"`
+	SessionID   string    `json:"session_id,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// DependencyStatus describes one project's position in the dependency graph
+// and whether it is ready to be scheduled.
+type DependencyStatus struct {
+	Project        string            `json:"project"`
+	DependsOn      []string          `json:"depends_on"`
+	Dependents     []string          `json:"dependents"`
+	WaitingOn      []string          `json:"waiting_on"`
+	Ready          bool              `json:"ready"`
+	AutoStart      bool              `json:"auto_start"`
+	ActiveSessions int               `json:"active_sessions"`
+	LastMilestone  *ProjectMilestone `json:"last_milestone,omitempty"`
+	TriggeredAt    *time.Time        `json:"triggered_at,omitempty"`
+}
+
+// DependencyScheduler starts sessions for dependent projects once every
+// project they depend on has completed a milestone since the dependent was
+// last started. A project is never started while it already has a running
+// or idle session.
+type DependencyScheduler struct {
+	graph      *DependencyGraph
+	deps       map[string]config.ProjectDependency
+	tracker    *SessionTracker
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	}
+	db     *sql.DB
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu         sync.Mutex
+	milestones map[string]ProjectMilestone
+	triggered  map[string]time.Time
+}
+
+func NewDependencyScheduler(
+	graph *DependencyGraph,
+	deps map[string]config.ProjectDependency,
+	tracker *SessionTracker,
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	},
+	db *sql.DB,
+	logger *zap.Logger,
+) *DependencyScheduler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DependencyScheduler{
+		graph:      graph,
+		deps:       deps,
+		tracker:    tracker,
+		dispatcher: dispatcher,
+		db:         db,
+		logger:     logger,
+		now:        func() time.Time { return time.Now().UTC() },
+		milestones: make(map[string]ProjectMilestone),
+		triggered:  make(map[string]time.Time),
+	}
+}
+
+// LoadMilestones restores the latest milestone of each project, and when
+// each dependent was last started, from the database.
+func (s *DependencyScheduler) LoadMilestones() error {
+	if s.db == nil {
+		return nil
+	}
+
+	rows, err := s.db.Query(`SELECT project, milestone, COALESCE(session_id, ''), completed_at FROM project_milestones`)
+	if err != nil {
+		return fmt.Errorf("query project milestones: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for rows.Next() {
+		var m ProjectMilestone
+		if err := rows.Scan(&m.Project, &m.Milestone, &m.SessionID, &m.CompletedAt); err != nil {
+			return fmt.Errorf("scan project milestone: %w", err)
+		}
+		s.rememberLocked(m)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	triggers, err := s.db.Query(`SELECT project, triggered_at FROM dependency_triggers`)
+	if err != nil {
+		return fmt.Errorf("query dependency triggers: %w", err)
+	}
+	defer triggers.Close()
+	for triggers.Next() {
+		var project string
+		var at time.Time
+		if err := triggers.Scan(&project, &at); err != nil {
+			return fmt.Errorf("scan dependency trigger: %w", err)
+		}
+		s.triggered[project] = at
+	}
+	return triggers.Err()
+}
+
+// HandleEvent records milestone events carried by an agent event payload.
+// Dependents are dispatched asynchronously because the caller is the agent's
+// read loop, which must stay free to receive command results.
+func (s *DependencyScheduler) HandleEvent(payload []byte) {
+	milestone, ok := s.parseMilestoneEvent(payload)
+	if !ok {
+		return
+	}
+
+	go func() {
+		if _, err := s.RecordMilestone(milestone); err != nil {
+			s.logger.Warn("milestone ingest failed",
+				zap.String("project", milestone.Project),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+func (s *DependencyScheduler) parseMilestoneEvent(payload []byte) (ProjectMilestone, bool) {
+	event, ok := parseProgressEvent(payload)
+	if !ok || event.Type != EventTypeMilestoneReached {
+		return ProjectMilestone{}, false
+	}
+
+	milestone := ProjectMilestone{
+		Project:   event.Project,
+		Milestone: event.Milestone,
+		SessionID: event.SessionID,
+	}
+	if milestone.Project == "" && milestone.SessionID != "" && s.tracker != nil {
+		if session, err := s.tracker.GetSession(milestone.SessionID); err == nil {
+			milestone.Project = session.Project
+		}
+	}
+	return milestone, milestone.Project != ""
+}
+
+// RecordMilestone stores a completed milestone and starts every dependent
+// that became ready. It returns the projects a session was started for.
+func (s *DependencyScheduler) RecordMilestone(m ProjectMilestone) ([]string, error) {
+	if m.Project == "" {
+		return nil, fmt.Errorf("project name cannot be empty")
+	}
+	if m.CompletedAt.IsZero() {
+		m.CompletedAt = s.now()
+	}
+
+	if s.db != nil {
+		_, err := s.db.Exec(`
+			INSERT INTO project_milestones (project, milestone, session_id, completed_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(project, milestone) DO UPDATE SET
+				session_id = excluded.session_id,
+				completed_at = excluded.completed_at
+		`, m.Project, m.Milestone, m.SessionID, m.CompletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store project milestone: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.rememberLocked(m)
+	s.mu.Unlock()
+
+	s.logger.Info("project milestone completed",
+		zap.String("project", m.Project),
+		zap.String("milestone", m.Milestone),
+	)
+
+	started := make([]string, 0)
+	for _, dependent := range s.graph.GetDependents(m.Project) {
+		ok, err := s.startIfReady(dependent)
+		if err != nil {
+			s.logger.Warn("dependent project start failed",
+				zap.String("project", dependent),
+				zap.String("completed", m.Project),
+				zap.Error(err),
+			)
+			continue
+		}
+		if ok {
+			started = append(started, dependent)
+		}
+	}
+	sort.Strings(started)
+	return started, nil
+}
+
+func (s *DependencyScheduler) rememberLocked(m ProjectMilestone) {
+	if latest, ok := s.milestones[m.Project]; ok && latest.CompletedAt.After(m.CompletedAt) {
+		return
+	}
+	s.milestones[m.Project] = m
+}
+
+func (s *DependencyScheduler) startIfReady(project string) (bool, error) {
+	dep := s.deps[project]
+	if !dep.AutoStartEnabled() || s.activeSessions(project) > 0 {
+		return false, nil
+	}
+
+	// Reserve the project before dispatching so that concurrent milestones
+	// cannot start it twice; the reservation is dropped if the start fails.
+	s.mu.Lock()
+	if !s.readyLocked(project) {
+		s.mu.Unlock()
+		return false, nil
+	}
+	previous, hadPrevious := s.triggered[project]
+	at := s.now()
+	s.triggered[project] = at
+	s.mu.Unlock()
+
+	if err := s.dispatchStart(project, dep); err != nil {
+		s.mu.Lock()
+		if hadPrevious {
+			s.triggered[project] = previous
+		} else {
+			delete(s.triggered, project)
+		}
+		s.mu.Unlock()
+		return false, err
+	}
+
+	if s.db != nil {
+		_, err := s.db.Exec(`
+			INSERT INTO dependency_triggers (project, triggered_at) VALUES (?, ?)
+			ON CONFLICT(project) DO UPDATE SET triggered_at = excluded.triggered_at
+		`, project, at)
+		if err != nil {
+			// The session is already starting; a restart may start it again.
+			s.logger.Warn("store dependency trigger failed",
+				zap.String("project", project),
+				zap.Error(err),
+			)
+		}
+	}
+
+	s.logger.Info("dependent project started", zap.String("project", project))
+	return true, nil
+}
+
+func (s *DependencyScheduler) dispatchStart(project string, dep config.ProjectDependency) error {
+	if s.dispatcher == nil {
+		return fmt.Errorf("command dispatcher is not configured")
+	}
+
+	prompt := dep.Prompt
+	if prompt == "" {
+		prompt = fmt.Sprintf(defaultDependencyPromptFormat, strings.Join(s.graph.GetDependencies(project), ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeout)
+	defer cancel()
+
+	result, err := s.dispatcher.DispatchCommand(ctx, Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: project},
+		Args:   map[string]interface{}{"prompt": prompt},
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil && result.Status != CommandStatusSuccess {
+		return fmt.Errorf("create_session %s: %s", result.Status, result.Error)
+	}
+	return nil
+}
+
+// readyLocked reports whether every dependency of project has completed a
+// milestone since project was last started.
+func (s *DependencyScheduler) readyLocked(project string) bool {
+	return s.graph.readyWhen(project, func(dep string) bool {
+		return s.completedSinceLocked(dep, project)
+	})
+}
+
+// completedSinceLocked reports whether dep has a milestone newer than the
+// last start of project, or any milestone if project was never started.
+func (s *DependencyScheduler) completedSinceLocked(dep, project string) bool {
+	m, ok := s.milestones[dep]
+	if !ok {
+		return false
+	}
+	triggeredAt, triggered := s.triggered[project]
+	return !triggered || m.CompletedAt.After(triggeredAt)
+}
+
+func (s *DependencyScheduler) activeSessions(project string) int {
+	if s.tracker == nil {
+		return 0
+	}
+	count := 0
+	for _, session := range s.tracker.GetSessionsByProject(project) {
+		if session.Status.Active() {
+			count++
+		}
+	}
+	return count
+}
+
+// Status returns every project in the graph with its readiness, sorted by
+// project name.
+func (s *DependencyScheduler) Status() []DependencyStatus {
+	projects := s.graph.Projects()
+	out := make([]DependencyStatus, 0, len(projects))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, project := range projects {
+		status := DependencyStatus{
+			Project:        project,
+			DependsOn:      s.graph.GetDependencies(project),
+			Dependents:     s.graph.GetDependents(project),
+			WaitingOn:      make([]string, 0),
+			AutoStart:      s.deps[project].AutoStartEnabled(),
+			ActiveSessions: s.activeSessions(project),
+		}
+		sort.Strings(status.Dependents)
+		for _, dep := range status.DependsOn {
+			if !s.completedSinceLocked(dep, project) {
+				status.WaitingOn = append(status.WaitingOn, dep)
+			}
+		}
+		status.Ready = len(status.WaitingOn) == 0
+		if m, ok := s.milestones[project]; ok {
+			status.LastMilestone = &m
+		}
+		if at, ok := s.triggered[project]; ok {
+			status.TriggeredAt = &at
+		}
+		out = append(out, status)
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func testDependencyConfig() map[string]config.ProjectDependency {
+	manual := false
+	return map[string]config.ProjectDependency{
+		"ai-os-l0":  {DependsOn: []string{"ai-os-interfaces"}, Prompt: "Implement L0"},
+		"ai-os-l1":  {DependsOn: []string{"ai-os-interfaces"}, AutoStart: &manual},
+		"ai-os-rom": {DependsOn: []string{"ai-os-l0", "ai-os-l1"}},
+	}
+}
+
+func newTestDependencyScheduler(t *testing.T, tracker *SessionTracker, dispatcher *policyTestDispatcher) *DependencyScheduler {
+	t.Helper()
+	deps := testDependencyConfig()
+	graph, err := NewDependencyGraph(deps)
+	if err != nil {
+		t.Fatalf("NewDependencyGraph: %v", err)
+	}
+	if tracker == nil {
+		return NewDependencyScheduler(graph, deps, nil, dispatcher, setupSupervisorTestDB(t), zap.NewNop())
+	}
+	return NewDependencyScheduler(graph, deps, tracker, dispatcher, tracker.db, zap.NewNop())
+}
+
+func TestDependencySchedulerStartsReadyDependents(t *testing.T) {
+	dispatcher := &policyTestDispatcher{}
+	scheduler := newTestDependencyScheduler(t, nil, dispatcher)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	scheduler.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Minute)
+	}
+
+	started, err := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces", Milestone: "v1"})
+	if err != nil {
+		t.Fatalf("RecordMilestone: %v", err)
+	}
+	// ai-os-l1 is ready too but has auto_start disabled.
+	if len(started) != 1 || started[0] != "ai-os-l0" {
+		t.Fatalf("expected only ai-os-l0 started, got %v", started)
+	}
+	if dispatcher.callCount() != 1 {
+		t.Fatalf("expected 1 dispatch, got %d", dispatcher.callCount())
+	}
+	cmd := dispatcher.calls[0]
+	if cmd.Type != CommandTypeCreateSession || cmd.Target.Project != "ai-os-l0" || cmd.Args["prompt"] != "Implement L0" {
+		t.Fatalf("unexpected command %+v", cmd)
+	}
+
+	// A milestone completed before ai-os-l0 was started must not start it
+	// again; one completed after it does.
+	if started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces", Milestone: "v1", CompletedAt: base}); len(started) != 0 {
+		t.Fatalf("expected no restarts, got %v", started)
+	}
+	if started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces", Milestone: "v2"}); len(started) != 1 || started[0] != "ai-os-l0" {
+		t.Fatalf("expected ai-os-l0 started for the new milestone, got %v", started)
+	}
+
+	if started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-l0"}); len(started) != 0 {
+		t.Fatalf("ai-os-rom should wait for ai-os-l1, got %v", started)
+	}
+	started, _ = scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-l1"})
+	if len(started) != 1 || started[0] != "ai-os-rom" {
+		t.Fatalf("expected ai-os-rom started, got %v", started)
+	}
+	prompt, _ := dispatcher.calls[2].Args["prompt"].(string)
+	if prompt != "Dependencies ai-os-l0, ai-os-l1 have completed a milestone. Read .context/PROGRESS.md and CURRENT_TASK.md, then start the next task." {
+		t.Fatalf("unexpected default prompt %q", prompt)
+	}
+}
+
+func TestDependencySchedulerSkipsActiveAndRetriesFailures(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES ('n-1', 'n-1', 'online', ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "ai-os-l0", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	dispatcher := &policyTestDispatcher{results: []*CommandResult{{Status: CommandStatusFailure, Error: "no node"}}}
+	scheduler := newTestDependencyScheduler(t, tracker, dispatcher)
+
+	if started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces"}); len(started) != 0 || dispatcher.callCount() != 0 {
+		t.Fatalf("expected no start while ai-os-l0 is running, got %v", started)
+	}
+
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusError)}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	if started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces", Milestone: "v2"}); len(started) != 0 {
+		t.Fatalf("expected failed dispatch, got %v", started)
+	}
+	started, _ := scheduler.RecordMilestone(ProjectMilestone{Project: "ai-os-interfaces", Milestone: "v3"})
+	if len(started) != 1 || dispatcher.callCount() != 2 {
+		t.Fatalf("expected retry after failure, got %v with %d calls", started, dispatcher.callCount())
+	}
+}
+
+func TestDependencySchedulerMilestoneEventsAndReload(t *testing.T) {
+	dispatcher := &policyTestDispatcher{}
+	scheduler := newTestDependencyScheduler(t, nil, dispatcher)
+
+	scheduler.HandleEvent([]byte(`{"type":"session.idle","payload":{"project":"ai-os-interfaces"}}`))
+	scheduler.HandleEvent([]byte(`{"type":"milestone.reached","session_id":"s-9","payload":{"project":"ai-os-interfaces","milestone":"Phase 1"}}`))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for dispatcher.callCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if dispatcher.callCount() != 1 {
+		t.Fatalf("expected milestone event to start ai-os-l0, got %d calls", dispatcher.callCount())
+	}
+
+	reloaded := NewDependencyScheduler(scheduler.graph, scheduler.deps, nil, dispatcher, scheduler.db, zap.NewNop())
+	if err := reloaded.LoadMilestones(); err != nil {
+		t.Fatalf("LoadMilestones: %v", err)
+	}
+	statuses := map[string]DependencyStatus{}
+	for _, status := range reloaded.Status() {
+		statuses[status.Project] = status
+	}
+	if len(statuses) != 4 {
+		t.Fatalf("expected 4 projects, got %d", len(statuses))
+	}
+	iface := statuses["ai-os-interfaces"]
+	if iface.LastMilestone == nil || iface.LastMilestone.Milestone != "Phase 1" || iface.LastMilestone.SessionID != "s-9" {
+		t.Fatalf("expected persisted milestone, got %+v", iface.LastMilestone)
+	}
+	l0 := statuses["ai-os-l0"]
+	if l0.Ready || l0.TriggeredAt == nil || len(l0.WaitingOn) != 1 || !statuses["ai-os-l1"].Ready || statuses["ai-os-l1"].AutoStart {
+		t.Fatalf("unexpected l0/l1 status %+v %+v", l0, statuses["ai-os-l1"])
+	}
+	rom := statuses["ai-os-rom"]
+	if rom.Ready || len(rom.WaitingOn) != 2 {
+		t.Fatalf("expected ai-os-rom waiting on both layers, got %+v", rom)
+	}
+
+	// The trigger survives the restart, so the same milestone reported
+	// again does not start ai-os-l0 a second time.
+	replay := *iface.LastMilestone
+	if started, err := reloaded.RecordMilestone(replay); err != nil || len(started) != 0 || dispatcher.callCount() != 1 {
+		t.Fatalf("expected no restart after reload, got %v (%v) with %d calls", started, err, dispatcher.callCount())
+	}
+}
+
+func TestHTTPAPIDependencyGraph(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/deps", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without scheduler, got %d", rec.Code)
+	}
+
+	dispatcher := &policyTestDispatcher{}
+	api.SetDependencyScheduler(newTestDependencyScheduler(t, tracker, dispatcher))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/deps/ai-os-interfaces/milestones", `{"milestone":"v1"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recorded struct {
+		Data milestoneResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &recorded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(recorded.Data.Started) != 1 || recorded.Data.Started[0] != "ai-os-l0" || recorded.Data.Milestone.Milestone != "v1" {
+		t.Fatalf("unexpected milestone response %+v", recorded.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/deps/unknown/milestones", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown project, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/deps", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var graph struct {
+		Data []DependencyStatus `json:"data"`
+		Meta apiMeta            `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &graph); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if graph.Meta.Total != 4 || graph.Data[0].Project != "ai-os-interfaces" || graph.Data[1].TriggeredAt == nil {
+		t.Fatalf("unexpected graph %+v", graph)
+	}
+}
@@ -2,12 +2,13 @@ package supervisor
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
 )
 
-type DependencyConfig struct {
-	DependsOn []string `json:"depends_on"`
-}
+type DependencyConfig = config.ProjectDependency
 
 type DependencyGraph struct {
 	graph        map[string][]string
@@ -111,18 +112,34 @@ func (dg *DependencyGraph) GetDependents(project string) []string {
 	return []string{}
 }
 
-func (dg *DependencyGraph) IsReady(project string, tracker *SessionTracker) bool {
-	deps := dg.GetDependencies(project)
-	if len(deps) == 0 {
-		return true
+// Projects returns every project named in the graph, sorted.
+func (dg *DependencyGraph) Projects() []string {
+	projects := make([]string, 0, len(dg.allProjects))
+	for project := range dg.allProjects {
+		projects = append(projects, project)
 	}
+	sort.Strings(projects)
+	return projects
+}
 
-	for _, dep := range deps {
-		if !dg.hasCompletedSession(dep, tracker) {
+// HasProject reports whether project appears in the graph.
+func (dg *DependencyGraph) HasProject(project string) bool {
+	return dg.allProjects[project]
+}
+
+func (dg *DependencyGraph) IsReady(project string, tracker *SessionTracker) bool {
+	return dg.readyWhen(project, func(dep string) bool {
+		return dg.hasCompletedSession(dep, tracker)
+	})
+}
+
+// readyWhen reports whether done holds for every dependency of project.
+func (dg *DependencyGraph) readyWhen(project string, done func(string) bool) bool {
+	for _, dep := range dg.graph[project] {
+		if !done(dep) {
 			return false
 		}
 	}
-
 	return true
 }
 
@@ -133,7 +150,7 @@ func (dg *DependencyGraph) hasCompletedSession(project string, tracker *SessionT
 
 	sessions := tracker.GetSessionsByProject(project)
 	for _, session := range sessions {
-		if session.Status != SessionStatusError && session.Status != SessionStatusUnreachable {
+		if session.Status != SessionStatusError && session.Status != SessionStatusUnreachable && session.Status != SessionStatusKilled {
 			return true
 		}
 	}
@@ -319,8 +319,27 @@ func setupTestDB(t *testing.T) *sql.DB {
 		project TEXT NOT NULL,
 		status TEXT NOT NULL,
 		tokens INTEGER DEFAULT 0,
+		input_tokens INTEGER NOT NULL DEFAULT 0,
+		output_tokens INTEGER NOT NULL DEFAULT 0,
+		cache_read_tokens INTEGER NOT NULL DEFAULT 0,
+		cache_write_tokens INTEGER NOT NULL DEFAULT 0,
 		cost REAL DEFAULT 0,
-		started_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		parent_session_id TEXT NOT NULL DEFAULT '',
+		model TEXT NOT NULL DEFAULT '',
+		current_task TEXT NOT NULL DEFAULT '',
+		compaction_count INTEGER NOT NULL DEFAULT 0,
+		context_tokens INTEGER NOT NULL DEFAULT 0,
+		last_activity DATETIME
+	);
+	CREATE TABLE session_transitions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		from_status TEXT NOT NULL DEFAULT '',
+		to_status TEXT NOT NULL,
+		cause TEXT NOT NULL,
+		detail TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
 	);
 	`
 
@@ -2,8 +2,11 @@ package supervisor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +33,7 @@ type DiscordSession interface {
 	ApplicationCommandDelete(appID string, guildID string, cmdID string, options ...discordgo.RequestOption) error
 	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
 	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, params *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
 	State() *discordgo.State
 }
 
@@ -65,6 +69,10 @@ func (r *realDiscordSession) FollowupMessageCreate(interaction *discordgo.Intera
 	return r.s.FollowupMessageCreate(interaction, wait, params, options...)
 }
 
+func (r *realDiscordSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return r.s.ChannelMessageSendEmbed(channelID, embed, options...)
+}
+
 func (r *realDiscordSession) State() *discordgo.State {
 	return r.s.State
 }
@@ -77,6 +85,12 @@ type DiscordBot struct {
 	dispatcher *CommandDispatcher
 	hub        *Hub
 	tracker    *SessionTracker
+	costs      *CostAggregator
+	tasks      *TaskQueue
+	taskInfo   *TaskTracker
+	perms      *DiscordPermissions
+	audit      *AuditLogger
+	alertsChan string
 
 	mu            sync.Mutex
 	commandIDs    []string
@@ -123,7 +137,7 @@ func NewDiscordBotWithSession(session DiscordSession, guildID string, dispatcher
 	}
 }
 
-// slashCommands returns the 9 slash command definitions.
+// slashCommands returns the 10 slash command definitions.
 func slashCommands() []*discordgo.ApplicationCommand {
 	return []*discordgo.ApplicationCommand{
 		{
@@ -244,6 +258,30 @@ func slashCommands() []*discordgo.ApplicationCommand {
 				},
 			},
 		},
+		{
+			Name:        "queue",
+			Description: "Show the task queue, or queue a task when a prompt is given",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "project",
+					Description: "Project name",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "prompt",
+					Description: "Task prompt to queue",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "priority",
+					Description: "Task priority, higher runs first (default 0)",
+					Required:    false,
+				},
+			},
+		},
 	}
 }
 
@@ -356,6 +394,33 @@ func (b *DiscordBot) handleInteraction(i *discordgo.InteractionCreate) {
 	data := i.ApplicationCommandData()
 	cmdName := data.Name
 
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range data.Options {
+		opts[opt.Name] = opt
+	}
+
+	user, roleIDs := discordUser(i)
+	actor := discordActor(user)
+	if scope := discordCommandScope(cmdName, opts); !b.permitted(user, roleIDs, scope) {
+		b.logger.Warn("discord command not permitted",
+			zap.String("command", cmdName),
+			zap.String("actor", actor),
+			zap.String("scope", string(scope)),
+		)
+		// Refusals are only shown to the caller, since they name the
+		// required scope.
+		if err := b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{notPermittedEmbed(cmdName, scope)},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			},
+		}); err != nil {
+			b.logger.Error("failed to send response", zap.String("command", cmdName), zap.Error(err))
+		}
+		return
+	}
+
 	if err := b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	}); err != nil {
@@ -363,32 +428,29 @@ func (b *DiscordBot) handleInteraction(i *discordgo.InteractionCreate) {
 		return
 	}
 
-	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
-	for _, opt := range data.Options {
-		opts[opt.Name] = opt
-	}
-
 	var embed *discordgo.MessageEmbed
 
 	switch cmdName {
 	case "status":
-		embed = b.handleStatus(opts)
+		embed = b.handleStatus(actor, opts)
 	case "nodes":
 		embed = b.handleNodes()
 	case "logs":
 		embed = b.handleLogs(opts)
 	case "resume":
-		embed = b.handleResume(opts)
+		embed = b.handleResume(actor, opts)
 	case "inject":
-		embed = b.handleInject(opts)
+		embed = b.handleInject(actor, opts)
 	case "restart":
-		embed = b.handleRestart(opts)
+		embed = b.handleRestart(actor, opts)
 	case "kill":
-		embed = b.handleKill(opts)
+		embed = b.handleKill(actor, opts)
 	case "start":
-		embed = b.handleStart(opts)
+		embed = b.handleStart(actor, opts)
 	case "cost":
 		embed = b.handleCost(opts)
+	case "queue":
+		embed = b.handleQueue(opts)
 	default:
 		embed = errorEmbed("Unknown Command", fmt.Sprintf("Command `/%s` is not recognized.", cmdName))
 	}
@@ -400,8 +462,112 @@ func (b *DiscordBot) handleInteraction(i *discordgo.InteractionCreate) {
 	}
 }
 
+// SetPermissions sets the roles Discord users hold. Without permissions
+// every command is refused.
+func (b *DiscordBot) SetPermissions(perms *DiscordPermissions) {
+	b.perms = perms
+}
+
+// SetAuditLogger records dispatched commands with the Discord user as the
+// actor.
+func (b *DiscordBot) SetAuditLogger(audit *AuditLogger) {
+	b.audit = audit
+}
+
+// SetAlertChannel posts budget alerts to the channel with channelID. An
+// empty ID disables alert posts.
+func (b *DiscordBot) SetAlertChannel(channelID string) {
+	b.alertsChan = channelID
+}
+
+// ProcessEvent posts budget.alert and budget.forecast_alert events to the
+// alert channel and ignores every other event.
+func (b *DiscordBot) ProcessEvent(agentID string, event Event) error {
+	if b.alertsChan == "" {
+		return nil
+	}
+	embed, ok := budgetAlertEmbed(event)
+	if !ok {
+		return nil
+	}
+	if _, err := b.session.ChannelMessageSendEmbed(b.alertsChan, embed); err != nil {
+		b.logger.Warn("post discord alert failed",
+			zap.String("event_type", event.Type),
+			zap.Error(err),
+		)
+		return fmt.Errorf("post %s to discord: %w", event.Type, err)
+	}
+	return nil
+}
+
+// budgetAlertEmbed renders a budget event; ok is false for other events.
+func budgetAlertEmbed(event Event) (*discordgo.MessageEmbed, bool) {
+	var title string
+	color := colorTimeout
+	switch event.Type {
+	case "budget.alert":
+		title = "Budget Alert"
+	case "budget.forecast_alert":
+		title = "Budget Forecast Alert"
+	default:
+		return nil, false
+	}
+
+	var payload struct {
+		Scope            string  `json:"scope"`
+		Project          string  `json:"project"`
+		Period           string  `json:"period"`
+		ThresholdPercent float64 `json:"threshold_percent"`
+		SpentUSD         float64 `json:"spent_usd"`
+		ProjectedUSD     float64 `json:"projected_usd"`
+		LimitUSD         float64 `json:"limit_usd"`
+		Exceeded         bool    `json:"exceeded"`
+	}
+	_ = json.Unmarshal(event.Data, &payload)
+
+	budget := payload.Scope
+	if payload.Project != "" {
+		budget = payload.Project
+	}
+	description := fmt.Sprintf("%s %s budget: $%.2f of $%.2f spent", budget, payload.Period, payload.SpentUSD, payload.LimitUSD)
+	if event.Type == "budget.alert" {
+		description += fmt.Sprintf(" (crossed %.0f%%)", payload.ThresholdPercent)
+		if payload.Exceeded {
+			color = colorFailure
+		}
+	} else {
+		description += fmt.Sprintf(", projected $%.2f", payload.ProjectedUSD)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description,
+		Color:       color,
+		Timestamp:   event.Timestamp.UTC().Format(time.RFC3339),
+	}, true
+}
+
+// permitted reports whether the user, through their ID or guild roles, holds
+// a role that carries scope.
+func (b *DiscordBot) permitted(user *discordgo.User, roleIDs []string, scope APIScope) bool {
+	if b.perms == nil || user == nil {
+		return false
+	}
+	return b.perms.Allows(b.perms.Role(user.ID, roleIDs), scope)
+}
+
+// dispatch sends cmd and records it in the audit log under actor.
+func (b *DiscordBot) dispatch(ctx context.Context, actor string, cmd Command) (*CommandResult, error) {
+	start := time.Now()
+	result, err := b.dispatcher.DispatchCommand(ctx, cmd)
+	if b.audit != nil {
+		b.audit.LogCommand(cmd, result, actor, "", time.Since(start))
+	}
+	return result, err
+}
+
 // handleStatus dispatches a session_status command for the given project.
-func (b *DiscordBot) handleStatus(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleStatus(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	projectOpt, ok := opts["project"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `project`")
@@ -411,7 +577,7 @@ func (b *DiscordBot) handleStatus(opts map[string]*discordgo.ApplicationCommandI
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypeSessionStatus,
 		Target: CommandTarget{Project: project},
 	})
@@ -419,7 +585,44 @@ func (b *DiscordBot) handleStatus(opts map[string]*discordgo.ApplicationCommandI
 		return errorEmbed("Status Failed", "Could not retrieve status. Please try again later.")
 	}
 
-	return commandResultEmbed("Status: "+project, result)
+	embed := commandResultEmbed("Status: "+project, result)
+	embed.Fields = append(embed.Fields, b.taskFields(project)...)
+	return embed
+}
+
+// taskFields describes the task the project's active sessions are working on.
+func (b *DiscordBot) taskFields(project string) []*discordgo.MessageEmbedField {
+	if b.taskInfo == nil || b.tracker == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	fields := make([]*discordgo.MessageEmbedField, 0, 2)
+	for _, s := range b.tracker.GetAllSessions() {
+		if s.Project != project || !s.Status.Active() {
+			continue
+		}
+		task, err := b.taskInfo.ForSession(s.SessionID)
+		if err != nil {
+			if !errors.Is(err, ErrTaskNotFound) {
+				b.logger.Warn("task lookup failed", zap.String("session_id", s.SessionID), zap.Error(err))
+			}
+			continue
+		}
+		if seen[task.ID] {
+			continue
+		}
+		seen[task.ID] = true
+		fields = append(fields,
+			&discordgo.MessageEmbedField{Name: "Task", Value: truncateEmbedValue(fmt.Sprintf("%s (%s)", task.Title, task.Outcome), 1024)},
+			&discordgo.MessageEmbedField{Name: "Task Progress", Value: formatTaskProgress(task)},
+		)
+	}
+	return fields
+}
+
+// SetTaskTracker adds the active task and its roll-up to /status.
+func (b *DiscordBot) SetTaskTracker(tasks *TaskTracker) {
+	b.taskInfo = tasks
 }
 
 // handleNodes queries the hub for connected agent count and the registry for node details.
@@ -456,12 +659,19 @@ func (b *DiscordBot) handleNodes() *discordgo.MessageEmbed {
 				lastHeartbeat = node.LastHeartbeat.UTC().Format("2006-01-02 15:04:05")
 			}
 
-			lines = append(lines, fmt.Sprintf("`%s` (%s) - %s - hb: %s",
+			line := fmt.Sprintf("`%s` (%s) - %s - hb: %s",
 				valueOrDash(node.ID),
 				valueOrDash(node.Hostname),
 				node.Status,
 				lastHeartbeat,
-			))
+			)
+			if !node.Schedulable() {
+				line += " - " + string(node.Lifecycle)
+			}
+			if len(node.Labels) > 0 {
+				line += " - " + formatLabels(node.Labels)
+			}
+			lines = append(lines, line)
 		}
 
 		description = strings.Join(lines, "\n")
@@ -516,14 +726,14 @@ func (b *DiscordBot) handleLogs(opts map[string]*discordgo.ApplicationCommandInt
 		Fields: []*discordgo.MessageEmbedField{
 			{Name: "Model", Value: valueOrDash(session.Model), Inline: true},
 			{Name: "Task", Value: valueOrDash(session.CurrentTask), Inline: true},
-			{Name: "Tokens", Value: fmt.Sprintf("%d", session.TokenUsage.Total), Inline: true},
+			{Name: "Tokens", Value: formatContextUsage(session), Inline: true},
 		},
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 }
 
 // handleResume dispatches a prompt_session command targeted by project.
-func (b *DiscordBot) handleResume(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleResume(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	projectOpt, ok := opts["project"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `project`")
@@ -536,7 +746,7 @@ func (b *DiscordBot) handleResume(opts map[string]*discordgo.ApplicationCommandI
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypePromptSession,
 		Target: CommandTarget{Project: projectOpt.StringValue()},
 		Args:   map[string]interface{}{"message": messageOpt.StringValue()},
@@ -549,7 +759,7 @@ func (b *DiscordBot) handleResume(opts map[string]*discordgo.ApplicationCommandI
 }
 
 // handleInject dispatches a prompt_session command targeted by session_id.
-func (b *DiscordBot) handleInject(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleInject(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	sessionOpt, ok := opts["session_id"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `session_id`")
@@ -574,7 +784,7 @@ func (b *DiscordBot) handleInject(opts map[string]*discordgo.ApplicationCommandI
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypePromptSession,
 		Target: target,
 		Args:   map[string]interface{}{"message": messageOpt.StringValue(), "session_id": sessionOpt.StringValue()},
@@ -587,7 +797,7 @@ func (b *DiscordBot) handleInject(opts map[string]*discordgo.ApplicationCommandI
 }
 
 // handleRestart dispatches a restart_session command.
-func (b *DiscordBot) handleRestart(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleRestart(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	sessionOpt, ok := opts["session_id"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `session_id`")
@@ -608,7 +818,7 @@ func (b *DiscordBot) handleRestart(opts map[string]*discordgo.ApplicationCommand
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypeRestartSession,
 		Target: target,
 		Args:   map[string]interface{}{"session_id": sessionOpt.StringValue()},
@@ -621,7 +831,7 @@ func (b *DiscordBot) handleRestart(opts map[string]*discordgo.ApplicationCommand
 }
 
 // handleKill dispatches a kill_session command.
-func (b *DiscordBot) handleKill(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleKill(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	sessionOpt, ok := opts["session_id"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `session_id`")
@@ -642,7 +852,7 @@ func (b *DiscordBot) handleKill(opts map[string]*discordgo.ApplicationCommandInt
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypeKillSession,
 		Target: target,
 		Args:   map[string]interface{}{"session_id": sessionOpt.StringValue()},
@@ -655,7 +865,7 @@ func (b *DiscordBot) handleKill(opts map[string]*discordgo.ApplicationCommandInt
 }
 
 // handleStart dispatches a create_session command.
-func (b *DiscordBot) handleStart(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+func (b *DiscordBot) handleStart(actor string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	projectOpt, ok := opts["project"]
 	if !ok {
 		return validationErrorEmbed("Missing required argument: `project`")
@@ -664,7 +874,7 @@ func (b *DiscordBot) handleStart(opts map[string]*discordgo.ApplicationCommandIn
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := b.dispatcher.DispatchCommand(ctx, Command{
+	result, err := b.dispatch(ctx, actor, Command{
 		Type:   CommandTypeCreateSession,
 		Target: CommandTarget{Project: projectOpt.StringValue()},
 	})
@@ -675,6 +885,11 @@ func (b *DiscordBot) handleStart(opts map[string]*discordgo.ApplicationCommandIn
 	return commandResultEmbed("Start: "+projectOpt.StringValue(), result)
 }
 
+// SetCostAggregator enables spend forecasts in /cost responses.
+func (b *DiscordBot) SetCostAggregator(costs *CostAggregator) {
+	b.costs = costs
+}
+
 // handleCost queries session cost data.
 func (b *DiscordBot) handleCost(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
 	period := "today"
@@ -697,17 +912,149 @@ func (b *DiscordBot) handleCost(opts map[string]*discordgo.ApplicationCommandInt
 		totalTokens += s.TokenUsage.Total
 	}
 
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Total Cost", Value: fmt.Sprintf("$%.2f", totalCost), Inline: true},
+		{Name: "Total Tokens", Value: fmt.Sprintf("%d", totalTokens), Inline: true},
+		{Name: "Sessions", Value: fmt.Sprintf("%d", len(sessions)), Inline: true},
+	}
+
+	if b.costs != nil {
+		if forecast, err := b.costs.Forecast(); err != nil {
+			b.logger.Warn("cost forecast failed", zap.Error(err))
+		} else {
+			fields = append(fields,
+				&discordgo.MessageEmbedField{Name: "Projected (week)", Value: fmt.Sprintf("$%.2f", forecastTotal(forecast.EndOfWeek)), Inline: true},
+				&discordgo.MessageEmbedField{Name: "Projected (month)", Value: fmt.Sprintf("$%.2f", forecastTotal(forecast.EndOfMonth)), Inline: true},
+			)
+		}
+	}
+
 	return &discordgo.MessageEmbed{
 		Title:       "Cost Summary",
 		Description: fmt.Sprintf("Period: **%s**", period),
 		Color:       colorInfo,
-		Fields: []*discordgo.MessageEmbedField{
-			{Name: "Total Cost", Value: fmt.Sprintf("$%.2f", totalCost), Inline: true},
-			{Name: "Total Tokens", Value: fmt.Sprintf("%d", totalTokens), Inline: true},
-			{Name: "Sessions", Value: fmt.Sprintf("%d", len(sessions)), Inline: true},
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Fields:      fields,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// SetTaskQueue enables the /queue command.
+func (b *DiscordBot) SetTaskQueue(queue *TaskQueue) {
+	b.tasks = queue
+}
+
+// maxQueueEmbedTasks caps the tasks listed in a /queue response.
+const maxQueueEmbedTasks = 10
+
+// handleQueue queues a task when a prompt is given, otherwise lists queued
+// tasks, then expired ones, optionally for one project.
+func (b *DiscordBot) handleQueue(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+	if b.tasks == nil {
+		return errorEmbed("Queue Unavailable", "Task queue is not available.")
+	}
+
+	project := ""
+	if projectOpt, ok := opts["project"]; ok {
+		project = projectOpt.StringValue()
+	}
+
+	if promptOpt, ok := opts["prompt"]; ok {
+		if project == "" {
+			return validationErrorEmbed("Missing required argument: `project`")
+		}
+		priority := 0
+		if priorityOpt, ok := opts["priority"]; ok {
+			priority = int(priorityOpt.IntValue())
+		}
+		task, err := b.tasks.Enqueue(QueuedTask{Project: project, Prompt: promptOpt.StringValue(), Priority: priority})
+		if err != nil {
+			return validationErrorEmbed(err.Error())
+		}
+		return &discordgo.MessageEmbed{
+			Title:       "Queued: " + project,
+			Description: task.Prompt,
+			Color:       colorSuccess,
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Task ID", Value: task.ID, Inline: true},
+				{Name: "Priority", Value: strconv.Itoa(task.Priority), Inline: true},
+				{Name: "Position", Value: strconv.Itoa(task.Position), Inline: true},
+			},
+			Timestamp: task.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	tasks, err := b.tasks.List(project, TaskStatusQueued)
+	if err != nil {
+		b.logger.Warn("list queued tasks failed", zap.Error(err))
+		return errorEmbed("Queue Failed", "Could not read the task queue. Please try again later.")
+	}
+	expired, err := b.tasks.List(project, TaskStatusExpired)
+	if err != nil {
+		b.logger.Warn("list expired tasks failed", zap.Error(err))
+		return errorEmbed("Queue Failed", "Could not read the task queue. Please try again later.")
 	}
+
+	title := "Task Queue"
+	if project != "" {
+		title += ": " + project
+	}
+	if len(tasks) == 0 && len(expired) == 0 {
+		return &discordgo.MessageEmbed{
+			Title:       title,
+			Description: "No queued tasks.",
+			Color:       colorInfo,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	fields := make([]*discordgo.MessageEmbedField, 0, maxQueueEmbedTasks)
+	for _, task := range tasks {
+		if len(fields) == maxQueueEmbedTasks {
+			break
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s #%d (priority %d)", task.Project, task.Position, task.Priority),
+			Value: truncateEmbedValue(task.Prompt, 200),
+		})
+	}
+	for _, task := range expired {
+		if len(fields) == maxQueueEmbedTasks {
+			break
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s expired (deadline %s)", task.Project, task.Deadline.Format("2006-01-02 15:04")),
+			Value: truncateEmbedValue(task.Prompt, 200),
+		})
+	}
+
+	description := fmt.Sprintf("%d queued task(s)", len(tasks))
+	if len(expired) > 0 {
+		description += fmt.Sprintf(", %d expired", len(expired))
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description,
+		Color:       colorInfo,
+		Fields:      fields,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// truncateEmbedValue shortens s to at most max runes.
+func truncateEmbedValue(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func forecastTotal(window ForecastWindow) float64 {
+	if window.ProjectedSessionsUSD > window.ProjectedUSD {
+		return window.ProjectedSessionsUSD
+	}
+	return window.ProjectedUSD
 }
 
 // commandResultEmbed converts a CommandResult into a Discord embed.
@@ -730,6 +1077,13 @@ func commandResultEmbed(title string, result *CommandResult) *discordgo.MessageE
 		{Name: "Status", Value: status, Inline: true},
 		{Name: "Command ID", Value: result.CommandID, Inline: true},
 	}
+	if result.NodeID != "" {
+		node := result.NodeID
+		if result.Placement != "" {
+			node += " (" + result.Placement + ")"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Node", Value: truncateEmbedValue(node, 1024)})
+	}
 
 	description := ""
 	if result.Output != "" {
@@ -758,6 +1112,12 @@ func errorEmbed(title, description string) *discordgo.MessageEmbed {
 	}
 }
 
+// notPermittedEmbed explains that the caller lacks the role for a command.
+func notPermittedEmbed(cmdName string, scope APIScope) *discordgo.MessageEmbed {
+	return errorEmbed("Not Permitted",
+		fmt.Sprintf("You are not permitted to run `/%s`. It requires the `%s` permission; ask a supervisor admin for access.", cmdName, scope))
+}
+
 // validationErrorEmbed creates a red embed for validation/argument errors.
 func validationErrorEmbed(description string) *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
@@ -795,3 +1155,32 @@ func valueOrDash(v string) string {
 	}
 	return v
 }
+
+// formatContextUsage renders the session's context use as
+// "45,231 / 200,000 (22%)", or just the token count when the model's window
+// is unknown.
+func formatContextUsage(session TrackedSession) string {
+	used := formatThousands(session.ContextUsed())
+	utilization, ok := session.ContextUtilization()
+	if !ok {
+		return used
+	}
+	return fmt.Sprintf("%s / %s (%d%%)", used, formatThousands(session.ContextWindow), int(utilization))
+}
+
+// formatThousands formats n with comma thousands separators.
+func formatThousands(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var out strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteByte(',')
+		}
+		out.WriteRune(digit)
+	}
+	return sign + out.String()
+}
@@ -0,0 +1,113 @@
+package supervisor
+
+import (
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordRoleRank orders the supervisor roles so the highest one granted to
+// a user wins.
+var discordRoleRank = map[string]int{
+	config.DiscordRoleViewer:   1,
+	config.DiscordRoleOperator: 2,
+	config.DiscordRoleAdmin:    3,
+}
+
+// discordRoleScopes lists the API scopes each supervisor role carries, so
+// Discord commands are checked with the same scopes as the HTTP API.
+var discordRoleScopes = map[string][]APIScope{
+	config.DiscordRoleViewer:   {ScopeSessionsRead},
+	config.DiscordRoleOperator: {ScopeSessionsRead, ScopeSessionsControl},
+	config.DiscordRoleAdmin:    {ScopeAdmin},
+}
+
+// discordCommandTypes maps the slash commands that dispatch a command to
+// the command type they dispatch.
+var discordCommandTypes = map[string]CommandType{
+	"status":  CommandTypeSessionStatus,
+	"resume":  CommandTypePromptSession,
+	"inject":  CommandTypePromptSession,
+	"restart": CommandTypeRestartSession,
+	"kill":    CommandTypeKillSession,
+	"start":   CommandTypeCreateSession,
+}
+
+// DiscordPermissions resolves the supervisor role of a Discord user from the
+// user and role IDs in channels.discord.permissions.
+type DiscordPermissions struct {
+	users       map[string]string
+	roles       map[string]string
+	defaultRole string
+}
+
+// NewDiscordPermissions creates DiscordPermissions from a validated config.
+func NewDiscordPermissions(cfg config.DiscordPermissionsConfig) *DiscordPermissions {
+	return &DiscordPermissions{
+		users:       cfg.Users,
+		roles:       cfg.Roles,
+		defaultRole: cfg.DefaultRole,
+	}
+}
+
+// Configured reports whether any user or role can run a command.
+func (p *DiscordPermissions) Configured() bool {
+	return len(p.users) > 0 || len(p.roles) > 0 || p.defaultRole != ""
+}
+
+// Role returns the highest role granted to userID or to any of roleIDs, or
+// the default role. An empty role grants nothing.
+func (p *DiscordPermissions) Role(userID string, roleIDs []string) string {
+	role := p.defaultRole
+	grant := func(r string) {
+		if discordRoleRank[r] > discordRoleRank[role] {
+			role = r
+		}
+	}
+	if userID != "" {
+		grant(p.users[userID])
+	}
+	for _, id := range roleIDs {
+		grant(p.roles[id])
+	}
+	return role
+}
+
+// Allows reports whether role carries scope.
+func (p *DiscordPermissions) Allows(role string, scope APIScope) bool {
+	for _, granted := range discordRoleScopes[role] {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// discordCommandScope returns the scope a slash command needs. Commands that
+// dispatch need the scope of their command type, /queue needs
+// sessions:control to add a task, and the other commands only read.
+func discordCommandScope(name string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) APIScope {
+	if cmdType, ok := discordCommandTypes[name]; ok {
+		return commandScope(cmdType)
+	}
+	if _, ok := opts["prompt"]; ok && name == "queue" {
+		return ScopeSessionsControl
+	}
+	return ScopeSessionsRead
+}
+
+// discordUser returns the user behind an interaction and, in a guild, their
+// role IDs. Direct messages carry no member.
+func discordUser(i *discordgo.InteractionCreate) (*discordgo.User, []string) {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User, i.Member.Roles
+	}
+	return i.User, nil
+}
+
+// discordActor is the audit actor recorded for a Discord user.
+func discordActor(user *discordgo.User) string {
+	if user == nil {
+		return "discord"
+	}
+	return "discord:" + user.ID
+}
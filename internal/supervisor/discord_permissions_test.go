@@ -0,0 +1,141 @@
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDiscordPermissionsRole(t *testing.T) {
+	perms := NewDiscordPermissions(config.DiscordPermissionsConfig{
+		Users: map[string]string{"alice": config.DiscordRoleAdmin, "bob": config.DiscordRoleViewer},
+		Roles: map[string]string{"oncall": config.DiscordRoleOperator},
+	})
+
+	tests := []struct {
+		user  string
+		roles []string
+		want  string
+	}{
+		{"alice", nil, config.DiscordRoleAdmin},
+		{"bob", nil, config.DiscordRoleViewer},
+		{"bob", []string{"oncall"}, config.DiscordRoleOperator},
+		{"alice", []string{"oncall"}, config.DiscordRoleAdmin},
+		{"carol", []string{"other"}, ""},
+	}
+	for _, tc := range tests {
+		if got := perms.Role(tc.user, tc.roles); got != tc.want {
+			t.Errorf("Role(%s, %v) = %q, want %q", tc.user, tc.roles, got, tc.want)
+		}
+	}
+	if perms.Allows("", ScopeSessionsRead) {
+		t.Fatal("an unmapped user must not be allowed anything")
+	}
+	if !perms.Allows(config.DiscordRoleOperator, ScopeSessionsControl) || perms.Allows(config.DiscordRoleOperator, ScopeCredentialsPush) {
+		t.Fatal("unexpected operator scopes")
+	}
+
+	withDefault := NewDiscordPermissions(config.DiscordPermissionsConfig{DefaultRole: config.DiscordRoleViewer})
+	if got := withDefault.Role("carol", nil); got != config.DiscordRoleViewer {
+		t.Fatalf("expected the default role, got %q", got)
+	}
+}
+
+func TestDiscordCommandScope(t *testing.T) {
+	prompt := map[string]*discordgo.ApplicationCommandInteractionDataOption{"prompt": {Name: "prompt"}}
+	tests := []struct {
+		name string
+		opts map[string]*discordgo.ApplicationCommandInteractionDataOption
+		want APIScope
+	}{
+		{"status", nil, ScopeSessionsRead},
+		{"nodes", nil, ScopeSessionsRead},
+		{"logs", nil, ScopeSessionsRead},
+		{"cost", nil, ScopeSessionsRead},
+		{"queue", nil, ScopeSessionsRead},
+		{"queue", prompt, ScopeSessionsControl},
+		{"resume", nil, ScopeSessionsControl},
+		{"inject", nil, ScopeSessionsControl},
+		{"restart", nil, ScopeSessionsControl},
+		{"kill", nil, ScopeSessionsControl},
+		{"start", nil, ScopeSessionsControl},
+	}
+	for _, tc := range tests {
+		if got := discordCommandScope(tc.name, tc.opts); got != tc.want {
+			t.Errorf("/%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestDiscordInteractionPermissions(t *testing.T) {
+	bot, mock, dispatcher := newTestDiscordBot(t)
+	audit := NewAuditLogger(dispatcher.db, nil)
+	bot.SetAuditLogger(audit)
+	bot.SetPermissions(NewDiscordPermissions(config.DiscordPermissionsConfig{
+		Users: map[string]string{"viewer-1": config.DiscordRoleViewer},
+		Roles: map[string]string{"role-ops": config.DiscordRoleOperator},
+	}))
+
+	interact := func(member *discordgo.Member, user *discordgo.User, name string, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.MessageEmbed {
+		t.Helper()
+		bot.handleInteraction(&discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				Type:   discordgo.InteractionApplicationCommand,
+				Data:   discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+				Member: member,
+				User:   user,
+			},
+		})
+		// Refusals are answered directly and only to the caller; allowed
+		// commands are deferred and answered with a followup.
+		if resp := mock.lastRespond(); resp != nil && resp.Type == discordgo.InteractionResponseChannelMessageWithSource {
+			if resp.Data == nil || len(resp.Data.Embeds) == 0 {
+				t.Fatalf("/%s: expected response embed", name)
+			}
+			if resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Fatalf("/%s: expected the refusal to be ephemeral", name)
+			}
+			return resp.Data.Embeds[0]
+		}
+		embed := mock.lastFollowupEmbed()
+		if embed == nil {
+			t.Fatalf("/%s: expected followup embed", name)
+		}
+		return embed
+	}
+	kill := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "session_id", Type: discordgo.ApplicationCommandOptionString, Value: "sess-1"},
+	}
+	status := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "project", Type: discordgo.ApplicationCommandOptionString, Value: "myproject"},
+	}
+	viewer := &discordgo.Member{User: &discordgo.User{ID: "viewer-1"}}
+	operator := &discordgo.Member{User: &discordgo.User{ID: "ops-1"}, Roles: []string{"role-ops"}}
+
+	if embed := interact(viewer, nil, "kill", kill); embed.Title != "Not Permitted" {
+		t.Fatalf("expected a viewer to be refused /kill, got %q", embed.Title)
+	}
+	if entries, _ := audit.QueryByActor("discord:viewer-1", 10); len(entries) != 0 {
+		t.Fatalf("a refused command must not be dispatched: %+v", entries)
+	}
+	if embed := interact(viewer, nil, "status", status); embed.Title == "Not Permitted" {
+		t.Fatal("expected a viewer to run /status")
+	}
+	if embed := interact(nil, &discordgo.User{ID: "stranger"}, "status", status); embed.Title != "Not Permitted" {
+		t.Fatalf("expected an unmapped user to be refused, got %q", embed.Title)
+	}
+
+	if embed := interact(operator, nil, "kill", kill); embed.Title != "Kill: sess-1" {
+		t.Fatalf("expected the operator role to run /kill, got %q: %s", embed.Title, embed.Description)
+	}
+	entries, err := audit.QueryByActor("discord:ops-1", 10)
+	if err != nil || len(entries) != 1 || entries[0].Action != string(CommandTypeKillSession) {
+		t.Fatalf("expected /kill audited under the discord user: %+v, %v", entries, err)
+	}
+
+	bot.SetPermissions(nil)
+	if embed := interact(operator, nil, "status", status); embed.Title != "Not Permitted" {
+		t.Fatal("expected every command to be refused without permissions")
+	}
+}
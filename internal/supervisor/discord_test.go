@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
 	"github.com/bwmarrin/discordgo"
 	"go.uber.org/zap"
 )
@@ -22,6 +23,7 @@ type mockDiscordSession struct {
 
 	registeredCmds []*discordgo.ApplicationCommand
 	deletedCmdIDs  []string
+	channelEmbeds  []channelEmbed
 
 	handler func(s *discordgo.Session, i *discordgo.InteractionCreate)
 	state   *discordgo.State
@@ -32,6 +34,11 @@ type respondCall struct {
 	Response    *discordgo.InteractionResponse
 }
 
+type channelEmbed struct {
+	ChannelID string
+	Embed     *discordgo.MessageEmbed
+}
+
 type followupCall struct {
 	Interaction *discordgo.Interaction
 	Params      *discordgo.WebhookParams
@@ -90,6 +97,13 @@ func (m *mockDiscordSession) FollowupMessageCreate(interaction *discordgo.Intera
 	return &discordgo.Message{ID: "msg-1"}, nil
 }
 
+func (m *mockDiscordSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelEmbeds = append(m.channelEmbeds, channelEmbed{ChannelID: channelID, Embed: embed})
+	return &discordgo.Message{ID: "msg-1"}, nil
+}
+
 func (m *mockDiscordSession) State() *discordgo.State {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -110,12 +124,19 @@ func (m *mockDiscordSession) lastFollowupEmbed() *discordgo.MessageEmbed {
 }
 
 func (m *mockDiscordSession) lastRespondType() discordgo.InteractionResponseType {
+	if resp := m.lastRespond(); resp != nil {
+		return resp.Type
+	}
+	return 0
+}
+
+func (m *mockDiscordSession) lastRespond() *discordgo.InteractionResponse {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if len(m.respondCalls) == 0 {
-		return 0
+		return nil
 	}
-	return m.respondCalls[len(m.respondCalls)-1].Response.Type
+	return m.respondCalls[len(m.respondCalls)-1].Response
 }
 
 func newTestState(appID string) *discordgo.State {
@@ -170,6 +191,7 @@ func newTestDiscordBot(t *testing.T) (*DiscordBot, *mockDiscordSession, *Command
 	}
 
 	bot := NewDiscordBotWithSession(mock, "guild-1", dispatcher, nil, tracker, logger)
+	bot.SetPermissions(adminDiscordPermissions())
 	return bot, mock, dispatcher
 }
 
@@ -181,10 +203,16 @@ func simulateInteraction(bot *DiscordBot, name string, options []*discordgo.Appl
 				Name:    name,
 				Options: options,
 			},
+			Member: &discordgo.Member{User: &discordgo.User{ID: "user-1", Username: "tester"}},
 		},
 	})
 }
 
+// adminDiscordPermissions lets every Discord user run every command.
+func adminDiscordPermissions() *DiscordPermissions {
+	return NewDiscordPermissions(config.DiscordPermissionsConfig{DefaultRole: config.DiscordRoleAdmin})
+}
+
 func TestDiscordCommandsHappy(t *testing.T) {
 	t.Run("status", func(t *testing.T) {
 		bot, mock, _ := newTestDiscordBot(t)
@@ -356,6 +384,7 @@ func TestDiscordCommandsError(t *testing.T) {
 			state: newTestState("app-123"),
 		}
 		bot := NewDiscordBotWithSession(mock, "guild-1", dispatcher, nil, tracker, logger)
+		bot.SetPermissions(adminDiscordPermissions())
 
 		simulateInteraction(bot, "resume", []*discordgo.ApplicationCommandInteractionDataOption{
 			{Name: "project", Type: discordgo.ApplicationCommandOptionString, Value: "nonexistent"},
@@ -436,6 +465,7 @@ func TestDiscordCommandsError(t *testing.T) {
 			state: newTestState("app-123"),
 		}
 		bot := NewDiscordBotWithSession(mock, "guild-1", dispatcher, nil, tracker, logger)
+		bot.SetPermissions(adminDiscordPermissions())
 
 		simulateInteraction(bot, "kill", []*discordgo.ApplicationCommandInteractionDataOption{
 			{Name: "session_id", Type: discordgo.ApplicationCommandOptionString, Value: "sess-off"},
@@ -465,8 +495,8 @@ func TestDiscordBotStartStop(t *testing.T) {
 	if !mock.openCalled {
 		t.Fatal("expected Open() to be called")
 	}
-	if len(mock.registeredCmds) != 9 {
-		t.Fatalf("expected 9 registered commands, got %d", len(mock.registeredCmds))
+	if len(mock.registeredCmds) != 10 {
+		t.Fatalf("expected 10 registered commands, got %d", len(mock.registeredCmds))
 	}
 	mock.mu.Unlock()
 
@@ -478,22 +508,70 @@ func TestDiscordBotStartStop(t *testing.T) {
 	if !mock.closeCalled {
 		t.Fatal("expected Close() to be called")
 	}
-	if len(mock.deletedCmdIDs) != 9 {
-		t.Fatalf("expected 9 deleted commands, got %d", len(mock.deletedCmdIDs))
+	if len(mock.deletedCmdIDs) != 10 {
+		t.Fatalf("expected 10 deleted commands, got %d", len(mock.deletedCmdIDs))
 	}
 	mock.mu.Unlock()
 }
 
+func TestDiscordBotPostsBudgetAlerts(t *testing.T) {
+	bot, mock, _ := newTestDiscordBot(t)
+	bot.SetAlertChannel("alerts-1")
+
+	// Budget events belong to no session; like the supervisor's own database
+	// connection, store them without foreign key enforcement.
+	if _, err := bot.tracker.db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatalf("disable foreign keys: %v", err)
+	}
+	pipeline, err := NewEventPipeline(bot.tracker.db, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatalf("new event pipeline: %v", err)
+	}
+
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:         true,
+		AlertThresholds: []float64{50},
+		Projects:        map[string]config.BudgetLimits{"proj-a": {DailyUSD: 10}},
+	}, nil, &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-1", Project: "proj-a", SessionCost: 6, StartedAt: now.Add(-time.Hour)},
+	}}, NewEventFanout(pipeline, bot), zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+	enforcer.Evaluate()
+	pipeline.Close()
+
+	var persisted int
+	if err := bot.tracker.db.QueryRow(`SELECT COUNT(*) FROM events WHERE type = 'budget.alert'`).Scan(&persisted); err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if persisted != 1 {
+		t.Fatalf("expected budget alert in events table, got %d", persisted)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if len(mock.channelEmbeds) != 1 {
+		t.Fatalf("expected 1 alert post, got %d", len(mock.channelEmbeds))
+	}
+	post := mock.channelEmbeds[0]
+	if post.ChannelID != "alerts-1" || post.Embed.Title != "Budget Alert" {
+		t.Fatalf("unexpected alert post %+v", post)
+	}
+	if !strings.Contains(post.Embed.Description, "proj-a daily budget: $6.00 of $10.00 spent (crossed 50%)") {
+		t.Fatalf("unexpected alert description %q", post.Embed.Description)
+	}
+}
+
 func TestDiscordSlashCommandDefinitions(t *testing.T) {
 	cmds := slashCommands()
-	if len(cmds) != 9 {
-		t.Fatalf("expected 9 slash commands, got %d", len(cmds))
+	if len(cmds) != 10 {
+		t.Fatalf("expected 10 slash commands, got %d", len(cmds))
 	}
 
 	expected := map[string]bool{
 		"status": true, "nodes": true, "logs": true,
 		"resume": true, "inject": true, "restart": true,
-		"kill": true, "start": true, "cost": true,
+		"kill": true, "start": true, "cost": true, "queue": true,
 	}
 	for _, cmd := range cmds {
 		if !expected[cmd.Name] {
@@ -538,3 +616,16 @@ func TestCostDefaultPeriod(t *testing.T) {
 		t.Fatalf("expected 'Cost Summary', got %q", embed.Title)
 	}
 }
+
+func TestFormatContextUsage(t *testing.T) {
+	session := TrackedSession{ContextTokens: 45231, ContextWindow: 200000}
+	if got := formatContextUsage(session); got != "45,231 / 200,000 (22%)" {
+		t.Fatalf("unexpected context usage %q", got)
+	}
+	if got := formatContextUsage(TrackedSession{TokenUsage: TokenUsage{Total: 1234567}}); got != "1,234,567" {
+		t.Fatalf("expected plain token count for unknown window, got %q", got)
+	}
+	if got := formatThousands(-1000); got != "-1,000" {
+		t.Fatalf("unexpected negative formatting %q", got)
+	}
+}
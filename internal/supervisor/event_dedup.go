@@ -37,23 +37,39 @@ func (d *eventDedupCache) seen(eventKey string) bool {
 	}
 
 	d.mu.Lock()
-	cache, exists := d.caches[agentID]
-	if !exists {
-		var err error
-		cache, err = lru.New[string, struct{}](d.cacheSize)
-		if err != nil {
-			d.mu.Unlock()
-			return false
-		}
-		d.caches[agentID] = cache
+	defer d.mu.Unlock()
+	cache := d.cacheLocked(agentID)
+	if cache == nil {
+		return false
 	}
 
 	if cache.Contains(eventID) {
-		d.mu.Unlock()
 		return true
 	}
 
 	cache.Add(eventID, struct{}{})
-	d.mu.Unlock()
 	return false
 }
+
+// remember marks an event as seen without checking it, e.g. when restoring
+// recently applied events at startup.
+func (d *eventDedupCache) remember(agentID, eventID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cache := d.cacheLocked(agentID); cache != nil {
+		cache.Add(eventID, struct{}{})
+	}
+}
+
+func (d *eventDedupCache) cacheLocked(agentID string) *lru.Cache[string, struct{}] {
+	cache, exists := d.caches[agentID]
+	if !exists {
+		var err error
+		cache, err = lru.New[string, struct{}](d.cacheSize)
+		if err != nil {
+			return nil
+		}
+		d.caches[agentID] = cache
+	}
+	return cache
+}
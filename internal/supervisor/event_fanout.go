@@ -0,0 +1,67 @@
+package supervisor
+
+import (
+	"errors"
+	"sync"
+)
+
+// EventSink receives events emitted by supervisor components.
+type EventSink interface {
+	ProcessEvent(agentID string, event Event) error
+}
+
+// EventFanout hands each event to every registered sink in registration
+// order. Sinks may be added after the fanout is handed to an emitter, so
+// notifiers that start late still receive later events.
+type EventFanout struct {
+	mu    sync.RWMutex
+	sinks []EventSink
+}
+
+// NewEventFanout creates a fanout delivering to sinks; nil sinks are skipped.
+func NewEventFanout(sinks ...EventSink) *EventFanout {
+	f := &EventFanout{}
+	for _, sink := range sinks {
+		f.Add(sink)
+	}
+	return f
+}
+
+// Add registers sink to receive subsequent events.
+func (f *EventFanout) Add(sink EventSink) {
+	if sink == nil {
+		return
+	}
+	f.mu.Lock()
+	f.sinks = append(f.sinks, sink)
+	f.mu.Unlock()
+}
+
+// ProcessEvent delivers event to every sink, even when an earlier one fails,
+// and returns the sinks' errors joined.
+func (f *EventFanout) ProcessEvent(agentID string, event Event) error {
+	f.mu.RLock()
+	sinks := append([]EventSink(nil), f.sinks...)
+	f.mu.RUnlock()
+
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.ProcessEvent(agentID, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LastSequence reports the sequence from the first sink that tracks one, so
+// emitters resume numbering after a restart.
+func (f *EventFanout) LastSequence(agentID string) uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, sink := range f.sinks {
+		if source, ok := sink.(sequenceSource); ok {
+			return source.LastSequence(agentID)
+		}
+	}
+	return 0
+}
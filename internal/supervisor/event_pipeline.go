@@ -28,6 +28,18 @@ type RequestEventRange struct {
 
 type ReplayRequestSender func(agentID string, req RequestEventRange) error
 
+// sequenceSource reports the last event sequence applied for an agent.
+// Components that emit their own events resume numbering from it.
+type sequenceSource interface {
+	LastSequence(agentID string) uint64
+}
+
+// agentEvent is an event queued for persistence with the agent it came from.
+type agentEvent struct {
+	agentID string
+	event   Event
+}
+
 type sequenceStatus int
 
 const (
@@ -47,7 +59,7 @@ type EventPipeline struct {
 	agentSequences map[string]uint64
 	pendingEvents  map[string]map[uint64]Event
 
-	persistQueue chan Event
+	persistQueue chan agentEvent
 	stopCh       chan struct{}
 	workers      sync.WaitGroup
 }
@@ -69,10 +81,14 @@ func NewEventPipeline(db *sql.DB, logger *zap.Logger, sendReplayRequest ReplayRe
 		dedup:             dedup,
 		agentSequences:    make(map[string]uint64),
 		pendingEvents:     make(map[string]map[uint64]Event),
-		persistQueue:      make(chan Event, persistQueueSize),
+		persistQueue:      make(chan agentEvent, persistQueueSize),
 		stopCh:            make(chan struct{}),
 	}
 
+	if err := p.recoverSequences(); err != nil {
+		return nil, err
+	}
+
 	p.workers.Add(1)
 	go p.persistWorker()
 
@@ -108,7 +124,7 @@ func (p *EventPipeline) ProcessEvent(agentID string, event Event) error {
 		return p.handleGap(agentID, event)
 	case sequenceStatusMatch:
 		for _, ordered := range p.consumeInOrder(agentID, event) {
-			p.enqueuePersist(ordered)
+			p.enqueuePersist(agentID, ordered)
 		}
 		return nil
 	default:
@@ -140,7 +156,69 @@ func (p *EventPipeline) deduplicateEvent(eventID string) bool {
 	return p.dedup.seen(eventID)
 }
 
-func (p *EventPipeline) persistEvent(event Event) error {
+// recoverSequences restores each agent's last applied sequence and its most
+// recent event IDs, so events arriving after a restart are neither treated as
+// a gap nor applied twice.
+func (p *EventPipeline) recoverSequences() error {
+	if p.db == nil {
+		return nil
+	}
+
+	rows, err := p.db.Query(`SELECT agent_id, last_seq FROM agent_sequences`)
+	if err != nil {
+		return fmt.Errorf("recover event sequences: %w", err)
+	}
+	for rows.Next() {
+		var (
+			agentID string
+			lastSeq uint64
+		)
+		if err := rows.Scan(&agentID, &lastSeq); err != nil {
+			rows.Close()
+			return fmt.Errorf("recover event sequences: scan: %w", err)
+		}
+		p.agentSequences[agentID] = lastSeq
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("recover event sequences: iterate: %w", err)
+	}
+
+	for agentID := range p.agentSequences {
+		if err := p.recoverDedup(agentID); err != nil {
+			return err
+		}
+	}
+	if len(p.agentSequences) > 0 {
+		p.logger.Info("recovered event sequences", zap.Int("agents", len(p.agentSequences)))
+	}
+	return nil
+}
+
+func (p *EventPipeline) recoverDedup(agentID string) error {
+	rows, err := p.db.Query(`
+		SELECT id FROM (
+			SELECT id, seq FROM events WHERE agent_id = ? ORDER BY seq DESC LIMIT ?
+		) ORDER BY seq
+	`, agentID, dedupCacheSizePerAgent)
+	if err != nil {
+		return fmt.Errorf("recover dedup cache for %s: %w", agentID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID string
+		if err := rows.Scan(&eventID); err != nil {
+			return fmt.Errorf("recover dedup cache for %s: scan: %w", agentID, err)
+		}
+		p.dedup.remember(agentID, eventID)
+	}
+	return rows.Err()
+}
+
+// persistEvent stores an event and advances the agent's stored sequence in
+// one transaction. Events already stored are skipped.
+func (p *EventPipeline) persistEvent(agentID string, event Event) error {
 	if p.db == nil {
 		return nil
 	}
@@ -154,18 +232,36 @@ func (p *EventPipeline) persistEvent(event Event) error {
 		timestamp = time.Now().UTC()
 	}
 
-	_, err := p.db.Exec(
-		`INSERT INTO events (id, session_id, type, data, timestamp) VALUES (?, ?, ?, ?, ?)`,
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("persist event %s: begin: %w", event.ID, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT OR IGNORE INTO events (id, session_id, type, data, timestamp, agent_id, seq) VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		event.ID,
 		event.SessionID,
 		event.Type,
 		data,
 		timestamp.Format(time.RFC3339Nano),
-	)
-	if err != nil {
+		agentID,
+		event.Seq,
+	); err != nil {
 		return fmt.Errorf("persist event %s: %w", event.ID, err)
 	}
+	if _, err := tx.Exec(`
+		INSERT INTO agent_sequences (agent_id, last_seq, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(agent_id) DO UPDATE SET
+			last_seq = MAX(last_seq, excluded.last_seq),
+			updated_at = excluded.updated_at
+	`, agentID, event.Seq, time.Now().UTC()); err != nil {
+		return fmt.Errorf("persist event %s: sequence: %w", event.ID, err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persist event %s: commit: %w", event.ID, err)
+	}
 	return nil
 }
 
@@ -230,15 +326,19 @@ func (p *EventPipeline) consumeInOrder(agentID string, first Event) []Event {
 	return events
 }
 
-func (p *EventPipeline) enqueuePersist(event Event) {
+func (p *EventPipeline) enqueuePersist(agentID string, event Event) {
 	select {
-	case p.persistQueue <- event:
+	case p.persistQueue <- agentEvent{agentID: agentID, event: event}:
 	default:
-		p.logger.Warn(
-			"event persist queue full; dropping event",
+		// The in-memory sequence has already moved past this event, so it is
+		// written inline rather than dropped; the caller slows down until the
+		// worker catches up.
+		p.logger.Debug(
+			"event persist queue full; persisting inline",
 			zap.String("event_id", event.ID),
 			zap.String("session_id", event.SessionID),
 		)
+		p.persistQueued(agentEvent{agentID: agentID, event: event})
 	}
 }
 
@@ -247,17 +347,13 @@ func (p *EventPipeline) persistWorker() {
 
 	for {
 		select {
-		case event := <-p.persistQueue:
-			if err := p.persistEvent(event); err != nil {
-				p.logger.Warn("failed to persist event", zap.String("event_id", event.ID), zap.Error(err))
-			}
+		case queued := <-p.persistQueue:
+			p.persistQueued(queued)
 		case <-p.stopCh:
 			for {
 				select {
-				case event := <-p.persistQueue:
-					if err := p.persistEvent(event); err != nil {
-						p.logger.Warn("failed to persist event", zap.String("event_id", event.ID), zap.Error(err))
-					}
+				case queued := <-p.persistQueue:
+					p.persistQueued(queued)
 				default:
 					return
 				}
@@ -266,6 +362,12 @@ func (p *EventPipeline) persistWorker() {
 	}
 }
 
+func (p *EventPipeline) persistQueued(queued agentEvent) {
+	if err := p.persistEvent(queued.agentID, queued.event); err != nil {
+		p.logger.Warn("failed to persist event", zap.String("event_id", queued.event.ID), zap.Error(err))
+	}
+}
+
 func (p *EventPipeline) LastSequence(agentID string) uint64 {
 	p.mu.Lock()
 	last := p.agentSequences[agentID]
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
 	"github.com/Bldg-7/hal-o-swarm/internal/storage"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
@@ -156,6 +157,108 @@ func TestEventPipelineGap(t *testing.T) {
 	}
 }
 
+func TestEventPipelineRecoversAfterRestart(t *testing.T) {
+	db := setupPipelineTestDB(t)
+	logger := zap.NewNop()
+
+	pipeline, err := NewEventPipeline(db, logger, nil)
+	if err != nil {
+		t.Fatalf("create event pipeline: %v", err)
+	}
+	for seq := uint64(1); seq <= 3; seq++ {
+		if err := pipeline.ProcessEvent("agent-1", Event{ID: fmt.Sprintf("evt-%d", seq), SessionID: "session-1", Type: "session.idle", Seq: seq}); err != nil {
+			t.Fatalf("process event %d: %v", seq, err)
+		}
+	}
+	pipeline.Close()
+
+	requests := make([]RequestEventRange, 0)
+	restarted, err := NewEventPipeline(db, logger, func(_ string, req RequestEventRange) error {
+		requests = append(requests, req)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("recreate event pipeline: %v", err)
+	}
+	defer restarted.Close()
+
+	if got := restarted.LastSequence("agent-1"); got != 3 {
+		t.Fatalf("recovered last sequence = %d, want 3", got)
+	}
+
+	// A redelivered event, even under a new sequence number, is not applied
+	// again, and the next event follows on without a replay request.
+	for _, event := range []Event{
+		{ID: "evt-3", SessionID: "session-1", Type: "session.idle", Seq: 3},
+		{ID: "evt-2", SessionID: "session-1", Type: "session.idle", Seq: 4},
+		{ID: "evt-4", SessionID: "session-1", Type: "session.idle", Seq: 4},
+	} {
+		if err := restarted.ProcessEvent("agent-1", event); err != nil {
+			t.Fatalf("process %s: %v", event.ID, err)
+		}
+	}
+	if len(requests) != 0 {
+		t.Fatalf("expected no replay requests after restart, got %+v", requests)
+	}
+	if got := restarted.LastSequence("agent-1"); got != 4 {
+		t.Fatalf("last sequence = %d, want 4", got)
+	}
+	waitForEventCount(t, db, 4)
+
+	// Internal emitters continue numbering after the recovered sequence.
+	engine := NewPolicyEngine(config.PolicyConfig{}, nil, nil, restarted)
+	defer engine.Stop()
+	if err := restarted.ProcessEvent(policyEngineAgentID, Event{ID: "policy-1", Type: "policy.action", Seq: 1}); err != nil {
+		t.Fatalf("seed policy event: %v", err)
+	}
+	if err := engine.emitPolicyEvent("policy.action", "", map[string]interface{}{}); err != nil {
+		t.Fatalf("emit policy event: %v", err)
+	}
+	if got := restarted.LastSequence(policyEngineAgentID); got != 2 {
+		t.Fatalf("policy sequence = %d, want 2", got)
+	}
+	waitForEventCount(t, db, 6)
+}
+
+func TestEventPipelinePersistsWhenQueueFull(t *testing.T) {
+	db := setupPipelineTestDB(t)
+	logger := zap.NewNop()
+
+	pipeline, err := NewEventPipeline(db, logger, nil)
+	if err != nil {
+		t.Fatalf("create event pipeline: %v", err)
+	}
+	// Stop the worker and saturate its queue so every event takes the
+	// full-queue path.
+	pipeline.Close()
+	for i := 0; i < persistQueueSize; i++ {
+		pipeline.persistQueue <- agentEvent{}
+	}
+
+	for seq := uint64(1); seq <= 3; seq++ {
+		if err := pipeline.ProcessEvent("agent-1", Event{ID: fmt.Sprintf("evt-%d", seq), SessionID: "session-1", Type: "session.idle", Seq: seq}); err != nil {
+			t.Fatalf("process event %d: %v", seq, err)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&count); err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected every event persisted with a full queue, got %d", count)
+	}
+
+	restarted, err := NewEventPipeline(db, logger, nil)
+	if err != nil {
+		t.Fatalf("recreate event pipeline: %v", err)
+	}
+	defer restarted.Close()
+	if got := restarted.LastSequence("agent-1"); got != 3 {
+		t.Fatalf("recovered last sequence = %d, want 3", got)
+	}
+}
+
 func setupPipelineTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 
@@ -0,0 +1,244 @@
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	forecastMethodEWMA     = "ewma_dow"
+	forecastLookbackDays   = 28
+	forecastEWMAAlpha      = 0.3
+	forecastMinSeasonalDay = 14
+)
+
+// CostForecast projects end-of-period spend from the daily cost buckets
+// (per provider) and session usage priced by day (per project).
+type CostForecast struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Method      string         `json:"method"`
+	EndOfWeek   ForecastWindow `json:"end_of_week"`
+	EndOfMonth  ForecastWindow `json:"end_of_month"`
+}
+
+type ForecastWindow struct {
+	PeriodStart  time.Time       `json:"period_start"`
+	PeriodEnd    time.Time       `json:"period_end"`
+	SpentUSD     float64         `json:"spent_usd"`
+	ProjectedUSD float64         `json:"projected_usd"`
+	ByProvider   []ForecastEntry `json:"by_provider"`
+	ByProject    []ForecastEntry `json:"by_project"`
+
+	// ProjectedSessionsUSD is the sum of per-project projections, which are
+	// based on live session estimates rather than provider reports.
+	ProjectedSessionsUSD float64 `json:"projected_sessions_usd"`
+}
+
+type ForecastEntry struct {
+	Provider     string  `json:"provider,omitempty"`
+	Project      string  `json:"project,omitempty"`
+	SpentUSD     float64 `json:"spent_usd"`
+	ProjectedUSD float64 `json:"projected_usd"`
+}
+
+// dailySeries maps a UTC date (YYYY-MM-DD) to spend on that day.
+type dailySeries map[string]float64
+
+// Forecast projects end-of-week and end-of-month spend. Weeks and months use
+// the same calendar windows as budgets so projections compare directly.
+func (c *CostAggregator) Forecast() (*CostForecast, error) {
+	now := c.now().UTC()
+	lookbackStart := startOfUTCDay(now).AddDate(0, 0, -forecastLookbackDays)
+
+	providers := map[string]dailySeries{}
+	if c.db != nil {
+		series, err := c.queryProviderDailySeries(lookbackStart)
+		if err != nil {
+			return nil, err
+		}
+		providers = series
+	}
+
+	days, err := c.sessionSpendByDay(lookbackStart, startOfUTCDay(now).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	projects := projectDailySeries(days)
+
+	forecast := &CostForecast{
+		GeneratedAt: now,
+		Method:      forecastMethodEWMA,
+	}
+	forecast.EndOfWeek = buildForecastWindow(now, BudgetPeriodWeekly, providers, projects)
+	forecast.EndOfMonth = buildForecastWindow(now, BudgetPeriodMonthly, providers, projects)
+
+	return forecast, nil
+}
+
+func (c *CostAggregator) queryProviderDailySeries(start time.Time) (map[string]dailySeries, error) {
+	rows, err := c.db.Query(`
+		SELECT provider, substr(date, 1, 10) AS day, SUM(cost_usd)
+		FROM costs
+		WHERE date >= ?
+		GROUP BY provider, day
+	`, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("query provider daily series: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]dailySeries{}
+	for rows.Next() {
+		var provider, date string
+		var cost float64
+		if err := rows.Scan(&provider, &date, &cost); err != nil {
+			return nil, fmt.Errorf("scan provider daily series: %w", err)
+		}
+		if out[provider] == nil {
+			out[provider] = dailySeries{}
+		}
+		out[provider][date] += cost
+	}
+
+	return out, rows.Err()
+}
+
+// projectDailySeries sums session spend per project and day.
+func projectDailySeries(days []sessionDaySpend) map[string]dailySeries {
+	out := map[string]dailySeries{}
+	for _, day := range days {
+		if day.CostUSD <= 0 {
+			continue
+		}
+		if out[day.Project] == nil {
+			out[day.Project] = dailySeries{}
+		}
+		out[day.Project][day.Date] += day.CostUSD
+	}
+	return out
+}
+
+func buildForecastWindow(now time.Time, period BudgetPeriod, providers, projects map[string]dailySeries) ForecastWindow {
+	start, end := budgetPeriodWindow(now, period)
+	window := ForecastWindow{
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ByProvider:  make([]ForecastEntry, 0, len(providers)),
+		ByProject:   make([]ForecastEntry, 0, len(projects)),
+	}
+
+	for _, provider := range sortedSeriesKeys(providers) {
+		spent, projected := projectSeries(providers[provider], now, start, end)
+		window.ByProvider = append(window.ByProvider, ForecastEntry{Provider: provider, SpentUSD: spent, ProjectedUSD: projected})
+		window.SpentUSD += spent
+		window.ProjectedUSD += projected
+	}
+
+	for _, project := range sortedSeriesKeys(projects) {
+		spent, projected := projectSeries(projects[project], now, start, end)
+		window.ByProject = append(window.ByProject, ForecastEntry{Project: project, SpentUSD: spent, ProjectedUSD: projected})
+		window.ProjectedSessionsUSD += projected
+	}
+
+	return window
+}
+
+// projectSeries returns spend to date within [start, end) and the projected
+// total at end. Completed days before today feed an EWMA of daily spend which
+// is scaled by a day-of-week factor once two weeks of history exist. Today is
+// only partially observed, so it contributes whichever is larger of its
+// actual spend and the model's estimate.
+func projectSeries(series dailySeries, now, start, end time.Time) (float64, float64) {
+	today := startOfUTCDay(now)
+
+	spent := 0.0
+	for day := start; day.Before(end) && !day.After(today); day = day.AddDate(0, 0, 1) {
+		spent += series[day.Format("2006-01-02")]
+	}
+
+	history := make([]float64, 0, forecastLookbackDays)
+	historyStart := today.AddDate(0, 0, -forecastLookbackDays)
+	for day := historyStart; day.Before(today); day = day.AddDate(0, 0, 1) {
+		history = append(history, series[day.Format("2006-01-02")])
+	}
+
+	// Drop the leading run of empty days so a new provider or project is not
+	// dragged toward zero by history that predates it.
+	for len(history) > 0 && history[0] == 0 {
+		history = history[1:]
+		historyStart = historyStart.AddDate(0, 0, 1)
+	}
+
+	level := ewma(history, forecastEWMAAlpha)
+	seasonal := weekdayFactors(history, historyStart)
+
+	projected := spent
+	estimateToday := level * seasonal[today.Weekday()]
+	if actualToday := series[today.Format("2006-01-02")]; estimateToday > actualToday {
+		projected += estimateToday - actualToday
+	}
+	for day := today.AddDate(0, 0, 1); day.Before(end); day = day.AddDate(0, 0, 1) {
+		projected += level * seasonal[day.Weekday()]
+	}
+
+	return spent, projected
+}
+
+func ewma(values []float64, alpha float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	level := values[0]
+	for _, value := range values[1:] {
+		level = alpha*value + (1-alpha)*level
+	}
+	return level
+}
+
+// weekdayFactors returns a multiplier per weekday relative to the mean daily
+// spend. With less than two weeks of history every factor is 1.
+func weekdayFactors(history []float64, historyStart time.Time) [7]float64 {
+	factors := [7]float64{1, 1, 1, 1, 1, 1, 1}
+	if len(history) < forecastMinSeasonalDay {
+		return factors
+	}
+
+	total := 0.0
+	var sums [7]float64
+	var counts [7]int
+	for i, value := range history {
+		weekday := historyStart.AddDate(0, 0, i).Weekday()
+		sums[weekday] += value
+		counts[weekday]++
+		total += value
+	}
+
+	mean := total / float64(len(history))
+	if mean <= 0 {
+		return factors
+	}
+
+	for weekday := range factors {
+		if counts[weekday] == 0 {
+			continue
+		}
+		factors[weekday] = (sums[weekday] / float64(counts[weekday])) / mean
+	}
+	return factors
+}
+
+func sortedSeriesKeys(series map[string]dailySeries) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func startOfUTCDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
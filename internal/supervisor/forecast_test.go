@@ -0,0 +1,182 @@
+package supervisor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestProjectSeriesFlatTrend(t *testing.T) {
+	// Wednesday; the week runs Mon 16 - Sun 22.
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	series := dailySeries{}
+	for day := now.AddDate(0, 0, -10); day.Before(startOfUTCDay(now)); day = day.AddDate(0, 0, 1) {
+		series[day.Format("2006-01-02")] = 10
+	}
+	series["2026-02-18"] = 4
+
+	start, end := budgetPeriodWindow(now, BudgetPeriodWeekly)
+	spent, projected := projectSeries(series, now, start, end)
+
+	if spent != 24 {
+		t.Fatalf("expected 24 spent (mon+tue+partial wed), got %f", spent)
+	}
+	// 24 spent + 6 to finish today + 4 remaining days at 10/day.
+	if math.Abs(projected-70) > 1e-9 {
+		t.Fatalf("expected projection 70, got %f", projected)
+	}
+}
+
+func TestProjectSeriesIgnoresHistoryBeforeFirstSpend(t *testing.T) {
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	series := dailySeries{"2026-02-16": 5, "2026-02-17": 5}
+
+	start, end := budgetPeriodWindow(now, BudgetPeriodWeekly)
+	_, projected := projectSeries(series, now, start, end)
+
+	// Leading empty days must not dilute the EWMA: 10 spent + 5 for each of
+	// the 5 remaining days including today.
+	if math.Abs(projected-35) > 1e-9 {
+		t.Fatalf("expected projection 35, got %f", projected)
+	}
+}
+
+func TestWeekdayFactorsSeasonality(t *testing.T) {
+	historyStart := time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC) // Monday
+	history := make([]float64, 28)
+	for i := range history {
+		weekday := historyStart.AddDate(0, 0, i).Weekday()
+		if weekday == time.Saturday || weekday == time.Sunday {
+			history[i] = 0
+		} else {
+			history[i] = 7
+		}
+	}
+
+	factors := weekdayFactors(history, historyStart)
+	if factors[time.Saturday] != 0 || factors[time.Sunday] != 0 {
+		t.Fatalf("expected zero weekend factors, got %v", factors)
+	}
+	if math.Abs(factors[time.Monday]-1.4) > 1e-9 {
+		t.Fatalf("expected weekday factor 1.4, got %f", factors[time.Monday])
+	}
+
+	short := weekdayFactors(history[:7], historyStart)
+	for weekday, factor := range short {
+		if factor != 1 {
+			t.Fatalf("expected neutral factors with one week of history, weekday %d got %f", weekday, factor)
+		}
+	}
+}
+
+func TestCostAggregatorForecast(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+
+	if _, err := db.Exec(`
+		INSERT INTO costs (id, provider, model, date, tokens, cost_usd)
+		VALUES
+			('anthropic|claude-sonnet-4|2026-02-16', 'anthropic', 'claude-sonnet-4', '2026-02-16', 1000, 2),
+			('anthropic|claude-sonnet-4|2026-02-17', 'anthropic', 'claude-sonnet-4', '2026-02-17', 1000, 2)
+	`); err != nil {
+		t.Fatalf("insert costs: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", now.Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{
+		SessionID:   "s-1",
+		NodeID:      "n-1",
+		Project:     "proj-a",
+		Status:      SessionStatusRunning,
+		Model:       "claude-sonnet-4",
+		TokenUsage:  TokenUsage{Prompt: 1000, Total: 1000},
+		SessionCost: 3,
+		StartedAt:   time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC),
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{Providers: config.CostProviders{
+		Anthropic: config.CostProviderConfig{
+			APIKey:     "sk-ant-admin-test",
+			ModelRates: map[string]config.ModelRate{"claude-sonnet-4": {Input: 3}},
+		},
+	}}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+
+	report, err := agg.Report("today")
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if report.Forecast == nil {
+		t.Fatal("expected forecast in cost report")
+	}
+
+	week := report.Forecast.EndOfWeek
+	if len(week.ByProvider) != 1 || week.ByProvider[0].Provider != providerAnthropic {
+		t.Fatalf("unexpected provider forecast %+v", week.ByProvider)
+	}
+	if week.SpentUSD != 4 || math.Abs(week.ProjectedUSD-14) > 1e-9 {
+		t.Fatalf("unexpected weekly provider forecast spent=%f projected=%f", week.SpentUSD, week.ProjectedUSD)
+	}
+	if len(week.ByProject) != 1 || week.ByProject[0].Project != "proj-a" || math.Abs(week.ByProject[0].ProjectedUSD-18) > 1e-9 {
+		t.Fatalf("unexpected project forecast %+v", week.ByProject)
+	}
+	if !report.Forecast.EndOfMonth.PeriodEnd.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected month end %s", report.Forecast.EndOfMonth.PeriodEnd)
+	}
+}
+
+func TestBudgetEnforcerForecastAlert(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	now := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+
+	if _, err := db.Exec(`
+		INSERT INTO costs (id, provider, model, date, tokens, cost_usd)
+		VALUES
+			('openai|gpt-4|2026-02-16', 'openai', 'gpt-4', '2026-02-16', 1000, 10),
+			('openai|gpt-4|2026-02-17', 'openai', 'gpt-4', '2026-02-17', 1000, 10)
+	`); err != nil {
+		t.Fatalf("insert costs: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{}, db, tracker, zap.NewNop())
+	agg.now = func() time.Time { return now }
+	events := &policyTestEvents{}
+
+	enforcer := NewBudgetEnforcer(config.BudgetConfig{
+		Enabled: true,
+		Global:  config.BudgetLimits{WeeklyUSD: 50},
+	}, agg, tracker, events, zap.NewNop())
+	enforcer.now = func() time.Time { return now }
+
+	statuses := enforcer.Evaluate()
+	if len(statuses) != 1 || statuses[0].Exceeded {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+	if math.Abs(statuses[0].ProjectedUSD-70) > 1e-9 {
+		t.Fatalf("expected projected 70, got %f", statuses[0].ProjectedUSD)
+	}
+	if !events.hasEventType("budget.forecast_alert") {
+		t.Fatal("expected budget.forecast_alert event")
+	}
+
+	enforcer.Evaluate()
+	count := 0
+	events.mu.Lock()
+	for _, event := range events.events {
+		if event.Type == "budget.forecast_alert" {
+			count++
+		}
+	}
+	events.mu.Unlock()
+	if count != 1 {
+		t.Fatalf("expected forecast alert once per period, got %d", count)
+	}
+}
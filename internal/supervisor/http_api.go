@@ -1,12 +1,16 @@
 package supervisor
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,6 +28,14 @@ type HTTPAPI struct {
 	hub           *Hub
 	oauth         *OAuthOrchestrator
 	costs         *CostAggregator
+	budgets       *BudgetEnforcer
+	deps          *DependencyScheduler
+	tasks         *TaskQueue
+	taskTracker   *TaskTracker
+	maintenance   *NodeMaintenance
+	enrollment    *NodeEnrollment
+	tokens        *APITokenStore
+	nodeCA        *NodeCA
 	db            *sql.DB
 	authToken     string
 	logger        *zap.Logger
@@ -74,22 +86,47 @@ func (a *HTTPAPI) Handler() http.Handler {
 	mux.HandleFunc("GET /readyz", a.handleReadiness)
 	mux.Handle("GET /metrics", promhttp.Handler())
 
-	mux.Handle("GET /api/v1/sessions", a.requireAuth(http.HandlerFunc(a.handleListSessions)))
-	mux.Handle("GET /api/v1/sessions/{id}", a.requireAuth(http.HandlerFunc(a.handleGetSession)))
-	mux.Handle("GET /api/v1/nodes", a.requireAuth(http.HandlerFunc(a.handleListNodes)))
-	mux.Handle("GET /api/v1/nodes/{id}", a.requireAuth(http.HandlerFunc(a.handleGetNode)))
-	mux.Handle("GET /api/v1/nodes/{id}/auth", a.requireAuth(http.HandlerFunc(a.handleNodeAuth)))
-	mux.Handle("GET /api/v1/auth/drift", a.requireAuth(http.HandlerFunc(a.handleAuthDrift)))
-	mux.Handle("GET /api/v1/events", a.requireAuth(http.HandlerFunc(a.handleListEvents)))
-	mux.Handle("GET /api/v1/cost", a.requireAuth(http.HandlerFunc(a.handleCostReport)))
-	mux.Handle("GET /api/v1/cost/{period}", a.requireAuth(http.HandlerFunc(a.handleCostReportByPath)))
-	mux.Handle("GET /api/v1/env/status/{project}", a.requireAuth(http.HandlerFunc(a.handleEnvStatus)))
-	mux.Handle("GET /api/v1/agentmd/diff/{project}", a.requireAuth(http.HandlerFunc(a.handleAgentMDDiff)))
+	mux.Handle("GET /api/v1/sessions", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleListSessions)))
+	mux.Handle("GET /api/v1/sessions/{id}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleGetSession)))
+	mux.Handle("GET /api/v1/sessions/{id}/history", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleSessionHistory)))
+	mux.Handle("GET /api/v1/nodes", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleListNodes)))
+	mux.Handle("GET /api/v1/nodes/{id}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleGetNode)))
+	mux.Handle("DELETE /api/v1/nodes/{id}", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleRemoveNode)))
+	mux.Handle("POST /api/v1/nodes/{id}/cordon", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleCordonNode)))
+	mux.Handle("POST /api/v1/nodes/{id}/uncordon", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleUncordonNode)))
+	mux.Handle("POST /api/v1/nodes/{id}/drain", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleDrainNode)))
+	mux.Handle("POST /api/v1/nodes/{id}/revoke", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleRevokeNode)))
+	mux.Handle("POST /api/v1/nodes/join-tokens", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleCreateJoinToken)))
+	mux.Handle("GET /api/v1/nodes/{id}/auth", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleNodeAuth)))
+	mux.Handle("GET /api/v1/auth/drift", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleAuthDrift)))
+	mux.Handle("GET /api/v1/events", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleListEvents)))
+	mux.Handle("GET /api/v1/cost", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleCostReport)))
+	mux.Handle("GET /api/v1/cost/budgets", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleBudgetStatus)))
+	mux.Handle("GET /api/v1/cost/export", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleCostExport)))
+	mux.Handle("GET /api/v1/cost/rates", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handlePricingRates)))
+	mux.Handle("POST /api/v1/cost/rates/validate", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handlePricingValidate)))
+	mux.Handle("GET /api/v1/cost/{period}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleCostReportByPath)))
+	mux.Handle("GET /api/v1/deps", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleDependencyGraph)))
+	mux.Handle("POST /api/v1/deps/{project}/milestones", a.requireScope(ScopeSessionsControl, http.HandlerFunc(a.handleRecordMilestone)))
+	mux.Handle("GET /api/v1/tasks", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleListTasks)))
+	mux.Handle("POST /api/v1/tasks", a.requireScope(ScopeSessionsControl, http.HandlerFunc(a.handleEnqueueTask)))
+	mux.Handle("GET /api/v1/tasks/{id}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleGetTask)))
+	mux.Handle("DELETE /api/v1/tasks/{id}", a.requireScope(ScopeSessionsControl, http.HandlerFunc(a.handleCancelTask)))
+	mux.Handle("GET /api/v1/env/status/{project}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleEnvStatus)))
+	mux.Handle("GET /api/v1/agentmd/diff/{project}", a.requireScope(ScopeSessionsRead, http.HandlerFunc(a.handleAgentMDDiff)))
+	// The scope a command needs depends on its type; see commandScope.
 	mux.Handle("POST /api/v1/commands", a.requireAuth(http.HandlerFunc(a.handleCommand)))
-	mux.Handle("POST /api/v1/commands/credentials/push", a.requireAuth(http.HandlerFunc(a.handleCredentialPush)))
-	mux.Handle("POST /api/v1/oauth/trigger", a.requireAuth(http.HandlerFunc(a.handleOAuthTrigger)))
-	if a.hub != nil {
+	mux.Handle("POST /api/v1/commands/credentials/push", a.requireScope(ScopeCredentialsPush, http.HandlerFunc(a.handleCredentialPush)))
+	mux.Handle("POST /api/v1/oauth/trigger", a.requireScope(ScopeCredentialsPush, http.HandlerFunc(a.handleOAuthTrigger)))
+	mux.Handle("GET /api/v1/tokens", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleListAPITokens)))
+	mux.Handle("POST /api/v1/tokens", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleCreateAPIToken)))
+	mux.Handle("DELETE /api/v1/tokens/{name}", a.requireScope(ScopeAdmin, http.HandlerFunc(a.handleRevokeAPIToken)))
+	// Agents may connect through this port too, except with mTLS: it does
+	// not request node certificates, so agents must use the WebSocket port,
+	// which also serves certificate renewal.
+	if a.hub != nil && a.nodeCA == nil {
 		mux.HandleFunc("GET /ws/agent", a.hub.ServeWS)
+		mux.HandleFunc("POST /ws/enroll", a.hub.ServeEnroll)
 	}
 
 	return mux
@@ -99,6 +136,46 @@ func (a *HTTPAPI) SetCostAggregator(aggregator *CostAggregator) {
 	a.costs = aggregator
 }
 
+func (a *HTTPAPI) SetBudgetEnforcer(enforcer *BudgetEnforcer) {
+	a.budgets = enforcer
+}
+
+func (a *HTTPAPI) SetDependencyScheduler(scheduler *DependencyScheduler) {
+	a.deps = scheduler
+}
+
+func (a *HTTPAPI) SetTaskQueue(queue *TaskQueue) {
+	a.tasks = queue
+}
+
+// SetTaskTracker adds each session's task roll-up to session responses.
+func (a *HTTPAPI) SetTaskTracker(tasks *TaskTracker) {
+	a.taskTracker = tasks
+}
+
+// SetNodeMaintenance enables the cordon, drain and remove node routes.
+func (a *HTTPAPI) SetNodeMaintenance(maintenance *NodeMaintenance) {
+	a.maintenance = maintenance
+}
+
+// SetNodeEnrollment enables the join token and revoke node routes.
+func (a *HTTPAPI) SetNodeEnrollment(enrollment *NodeEnrollment) {
+	a.enrollment = enrollment
+}
+
+// SetAPITokenStore accepts named API tokens alongside the shared auth token
+// and enables the token management routes.
+func (a *HTTPAPI) SetAPITokenStore(tokens *APITokenStore) {
+	a.tokens = tokens
+}
+
+// SetNodeCA adds the node CA's fingerprint to new join tokens so agents can
+// verify the supervisor before they have pinned the CA, and stops serving
+// the agent routes, which need node certificates. Call it before Handler.
+func (a *HTTPAPI) SetNodeCA(ca *NodeCA) {
+	a.nodeCA = ca
+}
+
 func (a *HTTPAPI) SetHealthChecker(hc *HealthChecker) {
 	a.healthChecker = hc
 }
@@ -131,22 +208,90 @@ type apiError struct {
 	Code  string `json:"code"`
 }
 
+// legacyAPIActor names the shared server.auth_token in the audit log.
+const legacyAPIActor = "api"
+
+type apiPrincipalKey struct{}
+
+// requireAuth accepts the shared auth token, which grants every scope, or an
+// active named API token, and records which one made the request.
 func (a *HTTPAPI) requireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := ""
 		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
 			token = strings.TrimPrefix(authHeader, "Bearer ")
 		}
-
-		if token == "" || token != a.authToken {
+		if token == "" {
 			writeError(w, http.StatusUnauthorized, "unauthorized", "AUTH_REQUIRED")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		principal := APIToken{Name: legacyAPIActor, Scopes: []APIScope{ScopeAdmin}}
+		if token != a.authToken {
+			if a.tokens == nil {
+				writeError(w, http.StatusUnauthorized, "unauthorized", "AUTH_REQUIRED")
+				return
+			}
+			var err error
+			principal, err = a.tokens.Authenticate(token)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAPIToken) {
+					a.logger.Error("api token authentication failed", zap.Error(err))
+				}
+				writeError(w, http.StatusUnauthorized, "unauthorized", "AUTH_REQUIRED")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiPrincipalKey{}, principal)))
 	})
 }
 
+// requireScope is requireAuth for routes that need scope.
+func (a *HTTPAPI) requireScope(scope APIScope, next http.Handler) http.Handler {
+	return a.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.permitted(w, r, scope) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// permitted writes a 403 and returns false when the request's token lacks
+// scope.
+func (a *HTTPAPI) permitted(w http.ResponseWriter, r *http.Request, scope APIScope) bool {
+	principal, _ := r.Context().Value(apiPrincipalKey{}).(APIToken)
+	if principal.HasScope(scope) {
+		return true
+	}
+	writeError(w, http.StatusForbidden, fmt.Sprintf("token %q lacks scope %s", principal.Name, scope), "FORBIDDEN")
+	return false
+}
+
+// apiActor names the token that made the request for the audit log: "api"
+// for the shared auth token, "token:<name>" for a named token.
+func apiActor(r *http.Request) string {
+	principal, ok := r.Context().Value(apiPrincipalKey{}).(APIToken)
+	if !ok || principal.Name == legacyAPIActor {
+		return legacyAPIActor
+	}
+	return "token:" + principal.Name
+}
+
+// commandScope is the scope needed to dispatch a command of type t.
+func commandScope(t CommandType) APIScope {
+	switch t {
+	case CommandTypeSessionStatus:
+		return ScopeSessionsRead
+	case CommandTypeCredentialPush, CommandTypeOAuthTrigger:
+		return ScopeCredentialsPush
+	case CommandTypeEnvCheck, CommandTypeEnvProvision, CommandTypeAgentMDDiff, CommandTypeAgentMDSync:
+		return ScopeEnvProvision
+	default:
+		return ScopeSessionsControl
+	}
+}
+
 type healthResponse struct {
 	Status     string            `json:"status"`
 	Components map[string]string `json:"components"`
@@ -227,25 +372,69 @@ func (a *HTTPAPI) checkDispatcherHealth() string {
 }
 
 type sessionJSON struct {
-	ID        string        `json:"id"`
-	NodeID    string        `json:"node_id"`
-	Project   string        `json:"project"`
-	Status    SessionStatus `json:"status"`
-	Tokens    int           `json:"tokens"`
-	Cost      float64       `json:"cost"`
-	StartedAt time.Time     `json:"started_at"`
+	ID             string        `json:"id"`
+	NodeID         string        `json:"node_id"`
+	Project        string        `json:"project"`
+	Status         SessionStatus `json:"status"`
+	Model          string        `json:"model,omitempty"`
+	Tokens         int           `json:"tokens"`
+	ContextTokens  int           `json:"context_tokens"`
+	ContextWindow  int           `json:"context_window,omitempty"`
+	ContextPercent float64       `json:"context_percent,omitempty"`
+	Cost           float64       `json:"cost"`
+	StartedAt      time.Time     `json:"started_at"`
+	ParentID       string        `json:"parent_session_id,omitempty"`
+	CurrentTask    string        `json:"current_task,omitempty"`
+	Task           *Task         `json:"task,omitempty"`
 }
 
 func toSessionJSON(s TrackedSession) sessionJSON {
-	return sessionJSON{
-		ID:        s.SessionID,
-		NodeID:    s.NodeID,
-		Project:   s.Project,
-		Status:    s.Status,
-		Tokens:    s.TokenUsage.Total,
-		Cost:      s.SessionCost,
-		StartedAt: s.StartedAt,
+	out := sessionJSON{
+		ID:            s.SessionID,
+		NodeID:        s.NodeID,
+		Project:       s.Project,
+		Status:        s.Status,
+		Model:         s.Model,
+		Tokens:        s.TokenUsage.Total,
+		ContextTokens: s.ContextUsed(),
+		ContextWindow: s.ContextWindow,
+		Cost:          s.SessionCost,
+		StartedAt:     s.StartedAt,
+		ParentID:      s.ParentSessionID,
+		CurrentTask:   s.CurrentTask,
+	}
+	if utilization, ok := s.ContextUtilization(); ok {
+		out.ContextPercent = math.Round(utilization*10) / 10
+	}
+	return out
+}
+
+// toSessionJSONWithTask adds the session's task roll-up when tasks are
+// tracked. rollups caches tasks already resolved for this response.
+func (a *HTTPAPI) toSessionJSONWithTask(s TrackedSession, rollups map[string]*Task) sessionJSON {
+	out := toSessionJSON(s)
+	if a.taskTracker == nil {
+		return out
+	}
+	taskID, err := a.taskTracker.taskIDForSession(s.SessionID)
+	if err == nil {
+		if cached, ok := rollups[taskID]; ok {
+			out.Task = cached
+			return out
+		}
+		var task Task
+		if task, err = a.taskTracker.Get(taskID); err == nil {
+			out.Task = &task
+			if rollups != nil {
+				rollups[taskID] = &task
+			}
+			return out
+		}
+	}
+	if !errors.Is(err, ErrTaskNotFound) {
+		a.logger.Warn("session task lookup failed", zap.String("session_id", s.SessionID), zap.Error(err))
 	}
+	return out
 }
 
 func (a *HTTPAPI) handleListSessions(w http.ResponseWriter, r *http.Request) {
@@ -263,6 +452,7 @@ func (a *HTTPAPI) handleListSessions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filtered := make([]sessionJSON, 0, len(sessions))
+	rollups := make(map[string]*Task)
 	for _, s := range sessions {
 		if status != "" && string(s.Status) != status {
 			continue
@@ -270,7 +460,7 @@ func (a *HTTPAPI) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		if nodeID != "" && s.NodeID != nodeID {
 			continue
 		}
-		filtered = append(filtered, toSessionJSON(s))
+		filtered = append(filtered, a.toSessionJSONWithTask(s, rollups))
 		if len(filtered) >= limit {
 			break
 		}
@@ -299,24 +489,69 @@ func (a *HTTPAPI) handleGetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, apiResponse{Data: toSessionJSON(session)})
+	writeJSON(w, http.StatusOK, apiResponse{Data: a.toSessionJSONWithTask(session, nil)})
+}
+
+type sessionHistoryJSON struct {
+	SessionID   string              `json:"session_id"`
+	Chain       []sessionJSON       `json:"chain"`
+	Transitions []SessionTransition `json:"transitions"`
+}
+
+func (a *HTTPAPI) handleSessionHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	history, err := a.tracker.History(id)
+	if err != nil {
+		if err == ErrSessionNotFound {
+			writeError(w, http.StatusNotFound, "session not found", "NOT_FOUND")
+			return
+		}
+		a.logger.Error("get session history failed", zap.String("session_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "internal error", "INTERNAL_ERROR")
+		return
+	}
+
+	out := sessionHistoryJSON{
+		SessionID:   history.SessionID,
+		Chain:       make([]sessionJSON, len(history.Chain)),
+		Transitions: history.Transitions,
+	}
+	for i, s := range history.Chain {
+		out.Chain[i] = toSessionJSON(s)
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Data: out})
 }
 
 type nodeJSON struct {
-	ID            string     `json:"id"`
-	Hostname      string     `json:"hostname"`
-	Status        NodeStatus `json:"status"`
-	LastHeartbeat time.Time  `json:"last_heartbeat"`
-	ConnectedAt   time.Time  `json:"connected_at"`
+	ID            string                `json:"id"`
+	Hostname      string                `json:"hostname"`
+	Status        NodeStatus            `json:"status"`
+	Lifecycle     NodeLifecycle         `json:"lifecycle"`
+	LastHeartbeat time.Time             `json:"last_heartbeat"`
+	ConnectedAt   time.Time             `json:"connected_at"`
+	Projects      []string              `json:"projects,omitempty"`
+	Labels        map[string]string     `json:"labels,omitempty"`
+	Inventory     *shared.NodeInventory `json:"inventory,omitempty"`
+	Telemetry     *NodeLoad             `json:"telemetry,omitempty"`
 }
 
 func toNodeJSON(n NodeEntry) nodeJSON {
+	var telemetry *NodeLoad
+	if !n.Load.ReportedAt.IsZero() {
+		load := n.Load
+		telemetry = &load
+	}
 	return nodeJSON{
 		ID:            n.ID,
 		Hostname:      n.Hostname,
 		Status:        n.Status,
+		Lifecycle:     n.Lifecycle,
 		LastHeartbeat: n.LastHeartbeat,
 		ConnectedAt:   n.ConnectedAt,
+		Projects:      n.Projects,
+		Labels:        n.Labels,
+		Inventory:     n.Inventory,
+		Telemetry:     telemetry,
 	}
 }
 
@@ -350,6 +585,244 @@ func (a *HTTPAPI) handleGetNode(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, apiResponse{Data: toNodeJSON(node)})
 }
 
+type drainNodeRequest struct {
+	TimeoutSeconds int  `json:"timeout_seconds"`
+	Handover       bool `json:"handover"`
+}
+
+type removeNodeJSON struct {
+	NodeID         string   `json:"node_id"`
+	PurgedSessions []string `json:"purged_sessions"`
+}
+
+func (a *HTTPAPI) handleCordonNode(w http.ResponseWriter, r *http.Request) {
+	a.changeNodeLifecycle(w, r, "cordon", func(id string) (NodeEntry, error) {
+		return a.maintenance.Cordon(id)
+	})
+}
+
+func (a *HTTPAPI) handleUncordonNode(w http.ResponseWriter, r *http.Request) {
+	a.changeNodeLifecycle(w, r, "uncordon", func(id string) (NodeEntry, error) {
+		return a.maintenance.Uncordon(id)
+	})
+}
+
+func (a *HTTPAPI) handleDrainNode(w http.ResponseWriter, r *http.Request) {
+	var req drainNodeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+	}
+	if req.TimeoutSeconds < 0 {
+		writeError(w, http.StatusBadRequest, "timeout_seconds must not be negative", "BAD_REQUEST")
+		return
+	}
+
+	opts := DrainOptions{Timeout: time.Duration(req.TimeoutSeconds) * time.Second, Handover: req.Handover}
+	a.changeNodeLifecycle(w, r, "drain", func(id string) (NodeEntry, error) {
+		return a.maintenance.Drain(id, opts)
+	})
+}
+
+func (a *HTTPAPI) changeNodeLifecycle(w http.ResponseWriter, r *http.Request, action string, change func(id string) (NodeEntry, error)) {
+	if a.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "node maintenance unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	id := r.PathValue("id")
+	node, err := change(id)
+	switch {
+	case errors.Is(err, ErrNodeNotFound):
+		writeError(w, http.StatusNotFound, "node not found", "NOT_FOUND")
+		return
+	case err != nil:
+		a.logger.Error("node "+action+" failed", zap.String("node_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to "+action+" node", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: toNodeJSON(node)})
+}
+
+func (a *HTTPAPI) handleRemoveNode(w http.ResponseWriter, r *http.Request) {
+	if a.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "node maintenance unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	id := r.PathValue("id")
+	purged, err := a.maintenance.Remove(id)
+	switch {
+	case errors.Is(err, ErrNodeNotFound):
+		writeError(w, http.StatusNotFound, "node not found", "NOT_FOUND")
+		return
+	case errors.Is(err, ErrNodeOnline):
+		writeError(w, http.StatusConflict, "node is online; stop its agent before removing it", "CONFLICT")
+		return
+	case err != nil:
+		a.logger.Error("remove node failed", zap.String("node_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to remove node", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: removeNodeJSON{NodeID: id, PurgedSessions: purged}})
+}
+
+type createJoinTokenRequest struct {
+	NodeID     string `json:"node_id"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+type revokeNodeJSON struct {
+	NodeID       string    `json:"node_id"`
+	RevokedAt    time.Time `json:"revoked_at"`
+	Disconnected bool      `json:"disconnected"`
+}
+
+func (a *HTTPAPI) handleCreateJoinToken(w http.ResponseWriter, r *http.Request) {
+	if a.enrollment == nil {
+		writeError(w, http.StatusServiceUnavailable, "node enrollment unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	var req createJoinTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+	}
+	if req.TTLSeconds < 0 {
+		writeError(w, http.StatusBadRequest, "ttl_seconds must not be negative", "BAD_REQUEST")
+		return
+	}
+
+	token, err := a.enrollment.CreateJoinToken(strings.TrimSpace(req.NodeID), time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		a.logger.Error("create join token failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to create join token", "INTERNAL_ERROR")
+		return
+	}
+	if a.nodeCA != nil {
+		token.CAFingerprint = a.nodeCA.Fingerprint()
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: token})
+}
+
+func (a *HTTPAPI) handleRevokeNode(w http.ResponseWriter, r *http.Request) {
+	if a.enrollment == nil {
+		writeError(w, http.StatusServiceUnavailable, "node enrollment unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	id := r.PathValue("id")
+	revokedAt, err := a.enrollment.Revoke(id)
+	switch {
+	case errors.Is(err, ErrNodeNotFound):
+		writeError(w, http.StatusNotFound, "node not found", "NOT_FOUND")
+		return
+	case err != nil:
+		a.logger.Error("revoke node failed", zap.String("node_id", id), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to revoke node", "INTERNAL_ERROR")
+		return
+	}
+
+	disconnected := false
+	if a.hub != nil {
+		disconnected = a.hub.DisconnectNode(id)
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: revokeNodeJSON{NodeID: id, RevokedAt: revokedAt, Disconnected: disconnected}})
+}
+
+type createAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (a *HTTPAPI) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	if a.tokens == nil {
+		writeError(w, http.StatusServiceUnavailable, "api tokens unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	tokens, err := a.tokens.List()
+	if err != nil {
+		a.logger.Error("list api tokens failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to list api tokens", "INTERNAL_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Data: tokens})
+}
+
+func (a *HTTPAPI) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if a.tokens == nil {
+		writeError(w, http.StatusServiceUnavailable, "api tokens unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+		return
+	}
+	scopes, err := ParseAPIScopes(req.Scopes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+
+	token, err := a.tokens.Create(strings.TrimSpace(req.Name), scopes)
+	switch {
+	case errors.Is(err, ErrAPITokenExists):
+		writeError(w, http.StatusConflict, "an active token with this name exists", "CONFLICT")
+		return
+	case errors.Is(err, ErrInvalidAPITokenName):
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	case err != nil:
+		a.logger.Error("create api token failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to create api token", "INTERNAL_ERROR")
+		return
+	}
+
+	a.logger.Info("api token created via api", zap.String("name", token.Name), zap.String("actor", apiActor(r)))
+	if a.auditLogger != nil {
+		a.auditLogger.LogAction(auditActionAPITokenCreate, token.Name,
+			map[string]interface{}{"scopes": joinScopes(token.Scopes)}, apiActor(r), r.RemoteAddr)
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Data: token})
+}
+
+func (a *HTTPAPI) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if a.tokens == nil {
+		writeError(w, http.StatusServiceUnavailable, "api tokens unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	name := r.PathValue("name")
+	token, err := a.tokens.Revoke(name)
+	switch {
+	case errors.Is(err, ErrAPITokenNotFound):
+		writeError(w, http.StatusNotFound, "api token not found", "NOT_FOUND")
+		return
+	case err != nil:
+		a.logger.Error("revoke api token failed", zap.String("name", name), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to revoke api token", "INTERNAL_ERROR")
+		return
+	}
+
+	a.logger.Info("api token revoked via api", zap.String("name", name), zap.String("actor", apiActor(r)))
+	if a.auditLogger != nil {
+		a.auditLogger.LogAction(auditActionAPITokenRevoke, name, nil, apiActor(r), r.RemoteAddr)
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Data: token})
+}
+
 type nodeAuthJSON struct {
 	NodeID            string                   `json:"node_id"`
 	AuthStates        map[string]NodeAuthState `json:"auth_states"`
@@ -488,6 +961,7 @@ func (a *HTTPAPI) handleListEvents(w http.ResponseWriter, r *http.Request) {
 type commandRequest struct {
 	Type           string                 `json:"type"`
 	Target         string                 `json:"target"`
+	Selector       string                 `json:"selector"`
 	Args           map[string]interface{} `json:"args"`
 	IdempotencyKey string                 `json:"idempotency_key"`
 	Timeout        int                    `json:"timeout"`
@@ -499,6 +973,26 @@ type commandResultJSON struct {
 	Output    string        `json:"output"`
 	Error     string        `json:"error,omitempty"`
 	Timestamp time.Time     `json:"timestamp"`
+	NodeID    string        `json:"node_id,omitempty"`
+	Placement string        `json:"placement,omitempty"`
+
+	Results []commandResultJSON `json:"results,omitempty"`
+}
+
+func toCommandResultJSON(result *CommandResult) commandResultJSON {
+	out := commandResultJSON{
+		CommandID: result.CommandID,
+		Status:    result.Status,
+		Output:    result.Output,
+		Error:     result.Error,
+		Timestamp: result.Timestamp,
+		NodeID:    result.NodeID,
+		Placement: result.Placement,
+	}
+	for i := range result.Results {
+		out.Results = append(out.Results, toCommandResultJSON(&result.Results[i]))
+	}
+	return out
 }
 
 type oauthTriggerRequest struct {
@@ -528,15 +1022,28 @@ func (a *HTTPAPI) handleCommand(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
 		return
 	}
+	if !a.permitted(w, r, commandScope(commandType)) {
+		return
+	}
 
 	correlationID := shared.GetCorrelationID(r.Context())
 	ctx := shared.WithCorrelationID(r.Context(), correlationID)
 
+	target := CommandTarget{Project: req.Target}
+	if req.Selector != "" {
+		labels, err := ParseLabelSelector(req.Selector)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+			return
+		}
+		target.Labels = labels
+	}
+
 	timeout := time.Duration(req.Timeout) * time.Second
 	cmd := Command{
 		Type:           commandType,
 		IdempotencyKey: req.IdempotencyKey,
-		Target:         CommandTarget{Project: req.Target},
+		Target:         target,
 		Args:           req.Args,
 		Timeout:        timeout,
 	}
@@ -546,7 +1053,12 @@ func (a *HTTPAPI) handleCommand(w http.ResponseWriter, r *http.Request) {
 
 	start := time.Now()
 	result, err := a.dispatcher.DispatchCommand(ctx, cmd)
-	duration := time.Since(start).Seconds()
+	elapsed := time.Since(start)
+	duration := elapsed.Seconds()
+
+	if a.auditLogger != nil {
+		a.auditLogger.LogCommand(cmd, result, apiActor(r), r.RemoteAddr, elapsed)
+	}
 
 	if err != nil {
 		shared.LogErrorWithContext(ctx, a.logger, "dispatch command failed", err)
@@ -564,13 +1076,7 @@ func (a *HTTPAPI) handleCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, apiResponse{
-		Data: commandResultJSON{
-			CommandID: result.CommandID,
-			Status:    result.Status,
-			Output:    result.Output,
-			Error:     result.Error,
-			Timestamp: result.Timestamp,
-		},
+		Data: toCommandResultJSON(result),
 	})
 }
 
@@ -636,7 +1142,7 @@ func (a *HTTPAPI) handleCredentialPush(w http.ResponseWriter, r *http.Request) {
 
 	// Audit the credential push command regardless of outcome
 	if a.auditLogger != nil {
-		a.auditLogger.LogCommand(cmd, result, "api", r.RemoteAddr, elapsed)
+		a.auditLogger.LogCommand(cmd, result, apiActor(r), r.RemoteAddr, elapsed)
 	}
 
 	if err != nil {
@@ -655,13 +1161,7 @@ func (a *HTTPAPI) handleCredentialPush(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, apiResponse{
-		Data: commandResultJSON{
-			CommandID: result.CommandID,
-			Status:    result.Status,
-			Output:    result.Output,
-			Error:     result.Error,
-			Timestamp: result.Timestamp,
-		},
+		Data: toCommandResultJSON(result),
 	})
 }
 
@@ -809,7 +1309,13 @@ func (a *HTTPAPI) handleCostReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	period := strings.ToLower(r.URL.Query().Get("period"))
+	query := r.URL.Query()
+	if query.Has("from") || query.Has("to") || query.Has("granularity") || query.Has("group_by") {
+		a.writeCostRangeReport(w, r)
+		return
+	}
+
+	period := strings.ToLower(query.Get("period"))
 	if period == "" {
 		period = "today"
 	}
@@ -845,6 +1351,333 @@ func (a *HTTPAPI) handleCostReportByPath(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, apiResponse{Data: report})
 }
 
+func (a *HTTPAPI) handleCostExport(w http.ResponseWriter, r *http.Request) {
+	if a.costs == nil {
+		writeError(w, http.StatusServiceUnavailable, "cost aggregator unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	switch format {
+	case "", "csv":
+	case "json":
+		a.writeCostRangeReport(w, r)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, "format must be csv or json", "BAD_REQUEST")
+		return
+	}
+
+	report, ok := a.costRangeReport(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cost-%s-%s-%s.csv\"", report.GroupBy, report.From, report.To))
+	w.WriteHeader(http.StatusOK)
+	if err := WriteCostCSV(w, report); err != nil {
+		a.logger.Warn("cost csv export failed", zap.Error(err))
+	}
+}
+
+func (a *HTTPAPI) writeCostRangeReport(w http.ResponseWriter, r *http.Request) {
+	report, ok := a.costRangeReport(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apiResponse{
+		Data: report,
+		Meta: &apiMeta{Total: len(report.Rows)},
+	})
+}
+
+// costRangeReport parses range parameters and builds the report, writing an
+// error response and returning false on failure.
+func (a *HTTPAPI) costRangeReport(w http.ResponseWriter, r *http.Request) (CostRangeReport, bool) {
+	values := r.URL.Query()
+	query, err := ParseCostRangeQuery(a.costs.now(), values.Get("from"), values.Get("to"), values.Get("granularity"), values.Get("group_by"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return CostRangeReport{}, false
+	}
+
+	report, err := a.costs.RangeReport(query)
+	if err != nil {
+		a.logger.Error("cost range report failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "internal error", "INTERNAL_ERROR")
+		return CostRangeReport{}, false
+	}
+	return report, true
+}
+
+// handlePricingRates lists the pricing catalog. With model set it resolves the
+// single rate in effect on date (default today) for the optional provider.
+func (a *HTTPAPI) handlePricingRates(w http.ResponseWriter, r *http.Request) {
+	if a.costs == nil {
+		writeError(w, http.StatusServiceUnavailable, "cost aggregator unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	catalog := a.costs.PricingCatalog()
+	query := r.URL.Query()
+	model := query.Get("model")
+	if model == "" {
+		entries := catalog.Entries()
+		if entries == nil {
+			entries = []PricingEntry{}
+		}
+		writeJSON(w, http.StatusOK, apiResponse{
+			Data: entries,
+			Meta: &apiMeta{Total: len(entries)},
+		})
+		return
+	}
+
+	at := a.costs.now()
+	if raw := query.Get("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD", "BAD_REQUEST")
+			return
+		}
+		at = parsed
+	}
+
+	entry, ok := catalog.RateAt(query.Get("provider"), model, at)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no catalog rate in effect for model", "NOT_FOUND")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Data: entry})
+}
+
+type pricingValidationResult struct {
+	Valid   bool           `json:"valid"`
+	Source  string         `json:"source"`
+	Entries int            `json:"entries"`
+	Issues  []PricingIssue `json:"issues"`
+}
+
+// handlePricingValidate validates a catalog posted in the body, or the
+// configured catalog file when the body is empty.
+func (a *HTTPAPI) handlePricingValidate(w http.ResponseWriter, r *http.Request) {
+	if a.costs == nil {
+		writeError(w, http.StatusServiceUnavailable, "cost aggregator unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+		return
+	}
+
+	source := "request"
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		path := a.costs.PricingCatalogPath()
+		if path == "" {
+			writeError(w, http.StatusNotFound, "no pricing catalog configured", "NOT_FOUND")
+			return
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			a.logger.Warn("read pricing catalog failed", zap.String("path", path), zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "pricing catalog unreadable", "INTERNAL_ERROR")
+			return
+		}
+		source = path
+	}
+
+	catalog, issues, err := ParsePricingCatalog(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: pricingValidationResult{
+		Valid:   len(issues) == 0,
+		Source:  source,
+		Entries: len(catalog.Entries()),
+		Issues:  issues,
+	}})
+}
+
+func (a *HTTPAPI) handleBudgetStatus(w http.ResponseWriter, r *http.Request) {
+	if a.budgets == nil {
+		writeError(w, http.StatusServiceUnavailable, "budget enforcement unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	statuses := a.budgets.Statuses()
+	writeJSON(w, http.StatusOK, apiResponse{
+		Data: statuses,
+		Meta: &apiMeta{Total: len(statuses)},
+	})
+}
+
+func (a *HTTPAPI) handleDependencyGraph(w http.ResponseWriter, r *http.Request) {
+	if a.deps == nil {
+		writeError(w, http.StatusServiceUnavailable, "dependency scheduling unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	statuses := a.deps.Status()
+	writeJSON(w, http.StatusOK, apiResponse{
+		Data: statuses,
+		Meta: &apiMeta{Total: len(statuses)},
+	})
+}
+
+type milestoneRequest struct {
+	Milestone string `json:"milestone"`
+	SessionID string `json:"session_id"`
+}
+
+type milestoneResponse struct {
+	Milestone ProjectMilestone `json:"milestone"`
+	Started   []string         `json:"started"`
+}
+
+// handleRecordMilestone marks a project milestone as completed, for projects
+// whose agents do not report milestones themselves, and starts dependents
+// that became ready.
+func (a *HTTPAPI) handleRecordMilestone(w http.ResponseWriter, r *http.Request) {
+	if a.deps == nil {
+		writeError(w, http.StatusServiceUnavailable, "dependency scheduling unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	var req milestoneRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+	}
+
+	milestone := ProjectMilestone{
+		Project:     r.PathValue("project"),
+		Milestone:   req.Milestone,
+		SessionID:   req.SessionID,
+		CompletedAt: time.Now().UTC(),
+	}
+	if !a.deps.graph.HasProject(milestone.Project) {
+		writeError(w, http.StatusNotFound, "project not in dependency graph", "NOT_FOUND")
+		return
+	}
+	started, err := a.deps.RecordMilestone(milestone)
+	if err != nil {
+		a.logger.Error("record milestone failed", zap.String("project", milestone.Project), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to record milestone", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: milestoneResponse{Milestone: milestone, Started: started}})
+}
+
+type enqueueTaskRequest struct {
+	Project  string     `json:"project"`
+	Prompt   string     `json:"prompt"`
+	Priority int        `json:"priority"`
+	Deadline *time.Time `json:"deadline"`
+	Model    string     `json:"model"`
+}
+
+func (a *HTTPAPI) handleEnqueueTask(w http.ResponseWriter, r *http.Request) {
+	if a.tasks == nil {
+		writeError(w, http.StatusServiceUnavailable, "task queue unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	var req enqueueTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+		return
+	}
+
+	task, err := a.tasks.Enqueue(QueuedTask{
+		Project:  req.Project,
+		Prompt:   req.Prompt,
+		Priority: req.Priority,
+		Deadline: req.Deadline,
+		Model:    req.Model,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: task})
+}
+
+func (a *HTTPAPI) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	if a.tasks == nil {
+		writeError(w, http.StatusServiceUnavailable, "task queue unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	status := TaskStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", TaskStatusQueued, TaskStatusDispatched, TaskStatusFailed, TaskStatusCancelled, TaskStatusExpired:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid status %q", status), "BAD_REQUEST")
+		return
+	}
+
+	tasks, err := a.tasks.List(r.URL.Query().Get("project"), status)
+	if err != nil {
+		a.logger.Error("list tasks failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to list tasks", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: tasks, Meta: &apiMeta{Total: len(tasks)}})
+}
+
+func (a *HTTPAPI) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	if a.tasks == nil {
+		writeError(w, http.StatusServiceUnavailable, "task queue unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	task, err := a.tasks.Get(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			writeError(w, http.StatusNotFound, "task not found", "NOT_FOUND")
+			return
+		}
+		a.logger.Error("get task failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get task", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: task})
+}
+
+func (a *HTTPAPI) handleCancelTask(w http.ResponseWriter, r *http.Request) {
+	if a.tasks == nil {
+		writeError(w, http.StatusServiceUnavailable, "task queue unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	task, err := a.tasks.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		writeError(w, http.StatusNotFound, "task not found", "NOT_FOUND")
+		return
+	case errors.Is(err, ErrTaskNotQueued):
+		writeError(w, http.StatusConflict, fmt.Sprintf("task is %s", task.Status), "CONFLICT")
+		return
+	case err != nil:
+		a.logger.Error("cancel task failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to cancel task", "INTERNAL_ERROR")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiResponse{Data: task})
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
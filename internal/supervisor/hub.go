@@ -2,7 +2,9 @@ package supervisor
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
@@ -44,6 +46,11 @@ type Hub struct {
 	expectedCredVersion int64
 	commandDispatcher   *CommandDispatcher
 	nodeRegistry        *NodeRegistry
+	sessionTracker      *SessionTracker
+	dependencyScheduler *DependencyScheduler
+	taskTracker         *TaskTracker
+	enrollment          *NodeEnrollment
+	nodeCA              *NodeCA
 }
 
 func NewHub(
@@ -140,6 +147,9 @@ func (h *Hub) Run() {
 }
 
 // ServeWS handles WebSocket upgrade requests with token auth (header or query param).
+// With enrollment configured, a node that has a credential must present it
+// instead of the shared token, and a revoked node is refused. With a node CA
+// configured, the agent must also present the certificate issued to it.
 func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 	token := ""
 	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
@@ -148,22 +158,36 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 		token = r.URL.Query().Get("token")
 	}
 
-	h.mu.RLock()
-	currentToken := h.authToken
-	h.mu.RUnlock()
-
-	if token != currentToken {
+	agentID := strings.TrimSpace(r.Header.Get("X-Node-ID"))
+	if err := h.authenticateAgent(agentID, token); err != nil {
+		if errors.Is(err, ErrNodeRevoked) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !errors.Is(err, ErrInvalidNodeCredential) && !errors.Is(err, ErrNodeNotEnrolled) {
+			h.logger.Error("agent authentication failed", zap.String("agent_id", agentID), zap.Error(err))
+		}
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	h.mu.RLock()
+	nodeCA := h.nodeCA
+	h.mu.RUnlock()
+	if nodeCA != nil {
+		if err := nodeCA.VerifyNode(r, agentID); err != nil {
+			h.logger.Warn("agent refused without node certificate", zap.String("agent_id", agentID), zap.Error(err))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		h.logger.Error("websocket upgrade failed", zap.Error(err))
 		return
 	}
 
-	agentID := strings.TrimSpace(r.Header.Get("X-Node-ID"))
 	if agentID == "" {
 		agentID = uuid.New().String()
 	}
@@ -174,6 +198,192 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 	go agent.readPump()
 }
 
+// authenticateAgent checks the token an agent connects with. Without
+// enrollment the shared token is accepted. With enrollment it is accepted
+// only for a node that has not enrolled, while credentials are not required
+// and no node has enrolled or been revoked yet.
+func (h *Hub) authenticateAgent(nodeID, token string) error {
+	h.mu.RLock()
+	sharedToken := h.authToken
+	enrollment := h.enrollment
+	h.mu.RUnlock()
+
+	if enrollment == nil {
+		if token != sharedToken {
+			return ErrInvalidNodeCredential
+		}
+		return nil
+	}
+
+	err := enrollment.Authenticate(nodeID, token)
+	if !errors.Is(err, ErrNodeNotEnrolled) || enrollment.RequireCredentials() || token != sharedToken {
+		return err
+	}
+	inUse, useErr := enrollment.InUse()
+	if useErr != nil {
+		return useErr
+	}
+	if inUse {
+		return err
+	}
+	return nil
+}
+
+type enrollRequest struct {
+	JoinToken string `json:"join_token"`
+	NodeID    string `json:"node_id"`
+	CSR       string `json:"csr,omitempty"`
+}
+
+type enrollResponse struct {
+	NodeID        string `json:"node_id"`
+	Credential    string `json:"credential,omitempty"`
+	Certificate   string `json:"certificate,omitempty"`
+	CACertificate string `json:"ca_certificate,omitempty"`
+}
+
+// ServeEnroll exchanges an agent's join token for its node credential and,
+// with a node CA configured, signs the certificate request sent with it.
+func (h *Hub) ServeEnroll(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	enrollment := h.enrollment
+	nodeCA := h.nodeCA
+	h.mu.RUnlock()
+	if enrollment == nil {
+		writeError(w, http.StatusServiceUnavailable, "enrollment unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	var req enrollRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+		return
+	}
+	req.NodeID = strings.TrimSpace(req.NodeID)
+	if req.NodeID == "" {
+		writeError(w, http.StatusBadRequest, "node_id is required", "BAD_REQUEST")
+		return
+	}
+
+	// Sign only once the join token is checked, so the CA never signs for an
+	// unauthenticated caller. A CSR that cannot be signed leaves the token
+	// unused.
+	resp := enrollResponse{NodeID: req.NodeID}
+	if nodeCA != nil && req.CSR == "" {
+		writeError(w, http.StatusBadRequest, "csr is required", "BAD_REQUEST")
+		return
+	}
+	var signErr error
+	credential, err := enrollment.EnrollWith(req.JoinToken, req.NodeID, func() error {
+		if nodeCA == nil {
+			return nil
+		}
+		certPEM, err := nodeCA.SignNodeCSR(req.NodeID, []byte(req.CSR))
+		if err != nil {
+			signErr = err
+			return err
+		}
+		resp.Certificate = string(certPEM)
+		resp.CACertificate = string(nodeCA.CertificatePEM())
+		return nil
+	})
+	switch {
+	case signErr != nil:
+		writeError(w, http.StatusBadRequest, signErr.Error(), "BAD_REQUEST")
+		return
+	case errors.Is(err, ErrInvalidJoinToken):
+		h.logger.Warn("enrollment refused", zap.String("node_id", req.NodeID), zap.String("remote_addr", r.RemoteAddr))
+		writeError(w, http.StatusUnauthorized, "invalid join token", "AUTH_REQUIRED")
+		return
+	case errors.Is(err, ErrNodeRevoked):
+		writeError(w, http.StatusForbidden, "node is revoked", "FORBIDDEN")
+		return
+	case errors.Is(err, ErrNodeEnrolled):
+		h.logger.Warn("enrollment refused for enrolled node", zap.String("node_id", req.NodeID), zap.String("remote_addr", r.RemoteAddr))
+		writeError(w, http.StatusConflict, "node is already enrolled; remove or revoke it, or use a join token bound to it", "CONFLICT")
+		return
+	case err != nil:
+		h.logger.Error("enrollment failed", zap.String("node_id", req.NodeID), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "enrollment failed", "INTERNAL_ERROR")
+		return
+	}
+
+	resp.Credential = credential
+	writeJSON(w, http.StatusOK, apiResponse{Data: resp})
+}
+
+type renewRequest struct {
+	CSR string `json:"csr"`
+}
+
+// ServeRenew issues a fresh node certificate to an agent that presents its
+// current certificate and node credential, so it can rotate before expiry.
+func (h *Hub) ServeRenew(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	enrollment := h.enrollment
+	nodeCA := h.nodeCA
+	h.mu.RUnlock()
+	if enrollment == nil || nodeCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "certificate renewal unavailable", "SERVICE_UNAVAILABLE")
+		return
+	}
+
+	nodeID := strings.TrimSpace(r.Header.Get("X-Node-ID"))
+	if err := nodeCA.VerifyNode(r, nodeID); err != nil {
+		writeError(w, http.StatusUnauthorized, "node certificate required", "AUTH_REQUIRED")
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if err := enrollment.Authenticate(nodeID, token); err != nil {
+		if errors.Is(err, ErrNodeRevoked) {
+			writeError(w, http.StatusForbidden, "node is revoked", "FORBIDDEN")
+			return
+		}
+		if !errors.Is(err, ErrInvalidNodeCredential) && !errors.Is(err, ErrNodeNotEnrolled) {
+			h.logger.Error("certificate renewal failed", zap.String("node_id", nodeID), zap.Error(err))
+		}
+		writeError(w, http.StatusUnauthorized, "invalid node credential", "AUTH_REQUIRED")
+		return
+	}
+
+	var req renewRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+		return
+	}
+	certPEM, err := nodeCA.SignNodeCSR(nodeID, []byte(req.CSR))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+
+	h.logger.Info("node certificate renewed", zap.String("node_id", nodeID))
+	writeJSON(w, http.StatusOK, apiResponse{Data: enrollResponse{
+		NodeID:        nodeID,
+		Certificate:   string(certPEM),
+		CACertificate: string(nodeCA.CertificatePEM()),
+	}})
+}
+
+// DisconnectNode closes a node's connection, reporting whether it was
+// connected.
+func (h *Hub) DisconnectNode(nodeID string) bool {
+	h.mu.RLock()
+	conn, ok := h.clients[nodeID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	conn.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "node revoked"),
+		time.Now().Add(writeWait),
+	)
+	conn.conn.Close()
+	return true
+}
+
 func (h *Hub) Events() <-chan HubEvent {
 	return h.events
 }
@@ -259,6 +469,42 @@ func (h *Hub) ConfigureNodeRegistry(registry *NodeRegistry) {
 	h.nodeRegistry = registry
 }
 
+func (h *Hub) ConfigureSessionTracker(tracker *SessionTracker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessionTracker = tracker
+}
+
+func (h *Hub) ConfigureDependencyScheduler(scheduler *DependencyScheduler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dependencyScheduler = scheduler
+}
+
+// ConfigureNodeEnrollment authenticates agents by their per-node
+// credential and serves enrollment.
+func (h *Hub) ConfigureNodeEnrollment(enrollment *NodeEnrollment) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enrollment = enrollment
+}
+
+// ConfigureNodeCA requires agents to present a certificate issued by ca and
+// has enrollment and renewal sign node certificates.
+func (h *Hub) ConfigureNodeCA(ca *NodeCA) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodeCA = ca
+}
+
+// ConfigureTaskTracker records acceptance criteria and completions reported
+// by agents' progress events on first-class tasks.
+func (h *Hub) ConfigureTaskTracker(tasks *TaskTracker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.taskTracker = tasks
+}
+
 func (h *Hub) reconcileCredentialSync(payload []byte) {
 	h.mu.RLock()
 	registry := h.credentialRegistry
@@ -291,6 +537,90 @@ func (h *Hub) reconcileAuthState(nodeID string, payload []byte) {
 	}
 }
 
+// recordNodeInfo stores the hostname, projects and labels an agent reports
+// when it registers.
+func (h *Hub) recordNodeInfo(nodeID string, payload []byte) {
+	h.mu.RLock()
+	registry := h.nodeRegistry
+	h.mu.RUnlock()
+
+	if registry == nil || len(payload) == 0 || string(payload) == "null" {
+		return
+	}
+
+	var info shared.NodeInfo
+	if err := json.Unmarshal(payload, &info); err != nil {
+		h.logger.Warn("invalid register payload",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return
+	}
+	if err := registry.UpdateNodeInfo(nodeID, info); err != nil {
+		h.logger.Warn("record node info failed",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return
+	}
+	if info.Inventory != nil {
+		GetMetrics().SetNodeInventory(nodeID, *info.Inventory)
+	}
+}
+
+// recordHeartbeat stores the resource usage and inventory carried by a
+// heartbeat, if any, and publishes them as metrics.
+func (h *Hub) recordHeartbeat(nodeID string, payload []byte) {
+	h.mu.RLock()
+	registry := h.nodeRegistry
+	h.mu.RUnlock()
+
+	if registry == nil || len(payload) == 0 || string(payload) == "null" {
+		return
+	}
+
+	var heartbeat shared.HeartbeatPayload
+	if err := json.Unmarshal(payload, &heartbeat); err != nil {
+		h.logger.Warn("invalid heartbeat payload",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return
+	}
+	load := NodeLoad{
+		CPUPercent:           heartbeat.CPUPercent,
+		MemoryPercent:        heartbeat.MemoryPercent,
+		Load1:                heartbeat.Load1,
+		Load5:                heartbeat.Load5,
+		Load15:               heartbeat.Load15,
+		MemoryTotalBytes:     heartbeat.MemoryTotalBytes,
+		MemoryAvailableBytes: heartbeat.MemoryAvailableBytes,
+		Disks:                heartbeat.Disks,
+	}
+	if err := registry.UpdateLoad(nodeID, load); err != nil {
+		if !errors.Is(err, ErrNodeNotFound) {
+			h.logger.Warn("record node load failed",
+				zap.String("node_id", nodeID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+	GetMetrics().SetNodeTelemetry(nodeID, load)
+
+	if heartbeat.Inventory == nil {
+		return
+	}
+	if err := registry.UpdateInventory(nodeID, *heartbeat.Inventory); err != nil {
+		h.logger.Warn("record node inventory failed",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return
+	}
+	GetMetrics().SetNodeInventory(nodeID, *heartbeat.Inventory)
+}
+
 func (h *Hub) handleCommandResultEnvelope(env *shared.Envelope) {
 	h.mu.RLock()
 	dispatcher := h.commandDispatcher
@@ -305,6 +635,67 @@ func (h *Hub) handleCommandResultEnvelope(env *shared.Envelope) {
 	}
 }
 
+// ingestSessionEvent applies message token usage and the current task
+// carried by an agent event to the session tracker, and hands milestone
+// events to the dependency scheduler. Other events are ignored.
+func (h *Hub) ingestSessionEvent(payload []byte) {
+	h.mu.RLock()
+	tracker := h.sessionTracker
+	scheduler := h.dependencyScheduler
+	tasks := h.taskTracker
+	h.mu.RUnlock()
+
+	if scheduler != nil {
+		scheduler.HandleEvent(payload)
+	}
+	if tracker == nil {
+		return
+	}
+
+	if progress, ok := parseProgressEvent(payload); ok {
+		if progress.Project != "" && progress.CurrentTask != "" {
+			if err := tracker.SetProjectTask(progress.Project, progress.CurrentTask); err != nil {
+				h.logger.Debug("current task ingest skipped",
+					zap.String("project", progress.Project),
+					zap.Error(err),
+				)
+			}
+		}
+		if tasks != nil && progress.Project != "" {
+			h.applyTaskProgress(tasks, progress)
+		}
+		return
+	}
+
+	report, ok := parseTokenUsageEvent(payload)
+	if !ok {
+		return
+	}
+
+	if err := tracker.RecordMessageUsage(report.SessionID, report.MessageID, report.Model, report.Usage); err != nil {
+		h.logger.Debug("session usage ingest skipped",
+			zap.String("session_id", report.SessionID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (h *Hub) applyTaskProgress(tasks *TaskTracker, progress progressEvent) {
+	var err error
+	switch {
+	case progress.Type == EventTypeTaskCompleted && progress.Task != "":
+		_, err = tasks.Complete(progress.Project, progress.Task, "")
+	case progress.Type == EventTypeTaskUpdated && progress.AcceptanceCriteria != "":
+		err = tasks.SetAcceptanceCriteria(progress.Project, progress.CurrentTask, progress.AcceptanceCriteria)
+	}
+	if err != nil {
+		h.logger.Debug("task progress ingest skipped",
+			zap.String("project", progress.Project),
+			zap.Error(err),
+		)
+	}
+}
+
 func (h *Hub) checkHeartbeats() {
 	timeout := h.heartbeatInterval * time.Duration(h.heartbeatTimeout)
 	now := time.Now()
@@ -3,6 +3,7 @@ package supervisor
 import (
 	"sync"
 
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -20,6 +21,15 @@ type Metrics struct {
 	SessionsActive    prometheus.GaugeVec
 	NodesOnline       prometheus.Gauge
 
+	// Node telemetry and inventory, by node
+	NodeCPUPercent           prometheus.GaugeVec
+	NodeMemoryPercent        prometheus.GaugeVec
+	NodeMemoryAvailableBytes prometheus.GaugeVec
+	NodeLoad1                prometheus.GaugeVec
+	NodeDiskFreeBytes        prometheus.GaugeVec
+	NodeInfo                 prometheus.GaugeVec
+	NodeToolInfo             prometheus.GaugeVec
+
 	// Histograms
 	CommandDuration         prometheus.HistogramVec
 	EventProcessingDuration prometheus.HistogramVec
@@ -83,6 +93,55 @@ func InitMetrics() *Metrics {
 					Help: "Current online nodes",
 				},
 			),
+			NodeCPUPercent: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_cpu_percent",
+					Help: "CPU usage reported by each node",
+				},
+				[]string{"node"},
+			),
+			NodeMemoryPercent: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_memory_percent",
+					Help: "Memory usage reported by each node",
+				},
+				[]string{"node"},
+			),
+			NodeMemoryAvailableBytes: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_memory_available_bytes",
+					Help: "Available memory reported by each node",
+				},
+				[]string{"node"},
+			),
+			NodeLoad1: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_load1",
+					Help: "One-minute load average reported by each node",
+				},
+				[]string{"node"},
+			),
+			NodeDiskFreeBytes: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_disk_free_bytes",
+					Help: "Free disk space under each project directory",
+				},
+				[]string{"node", "project"},
+			),
+			NodeInfo: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_info",
+					Help: "Node platform and agent version (always 1)",
+				},
+				[]string{"node", "os", "kernel", "arch", "agent_version"},
+			),
+			NodeToolInfo: *promauto.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "hal_o_swarm_node_tool_info",
+					Help: "Tool versions installed on each node (always 1)",
+				},
+				[]string{"node", "tool", "version"},
+			),
 			CommandDuration: *promauto.NewHistogramVec(
 				prometheus.HistogramOpts{
 					Name:    "hal_o_swarm_command_duration_seconds",
@@ -176,6 +235,54 @@ func (m *Metrics) SetOnlineNodes(count int64) {
 	m.NodesOnline.Set(float64(count))
 }
 
+// SetNodeTelemetry sets a node's resource usage gauges, dropping disks it no
+// longer reports.
+func (m *Metrics) SetNodeTelemetry(nodeID string, load NodeLoad) {
+	if m == nil {
+		return
+	}
+	m.NodeCPUPercent.WithLabelValues(nodeID).Set(load.CPUPercent)
+	m.NodeMemoryPercent.WithLabelValues(nodeID).Set(load.MemoryPercent)
+	m.NodeMemoryAvailableBytes.WithLabelValues(nodeID).Set(float64(load.MemoryAvailableBytes))
+	m.NodeLoad1.WithLabelValues(nodeID).Set(load.Load1)
+	m.NodeDiskFreeBytes.DeletePartialMatch(prometheus.Labels{"node": nodeID})
+	for _, disk := range load.Disks {
+		m.NodeDiskFreeBytes.WithLabelValues(nodeID, disk.Project).Set(float64(disk.FreeBytes))
+	}
+}
+
+// SetNodeInventory replaces a node's platform and tool version series.
+func (m *Metrics) SetNodeInventory(nodeID string, inventory shared.NodeInventory) {
+	if m == nil {
+		return
+	}
+	m.NodeInfo.DeletePartialMatch(prometheus.Labels{"node": nodeID})
+	m.NodeInfo.WithLabelValues(nodeID, inventory.OS, inventory.Kernel, inventory.Arch, inventory.AgentVersion).Set(1)
+	m.NodeToolInfo.DeletePartialMatch(prometheus.Labels{"node": nodeID})
+	for tool, version := range inventory.Tools {
+		m.NodeToolInfo.WithLabelValues(nodeID, tool, version).Set(1)
+	}
+}
+
+// DeleteNode drops every series of a removed node.
+func (m *Metrics) DeleteNode(nodeID string) {
+	if m == nil {
+		return
+	}
+	labels := prometheus.Labels{"node": nodeID}
+	for _, vec := range []*prometheus.GaugeVec{
+		&m.NodeCPUPercent,
+		&m.NodeMemoryPercent,
+		&m.NodeMemoryAvailableBytes,
+		&m.NodeLoad1,
+		&m.NodeDiskFreeBytes,
+		&m.NodeInfo,
+		&m.NodeToolInfo,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
 // RecordError records an error
 func (m *Metrics) RecordError(component string, errorType string) {
 	if m == nil {
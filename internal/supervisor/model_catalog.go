@@ -0,0 +1,91 @@
+package supervisor
+
+import (
+	"strings"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// defaultModelInfo lists context windows for common models. Headroom matches
+// each model's maximum output, which is what agents reserve before they
+// auto-compact.
+var defaultModelInfo = map[string]config.ModelInfo{
+	"claude-opus-4":     {ContextWindow: 200000, CompactionHeadroom: 32000},
+	"claude-sonnet-4":   {ContextWindow: 200000, CompactionHeadroom: 64000},
+	"claude-3-7-sonnet": {ContextWindow: 200000, CompactionHeadroom: 64000},
+	"claude-3-5-sonnet": {ContextWindow: 200000, CompactionHeadroom: 8192},
+	"claude-3-5-haiku":  {ContextWindow: 200000, CompactionHeadroom: 8192},
+	"gpt-4o":            {ContextWindow: 128000, CompactionHeadroom: 16384},
+	"gpt-4.1":           {ContextWindow: 1047576, CompactionHeadroom: 32768},
+	"o3":                {ContextWindow: 200000, CompactionHeadroom: 100000},
+	"gemini-1.5-pro":    {ContextWindow: 2097152, CompactionHeadroom: 8192},
+	"gemini-2.5-pro":    {ContextWindow: 1048576, CompactionHeadroom: 65536},
+}
+
+// ModelCatalog resolves model names to context metadata. Configured entries
+// override the built-in defaults.
+type ModelCatalog struct {
+	models map[string]config.ModelInfo
+}
+
+func NewModelCatalog(overrides map[string]config.ModelInfo) *ModelCatalog {
+	models := make(map[string]config.ModelInfo, len(defaultModelInfo)+len(overrides))
+	for name, info := range defaultModelInfo {
+		models[name] = info
+	}
+	for name, info := range overrides {
+		models[name] = info
+	}
+	return &ModelCatalog{models: models}
+}
+
+// Lookup finds model by exact name, then without a "provider/" prefix, then
+// by the longest catalog name that prefixes it as a dated or tagged variant
+// (claude-sonnet-4-20250514, claude-sonnet-4@20250514).
+func (c *ModelCatalog) Lookup(model string) (config.ModelInfo, bool) {
+	if c == nil || model == "" {
+		return config.ModelInfo{}, false
+	}
+	if info, ok := c.models[model]; ok {
+		return info, true
+	}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+		if info, ok := c.models[model]; ok {
+			return info, true
+		}
+	}
+
+	best := ""
+	for name := range c.models {
+		if len(name) <= len(best) || !strings.HasPrefix(model, name) {
+			continue
+		}
+		if sep := model[len(name)]; sep == '-' || sep == '@' || sep == ':' {
+			best = name
+		}
+	}
+	if best == "" {
+		return config.ModelInfo{}, false
+	}
+	return c.models[best], true
+}
+
+// ContextUsed returns the tokens occupying the session's context: the size
+// of the most recent request when the agent reports per-message usage,
+// otherwise the session's total usage.
+func (s TrackedSession) ContextUsed() int {
+	if s.ContextTokens > 0 {
+		return s.ContextTokens
+	}
+	return s.TokenUsage.Total
+}
+
+// ContextUtilization returns the percentage of the model's context window in
+// use. It reports false when the session's model has no known window.
+func (s TrackedSession) ContextUtilization() (float64, bool) {
+	if s.ContextWindow <= 0 {
+		return 0, false
+	}
+	return float64(s.ContextUsed()) * 100 / float64(s.ContextWindow), true
+}
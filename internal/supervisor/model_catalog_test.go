@@ -0,0 +1,146 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestModelCatalogLookup(t *testing.T) {
+	catalog := NewModelCatalog(map[string]config.ModelInfo{
+		"llama-3-70b": {ContextWindow: 8192},
+		"gpt-4o":      {ContextWindow: 64000},
+	})
+
+	cases := map[string]int{
+		"claude-sonnet-4":                  200000,
+		"claude-sonnet-4-20250514":         200000,
+		"claude-sonnet-4@20250514":         200000,
+		"anthropic/claude-opus-4":          200000,
+		"llama-3-70b":                      8192,
+		"gpt-4o":                           64000,
+		"openrouter/google/gemini-2.5-pro": 1048576,
+		"gemini-2.5-pro-preview-05-06":     1048576,
+	}
+	for model, want := range cases {
+		info, ok := catalog.Lookup(model)
+		if !ok || info.ContextWindow != want {
+			t.Fatalf("%s: expected window %d, got %+v ok=%v", model, want, info, ok)
+		}
+	}
+
+	for _, model := range []string{"", "claude-sonnet-40", "mystery-model"} {
+		if _, ok := catalog.Lookup(model); ok {
+			t.Fatalf("expected no match for %q", model)
+		}
+	}
+}
+
+func TestTrackedSessionContextUtilization(t *testing.T) {
+	session := TrackedSession{TokenUsage: TokenUsage{Total: 500000}, ContextTokens: 45231, ContextWindow: 200000}
+	utilization, ok := session.ContextUtilization()
+	if !ok || session.ContextUsed() != 45231 || utilization < 22.6 || utilization > 22.7 {
+		t.Fatalf("expected 22.6%% from the last request size, got %f ok=%v", utilization, ok)
+	}
+
+	session.ContextTokens = 0
+	if session.ContextUsed() != 500000 {
+		t.Fatalf("expected fallback to total usage, got %d", session.ContextUsed())
+	}
+
+	if _, ok := (TrackedSession{TokenUsage: TokenUsage{Total: 10}}).ContextUtilization(); ok {
+		t.Fatal("expected unknown window to report no utilization")
+	}
+}
+
+func TestSessionTrackerResolvesContextWindow(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	tracker.SetModelCatalog(NewModelCatalog(map[string]config.ModelInfo{"llama-3-70b": {ContextWindow: 8192, CompactionHeadroom: 1024}}))
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Model: "llama-3-70b"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-1", "msg-1", "", TokenUsage{Prompt: 1000, CacheRead: 3000, Completion: 96}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-1", "msg-2", "", TokenUsage{Prompt: 100, CacheRead: 4000, Completion: 4}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+
+	session, err := tracker.GetSession("s-1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if session.ContextWindow != 8192 || session.CompactionHeadroom != 1024 || session.ContextTokens != 4104 {
+		t.Fatalf("unexpected context fields %+v", session)
+	}
+	if all := tracker.GetAllSessions(); len(all) != 1 || all[0].ContextWindow != 8192 {
+		t.Fatalf("expected GetAllSessions to resolve the window, got %+v", all)
+	}
+	if byProject := tracker.GetSessionsByProject("proj-a"); len(byProject) != 1 || byProject[0].ContextWindow != 8192 {
+		t.Fatalf("expected GetSessionsByProject to resolve the window, got %+v", byProject)
+	}
+}
+
+func TestCompactionThresholdReached(t *testing.T) {
+	policy := config.CompactionPolicyConfig{TokenThreshold: 180000, ContextPercent: 80}
+
+	known := TrackedSession{ContextTokens: 170000, ContextWindow: 200000}
+	if !compactionThresholdReached(policy, known) {
+		t.Fatal("expected 85% of the window to reach an 80% threshold")
+	}
+	known.ContextTokens = 150000
+	if compactionThresholdReached(policy, known) {
+		t.Fatal("expected 75% of the window to stay under an 80% threshold")
+	}
+
+	// Without a known window the absolute threshold still applies.
+	unknown := TrackedSession{TokenUsage: TokenUsage{Total: 190000}}
+	if !compactionThresholdReached(policy, unknown) {
+		t.Fatal("expected token threshold fallback for unknown models")
+	}
+
+	policy.ContextPercent = 0
+	known.TokenUsage.Total = 100000
+	if compactionThresholdReached(policy, known) {
+		t.Fatal("expected token threshold when context_percent is unset")
+	}
+}
+
+func TestHTTPAPISessionContextFields(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+
+	if _, err := api.db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-ctx", "node-ctx", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-ctx", NodeID: "n-ctx", Project: "proj-ctx", Model: "claude-sonnet-4-20250514"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-ctx", "msg-1", "", TokenUsage{Prompt: 231, CacheRead: 45000}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	api.Handler().ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/sessions/s-ctx", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data sessionJSON `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Data.ContextTokens != 45231 || resp.Data.ContextWindow != 200000 || resp.Data.ContextPercent != 22.6 {
+		t.Fatalf("unexpected context fields %+v", resp.Data)
+	}
+}
@@ -0,0 +1,292 @@
+package supervisor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	caCertFile     = "ca.pem"
+	caKeyFile      = "ca-key.pem"
+	caValidity     = 10 * 365 * 24 * time.Hour
+	caCommonName   = "hal-o-swarm node CA"
+	nodeCertPrefix = "node:"
+)
+
+// ErrClientCertRequired is returned when an agent connects without a
+// certificate issued to it by the node CA.
+var ErrClientCertRequired = errors.New("node client certificate required")
+
+// NodeCA is the supervisor's internal certificate authority. It signs node
+// certificates from agents' CSRs at enrollment and renewal, and issues the
+// WebSocket port's own server certificate, re-issuing it before it expires.
+// Only the CA key is kept on disk.
+type NodeCA struct {
+	cfg    config.MTLSConfig
+	cert   *x509.Certificate
+	pem    []byte
+	key    *ecdsa.PrivateKey
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu         sync.Mutex
+	serverCert *tls.Certificate
+}
+
+// LoadOrCreateNodeCA loads the CA from cfg.CADir, creating it on first use.
+func LoadOrCreateNodeCA(cfg config.MTLSConfig, logger *zap.Logger) (*NodeCA, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	ca := &NodeCA{cfg: cfg, logger: logger, now: time.Now}
+
+	certPath := filepath.Join(cfg.CADir, caCertFile)
+	keyPath := filepath.Join(cfg.CADir, caKeyFile)
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	switch {
+	case certErr == nil && keyErr == nil:
+		if err := ca.load(certPEM, keyPEM); err != nil {
+			return nil, fmt.Errorf("load node CA: %w", err)
+		}
+	case errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist):
+		if err := ca.create(certPath, keyPath); err != nil {
+			return nil, fmt.Errorf("create node CA: %w", err)
+		}
+		logger.Info("node CA created", zap.String("dir", cfg.CADir), zap.String("fingerprint", ca.Fingerprint()))
+	case certErr != nil:
+		return nil, fmt.Errorf("load node CA: %w", certErr)
+	default:
+		return nil, fmt.Errorf("load node CA: %w", keyErr)
+	}
+
+	if ca.now().After(ca.cert.NotAfter) {
+		return nil, fmt.Errorf("node CA expired at %s", ca.cert.NotAfter.Format(time.RFC3339))
+	}
+	return ca, nil
+}
+
+func (ca *NodeCA) load(certPEM, keyPEM []byte) error {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return fmt.Errorf("%s is not PEM", caCertFile)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return fmt.Errorf("%s is not PEM", caKeyFile)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return err
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return fmt.Errorf("%s does not match %s", caKeyFile, caCertFile)
+	}
+	ca.cert, ca.pem, ca.key = cert, certPEM, key
+	return nil
+}
+
+func (ca *NodeCA) create(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := ca.now().UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.MkdirAll(filepath.Dir(certPath), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return err
+	}
+	return ca.load(certPEM, keyPEM)
+}
+
+// CertificatePEM returns the CA certificate agents pin.
+func (ca *NodeCA) CertificatePEM() []byte {
+	return ca.pem
+}
+
+// Fingerprint is the hex SHA-256 of the CA certificate, which agents check
+// before they have pinned it.
+func (ca *NodeCA) Fingerprint() string {
+	digest := sha256.Sum256(ca.cert.Raw)
+	return hex.EncodeToString(digest[:])
+}
+
+// SignNodeCSR issues a client certificate for nodeID from an agent's CSR.
+// The CSR's subject is ignored; the certificate always names nodeID.
+func (ca *NodeCA) SignNodeCSR(nodeID string, csrPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("csr is not a PEM certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse csr: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("csr signature: %w", err)
+	}
+
+	der, err := ca.issue(csr.PublicKey, pkix.Name{CommonName: nodeCertPrefix + nodeID}, x509.ExtKeyUsageClientAuth, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sign node certificate for %s: %w", nodeID, err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func (ca *NodeCA) issue(pub interface{}, subject pkix.Name, usage x509.ExtKeyUsage, dnsNames []string, ips []net.IP) ([]byte, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := ca.now().UTC()
+	notAfter := now.Add(time.Duration(ca.cfg.CertValidityHours) * time.Hour)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	return x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.key)
+}
+
+// ServerTLSConfig is the WebSocket listener's TLS configuration. Client
+// certificates are verified when given; ServeWS and ServeRenew require
+// them, while enrollment works without one.
+func (ca *NodeCA) ServerTLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     tls.VerifyClientCertIfGiven,
+		ClientCAs:      pool,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return ca.currentServerCert() },
+	}
+}
+
+func (ca *NodeCA) currentServerCert() (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if ca.serverCert != nil && !certificateNeedsRenewal(ca.serverCert.Leaf, ca.now()) {
+		return ca.serverCert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate server key: %w", err)
+	}
+	var (
+		dnsNames []string
+		ips      []net.IP
+	)
+	for _, name := range ca.cfg.ServerNames {
+		if ip := net.ParseIP(name); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+	der, err := ca.issue(&key.PublicKey, pkix.Name{CommonName: "hal-o-swarm supervisor"}, x509.ExtKeyUsageServerAuth, dnsNames, ips)
+	if err != nil {
+		return nil, fmt.Errorf("issue server certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("issue server certificate: %w", err)
+	}
+
+	ca.serverCert = &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	ca.logger.Info("supervisor certificate issued", zap.Time("not_after", leaf.NotAfter))
+	return ca.serverCert, nil
+}
+
+// VerifyNode checks that the request came with a certificate this CA issued
+// to nodeID.
+func (ca *NodeCA) VerifyNode(r *http.Request, nodeID string) error {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ErrClientCertRequired
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	if nodeID == "" || leaf.Subject.CommonName != nodeCertPrefix+nodeID {
+		return fmt.Errorf("%w: certificate is for %q", ErrClientCertRequired, leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+// certificateNeedsRenewal reports whether a certificate is in the last third
+// of its validity.
+func certificateNeedsRenewal(cert *x509.Certificate, now time.Time) bool {
+	if cert == nil {
+		return true
+	}
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return now.After(cert.NotAfter.Add(-lifetime / 3))
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
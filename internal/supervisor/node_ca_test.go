@@ -0,0 +1,237 @@
+package supervisor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/agent"
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func testMTLSConfig(t *testing.T) config.MTLSConfig {
+	t.Helper()
+	return config.MTLSConfig{
+		Enabled:           true,
+		CADir:             filepath.Join(t.TempDir(), "ca"),
+		ServerNames:       []string{"127.0.0.1", "localhost"},
+		CertValidityHours: 24,
+	}
+}
+
+func TestNodeCALoadOrCreate(t *testing.T) {
+	cfg := testMTLSConfig(t)
+	ca, err := LoadOrCreateNodeCA(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	if len(ca.Fingerprint()) != 64 {
+		t.Fatalf("unexpected fingerprint %q", ca.Fingerprint())
+	}
+
+	info, err := os.Stat(filepath.Join(cfg.CADir, caKeyFile))
+	if err != nil {
+		t.Fatalf("CA key not saved: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected CA key mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := LoadOrCreateNodeCA(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("reload CA: %v", err)
+	}
+	if reloaded.Fingerprint() != ca.Fingerprint() {
+		t.Fatal("reloading the CA must not replace it")
+	}
+
+	if _, err := ca.SignNodeCSR("n-1", []byte("not a csr")); err == nil {
+		t.Fatal("expected an invalid CSR to be refused")
+	}
+
+	serverCert, err := ca.currentServerCert()
+	if err != nil {
+		t.Fatalf("server certificate: %v", err)
+	}
+	if again, _ := ca.currentServerCert(); again != serverCert {
+		t.Fatal("expected the server certificate to be reused until renewal")
+	}
+	ca.now = func() time.Time { return serverCert.Leaf.NotAfter.Add(-time.Hour) }
+	if renewed, _ := ca.currentServerCert(); renewed == serverCert {
+		t.Fatal("expected the server certificate to be re-issued near expiry")
+	}
+}
+
+func TestNodeMutualTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrollment, _ := setupNodeEnrollment(t, false)
+	ca, err := LoadOrCreateNodeCA(testMTLSConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	hub := newTestHub(ctx, 30*time.Second, 3)
+	hub.ConfigureNodeEnrollment(enrollment)
+	hub.ConfigureNodeCA(ca)
+	go hub.Run()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/agent", hub.ServeWS)
+	mux.HandleFunc("POST /ws/enroll", hub.ServeEnroll)
+	mux.HandleFunc("POST /ws/renew", hub.ServeRenew)
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = tls.NewListener(server.Listener, ca.ServerTLSConfig())
+	server.Start()
+	defer server.Close()
+	baseURL := "https://" + server.Listener.Addr().String()
+	supervisorURL := "wss://" + server.Listener.Addr().String() + "/ws/agent"
+
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(ca.CertificatePEM())
+	noCert := &tls.Config{RootCAs: caPool}
+
+	// Enrollment without a CSR is refused and does not spend the join token.
+	join, _ := enrollment.CreateJoinToken("", 0)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: noCert}}
+	resp, err := client.Post(baseURL+"/ws/enroll", "application/json",
+		strings.NewReader(`{"join_token":"`+join.Token+`","node_id":"n-1"}`))
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a csr, got %d", resp.StatusCode)
+	}
+
+	// The join token is checked before the CA looks at the CSR.
+	resp, err = client.Post(baseURL+"/ws/enroll", "application/json",
+		strings.NewReader(`{"join_token":"unknown","node_id":"n-1","csr":"not a csr"}`))
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown join token, got %d", resp.StatusCode)
+	}
+
+	// A CSR the CA cannot sign does not spend the join token.
+	resp, err = client.Post(baseURL+"/ws/enroll", "application/json",
+		strings.NewReader(`{"join_token":"`+join.Token+`","node_id":"n-1","csr":"not a csr"}`))
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid csr, got %d", resp.StatusCode)
+	}
+
+	// An agent that does not trust this CA refuses to enroll.
+	stateDir := t.TempDir()
+	untrusting, _ := agent.NewNodeTLS(t.TempDir(), strings.Repeat("0", 64), nil)
+	mistrusted := agent.NewNodeCredentials(supervisorURL, "test-token", join.Token, filepath.Join(t.TempDir(), "node.credential"), "n-1", nil)
+	mistrusted.SetNodeTLS(untrusting)
+	if _, err := mistrusted.Token(ctx); err == nil || !strings.Contains(err.Error(), "ca_fingerprint") {
+		t.Fatalf("expected a fingerprint mismatch, got %v", err)
+	}
+
+	nodeTLS, err := agent.NewNodeTLS(stateDir, ca.Fingerprint(), nil)
+	if err != nil {
+		t.Fatalf("node tls: %v", err)
+	}
+	credentials := agent.NewNodeCredentials(supervisorURL, "test-token", join.Token, filepath.Join(stateDir, "node.credential"), "n-1", nil)
+	credentials.SetNodeTLS(nodeTLS)
+	credential, err := credentials.Token(ctx)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if !nodeTLS.HasCertificate() {
+		t.Fatal("expected a node certificate after enrollment")
+	}
+
+	dial := func(nodeID, token string, tlsConfig *tls.Config) int {
+		t.Helper()
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+token)
+		header.Set("X-Node-ID", nodeID)
+		dialer := websocket.Dialer{TLSClientConfig: tlsConfig, HandshakeTimeout: 5 * time.Second}
+		conn, resp, err := dialer.Dial(supervisorURL, header)
+		if err != nil {
+			if resp == nil {
+				t.Fatalf("dial %s: %v", nodeID, err)
+			}
+			return resp.StatusCode
+		}
+		conn.Close()
+		return http.StatusSwitchingProtocols
+	}
+
+	if code := dial("n-1", credential, noCert); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a client certificate, got %d", code)
+	}
+	if code := dial("n-1", credential, nodeTLS.ClientConfig()); code != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the node certificate to be accepted, got %d", code)
+	}
+	// Once n-1 has enrolled, n-2 cannot connect with the shared token.
+	if code := dial("n-2", "test-token", nodeTLS.ClientConfig()); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for another node's certificate, got %d", code)
+	}
+
+	// The CA is pinned: a restarted agent trusts it without a fingerprint.
+	restarted, err := agent.NewNodeTLS(stateDir, "", nil)
+	if err != nil || !restarted.HasCertificate() {
+		t.Fatalf("reload node tls: %v", err)
+	}
+	if code := dial("n-1", credential, restarted.ClientConfig()); code != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the saved certificate to be accepted, got %d", code)
+	}
+
+	if err := credentials.RenewCertificate(ctx); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if code := dial("n-1", credential, nodeTLS.ClientConfig()); code != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the renewed certificate to be accepted, got %d", code)
+	}
+
+	if _, err := enrollment.Revoke("n-1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := credentials.RenewCertificate(ctx); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("expected renewal to be refused after revocation, got %v", err)
+	}
+}
+
+func TestHTTPAPIAgentRoutesWithMTLS(t *testing.T) {
+	api, _, _ := setupHTTPAPI(t)
+	api.SetHub(NewHub(context.Background(), testAuthToken, nil, 30*time.Second, 3, zap.NewNop()))
+	ca, err := LoadOrCreateNodeCA(testMTLSConfig(t), zap.NewNop())
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	api.SetNodeCA(ca)
+	handler := api.Handler()
+
+	// The API port cannot check node certificates, so agents are sent to the
+	// WebSocket port.
+	for _, path := range []string{"/ws/agent", "/ws/enroll", "/ws/renew"} {
+		rec := httptest.NewRecorder()
+		method := http.MethodPost
+		if path == "/ws/agent" {
+			method = http.MethodGet
+		}
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("{}")))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404 with mTLS, got %d", method, path, rec.Code)
+		}
+	}
+}
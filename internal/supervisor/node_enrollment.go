@@ -0,0 +1,297 @@
+package supervisor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidJoinToken is returned for a join token that is unknown,
+	// already used, expired or bound to another node.
+	ErrInvalidJoinToken = errors.New("invalid join token")
+	// ErrNodeNotEnrolled is returned when a node has no per-node credential.
+	ErrNodeNotEnrolled = errors.New("node is not enrolled")
+	// ErrInvalidNodeCredential is returned when a node presents the wrong
+	// credential.
+	ErrInvalidNodeCredential = errors.New("invalid node credential")
+	// ErrNodeRevoked is returned when a revoked node connects or enrolls.
+	ErrNodeRevoked = errors.New("node is revoked")
+	// ErrNodeEnrolled is returned when a join token not bound to a node is
+	// used for a node that already holds an active credential.
+	ErrNodeEnrolled = errors.New("node is already enrolled")
+)
+
+// JoinToken is a one-time token an agent exchanges for its node credential.
+// Token is only available when the join token is created. A join token with
+// a NodeID can only enroll that node. CAFingerprint identifies the node CA
+// when mTLS is enabled.
+type JoinToken struct {
+	Token         string    `json:"token"`
+	NodeID        string    `json:"node_id,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CAFingerprint string    `json:"ca_fingerprint,omitempty"`
+}
+
+// NodeEnrollment issues join tokens, exchanges them for per-node
+// credentials, authenticates nodes by credential and revokes them. Only
+// hashes of tokens and credentials are stored.
+type NodeEnrollment struct {
+	cfg      config.EnrollmentConfig
+	db       *sql.DB
+	registry *NodeRegistry
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewNodeEnrollment(cfg config.EnrollmentConfig, db *sql.DB, registry *NodeRegistry, logger *zap.Logger) *NodeEnrollment {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &NodeEnrollment{
+		cfg:      cfg,
+		db:       db,
+		registry: registry,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// RequireCredentials reports whether nodes without a credential are refused
+// instead of falling back to the shared auth token.
+func (e *NodeEnrollment) RequireCredentials() bool {
+	return e.cfg.RequireNodeCredentials
+}
+
+// InUse reports whether any node has enrolled or been revoked. From then on
+// the shared auth token no longer admits agents, so a leaked agent config or
+// a revoked node cannot connect under another node ID.
+func (e *NodeEnrollment) InUse() (bool, error) {
+	var inUse bool
+	if err := e.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM node_credentials)`).Scan(&inUse); err != nil {
+		return false, fmt.Errorf("check enrollment: %w", err)
+	}
+	return inUse, nil
+}
+
+// CreateJoinToken issues a join token valid for ttl, or for the configured
+// default when ttl is zero. nodeID, when set, binds the token to that node.
+func (e *NodeEnrollment) CreateJoinToken(nodeID string, ttl time.Duration) (JoinToken, error) {
+	if ttl <= 0 {
+		ttl = time.Duration(e.cfg.JoinTokenTTLSeconds) * time.Second
+	}
+	if ttl <= 0 {
+		return JoinToken{}, fmt.Errorf("join token ttl must be positive")
+	}
+
+	token, err := randomSecret()
+	if err != nil {
+		return JoinToken{}, fmt.Errorf("create join token: %w", err)
+	}
+	now := e.now().UTC()
+	joinToken := JoinToken{Token: token, NodeID: nodeID, ExpiresAt: now.Add(ttl)}
+
+	if _, err := e.db.Exec(`
+		INSERT INTO join_tokens (token_hash, node_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, hashSecret(token), nodeID, now.Format(time.RFC3339Nano), joinToken.ExpiresAt.Format(time.RFC3339Nano)); err != nil {
+		return JoinToken{}, fmt.Errorf("create join token: %w", err)
+	}
+
+	e.logger.Info("join token created",
+		zap.String("node_id", nodeID),
+		zap.Time("expires_at", joinToken.ExpiresAt),
+	)
+	return joinToken, nil
+}
+
+// Enroll exchanges a join token for a new credential for nodeID. The join
+// token cannot be used again. A node that already holds an active credential
+// is only re-enrolled with a token bound to it; otherwise it must be removed
+// or revoked first.
+func (e *NodeEnrollment) Enroll(joinToken, nodeID string) (string, error) {
+	return e.EnrollWith(joinToken, nodeID, nil)
+}
+
+// EnrollWith enrolls like Enroll and calls issue once the join token has
+// been checked and spent, before the enrollment is committed. An error from
+// issue aborts the enrollment and leaves the token unused.
+func (e *NodeEnrollment) EnrollWith(joinToken, nodeID string, issue func() error) (string, error) {
+	if joinToken == "" {
+		return "", ErrInvalidJoinToken
+	}
+	if nodeID == "" {
+		return "", fmt.Errorf("node id is required")
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		boundNode string
+		expiresAt string
+		usedAt    sql.NullString
+	)
+	tokenHash := hashSecret(joinToken)
+	err = tx.QueryRow(`SELECT node_id, expires_at, used_at FROM join_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&boundNode, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidJoinToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	expiry, err := parseSQLiteTimestamp(expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	now := e.now().UTC()
+	if usedAt.Valid || !now.Before(expiry) || (boundNode != "" && boundNode != nodeID) {
+		return "", ErrInvalidJoinToken
+	}
+
+	var (
+		currentHash string
+		revokedAt   sql.NullString
+	)
+	err = tx.QueryRow(`SELECT credential_hash, revoked_at FROM node_credentials WHERE node_id = ?`, nodeID).Scan(&currentHash, &revokedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	if revokedAt.Valid {
+		return "", ErrNodeRevoked
+	}
+	// Removed nodes keep a row with an empty credential and enroll freely.
+	if currentHash != "" && boundNode != nodeID {
+		return "", ErrNodeEnrolled
+	}
+
+	credential, err := randomSecret()
+	if err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+
+	result, err := tx.Exec(`UPDATE join_tokens SET used_at = ?, used_by = ? WHERE token_hash = ? AND used_at IS NULL`,
+		now.Format(time.RFC3339Nano), nodeID, tokenHash)
+	if err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		return "", ErrInvalidJoinToken
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO node_credentials (node_id, credential_hash, enrolled_at, revoked_at)
+		VALUES (?, ?, ?, NULL)
+		ON CONFLICT(node_id) DO UPDATE SET
+			credential_hash = excluded.credential_hash,
+			enrolled_at = excluded.enrolled_at
+	`, nodeID, hashSecret(credential), now.Format(time.RFC3339Nano)); err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+	if issue != nil {
+		if err := issue(); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("enroll node %s: %w", nodeID, err)
+	}
+
+	e.logger.Info("node enrolled", zap.String("node_id", nodeID))
+	return credential, nil
+}
+
+// Authenticate checks the credential nodeID presents. It returns
+// ErrNodeNotEnrolled when the node has none, so the caller can decide
+// whether the shared token is still accepted.
+func (e *NodeEnrollment) Authenticate(nodeID, credential string) error {
+	if nodeID == "" {
+		return ErrNodeNotEnrolled
+	}
+
+	var (
+		credentialHash string
+		revokedAt      sql.NullString
+	)
+	err := e.db.QueryRow(`SELECT credential_hash, revoked_at FROM node_credentials WHERE node_id = ?`, nodeID).
+		Scan(&credentialHash, &revokedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNodeNotEnrolled
+	case err != nil:
+		return fmt.Errorf("authenticate node %s: %w", nodeID, err)
+	case revokedAt.Valid:
+		return ErrNodeRevoked
+	case credentialHash == "":
+		return ErrNodeNotEnrolled
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(credential)), []byte(credentialHash)) != 1 {
+		return ErrInvalidNodeCredential
+	}
+	return nil
+}
+
+// Revoke discards a node's credential and unused join tokens bound to it,
+// and blocks the node from connecting or enrolling until it is removed. The
+// node must be registered or enrolled.
+func (e *NodeEnrollment) Revoke(nodeID string) (time.Time, error) {
+	if nodeID == "" {
+		return time.Time{}, ErrNodeNotFound
+	}
+	if e.registry != nil {
+		if _, err := e.registry.GetNode(nodeID); errors.Is(err, ErrNodeNotFound) {
+			var exists int
+			err := e.db.QueryRow(`SELECT COUNT(*) FROM node_credentials WHERE node_id = ? AND (credential_hash != '' OR revoked_at IS NOT NULL)`, nodeID).Scan(&exists)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("revoke node %s: %w", nodeID, err)
+			}
+			if exists == 0 {
+				return time.Time{}, ErrNodeNotFound
+			}
+		} else if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	revokedAt := e.now().UTC()
+	if _, err := e.db.Exec(`
+		INSERT INTO node_credentials (node_id, credential_hash, revoked_at)
+		VALUES (?, '', ?)
+		ON CONFLICT(node_id) DO UPDATE SET
+			credential_hash = '',
+			revoked_at = excluded.revoked_at
+	`, nodeID, revokedAt.Format(time.RFC3339Nano)); err != nil {
+		return time.Time{}, fmt.Errorf("revoke node %s: %w", nodeID, err)
+	}
+	if _, err := e.db.Exec(`DELETE FROM join_tokens WHERE node_id = ? AND used_at IS NULL`, nodeID); err != nil {
+		return time.Time{}, fmt.Errorf("revoke node %s: discard join tokens: %w", nodeID, err)
+	}
+
+	e.logger.Warn("node revoked", zap.String("node_id", nodeID))
+	return revokedAt, nil
+}
+
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	digest := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,346 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func setupNodeEnrollment(t *testing.T, require bool) (*NodeEnrollment, *NodeRegistry) {
+	t.Helper()
+	db := setupSupervisorTestDB(t)
+	registry := NewNodeRegistry(db, zap.NewNop())
+	cfg := config.EnrollmentConfig{RequireNodeCredentials: require, JoinTokenTTLSeconds: 3600}
+	return NewNodeEnrollment(cfg, db, registry, zap.NewNop()), registry
+}
+
+func TestNodeEnrollmentJoinTokens(t *testing.T) {
+	enrollment, _ := setupNodeEnrollment(t, false)
+
+	token, err := enrollment.CreateJoinToken("", 0)
+	if err != nil {
+		t.Fatalf("create join token: %v", err)
+	}
+	if len(token.Token) != 64 || time.Until(token.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("unexpected join token %+v", token)
+	}
+
+	credential, err := enrollment.Enroll(token.Token, "n-1")
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", credential); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", "wrong"); !errors.Is(err, ErrInvalidNodeCredential) {
+		t.Fatalf("expected ErrInvalidNodeCredential, got %v", err)
+	}
+	if err := enrollment.Authenticate("n-2", credential); !errors.Is(err, ErrNodeNotEnrolled) {
+		t.Fatalf("expected ErrNodeNotEnrolled, got %v", err)
+	}
+
+	// A join token works once.
+	if _, err := enrollment.Enroll(token.Token, "n-2"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Fatalf("expected a used token to be refused, got %v", err)
+	}
+
+	// Another unbound token cannot take over an enrolled node; a token bound
+	// to it re-enrolls it.
+	stray, _ := enrollment.CreateJoinToken("", time.Minute)
+	if _, err := enrollment.Enroll(stray.Token, "n-1"); !errors.Is(err, ErrNodeEnrolled) {
+		t.Fatalf("expected ErrNodeEnrolled for an enrolled node, got %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", credential); err != nil {
+		t.Fatalf("expected the enrolled credential to keep working: %v", err)
+	}
+	rebind, _ := enrollment.CreateJoinToken("n-1", time.Minute)
+	renewed, err := enrollment.Enroll(rebind.Token, "n-1")
+	if err != nil {
+		t.Fatalf("re-enroll with bound token: %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", credential); !errors.Is(err, ErrInvalidNodeCredential) {
+		t.Fatalf("expected the replaced credential to be refused, got %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", renewed); err != nil {
+		t.Fatalf("authenticate re-enrolled node: %v", err)
+	}
+
+	bound, _ := enrollment.CreateJoinToken("n-3", time.Minute)
+	if _, err := enrollment.Enroll(bound.Token, "n-4"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Fatalf("expected a token bound to another node to be refused, got %v", err)
+	}
+	if _, err := enrollment.Enroll(bound.Token, "n-3"); err != nil {
+		t.Fatalf("enroll bound node: %v", err)
+	}
+
+	expiring, _ := enrollment.CreateJoinToken("", time.Minute)
+	enrollment.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := enrollment.Enroll(expiring.Token, "n-5"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Fatalf("expected an expired token to be refused, got %v", err)
+	}
+	if _, err := enrollment.Enroll("unknown", "n-5"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Fatalf("expected an unknown token to be refused, got %v", err)
+	}
+}
+
+func TestNodeEnrollmentRevoke(t *testing.T) {
+	enrollment, registry := setupNodeEnrollment(t, false)
+
+	token, _ := enrollment.CreateJoinToken("", 0)
+	credential, err := enrollment.Enroll(token.Token, "n-1")
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	pending, _ := enrollment.CreateJoinToken("n-1", 0)
+
+	if _, err := enrollment.Revoke("n-1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := enrollment.Authenticate("n-1", credential); !errors.Is(err, ErrNodeRevoked) {
+		t.Fatalf("expected ErrNodeRevoked, got %v", err)
+	}
+	if _, err := enrollment.Enroll(pending.Token, "n-1"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Fatalf("expected the node's pending join token to be discarded, got %v", err)
+	}
+	fresh, _ := enrollment.CreateJoinToken("", 0)
+	if _, err := enrollment.Enroll(fresh.Token, "n-1"); !errors.Is(err, ErrNodeRevoked) {
+		t.Fatalf("expected a revoked node not to enroll again, got %v", err)
+	}
+
+	// A registered node that never enrolled can be revoked too; unknown
+	// nodes cannot.
+	seedNode(t, registry, "n-2", "host-2")
+	if _, err := enrollment.Revoke("n-2"); err != nil {
+		t.Fatalf("revoke registered node: %v", err)
+	}
+	if err := enrollment.Authenticate("n-2", testAuthToken); !errors.Is(err, ErrNodeRevoked) {
+		t.Fatalf("expected ErrNodeRevoked for n-2, got %v", err)
+	}
+	if _, err := enrollment.Revoke("n-9"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+
+	// Removing the node lifts the revocation.
+	if err := registry.MarkOffline("n-2"); err != nil {
+		t.Fatalf("mark offline: %v", err)
+	}
+	maintenance := NewNodeMaintenance(enrollment.db, registry, nil, nil, zap.NewNop())
+	defer maintenance.Stop()
+	if _, err := maintenance.Remove("n-2"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if inUse, err := enrollment.InUse(); err != nil || !inUse {
+		t.Fatalf("expected enrollment to stay in use after a removal: %v, %v", inUse, err)
+	}
+	if _, err := enrollment.Revoke("n-2"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected a removed node to be unknown, got %v", err)
+	}
+	if _, err := enrollment.Enroll(fresh.Token, "n-2"); err != nil {
+		t.Fatalf("enroll after remove: %v", err)
+	}
+}
+
+func TestHubNodeCredentialAuth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrollment, _ := setupNodeEnrollment(t, false)
+	hub := newTestHub(ctx, 30*time.Second, 3)
+	hub.ConfigureNodeEnrollment(enrollment)
+	go hub.Run()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/agent", hub.ServeWS)
+	mux.HandleFunc("POST /ws/enroll", hub.ServeEnroll)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dial := func(nodeID, token string) (*websocket.Conn, int) {
+		t.Helper()
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+token)
+		header.Set("X-Node-ID", nodeID)
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(server), header)
+		if err != nil {
+			if resp == nil {
+				t.Fatalf("dial %s: %v", nodeID, err)
+			}
+			return nil, resp.StatusCode
+		}
+		return conn, http.StatusSwitchingProtocols
+	}
+
+	// Before any node enrolls, agents may still use the shared token.
+	legacy, status := dial("n-2", "test-token")
+	if legacy == nil {
+		t.Fatalf("expected the shared token to be accepted, got %d", status)
+	}
+	legacy.Close()
+	<-hub.Events()
+
+	join, _ := enrollment.CreateJoinToken("", 0)
+	resp, err := http.Post(server.URL+"/ws/enroll", "application/json",
+		strings.NewReader(`{"join_token":"`+join.Token+`","node_id":"n-1"}`))
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	var enrolled struct {
+		Data enrollResponse `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&enrolled)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || enrolled.Data.Credential == "" {
+		t.Fatalf("unexpected enroll response %d %+v", resp.StatusCode, enrolled)
+	}
+
+	resp, _ = http.Post(server.URL+"/ws/enroll", "application/json",
+		strings.NewReader(`{"join_token":"`+join.Token+`","node_id":"n-1"}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a used join token, got %d", resp.StatusCode)
+	}
+
+	// An enrolled node must use its credential.
+	if _, status := dial("n-1", "test-token"); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for the shared token, got %d", status)
+	}
+	conn, status := dial("n-1", enrolled.Data.Credential)
+	if conn == nil {
+		t.Fatalf("expected the credential to be accepted, got %d", status)
+	}
+	defer conn.Close()
+	<-hub.Events()
+
+	// Once a node has enrolled, the shared token admits no other node.
+	if _, status := dial("n-2", "test-token"); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for the shared token after enrollment, got %d", status)
+	}
+
+	if _, err := enrollment.Revoke("n-1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if !hub.DisconnectNode("n-1") {
+		t.Fatal("expected n-1 to be disconnected")
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("expected a policy violation close, got %v", err)
+	}
+	if _, status := dial("n-1", enrolled.Data.Credential); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for a revoked node, got %d", status)
+	}
+	if _, status := dial("n-1", "test-token"); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for a revoked node using the shared token, got %d", status)
+	}
+}
+
+func TestHubRevokedNodeCannotReconnectUnderNewID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrollment, registry := setupNodeEnrollment(t, false)
+	hub := newTestHub(ctx, 30*time.Second, 3)
+	hub.ConfigureNodeEnrollment(enrollment)
+
+	// A fleet still on the shared token, before enrollment is used.
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "host-1"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := hub.authenticateAgent("n-1", "test-token"); err != nil {
+		t.Fatalf("expected the shared token to be accepted, got %v", err)
+	}
+
+	if _, err := enrollment.Revoke("n-1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := hub.authenticateAgent("n-1", "test-token"); !errors.Is(err, ErrNodeRevoked) {
+		t.Fatalf("expected ErrNodeRevoked, got %v", err)
+	}
+	// The same agent config under a fresh node ID is refused as well.
+	for _, nodeID := range []string{"n-1-new", ""} {
+		if err := hub.authenticateAgent(nodeID, "test-token"); !errors.Is(err, ErrNodeNotEnrolled) {
+			t.Fatalf("expected the shared token to be refused for %q, got %v", nodeID, err)
+		}
+	}
+}
+
+func TestHubRequireNodeCredentials(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrollment, _ := setupNodeEnrollment(t, true)
+	hub := newTestHub(ctx, 30*time.Second, 3)
+	hub.ConfigureNodeEnrollment(enrollment)
+
+	if err := hub.authenticateAgent("n-1", "test-token"); !errors.Is(err, ErrNodeNotEnrolled) {
+		t.Fatalf("expected the shared token to be refused, got %v", err)
+	}
+	if err := hub.authenticateAgent("", "test-token"); !errors.Is(err, ErrNodeNotEnrolled) {
+		t.Fatalf("expected an anonymous agent to be refused, got %v", err)
+	}
+}
+
+func TestHTTPAPINodeEnrollment(t *testing.T) {
+	api, registry, _ := setupHTTPAPI(t)
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/join-tokens", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without enrollment, got %d", rec.Code)
+	}
+
+	enrollment := NewNodeEnrollment(config.EnrollmentConfig{JoinTokenTTLSeconds: 600}, api.db, registry, zap.NewNop())
+	api.SetNodeEnrollment(enrollment)
+	hub := NewHub(context.Background(), testAuthToken, nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureNodeEnrollment(enrollment)
+	api.SetHub(hub)
+	handler = api.Handler()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/join-tokens", `{"node_id":"n-1","ttl_seconds":120}`))
+	var created struct {
+		Data JoinToken `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected join token response %d: %s", rec.Code, rec.Body.String())
+	}
+	if created.Data.Token == "" || created.Data.NodeID != "n-1" || time.Until(created.Data.ExpiresAt) > 2*time.Minute {
+		t.Fatalf("unexpected join token %+v", created.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/join-tokens", `{"ttl_seconds":-5}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative ttl, got %d", rec.Code)
+	}
+
+	// The HTTP port serves enrollment next to the agent WebSocket route.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ws/enroll",
+		strings.NewReader(`{"join_token":"`+created.Data.Token+`","node_id":"n-1"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"credential"`) {
+		t.Fatalf("unexpected enroll response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-1/revoke", ""))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"disconnected":false`) {
+		t.Fatalf("unexpected revoke response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-9/revoke", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+func gaugeValue(t *testing.T, gauge interface{ Write(*dto.Metric) error }) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := gauge.Write(&metric); err != nil {
+		t.Fatalf("read gauge: %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestHubHeartbeatRecordsInventory(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	registry := NewNodeRegistry(db, zap.NewNop())
+	if err := registry.Register(NodeEntry{ID: "inv-1", Hostname: "inv-1"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureNodeRegistry(registry)
+
+	hub.recordHeartbeat("inv-1", []byte(`{
+		"cpu_percent": 12.5, "memory_percent": 40, "load1": 0.5, "load5": 0.4, "load15": 0.3,
+		"memory_total_bytes": 8589934592, "memory_available_bytes": 5153960755,
+		"disks": [{"project": "proj-a", "path": "/srv/proj-a", "free_bytes": 1073741824, "total_bytes": 10737418240}],
+		"inventory": {"os": "Ubuntu 24.04.1 LTS", "kernel": "6.8.0", "arch": "amd64", "cpu_count": 8, "agent_version": "v1.4.0", "tools": {"git": "2.43.0", "claude": "1.0.51"}}
+	}`))
+
+	node, err := registry.GetNode("inv-1")
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if node.Load.Load1 != 0.5 || node.Load.MemoryTotalBytes != 8589934592 || len(node.Load.Disks) != 1 {
+		t.Fatalf("unexpected telemetry %+v", node.Load)
+	}
+	if node.Inventory == nil || node.Inventory.AgentVersion != "v1.4.0" || node.Inventory.Tools["git"] != "2.43.0" {
+		t.Fatalf("unexpected inventory %+v", node.Inventory)
+	}
+
+	metrics := GetMetrics()
+	if got := gaugeValue(t, metrics.NodeCPUPercent.WithLabelValues("inv-1")); got != 12.5 {
+		t.Fatalf("expected cpu gauge 12.5, got %v", got)
+	}
+	if got := gaugeValue(t, metrics.NodeDiskFreeBytes.WithLabelValues("inv-1", "proj-a")); got != 1073741824 {
+		t.Fatalf("expected disk gauge, got %v", got)
+	}
+	if got := gaugeValue(t, metrics.NodeToolInfo.WithLabelValues("inv-1", "claude", "1.0.51")); got != 1 {
+		t.Fatalf("expected tool info gauge, got %v", got)
+	}
+
+	// The inventory outlives a reconnect and a supervisor restart; telemetry
+	// is only kept in memory.
+	if err := registry.Register(NodeEntry{ID: "inv-1", Hostname: "inv-1"}); err != nil {
+		t.Fatalf("re-register: %v", err)
+	}
+	reloaded := NewNodeRegistry(db, zap.NewNop())
+	if err := reloaded.LoadNodesFromDB(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	node, _ = reloaded.GetNode("inv-1")
+	if node.Inventory == nil || node.Inventory.OS != "Ubuntu 24.04.1 LTS" || !node.Load.ReportedAt.IsZero() {
+		t.Fatalf("unexpected node after reload %+v", node)
+	}
+
+	// A heartbeat without an inventory keeps the stored one.
+	hub.recordHeartbeat("inv-1", []byte(`{"cpu_percent": 5}`))
+	if node, _ := registry.GetNode("inv-1"); node.Inventory == nil || node.Load.CPUPercent != 5 {
+		t.Fatalf("unexpected node after a bare heartbeat %+v", node)
+	}
+}
+
+func TestHTTPAPINodeInventory(t *testing.T) {
+	api, registry, _ := setupHTTPAPI(t)
+	seedNode(t, registry, "n-1", "host-1")
+	handler := api.Handler()
+
+	getNode := func() nodeJSON {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/nodes/n-1", ""))
+		var resp struct {
+			Data nodeJSON `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+
+	if node := getNode(); node.Inventory != nil || node.Telemetry != nil {
+		t.Fatalf("expected no inventory or telemetry before reports, got %+v", node)
+	}
+
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureNodeRegistry(registry)
+	hub.recordNodeInfo("n-1", []byte(`{"hostname":"host-1","inventory":{"os":"Debian 12","agent_version":"v1.4.0"}}`))
+	hub.recordHeartbeat("n-1", []byte(`{"cpu_percent": 30, "memory_percent": 50, "load1": 1.25}`))
+
+	node := getNode()
+	if node.Inventory == nil || node.Inventory.OS != "Debian 12" {
+		t.Fatalf("expected inventory, got %+v", node.Inventory)
+	}
+	if node.Telemetry == nil || node.Telemetry.CPUPercent != 30 || node.Telemetry.Load1 != 1.25 {
+		t.Fatalf("expected telemetry, got %+v", node.Telemetry)
+	}
+}
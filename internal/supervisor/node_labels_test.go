@@ -0,0 +1,157 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
+	"go.uber.org/zap"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	labels, err := ParseLabelSelector(" zone=office, gpu=false ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !reflect.DeepEqual(labels, map[string]string{"zone": "office", "gpu": "false"}) {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	for _, bad := range []string{"", "zone", "=office"} {
+		if _, err := ParseLabelSelector(bad); err == nil {
+			t.Errorf("expected %q rejected", bad)
+		}
+	}
+}
+
+func TestNodeRegistryKeepsReportedLabels(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	registry := NewNodeRegistry(db, zap.NewNop())
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureNodeRegistry(registry)
+	hub.recordNodeInfo("n-1", []byte(`{"hostname":"build-01","projects":["proj-a"],"labels":{"zone":"office","tier":"android"},"sessions":[],"last_seq":0}`))
+
+	want := map[string]string{"zone": "office", "tier": "android"}
+	node, err := registry.GetNode("n-1")
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if node.Hostname != "build-01" || !reflect.DeepEqual(node.Projects, []string{"proj-a"}) || !reflect.DeepEqual(node.Labels, want) {
+		t.Fatalf("unexpected node %+v", node)
+	}
+
+	// Reconnecting keeps the labels until the agent reports again, and they
+	// survive a supervisor restart.
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1"}); err != nil {
+		t.Fatalf("re-register: %v", err)
+	}
+	if node, _ := registry.GetNode("n-1"); !reflect.DeepEqual(node.Labels, want) {
+		t.Fatalf("expected labels kept on reconnect, got %v", node.Labels)
+	}
+	reloaded := NewNodeRegistry(db, zap.NewNop())
+	if err := reloaded.LoadNodesFromDB(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if node, _ := reloaded.GetNode("n-1"); !reflect.DeepEqual(node.Labels, want) {
+		t.Fatalf("expected labels after reload, got %v", node.Labels)
+	}
+
+	if err := registry.UpdateNodeInfo("n-1", shared.NodeInfo{Projects: []string{"proj-a"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if node, _ := registry.GetNode("n-1"); len(node.Labels) != 0 || node.Hostname != "n-1" {
+		t.Fatalf("expected labels cleared and hostname kept, got %+v", node)
+	}
+}
+
+func TestDispatcherFansOutByLabel(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	for _, node := range []NodeEntry{
+		{ID: "n-1", Hostname: "n-1", Projects: []string{"proj-a"}, Labels: map[string]string{"zone": "office", "tier": "android"}},
+		{ID: "n-2", Hostname: "n-2", Projects: []string{"proj-a"}, Labels: map[string]string{"zone": "lab", "tier": "android"}},
+		{ID: "n-3", Hostname: "n-3", Projects: []string{"proj-b"}, Labels: map[string]string{"zone": "office"}},
+	} {
+		if err := registry.Register(node); err != nil {
+			t.Fatalf("register %s: %v", node.ID, err)
+		}
+	}
+
+	var dispatcher *CommandDispatcher
+	transport := &mockCommandTransport{}
+	transport.onSend = func(nodeID string, cmd Command) {
+		result := CommandResult{CommandID: cmd.CommandID, Status: CommandStatusSuccess, Output: "ok"}
+		if nodeID == "n-3" {
+			result = CommandResult{CommandID: cmd.CommandID, Status: CommandStatusFailure, Error: "disk full"}
+		}
+		go dispatcher.HandleCommandResult(result)
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+
+	dispatch := func(target CommandTarget) *CommandResult {
+		t.Helper()
+		result, err := dispatcher.DispatchCommand(context.Background(), Command{Type: CommandTypeSessionStatus, Target: target})
+		if err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		return result
+	}
+
+	result := dispatch(CommandTarget{Labels: map[string]string{"zone": "office"}})
+	if result.Status != CommandStatusFailure || len(result.Results) != 2 || transport.CallCount() != 2 {
+		t.Fatalf("expected a failed fan-out to 2 nodes, got %+v", result)
+	}
+	if result.Results[0].NodeID != "n-1" || result.Results[1].NodeID != "n-3" || result.Results[0].CommandID == result.CommandID {
+		t.Fatalf("unexpected per-node results %+v", result.Results)
+	}
+	if result.Output != "n-1: ok" || result.Error != "n-3: disk full" || result.Placement != "2 nodes match labels zone=office" {
+		t.Fatalf("unexpected aggregate %+v", result)
+	}
+
+	// A project narrows the selector to nodes serving it.
+	result = dispatch(CommandTarget{Project: "proj-a", Labels: map[string]string{"tier": "android"}})
+	if result.Status != CommandStatusSuccess || len(result.Results) != 2 || result.Output != "n-1: ok\nn-2: ok" {
+		t.Fatalf("expected success on n-1 and n-2, got %+v", result)
+	}
+
+	result = dispatch(CommandTarget{Labels: map[string]string{"gpu": "true"}})
+	if result.Status != CommandStatusFailure || result.Error != "no online node matches labels gpu=true" {
+		t.Fatalf("expected no match, got %+v", result)
+	}
+}
+
+func TestHTTPAPICommandSelector(t *testing.T) {
+	api, registry, _, _ := setupHTTPAPIWithDispatcher(t)
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1", Labels: map[string]string{"zone": "office"}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/commands", `{"type":"session_status","selector":"zone"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad selector, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/nodes/n-1", ""))
+	var resp struct {
+		Data nodeJSON `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Data.Labels["zone"] != "office" {
+		t.Fatalf("expected labels in node JSON, got %+v", resp.Data)
+	}
+}
@@ -0,0 +1,327 @@
+package supervisor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDrainTimeout      = 10 * time.Minute
+	defaultDrainPollInterval = 5 * time.Second
+)
+
+// ErrNodeOnline is returned when removing a node whose agent is connected.
+var ErrNodeOnline = errors.New("node is online")
+
+// DrainOptions controls a drain. Timeout bounds the wait for the node's
+// active sessions to end; Handover moves each of them to another node first.
+type DrainOptions struct {
+	Timeout  time.Duration
+	Handover bool
+}
+
+// NodeMaintenance cordons, drains and removes nodes. A drain runs in the
+// background until the node has no active session, then marks it drained;
+// one that times out leaves the node cordoned.
+type NodeMaintenance struct {
+	db         *sql.DB
+	registry   *NodeRegistry
+	tracker    *SessionTracker
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	}
+	logger       *zap.Logger
+	pollInterval time.Duration
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	drains map[string]*nodeDrain
+}
+
+type nodeDrain struct {
+	cancel context.CancelFunc
+}
+
+func NewNodeMaintenance(
+	db *sql.DB,
+	registry *NodeRegistry,
+	tracker *SessionTracker,
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	},
+	logger *zap.Logger,
+) *NodeMaintenance {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &NodeMaintenance{
+		db:           db,
+		registry:     registry,
+		tracker:      tracker,
+		dispatcher:   dispatcher,
+		logger:       logger,
+		pollInterval: defaultDrainPollInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+		drains:       make(map[string]*nodeDrain),
+	}
+}
+
+// Stop abandons running drains. Their nodes come back cordoned after a
+// restart.
+func (m *NodeMaintenance) Stop() {
+	m.stopOnce.Do(func() {
+		m.cancel()
+
+		done := make(chan struct{})
+		go func() {
+			m.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(250 * time.Millisecond):
+		}
+	})
+}
+
+// Cordon stops new sessions from being placed on a node, ending any drain.
+func (m *NodeMaintenance) Cordon(nodeID string) (NodeEntry, error) {
+	m.cancelDrain(nodeID)
+	return m.registry.SetLifecycle(nodeID, NodeLifecycleCordoned)
+}
+
+// Uncordon makes a node take new sessions again, ending any drain.
+func (m *NodeMaintenance) Uncordon(nodeID string) (NodeEntry, error) {
+	m.cancelDrain(nodeID)
+	return m.registry.SetLifecycle(nodeID, NodeLifecycleActive)
+}
+
+// Drain cordons a node and starts waiting for its active sessions to end,
+// handing them over to other nodes first when opts.Handover is set.
+func (m *NodeMaintenance) Drain(nodeID string, opts DrainOptions) (NodeEntry, error) {
+	node, err := m.registry.SetLifecycle(nodeID, NodeLifecycleDraining)
+	if err != nil {
+		return NodeEntry{}, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, opts.Timeout)
+	drain := &nodeDrain{cancel: cancel}
+	m.mu.Lock()
+	if previous, ok := m.drains[nodeID]; ok {
+		previous.cancel()
+	}
+	m.drains[nodeID] = drain
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.drain(ctx, drain, nodeID, opts.Handover)
+
+	m.logger.Info("node drain started",
+		zap.String("node_id", nodeID),
+		zap.Duration("timeout", opts.Timeout),
+		zap.Bool("handover", opts.Handover),
+	)
+	return node, nil
+}
+
+// Remove unregisters an offline node, ends its sessions and purges its event
+// sequence and credential, returning the sessions dropped from the live
+// view. Session history is kept. A revoked node may enroll again once
+// removed.
+func (m *NodeMaintenance) Remove(nodeID string) ([]string, error) {
+	node, err := m.registry.GetNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node.Status == NodeStatusOnline {
+		return nil, fmt.Errorf("remove node %s: %w", nodeID, ErrNodeOnline)
+	}
+	m.cancelDrain(nodeID)
+
+	purged := make([]string, 0)
+	if m.tracker != nil {
+		if purged, err = m.tracker.PurgeNode(nodeID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := m.db.Exec(`DELETE FROM agent_sequences WHERE agent_id = ?`, nodeID); err != nil {
+		return nil, fmt.Errorf("remove node %s: purge event sequence: %w", nodeID, err)
+	}
+	// The row is reset rather than deleted so the shared token stays refused
+	// once enrollment has been used; the node can enroll again.
+	if _, err := m.db.Exec(`UPDATE node_credentials SET credential_hash = '', enrolled_at = NULL, revoked_at = NULL WHERE node_id = ?`, nodeID); err != nil {
+		return nil, fmt.Errorf("remove node %s: purge credential: %w", nodeID, err)
+	}
+	if err := m.registry.Remove(nodeID); err != nil {
+		return nil, err
+	}
+	GetMetrics().DeleteNode(nodeID)
+
+	m.logger.Info("node removed",
+		zap.String("node_id", nodeID),
+		zap.Int("purged_sessions", len(purged)),
+	)
+	return purged, nil
+}
+
+func (m *NodeMaintenance) cancelDrain(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if drain, ok := m.drains[nodeID]; ok {
+		drain.cancel()
+		delete(m.drains, nodeID)
+	}
+}
+
+func (m *NodeMaintenance) drain(ctx context.Context, drain *nodeDrain, nodeID string, handover bool) {
+	defer m.wg.Done()
+	defer func() {
+		drain.cancel()
+		m.mu.Lock()
+		if m.drains[nodeID] == drain {
+			delete(m.drains, nodeID)
+		}
+		m.mu.Unlock()
+	}()
+
+	if handover {
+		for _, session := range m.activeSessions(nodeID) {
+			if err := m.handOver(ctx, session); err != nil {
+				m.logger.Warn("drain handover failed",
+					zap.String("node_id", nodeID),
+					zap.String("session_id", session.SessionID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+	for {
+		if len(m.activeSessions(nodeID)) == 0 {
+			m.finishDrain(ctx, nodeID, NodeLifecycleDrained)
+			m.logger.Info("node drained", zap.String("node_id", nodeID))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			// Only a timeout ends the drain here; uncordon, cordon, remove
+			// and shutdown have already decided the node's state.
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				m.finishDrain(ctx, nodeID, NodeLifecycleCordoned)
+				m.logger.Warn("node drain timed out",
+					zap.String("node_id", nodeID),
+					zap.Int("active_sessions", len(m.activeSessions(nodeID))),
+				)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishDrain records the outcome of a drain unless the node has been
+// removed or another operation has taken over.
+func (m *NodeMaintenance) finishDrain(ctx context.Context, nodeID string, lifecycle NodeLifecycle) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	node, err := m.registry.GetNode(nodeID)
+	if err != nil || node.Lifecycle != NodeLifecycleDraining {
+		return
+	}
+	if _, err := m.registry.SetLifecycle(nodeID, lifecycle); err != nil {
+		m.logger.Warn("record drain outcome failed", zap.String("node_id", nodeID), zap.Error(err))
+	}
+}
+
+func (m *NodeMaintenance) activeSessions(nodeID string) []TrackedSession {
+	if m.tracker == nil {
+		return nil
+	}
+	active := make([]TrackedSession, 0)
+	for _, session := range m.tracker.GetAllSessions() {
+		if session.NodeID == nodeID && session.Status.Active() {
+			active = append(active, session)
+		}
+	}
+	return active
+}
+
+// handOver starts a replacement for session on another node, continuing its
+// task, then kills the original.
+func (m *NodeMaintenance) handOver(ctx context.Context, session TrackedSession) error {
+	if m.dispatcher == nil {
+		return fmt.Errorf("command dispatcher is not configured")
+	}
+
+	prompt := fmt.Sprintf("Continue the work of session %s, which was moved off node %s for maintenance.", session.SessionID, session.NodeID)
+	if session.CurrentTask != "" {
+		prompt = fmt.Sprintf("Continue the task %q from session %s, which was moved off node %s for maintenance.", session.CurrentTask, session.SessionID, session.NodeID)
+	}
+	args := map[string]interface{}{"prompt": prompt}
+	if session.Model != "" {
+		args["model"] = session.Model
+	}
+
+	created, err := m.dispatcher.DispatchCommand(ctx, Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: session.Project},
+		Args:   args,
+	})
+	if err != nil {
+		return fmt.Errorf("start replacement: %w", err)
+	}
+	if created.Status != CommandStatusSuccess || created.Output == "" {
+		return fmt.Errorf("start replacement: %s %s", created.Status, created.Error)
+	}
+
+	reason := TransitionReason{Cause: TransitionCauseSystem, Detail: "node.drain"}
+	if err := m.tracker.AddSessionWithReason(TrackedSession{
+		SessionID:       created.Output,
+		NodeID:          created.NodeID,
+		Project:         session.Project,
+		Status:          SessionStatusRunning,
+		Model:           session.Model,
+		CurrentTask:     session.CurrentTask,
+		ParentSessionID: session.SessionID,
+	}, reason); err != nil {
+		m.logger.Warn("track replacement session failed",
+			zap.String("session_id", created.Output),
+			zap.Error(err),
+		)
+	}
+
+	killed, err := m.dispatcher.DispatchCommand(ctx, Command{
+		Type:   CommandTypeKillSession,
+		Target: CommandTarget{Project: session.Project, NodeID: session.NodeID},
+		Args:   map[string]interface{}{"session_id": session.SessionID},
+	})
+	if err != nil {
+		return fmt.Errorf("kill original: %w", err)
+	}
+	if killed.Status != CommandStatusSuccess {
+		return fmt.Errorf("kill original: %s %s", killed.Status, killed.Error)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func waitForLifecycle(t *testing.T, registry *NodeRegistry, nodeID string, want NodeLifecycle) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if node, err := registry.GetNode(nodeID); err == nil && node.Lifecycle == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	node, _ := registry.GetNode(nodeID)
+	t.Fatalf("expected %s to become %s, got %s", nodeID, want, node.Lifecycle)
+}
+
+func TestCordonedNodeTakesNoNewSessions(t *testing.T) {
+	dispatcher, registry, tracker := setupPlacementDispatcher(t)
+	maintenance := NewNodeMaintenance(registry.db, registry, tracker, dispatcher, zap.NewNop())
+	defer maintenance.Stop()
+
+	for _, id := range []string{"n-1", "n-2"} {
+		if _, err := maintenance.Cordon(id); err != nil {
+			t.Fatalf("cordon %s: %v", id, err)
+		}
+	}
+	result := dispatchCreate(t, dispatcher, "proj-a", nil)
+	if result.NodeID != "n-3" {
+		t.Fatalf("expected placement on n-3, got %q (%s)", result.NodeID, result.Placement)
+	}
+
+	result, err := dispatcher.DispatchCommand(context.Background(), Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: "proj-a", NodeID: "n-1"},
+	})
+	if err != nil || result.Status != CommandStatusFailure || result.Error != "target node n-1 is cordoned" {
+		t.Fatalf("expected explicit cordoned target refused, got %+v (%v)", result, err)
+	}
+
+	// Other commands still reach a cordoned node.
+	result, err = dispatcher.DispatchCommand(context.Background(), Command{
+		Type:   CommandTypeSessionStatus,
+		Target: CommandTarget{NodeID: "n-1"},
+	})
+	if err != nil || result.Status != CommandStatusSuccess {
+		t.Fatalf("expected status command on cordoned node, got %+v (%v)", result, err)
+	}
+
+	// The lifecycle survives a restart.
+	reloaded := NewNodeRegistry(registry.db, zap.NewNop())
+	if err := reloaded.LoadNodesFromDB(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if node, _ := reloaded.GetNode("n-1"); node.Lifecycle != NodeLifecycleCordoned {
+		t.Fatalf("expected cordoned after reload, got %q", node.Lifecycle)
+	}
+
+	if _, err := maintenance.Uncordon("n-1"); err != nil {
+		t.Fatalf("uncordon: %v", err)
+	}
+	if node, _ := registry.GetNode("n-1"); !node.Schedulable() {
+		t.Fatalf("expected n-1 schedulable, got %q", node.Lifecycle)
+	}
+	if _, err := maintenance.Cordon("n-9"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestNodeDrainWaitsForSessions(t *testing.T) {
+	dispatcher, registry, tracker := setupPlacementDispatcher(t)
+	maintenance := NewNodeMaintenance(registry.db, registry, tracker, dispatcher, zap.NewNop())
+	maintenance.pollInterval = 10 * time.Millisecond
+	defer maintenance.Stop()
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	node, err := maintenance.Drain("n-1", DrainOptions{Timeout: time.Minute})
+	if err != nil || node.Lifecycle != NodeLifecycleDraining {
+		t.Fatalf("expected draining, got %+v (%v)", node, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if node, _ := registry.GetNode("n-1"); node.Lifecycle != NodeLifecycleDraining {
+		t.Fatalf("expected n-1 still draining while s-1 runs, got %q", node.Lifecycle)
+	}
+
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusCompleted)}); err != nil {
+		t.Fatalf("complete session: %v", err)
+	}
+	waitForLifecycle(t, registry, "n-1", NodeLifecycleDrained)
+
+	// A drain that outlives its timeout leaves the node cordoned.
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-2", NodeID: "n-2", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if _, err := maintenance.Drain("n-2", DrainOptions{Timeout: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	waitForLifecycle(t, registry, "n-2", NodeLifecycleCordoned)
+
+	// Uncordoning ends a drain without it recording an outcome.
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-3", NodeID: "n-3", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if _, err := maintenance.Drain("n-3", DrainOptions{Timeout: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if _, err := maintenance.Uncordon("n-3"); err != nil {
+		t.Fatalf("uncordon: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if node, _ := registry.GetNode("n-3"); node.Lifecycle != NodeLifecycleActive {
+		t.Fatalf("expected n-3 active after uncordon, got %q", node.Lifecycle)
+	}
+}
+
+func TestNodeDrainHandsOverSessions(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	for _, node := range []NodeEntry{
+		{ID: "n-1", Hostname: "n-1", Projects: []string{"proj-a"}},
+		{ID: "n-2", Hostname: "n-2", Projects: []string{"proj-a"}},
+	} {
+		if err := registry.Register(node); err != nil {
+			t.Fatalf("register %s: %v", node.ID, err)
+		}
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Model: "anthropic/claude-sonnet-4", CurrentTask: "fix the build"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	var dispatcher *CommandDispatcher
+	sent := make(chan Command, 4)
+	transport := &mockCommandTransport{}
+	transport.onSend = func(nodeID string, cmd Command) {
+		result := CommandResult{CommandID: cmd.CommandID, Status: CommandStatusSuccess}
+		if cmd.Type == CommandTypeCreateSession {
+			result.Output = "s-2"
+		}
+		cmd.Target.NodeID = nodeID
+		sent <- cmd
+		go dispatcher.HandleCommandResult(result)
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+	maintenance := NewNodeMaintenance(db, registry, tracker, dispatcher, logger)
+	maintenance.pollInterval = 10 * time.Millisecond
+	defer maintenance.Stop()
+
+	if _, err := maintenance.Drain("n-1", DrainOptions{Timeout: time.Minute, Handover: true}); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	waitForLifecycle(t, registry, "n-1", NodeLifecycleDrained)
+
+	create, kill := <-sent, <-sent
+	if create.Type != CommandTypeCreateSession || create.Target.NodeID != "n-2" || !strings.Contains(create.Args["prompt"].(string), "fix the build") || create.Args["model"] != "anthropic/claude-sonnet-4" {
+		t.Fatalf("unexpected replacement command %+v", create)
+	}
+	if kill.Type != CommandTypeKillSession || kill.Target.NodeID != "n-1" || kill.Args["session_id"] != "s-1" {
+		t.Fatalf("unexpected kill command %+v", kill)
+	}
+
+	replacement, err := tracker.GetSession("s-2")
+	if err != nil || replacement.NodeID != "n-2" || replacement.ParentSessionID != "s-1" {
+		t.Fatalf("unexpected replacement %+v (%v)", replacement, err)
+	}
+	if original, _ := tracker.GetSession("s-1"); original.Status != SessionStatusKilled {
+		t.Fatalf("expected s-1 killed, got %q", original.Status)
+	}
+}
+
+func TestNodeRemovePurgesState(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	for _, id := range []string{"n-1", "n-2"} {
+		if err := registry.Register(NodeEntry{ID: id, Hostname: id}); err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	for _, session := range []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"},
+		{SessionID: "s-2", NodeID: "n-2", Project: "proj-a", ParentSessionID: "s-1"},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO agent_sequences (agent_id, last_seq, updated_at) VALUES ('n-1', 7, CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("seed sequence: %v", err)
+	}
+	maintenance := NewNodeMaintenance(db, registry, tracker, nil, logger)
+	defer maintenance.Stop()
+
+	if _, err := maintenance.Remove("n-1"); !errors.Is(err, ErrNodeOnline) {
+		t.Fatalf("expected ErrNodeOnline, got %v", err)
+	}
+	if err := registry.MarkOffline("n-1"); err != nil {
+		t.Fatalf("mark offline: %v", err)
+	}
+
+	purged, err := maintenance.Remove("n-1")
+	if err != nil || len(purged) != 1 || purged[0] != "s-1" {
+		t.Fatalf("unexpected remove result %v (%v)", purged, err)
+	}
+	if _, err := registry.GetNode("n-1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected n-1 gone, got %v", err)
+	}
+	for _, session := range tracker.GetAllSessions() {
+		if session.NodeID == "n-1" {
+			t.Fatalf("expected %s dropped from the live view", session.SessionID)
+		}
+	}
+	// History outlives the node: the session is ended, not deleted, and the
+	// handover lineage is kept.
+	if removed, err := tracker.GetSession("s-1"); err != nil || removed.Status != SessionStatusKilled {
+		t.Fatalf("expected s-1 kept and killed, got %+v (%v)", removed, err)
+	}
+	if child, _ := tracker.GetSession("s-2"); child.ParentSessionID != "s-1" {
+		t.Fatalf("expected s-2 to keep its parent s-1, got %q", child.ParentSessionID)
+	}
+	history, err := tracker.History("s-2")
+	if err != nil || len(history.Chain) != 2 {
+		t.Fatalf("expected the lineage kept, got %+v (%v)", history, err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM agent_sequences WHERE agent_id = 'n-1'`).Scan(&rows); err != nil || rows != 0 {
+		t.Fatalf("expected sequence purged, got %d (%v)", rows, err)
+	}
+
+	reloaded := NewNodeRegistry(db, logger)
+	if err := reloaded.LoadNodesFromDB(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, err := reloaded.GetNode("n-1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected n-1 gone after reload, got %v", err)
+	}
+	if _, err := maintenance.Remove("n-1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("expected ErrNodeNotFound, got %v", err)
+	}
+
+	// A host rejoining under the same ID starts as a fresh, active node.
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "host-1b"}); err != nil {
+		t.Fatalf("re-register: %v", err)
+	}
+	if node, err := registry.GetNode("n-1"); err != nil || node.Lifecycle != NodeLifecycleActive {
+		t.Fatalf("expected n-1 active again, got %+v (%v)", node, err)
+	}
+}
+
+func TestHTTPAPINodeMaintenance(t *testing.T) {
+	api, registry, tracker := setupHTTPAPI(t)
+	seedNode(t, registry, "n-1", "host-1")
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-1/cordon", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without maintenance, got %d", rec.Code)
+	}
+
+	maintenance := NewNodeMaintenance(api.db, registry, tracker, nil, zap.NewNop())
+	defer maintenance.Stop()
+	api.SetNodeMaintenance(maintenance)
+
+	decodeNode := func(rec *httptest.ResponseRecorder) nodeJSON {
+		t.Helper()
+		var resp struct {
+			Data nodeJSON `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-1/cordon", ""))
+	if rec.Code != http.StatusOK || decodeNode(rec).Lifecycle != NodeLifecycleCordoned {
+		t.Fatalf("unexpected cordon response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/nodes", ""))
+	if !strings.Contains(rec.Body.String(), `"lifecycle":"cordoned"`) {
+		t.Fatalf("expected lifecycle in node list, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-1/drain", `{"timeout_seconds":-1}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative timeout, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-1/drain", `{"timeout_seconds":60}`))
+	if rec.Code != http.StatusOK || decodeNode(rec).Lifecycle != NodeLifecycleDraining {
+		t.Fatalf("unexpected drain response %d: %s", rec.Code, rec.Body.String())
+	}
+	// n-1 has no active session, so the drain finishes straight away.
+	waitForLifecycle(t, registry, "n-1", NodeLifecycleDrained)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/nodes/n-9/uncordon", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodDelete, "/api/v1/nodes/n-1", ""))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for an online node, got %d", rec.Code)
+	}
+
+	if err := registry.MarkOffline("n-1"); err != nil {
+		t.Fatalf("mark offline: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodDelete, "/api/v1/nodes/n-1", ""))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"node_id":"n-1"`) {
+		t.Fatalf("unexpected remove response %d: %s", rec.Code, rec.Body.String())
+	}
+}
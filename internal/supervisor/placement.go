@@ -0,0 +1,157 @@
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// authStatusAuthenticated is the auth state a node must report for a tool
+// before commands needing that tool are placed on it.
+const authStatusAuthenticated = "authenticated"
+
+// PlacementCandidate is an online node able to serve a command, with the load
+// strategies weigh. AuthUnknown is set when the command needs a tool the node
+// has not reported an auth state for.
+type PlacementCandidate struct {
+	Node            NodeEntry
+	ActiveSessions  int
+	ProjectSessions int
+	AuthUnknown     bool
+}
+
+// PlacementStrategy picks the node a command for project runs on from a
+// non-empty list of candidates, and says why.
+type PlacementStrategy interface {
+	Name() string
+	Place(project string, candidates []PlacementCandidate) (PlacementCandidate, string, error)
+}
+
+// NewPlacementStrategy builds the strategy named by cfg, defaulting to
+// least-loaded.
+func NewPlacementStrategy(cfg config.PlacementConfig) PlacementStrategy {
+	switch cfg.Strategy {
+	case config.PlacementSpread:
+		return spreadStrategy{}
+	case config.PlacementPinned:
+		return pinnedStrategy{pins: cfg.Pins}
+	default:
+		return leastLoadedStrategy{}
+	}
+}
+
+// leastLoadedStrategy prefers nodes known to be authenticated for the tool,
+// then the fewest active sessions, then the lowest reported CPU and memory.
+type leastLoadedStrategy struct{}
+
+func (leastLoadedStrategy) Name() string { return config.PlacementLeastLoaded }
+
+func (leastLoadedStrategy) Place(_ string, candidates []PlacementCandidate) (PlacementCandidate, string, error) {
+	ranked := rankCandidates(candidates, func(a, b PlacementCandidate) (bool, bool) {
+		if a.ActiveSessions != b.ActiveSessions {
+			return a.ActiveSessions < b.ActiveSessions, true
+		}
+		return false, false
+	})
+	chosen := ranked[0]
+	return chosen, "least loaded: " + describeCandidate(chosen), nil
+}
+
+// spreadStrategy prefers the node running the fewest of the project's own
+// sessions, so a project's work is spread across the nodes that have it.
+type spreadStrategy struct{}
+
+func (spreadStrategy) Name() string { return config.PlacementSpread }
+
+func (spreadStrategy) Place(project string, candidates []PlacementCandidate) (PlacementCandidate, string, error) {
+	ranked := rankCandidates(candidates, func(a, b PlacementCandidate) (bool, bool) {
+		if a.ProjectSessions != b.ProjectSessions {
+			return a.ProjectSessions < b.ProjectSessions, true
+		}
+		if a.ActiveSessions != b.ActiveSessions {
+			return a.ActiveSessions < b.ActiveSessions, true
+		}
+		return false, false
+	})
+	chosen := ranked[0]
+	return chosen, fmt.Sprintf("spread: %d %s sessions, %s", chosen.ProjectSessions, project, describeCandidate(chosen)), nil
+}
+
+// pinnedStrategy sends a project to its pinned node and refuses to place it
+// anywhere else. Unpinned projects are placed least-loaded.
+type pinnedStrategy struct {
+	pins map[string]string
+}
+
+func (pinnedStrategy) Name() string { return config.PlacementPinned }
+
+func (s pinnedStrategy) Place(project string, candidates []PlacementCandidate) (PlacementCandidate, string, error) {
+	nodeID, ok := s.pins[project]
+	if !ok {
+		chosen, reason, err := leastLoadedStrategy{}.Place(project, candidates)
+		return chosen, "not pinned, " + reason, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Node.ID == nodeID {
+			return candidate, "pinned to " + nodeID, nil
+		}
+	}
+	return PlacementCandidate{}, "", fmt.Errorf("project %s is pinned to node %s, which cannot serve it", project, nodeID)
+}
+
+// rankCandidates orders candidates by auth certainty, then by primary, then
+// by reported CPU and memory, then by node ID so placement is deterministic.
+// primary returns (less, decided).
+func rankCandidates(candidates []PlacementCandidate, primary func(a, b PlacementCandidate) (bool, bool)) []PlacementCandidate {
+	ranked := append([]PlacementCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.AuthUnknown != b.AuthUnknown {
+			return !a.AuthUnknown
+		}
+		if less, decided := primary(a, b); decided {
+			return less
+		}
+		if a.Node.Load.CPUPercent != b.Node.Load.CPUPercent {
+			return a.Node.Load.CPUPercent < b.Node.Load.CPUPercent
+		}
+		if a.Node.Load.MemoryPercent != b.Node.Load.MemoryPercent {
+			return a.Node.Load.MemoryPercent < b.Node.Load.MemoryPercent
+		}
+		return a.Node.ID < b.Node.ID
+	})
+	return ranked
+}
+
+func describeCandidate(c PlacementCandidate) string {
+	parts := []string{fmt.Sprintf("%d active sessions", c.ActiveSessions)}
+	if !c.Node.Load.ReportedAt.IsZero() {
+		parts = append(parts, fmt.Sprintf("cpu %.0f%%", c.Node.Load.CPUPercent), fmt.Sprintf("memory %.0f%%", c.Node.Load.MemoryPercent))
+	}
+	if c.AuthUnknown {
+		parts = append(parts, "tool auth unknown")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// labelsMatch reports whether labels carry every required key and value.
+func labelsMatch(labels, required map[string]string) bool {
+	for key, value := range required {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,158 @@
+package supervisor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func setupPlacementDispatcher(t *testing.T) (*CommandDispatcher, *NodeRegistry, *SessionTracker) {
+	t.Helper()
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	for _, node := range []NodeEntry{
+		{ID: "n-1", Hostname: "n-1", Projects: []string{"proj-a"}, Labels: map[string]string{"zone": "office"}},
+		{ID: "n-2", Hostname: "n-2", Projects: []string{"proj-a", "proj-b"}, Labels: map[string]string{"zone": "lab"}},
+		{ID: "n-3", Hostname: "n-3", Projects: []string{"proj-a"}, Labels: map[string]string{"zone": "office"}},
+	} {
+		if err := registry.Register(node); err != nil {
+			t.Fatalf("register %s: %v", node.ID, err)
+		}
+	}
+
+	var dispatcher *CommandDispatcher
+	transport := &mockCommandTransport{}
+	transport.onSend = func(_ string, cmd Command) {
+		go dispatcher.HandleCommandResult(CommandResult{CommandID: cmd.CommandID, Status: CommandStatusSuccess})
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+	return dispatcher, registry, tracker
+}
+
+func dispatchCreate(t *testing.T, dispatcher *CommandDispatcher, project string, args map[string]interface{}) *CommandResult {
+	t.Helper()
+	result, err := dispatcher.DispatchCommand(context.Background(), Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: project},
+		Args:   args,
+	})
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	return result
+}
+
+func TestPlacementLeastLoaded(t *testing.T) {
+	dispatcher, registry, tracker := setupPlacementDispatcher(t)
+	for _, session := range []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"},
+		{SessionID: "s-2", NodeID: "n-3", Project: "proj-a", Status: SessionStatusKilled},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+	if err := registry.UpdateLoad("n-2", NodeLoad{CPUPercent: 80, MemoryPercent: 40}); err != nil {
+		t.Fatalf("update load: %v", err)
+	}
+	if err := registry.UpdateLoad("n-3", NodeLoad{CPUPercent: 20, MemoryPercent: 40}); err != nil {
+		t.Fatalf("update load: %v", err)
+	}
+
+	// n-2 and n-3 run no active sessions; n-3 reports less CPU.
+	result := dispatchCreate(t, dispatcher, "proj-a", nil)
+	if result.NodeID != "n-3" || result.Placement != "least loaded: 0 active sessions, cpu 20%, memory 40%" {
+		t.Fatalf("unexpected placement %q: %q", result.NodeID, result.Placement)
+	}
+
+	// Nodes reporting the tool unauthenticated are skipped, and nodes that
+	// have not reported it rank behind those that are authenticated.
+	if err := registry.UpdateAuthState("n-3", map[string]NodeAuthState{"claude": {Tool: "claude", Status: "unauthenticated"}}); err != nil {
+		t.Fatalf("update auth: %v", err)
+	}
+	if err := registry.UpdateAuthState("n-1", map[string]NodeAuthState{"claude": {Tool: "claude", Status: authStatusAuthenticated}}); err != nil {
+		t.Fatalf("update auth: %v", err)
+	}
+	result = dispatchCreate(t, dispatcher, "proj-a", map[string]interface{}{"tool": "claude"})
+	if result.NodeID != "n-1" {
+		t.Fatalf("expected authenticated n-1, got %q (%s)", result.NodeID, result.Placement)
+	}
+
+	// Required labels narrow the candidates.
+	dispatcher.SetPlacement(config.PlacementConfig{Labels: map[string]map[string]string{"proj-a": {"zone": "lab"}}})
+	result = dispatchCreate(t, dispatcher, "proj-a", map[string]interface{}{"tool": "claude"})
+	if result.NodeID != "n-2" || !strings.HasSuffix(result.Placement, "tool auth unknown") {
+		t.Fatalf("expected n-2 by label, got %q (%s)", result.NodeID, result.Placement)
+	}
+
+	dispatcher.SetPlacement(config.PlacementConfig{Labels: map[string]map[string]string{"proj-a": {"zone": "moon"}}})
+	result = dispatchCreate(t, dispatcher, "proj-a", nil)
+	if result.Status != CommandStatusFailure || !strings.Contains(result.Error, "n-1 lacks labels zone=moon") {
+		t.Fatalf("expected label failure, got %+v", result)
+	}
+}
+
+func TestPlacementSpreadAndPinned(t *testing.T) {
+	dispatcher, _, tracker := setupPlacementDispatcher(t)
+	for _, session := range []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-b"},
+		{SessionID: "s-2", NodeID: "n-1", Project: "proj-b"},
+		{SessionID: "s-3", NodeID: "n-2", Project: "proj-a"},
+		{SessionID: "s-4", NodeID: "n-3", Project: "proj-a"},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+
+	// n-1 runs the most sessions but none of proj-a's.
+	dispatcher.SetPlacement(config.PlacementConfig{Strategy: config.PlacementSpread})
+	result := dispatchCreate(t, dispatcher, "proj-a", nil)
+	if result.NodeID != "n-1" || result.Placement != "spread: 0 proj-a sessions, 2 active sessions" {
+		t.Fatalf("unexpected placement %q: %q", result.NodeID, result.Placement)
+	}
+
+	dispatcher.SetPlacement(config.PlacementConfig{Strategy: config.PlacementPinned, Pins: map[string]string{"proj-a": "n-3", "proj-b": "n-9"}})
+	result = dispatchCreate(t, dispatcher, "proj-a", nil)
+	if result.NodeID != "n-3" || result.Placement != "pinned to n-3" {
+		t.Fatalf("unexpected placement %q: %q", result.NodeID, result.Placement)
+	}
+	result = dispatchCreate(t, dispatcher, "proj-b", nil)
+	if result.Status != CommandStatusFailure || result.Error != "project proj-b is pinned to node n-9, which cannot serve it" {
+		t.Fatalf("expected pinned failure, got %+v", result)
+	}
+
+	// Commands for a session go to the node running it.
+	result, err := dispatcher.DispatchCommand(context.Background(), Command{
+		Type:   CommandTypePromptSession,
+		Target: CommandTarget{Project: "proj-a"},
+		Args:   map[string]interface{}{"session_id": "s-3", "prompt": "go on"},
+	})
+	if err != nil || result.NodeID != "n-2" || result.Placement != "session s-3 runs on n-2" {
+		t.Fatalf("unexpected session placement %+v (%v)", result, err)
+	}
+}
+
+func TestHubHeartbeatRecordsNodeLoad(t *testing.T) {
+	registry := NewNodeRegistry(setupSupervisorTestDB(t), zap.NewNop())
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureNodeRegistry(registry)
+
+	hub.recordHeartbeat("n-1", []byte(`{"cpu_percent":42.5,"memory_percent":63}`))
+	node, err := registry.GetNode("n-1")
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if node.Load.CPUPercent != 42.5 || node.Load.MemoryPercent != 63 || node.Load.ReportedAt.IsZero() {
+		t.Fatalf("unexpected load %+v", node.Load)
+	}
+}
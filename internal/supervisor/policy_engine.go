@@ -38,6 +38,8 @@ type PolicyEngine struct {
 
 	retryMu sync.Mutex
 	retries map[string]map[string]retryState
+	// compacting holds the sessions with a compaction in flight.
+	compacting map[string]bool
 
 	eventMu  sync.Mutex
 	eventSeq uint64
@@ -66,6 +68,7 @@ func NewPolicyEngine(
 		ctx:        ctx,
 		cancel:     cancel,
 		retries:    make(map[string]map[string]retryState),
+		compacting: make(map[string]bool),
 	}
 }
 
@@ -133,6 +136,7 @@ func (p *PolicyEngine) runChecks(now time.Time) {
 		default:
 		}
 		p.evaluateResumeOnIdle(session, now)
+		p.evaluateCompactOnContext(session, now)
 		p.evaluateRestartOnCompaction(session, now)
 		p.evaluateKillOnCost(session, now)
 	}
@@ -147,7 +151,7 @@ func (p *PolicyEngine) evaluateResumeOnIdle(session TrackedSession, now time.Tim
 		return
 	}
 
-	p.tryIntervention(session, "resume_on_idle", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypePromptSession)
+	p.tryIntervention(session, "resume_on_idle", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypePromptSession, policyCommandTimeout)
 }
 
 func (p *PolicyEngine) evaluateRestartOnCompaction(session TrackedSession, _ time.Time) {
@@ -155,11 +159,70 @@ func (p *PolicyEngine) evaluateRestartOnCompaction(session TrackedSession, _ tim
 	if !policy.Enabled {
 		return
 	}
-	if session.TokenUsage.Total < policy.TokenThreshold {
+	if !compactionThresholdReached(policy, session) {
 		return
 	}
 
-	p.tryIntervention(session, "restart_on_compaction", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypeRestartSession)
+	p.tryIntervention(session, "restart_on_compaction", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypeRestartSession, policyCommandTimeout)
+}
+
+// evaluateCompactOnContext summarizes sessions whose context use crosses the
+// configured share of their model's window. Sessions with an unknown window
+// are skipped, and a session is not compacted again within the cooldown or
+// while its compaction is in flight. A summary takes minutes, so it runs in
+// the background with CompactCommandTimeout instead of holding up the check.
+func (p *PolicyEngine) evaluateCompactOnContext(session TrackedSession, now time.Time) {
+	policy := p.config.CompactOnContext
+	if !policy.Enabled {
+		return
+	}
+	utilization, ok := session.ContextUtilization()
+	if !ok || utilization < policy.ContextPercent {
+		return
+	}
+	if last := p.lastSuccess(session.SessionID, "compact_on_context"); !last.IsZero() && now.Sub(last) < time.Duration(policy.CooldownSeconds)*time.Second {
+		return
+	}
+
+	if !session.Status.AllowsCommand(CommandTypeCompactSession) || !p.startCompaction(session.SessionID) {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.finishCompaction(session.SessionID)
+		p.tryIntervention(session, "compact_on_context", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypeCompactSession, CompactCommandTimeout)
+	}()
+}
+
+// startCompaction claims sessionID for a compaction, reporting false when
+// one is already in flight.
+func (p *PolicyEngine) startCompaction(sessionID string) bool {
+	p.retryMu.Lock()
+	defer p.retryMu.Unlock()
+	if p.compacting[sessionID] {
+		return false
+	}
+	p.compacting[sessionID] = true
+	return true
+}
+
+func (p *PolicyEngine) finishCompaction(sessionID string) {
+	p.retryMu.Lock()
+	defer p.retryMu.Unlock()
+	delete(p.compacting, sessionID)
+}
+
+// compactionThresholdReached uses the share of the model's context window
+// when the policy sets context_percent and the window is known, and the
+// absolute token threshold otherwise.
+func compactionThresholdReached(policy config.CompactionPolicyConfig, session TrackedSession) bool {
+	if policy.ContextPercent > 0 {
+		if utilization, ok := session.ContextUtilization(); ok {
+			return utilization >= policy.ContextPercent
+		}
+	}
+	return session.TokenUsage.Total >= policy.TokenThreshold
 }
 
 func (p *PolicyEngine) evaluateKillOnCost(session TrackedSession, _ time.Time) {
@@ -171,10 +234,17 @@ func (p *PolicyEngine) evaluateKillOnCost(session TrackedSession, _ time.Time) {
 		return
 	}
 
-	p.tryIntervention(session, "kill_on_cost", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypeKillSession)
+	p.tryIntervention(session, "kill_on_cost", policy.MaxRetries, time.Duration(policy.RetryResetSeconds)*time.Second, CommandTypeKillSession, policyCommandTimeout)
 }
 
-func (p *PolicyEngine) tryIntervention(session TrackedSession, policyName string, maxRetries int, retryResetWindow time.Duration, commandType CommandType) {
+// policyCommandTimeout bounds the quick interventions run inline by a check.
+const policyCommandTimeout = 2 * time.Second
+
+func (p *PolicyEngine) tryIntervention(session TrackedSession, policyName string, maxRetries int, retryResetWindow time.Duration, commandType CommandType, timeout time.Duration) {
+	if !session.Status.AllowsCommand(commandType) {
+		// e.g. no resume while the session is being handed over.
+		return
+	}
 	now := time.Now().UTC()
 	canAttempt, retries := p.canAttempt(session.SessionID, policyName, maxRetries, retryResetWindow, now)
 	if !canAttempt {
@@ -187,7 +257,7 @@ func (p *PolicyEngine) tryIntervention(session TrackedSession, policyName string
 			NodeID:  session.NodeID,
 			Project: session.Project,
 		},
-		Timeout: 2 * time.Second,
+		Timeout: timeout,
 		Args: map[string]interface{}{
 			"session_id": session.SessionID,
 			"policy":     policyName,
@@ -259,6 +329,13 @@ func (p *PolicyEngine) markSuccess(sessionID, policyName string, now time.Time)
 	p.setRetryStateLocked(sessionID, policyName, state)
 }
 
+func (p *PolicyEngine) lastSuccess(sessionID, policyName string) time.Time {
+	p.retryMu.Lock()
+	defer p.retryMu.Unlock()
+
+	return p.getRetryStateLocked(sessionID, policyName).lastSuccess
+}
+
 func (p *PolicyEngine) RetryCount(sessionID, policyName string) int {
 	p.retryMu.Lock()
 	defer p.retryMu.Unlock()
@@ -321,6 +398,12 @@ func (p *PolicyEngine) emitPolicyEvent(eventType, sessionID string, payload map[
 	}
 
 	p.eventMu.Lock()
+	if p.eventSeq == 0 {
+		// Continue after events emitted before a supervisor restart.
+		if source, ok := p.events.(sequenceSource); ok {
+			p.eventSeq = source.LastSequence(policyEngineAgentID)
+		}
+	}
 	p.eventSeq++
 	seq := p.eventSeq
 	p.eventMu.Unlock()
@@ -360,6 +443,19 @@ func withPolicyDefaults(cfg config.PolicyConfig) config.PolicyConfig {
 		cfg.RestartOnCompaction.RetryResetSeconds = 3600
 	}
 
+	if cfg.CompactOnContext.ContextPercent <= 0 {
+		cfg.CompactOnContext.ContextPercent = 70
+	}
+	if cfg.CompactOnContext.CooldownSeconds <= 0 {
+		cfg.CompactOnContext.CooldownSeconds = 600
+	}
+	if cfg.CompactOnContext.MaxRetries <= 0 {
+		cfg.CompactOnContext.MaxRetries = 2
+	}
+	if cfg.CompactOnContext.RetryResetSeconds <= 0 {
+		cfg.CompactOnContext.RetryResetSeconds = 3600
+	}
+
 	if cfg.KillOnCost.CostThresholdUSD <= 0 {
 		cfg.KillOnCost.CostThresholdUSD = 10
 	}
@@ -342,3 +342,113 @@ func TestPolicyEngineStopStopsChecks(t *testing.T) {
 		t.Fatalf("expected no additional checks after stop, at_stop=%d after=%d", atStop, afterStop)
 	}
 }
+
+func TestPolicyEngineCompactOnContext(t *testing.T) {
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "session-full", NodeID: "node-5", Project: "proj-e", Status: SessionStatusRunning, ContextTokens: 150000, ContextWindow: 200000},
+		{SessionID: "session-light", NodeID: "node-5", Project: "proj-e", Status: SessionStatusRunning, ContextTokens: 50000, ContextWindow: 200000},
+		{SessionID: "session-unknown", NodeID: "node-5", Project: "proj-e", Status: SessionStatusRunning, TokenUsage: TokenUsage{Total: 900000}},
+	}}
+	dispatcher := &policyTestDispatcher{}
+	events := &policyTestEvents{}
+	engine := NewPolicyEngine(config.PolicyConfig{
+		CompactOnContext: config.ContextCompactionPolicyConfig{
+			Enabled:         true,
+			ContextPercent:  70,
+			CooldownSeconds: 600,
+		},
+	}, tracker, dispatcher, events)
+
+	now := time.Now().UTC()
+	engine.runChecks(now)
+	engine.wg.Wait()
+
+	if dispatcher.callCount() != 1 {
+		t.Fatalf("expected one compaction, got %d calls", dispatcher.callCount())
+	}
+	dispatcher.mu.Lock()
+	call := dispatcher.calls[0]
+	dispatcher.mu.Unlock()
+	if call.Type != CommandTypeCompactSession || call.Args["session_id"] != "session-full" || call.Target.NodeID != "node-5" {
+		t.Fatalf("unexpected compaction command %+v", call)
+	}
+	if call.Timeout != CompactCommandTimeout {
+		t.Fatalf("expected the compaction timeout, got %v", call.Timeout)
+	}
+	if !events.hasEventType("policy.action") {
+		t.Fatal("expected policy.action event")
+	}
+
+	// Usage has not been re-reported yet; the cooldown prevents a second
+	// compaction of the same session.
+	engine.runChecks(now.Add(time.Minute))
+	engine.wg.Wait()
+	if dispatcher.callCount() != 1 {
+		t.Fatalf("expected cooldown to suppress re-compaction, got %d calls", dispatcher.callCount())
+	}
+
+	engine.runChecks(now.Add(11 * time.Minute))
+	engine.wg.Wait()
+	if dispatcher.callCount() != 2 {
+		t.Fatalf("expected compaction after cooldown, got %d calls", dispatcher.callCount())
+	}
+}
+
+// blockingDispatcher holds every command until release is closed, like a
+// summary the agent is still generating.
+type blockingDispatcher struct {
+	policyTestDispatcher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDispatcher) DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error) {
+	d.started <- struct{}{}
+	<-d.release
+	return d.policyTestDispatcher.DispatchCommand(ctx, cmd)
+}
+
+func TestPolicyEngineCompactOnContextInFlight(t *testing.T) {
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "session-full", NodeID: "node-5", Project: "proj-e", Status: SessionStatusRunning, ContextTokens: 150000, ContextWindow: 200000},
+	}}
+	dispatcher := &blockingDispatcher{started: make(chan struct{}, 4), release: make(chan struct{})}
+	events := &policyTestEvents{}
+	engine := NewPolicyEngine(config.PolicyConfig{
+		CompactOnContext: config.ContextCompactionPolicyConfig{
+			Enabled:         true,
+			ContextPercent:  70,
+			CooldownSeconds: 600,
+			MaxRetries:      1,
+		},
+	}, tracker, dispatcher, events)
+
+	now := time.Now().UTC()
+	engine.runChecks(now)
+	select {
+	case <-dispatcher.started:
+	case <-time.After(time.Second):
+		t.Fatal("expected a compaction to start")
+	}
+
+	// Checks while the summary is running neither wait for it nor start
+	// another one.
+	engine.runChecks(now.Add(30 * time.Second))
+	engine.runChecks(now.Add(time.Minute))
+	select {
+	case <-dispatcher.started:
+		t.Fatal("expected no compaction while one is in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(dispatcher.release)
+	engine.wg.Wait()
+	if dispatcher.callCount() != 1 || events.hasEventType("policy.alert") {
+		t.Fatalf("expected one successful compaction, got %d calls", dispatcher.callCount())
+	}
+	engine.runChecks(now.Add(2 * time.Minute))
+	engine.wg.Wait()
+	if dispatcher.callCount() != 1 {
+		t.Fatalf("expected the cooldown to follow the finished compaction, got %d calls", dispatcher.callCount())
+	}
+}
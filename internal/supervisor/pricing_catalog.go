@@ -0,0 +1,181 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// PricingEntry is one model rate and the UTC date from which it applies. An
+// entry without a provider applies to the model under any provider.
+type PricingEntry struct {
+	Model         string `json:"model"`
+	Provider      string `json:"provider,omitempty"`
+	EffectiveFrom string `json:"effective_from"`
+	config.ModelRate
+
+	effective time.Time
+}
+
+type pricingCatalogFile struct {
+	Rates []PricingEntry `json:"rates"`
+}
+
+// PricingCatalog holds versioned model rates so that a price change only
+// affects usage on or after its effective date.
+type PricingCatalog struct {
+	entries []PricingEntry
+}
+
+// PricingIssue describes one invalid catalog entry.
+type PricingIssue struct {
+	Index   int    `json:"index"`
+	Model   string `json:"model,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i PricingIssue) Error() string {
+	if i.Model == "" {
+		return fmt.Sprintf("rates[%d]: %s", i.Index, i.Message)
+	}
+	return fmt.Sprintf("rates[%d] (%s): %s", i.Index, i.Model, i.Message)
+}
+
+// LoadPricingCatalog reads and validates a catalog file.
+func LoadPricingCatalog(path string) (*PricingCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing catalog: %w", err)
+	}
+	catalog, issues, err := ParsePricingCatalog(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		return nil, fmt.Errorf("invalid pricing catalog: %w", issues[0])
+	}
+	return catalog, nil
+}
+
+// ParsePricingCatalog decodes a catalog and reports every invalid entry. The
+// returned catalog only contains valid entries; err is set only when the
+// document itself cannot be decoded.
+func ParsePricingCatalog(data []byte) (*PricingCatalog, []PricingIssue, error) {
+	var file pricingCatalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("decode pricing catalog: %w", err)
+	}
+
+	catalog := &PricingCatalog{entries: make([]PricingEntry, 0, len(file.Rates))}
+	issues := make([]PricingIssue, 0)
+	seen := map[string]int{}
+
+	for i, entry := range file.Rates {
+		issue := func(msg string, args ...interface{}) {
+			issues = append(issues, PricingIssue{Index: i, Model: entry.Model, Message: fmt.Sprintf(msg, args...)})
+		}
+
+		if entry.Model == "" {
+			issue("model is required")
+			continue
+		}
+		effective, err := time.Parse("2006-01-02", entry.EffectiveFrom)
+		if err != nil {
+			issue("effective_from %q must be YYYY-MM-DD", entry.EffectiveFrom)
+			continue
+		}
+		if entry.Input < 0 || entry.Output < 0 || entry.CacheRead < 0 || entry.CacheWrite < 0 {
+			issue("rates must be >= 0")
+			continue
+		}
+		if entry.Input == 0 && entry.Output == 0 {
+			issue("input or output rate is required")
+			continue
+		}
+		key := entry.Provider + "|" + entry.Model + "|" + entry.EffectiveFrom
+		if previous, ok := seen[key]; ok {
+			issue("duplicates rates[%d] for the same provider and effective_from", previous)
+			continue
+		}
+		seen[key] = i
+
+		entry.effective = effective
+		catalog.entries = append(catalog.entries, entry)
+	}
+
+	sort.SliceStable(catalog.entries, func(i, j int) bool {
+		a, b := catalog.entries[i], catalog.entries[j]
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.effective.Before(b.effective)
+	})
+
+	return catalog, issues, nil
+}
+
+// Entries returns the catalog sorted by model, provider and effective date.
+func (c *PricingCatalog) Entries() []PricingEntry {
+	if c == nil {
+		return nil
+	}
+	out := make([]PricingEntry, len(c.entries))
+	copy(out, c.entries)
+	return out
+}
+
+// RateAt returns the rate in effect for model on the UTC date of at. Entries
+// for the given provider win over provider-agnostic entries with the same or
+// an earlier effective date.
+func (c *PricingCatalog) RateAt(provider, model string, at time.Time) (PricingEntry, bool) {
+	if c == nil || model == "" {
+		return PricingEntry{}, false
+	}
+
+	day := startOfUTCDay(at)
+	var (
+		best  PricingEntry
+		found bool
+	)
+	for _, entry := range c.entries {
+		if entry.Model != model || entry.effective.After(day) {
+			continue
+		}
+		if entry.Provider != "" && entry.Provider != provider {
+			continue
+		}
+		if !found || entry.effective.After(best.effective) ||
+			(entry.effective.Equal(best.effective) && entry.Provider != "" && best.Provider == "") {
+			best = entry
+			found = true
+		}
+	}
+	return best, found
+}
+
+// providerFor returns the provider named by the model's most recent
+// provider-specific entry, if any.
+func (c *PricingCatalog) providerFor(model string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	provider := ""
+	var latest time.Time
+	for _, entry := range c.entries {
+		if entry.Model != model || entry.Provider == "" {
+			continue
+		}
+		if provider == "" || entry.effective.After(latest) {
+			provider = entry.Provider
+			latest = entry.effective
+		}
+	}
+	return provider, provider != ""
+}
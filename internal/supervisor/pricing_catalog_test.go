@@ -0,0 +1,284 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+const testPricingCatalog = `{
+	"rates": [
+		{"model": "gpt-4o", "effective_from": "2024-05-13", "input": 0.005, "output": 0.015},
+		{"model": "gpt-4o", "effective_from": "2024-08-06", "input": 0.0025, "output": 0.01},
+		{"model": "gpt-4o", "provider": "azure", "effective_from": "2024-08-06", "input": 0.003, "output": 0.012},
+		{"model": "claude-sonnet-4", "provider": "anthropic", "effective_from": "2025-05-22", "input": 0.003, "output": 0.015, "cache_read": 0.0003}
+	]
+}`
+
+func TestParsePricingCatalogReportsIssues(t *testing.T) {
+	catalog, issues, err := ParsePricingCatalog([]byte(`{
+		"rates": [
+			{"model": "gpt-4o", "effective_from": "2024-05-13", "input": 0.005, "output": 0.015},
+			{"effective_from": "2024-05-13", "input": 1},
+			{"model": "gpt-4o", "effective_from": "05/13/2024", "input": 1},
+			{"model": "gpt-4o", "effective_from": "2024-06-01", "input": -1},
+			{"model": "gpt-4o", "effective_from": "2024-07-01"},
+			{"model": "gpt-4o", "effective_from": "2024-05-13", "input": 0.004}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(issues) != 5 {
+		t.Fatalf("expected 5 issues, got %+v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Index != i+1 {
+			t.Fatalf("expected issue for rates[%d], got %+v", i+1, issue)
+		}
+	}
+	if len(catalog.Entries()) != 1 {
+		t.Fatalf("expected only the valid entry to load, got %+v", catalog.Entries())
+	}
+
+	if _, _, err := ParsePricingCatalog([]byte(`{"rates": [`)); err == nil {
+		t.Fatal("expected decode error for malformed document")
+	}
+}
+
+func TestLoadPricingCatalogRejectsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(`{"rates": [{"model": "gpt-4o", "effective_from": "soon", "input": 1}]}`), 0o600); err != nil {
+		t.Fatalf("write catalog: %v", err)
+	}
+	if _, err := LoadPricingCatalog(path); err == nil {
+		t.Fatal("expected invalid catalog to fail to load")
+	}
+	if _, err := LoadPricingCatalog(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected missing catalog to fail to load")
+	}
+}
+
+func TestPricingCatalogRateAt(t *testing.T) {
+	catalog, issues, err := ParsePricingCatalog([]byte(testPricingCatalog))
+	if err != nil || len(issues) > 0 {
+		t.Fatalf("parse: %v %+v", err, issues)
+	}
+
+	if _, ok := catalog.RateAt("openai", "gpt-4o", time.Date(2024, 5, 12, 23, 0, 0, 0, time.UTC)); ok {
+		t.Fatal("expected no rate before the first effective date")
+	}
+
+	entry, ok := catalog.RateAt("openai", "gpt-4o", time.Date(2024, 8, 5, 23, 59, 0, 0, time.UTC))
+	if !ok || entry.Input != 0.005 {
+		t.Fatalf("expected launch rate the day before the price cut, got %+v ok=%v", entry, ok)
+	}
+
+	entry, ok = catalog.RateAt("openai", "gpt-4o", time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC))
+	if !ok || entry.Input != 0.0025 || entry.Provider != "" {
+		t.Fatalf("expected reduced rate from its effective date, got %+v ok=%v", entry, ok)
+	}
+
+	entry, ok = catalog.RateAt("azure", "gpt-4o", time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC))
+	if !ok || entry.Input != 0.003 {
+		t.Fatalf("expected provider-specific rate to win, got %+v ok=%v", entry, ok)
+	}
+
+	if _, ok := catalog.RateAt("openrouter", "claude-sonnet-4", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatal("expected provider-specific entry to be ignored for other providers")
+	}
+
+	var nilCatalog *PricingCatalog
+	if _, ok := nilCatalog.RateAt("openai", "gpt-4o", time.Now()); ok || nilCatalog.Entries() != nil {
+		t.Fatal("expected nil catalog to have no rates")
+	}
+}
+
+func TestCostFallbackUsesCatalogRateOfUsageDay(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	usage := TokenUsage{Prompt: 1000, Completion: 1000, Total: 2000}
+	for _, session := range []TrackedSession{
+		{SessionID: "before", NodeID: "n-1", Project: "proj-a", Model: "gpt-4o", TokenUsage: usage, StartedAt: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{SessionID: "after", NodeID: "n-1", Project: "proj-a", Model: "gpt-4o", TokenUsage: usage, StartedAt: time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+
+	// The session started before the price cut keeps reporting usage today,
+	// which is priced at today's rate.
+	if err := tracker.RecordMessageUsage("before", "msg-1", "", usage); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(testPricingCatalog), 0o600); err != nil {
+		t.Fatalf("write catalog: %v", err)
+	}
+
+	agg := NewCostAggregator(config.CostConfig{
+		PricingCatalog: path,
+		Providers: config.CostProviders{
+			OpenAI: config.CostProviderConfig{
+				APIKey:     "sk-org-test",
+				ModelRates: map[string]config.ModelRate{"gpt-4o": {Input: 1, Output: 1}},
+			},
+		},
+	}, db, tracker, zap.NewNop())
+
+	provider, ok := agg.providerForModel("gpt-4o")
+	if !ok {
+		t.Fatal("expected a provider for gpt-4o")
+	}
+	agg.applySessionEstimateFallback(provider)
+
+	for id, want := range map[string]float64{"before": 0.02 + 0.0125, "after": 0.0125} {
+		session, err := tracker.GetSession(id)
+		if err != nil {
+			t.Fatalf("get session: %v", err)
+		}
+		if math.Abs(session.SessionCost-want) > 1e-9 {
+			t.Fatalf("session %s: expected %f at the catalog rate of each usage day, got %f", id, want, session.SessionCost)
+		}
+	}
+}
+
+func TestLocalPricingProviderPrefersCatalogRate(t *testing.T) {
+	// The session started before the price cut; its usage on this day is
+	// priced at the rate in effect on it.
+	days := []SessionDayUsage{
+		{SessionID: "s-1", Model: "gpt-4o", Date: "2024-07-01", Usage: TokenUsage{Prompt: 1000, Total: 1000}},
+		{SessionID: "s-1", Model: "gpt-4o", Date: "2024-09-01", Usage: TokenUsage{Prompt: 1000, Total: 1000}},
+	}
+	catalog, _, err := ParsePricingCatalog([]byte(testPricingCatalog))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	provider, err := newLocalPricingCostProvider(providerLocal, config.CostProviderConfig{
+		ModelRates: map[string]config.ModelRate{"gpt-4o": {Input: 1, Output: 1}},
+	}, CostProviderDeps{
+		DailyUsage: dailyUsageFrom(days),
+		RateAt: func(provider, model string, at time.Time) (config.ModelRate, bool) {
+			entry, ok := catalog.RateAt(provider, model, at)
+			return entry.ModelRate, ok
+		},
+	})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	records, err := provider.FetchUsage(context.Background(), time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("fetch usage: %v", err)
+	}
+	if len(records) != 1 || math.Abs(records[0].CostUSD-0.0025) > 1e-9 {
+		t.Fatalf("expected catalog-priced record, got %+v", records)
+	}
+}
+
+func TestHTTPAPIPricingRates(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(testPricingCatalog), 0o600); err != nil {
+		t.Fatalf("write catalog: %v", err)
+	}
+	api.SetCostAggregator(NewCostAggregator(config.CostConfig{PricingCatalog: path}, api.db, tracker, zap.NewNop()))
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/rates", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Data []PricingEntry `json:"data"`
+		Meta apiMeta        `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if list.Meta.Total != 4 || list.Data[0].Model != "claude-sonnet-4" || list.Data[0].CacheRead != 0.0003 {
+		t.Fatalf("unexpected catalog listing %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/rates?model=gpt-4o&provider=openai&date=2024-07-01", ""))
+	var single struct {
+		Data PricingEntry `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &single); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("resolve rate: %d %s", rec.Code, rec.Body.String())
+	}
+	if single.Data.EffectiveFrom != "2024-05-13" || single.Data.Input != 0.005 {
+		t.Fatalf("unexpected resolved rate %+v", single.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/rates?model=gpt-4o&date=2020-01-01", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before first effective date, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/cost/rates?model=gpt-4o&date=yesterday", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad date, got %d", rec.Code)
+	}
+}
+
+func TestHTTPAPIPricingValidate(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(testPricingCatalog), 0o600); err != nil {
+		t.Fatalf("write catalog: %v", err)
+	}
+	api.SetCostAggregator(NewCostAggregator(config.CostConfig{PricingCatalog: path}, api.db, tracker, zap.NewNop()))
+	handler := api.Handler()
+
+	var result pricingValidationResult
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/cost/rates/validate", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiResponse{Data: &result}); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !result.Valid || result.Source != path || result.Entries != 4 {
+		t.Fatalf("unexpected validation of configured catalog %+v", result)
+	}
+
+	result = pricingValidationResult{}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/cost/rates/validate", `{"rates": [{"model": "gpt-4o", "effective_from": "2024-01-01"}]}`))
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiResponse{Data: &result}); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Valid || result.Source != "request" || len(result.Issues) != 1 {
+		t.Fatalf("expected posted catalog to be invalid, got %+v", result)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/cost/rates/validate", `{"rates": `))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed catalog, got %d", rec.Code)
+	}
+}
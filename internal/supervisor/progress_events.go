@@ -0,0 +1,72 @@
+package supervisor
+
+import "encoding/json"
+
+// Progress events are emitted by agents from a project's .context/PROGRESS.md
+// and CURRENT_TASK.md.
+const (
+	EventTypeTaskCompleted    = "task.completed"
+	EventTypeTaskUpdated      = "task.updated"
+	EventTypeMilestoneReached = "milestone.reached"
+)
+
+type progressEvent struct {
+	Type        string
+	SessionID   string
+	Project     string
+	Task        string
+	Milestone   string
+	CurrentTask string
+	// AcceptanceCriteria is only set on task.updated events.
+	AcceptanceCriteria string
+}
+
+// parseProgressEvent decodes a task or milestone event. The body may sit
+// under payload (agent events) or data (pipeline events).
+func parseProgressEvent(raw []byte) (progressEvent, bool) {
+	var envelope struct {
+		Type      string          `json:"type"`
+		SessionID string          `json:"session_id"`
+		Payload   json.RawMessage `json:"payload"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return progressEvent{}, false
+	}
+	switch envelope.Type {
+	case EventTypeTaskCompleted, EventTypeTaskUpdated, EventTypeMilestoneReached:
+	default:
+		return progressEvent{}, false
+	}
+
+	body := envelope.Payload
+	if len(body) == 0 {
+		body = envelope.Data
+	}
+	var fields struct {
+		Project            string `json:"project"`
+		Task               string `json:"task"`
+		Milestone          string `json:"milestone"`
+		CurrentTask        string `json:"current_task"`
+		AcceptanceCriteria string `json:"acceptance_criteria"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return progressEvent{}, false
+		}
+	}
+
+	event := progressEvent{
+		Type:               envelope.Type,
+		SessionID:          envelope.SessionID,
+		Project:            fields.Project,
+		Task:               fields.Task,
+		Milestone:          fields.Milestone,
+		CurrentTask:        fields.CurrentTask,
+		AcceptanceCriteria: fields.AcceptanceCriteria,
+	}
+	if event.Type == EventTypeTaskUpdated && event.CurrentTask == "" {
+		event.CurrentTask = event.Task
+	}
+	return event, true
+}
@@ -0,0 +1,70 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestParseProgressEvent(t *testing.T) {
+	event, ok := parseProgressEvent([]byte(`{"type":"task.updated","payload":{"project":"proj-a","task":"Wire HAL"}}`))
+	if !ok || event.Project != "proj-a" || event.CurrentTask != "Wire HAL" {
+		t.Fatalf("unexpected task.updated %+v (%v)", event, ok)
+	}
+
+	event, ok = parseProgressEvent([]byte(`{"type":"task.updated","payload":{"project":"proj-a","task":"Wire HAL","acceptance_criteria":"- Boots"}}`))
+	if !ok || event.AcceptanceCriteria != "- Boots" {
+		t.Fatalf("expected acceptance criteria, got %+v (%v)", event, ok)
+	}
+
+	event, ok = parseProgressEvent([]byte(`{"Type":"milestone.reached","Data":{"project":"proj-a","milestone":"Interfaces"}}`))
+	if !ok || event.Milestone != "Interfaces" || event.Project != "proj-a" {
+		t.Fatalf("expected milestone from data body, got %+v (%v)", event, ok)
+	}
+
+	if _, ok := parseProgressEvent([]byte(`{"type":"message.updated","payload":{"project":"proj-a"}}`)); ok {
+		t.Fatal("expected non-progress event to be ignored")
+	}
+}
+
+func TestHubIngestProgressEventSetsCurrentTask(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	for _, session := range []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"},
+		{SessionID: "s-2", NodeID: "n-1", Project: "proj-a", Status: SessionStatusError, CurrentTask: "old"},
+		{SessionID: "s-3", NodeID: "n-1", Project: "proj-b"},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureSessionTracker(tracker)
+	hub.ingestSessionEvent([]byte(`{"type":"task.completed","payload":{"project":"proj-a","task":"Define HAL","current_task":"Publish AIDL"}}`))
+
+	want := map[string]string{"s-1": "Publish AIDL", "s-2": "old", "s-3": ""}
+	for id, task := range want {
+		session, err := tracker.GetSession(id)
+		if err != nil {
+			t.Fatalf("get session %s: %v", id, err)
+		}
+		if session.CurrentTask != task {
+			t.Fatalf("session %s: expected task %q, got %q", id, task, session.CurrentTask)
+		}
+	}
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-4", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if session, _ := tracker.GetSession("s-4"); session.CurrentTask != "Publish AIDL" {
+		t.Fatalf("expected new session to inherit project task, got %q", session.CurrentTask)
+	}
+}
@@ -2,12 +2,15 @@ package supervisor
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/Bldg-7/hal-o-swarm/internal/shared"
 	"go.uber.org/zap"
 )
 
@@ -15,11 +18,26 @@ type NodeStatus string
 
 type CredentialSyncStatus string
 
+// NodeLifecycle is a node's maintenance state. Only active nodes take new
+// sessions; cordoned, draining and drained nodes keep serving the sessions
+// they already run.
+type NodeLifecycle string
+
 const (
 	NodeStatusOnline   NodeStatus = "online"
 	NodeStatusOffline  NodeStatus = "offline"
 	NodeStatusDegraded NodeStatus = "degraded"
 
+	NodeLifecycleActive   NodeLifecycle = "active"
+	NodeLifecycleCordoned NodeLifecycle = "cordoned"
+	NodeLifecycleDraining NodeLifecycle = "draining"
+	NodeLifecycleDrained  NodeLifecycle = "drained"
+
+	// nodeLifecycleRemoved marks the stored row of a removed node, kept so the
+	// sessions that ran on it keep their history. Removed nodes are never
+	// loaded; a node registering under the same ID starts afresh.
+	nodeLifecycleRemoved NodeLifecycle = "removed"
+
 	CredentialSyncStatusInSync        CredentialSyncStatus = "in_sync"
 	CredentialSyncStatusDriftDetected CredentialSyncStatus = "drift_detected"
 	CredentialSyncStatusUnknown       CredentialSyncStatus = "unknown"
@@ -47,6 +65,29 @@ type NodeEntry struct {
 	CredVersion    int                      `json:"cred_version,omitempty"`
 	AuthStates     map[string]NodeAuthState `json:"auth_states,omitempty"`
 	AuthUpdatedAt  time.Time                `json:"auth_updated_at,omitempty"`
+	Labels         map[string]string        `json:"labels,omitempty"`
+	Load           NodeLoad                 `json:"load"`
+	Lifecycle      NodeLifecycle            `json:"lifecycle"`
+	Inventory      *shared.NodeInventory    `json:"inventory,omitempty"`
+}
+
+// Schedulable reports whether new sessions may be placed on the node.
+func (n NodeEntry) Schedulable() bool {
+	return n.Lifecycle == "" || n.Lifecycle == NodeLifecycleActive
+}
+
+// NodeLoad is the resource usage an agent last reported in its heartbeat.
+// Values are zero until the agent reports them.
+type NodeLoad struct {
+	CPUPercent           float64            `json:"cpu_percent"`
+	MemoryPercent        float64            `json:"memory_percent"`
+	Load1                float64            `json:"load1"`
+	Load5                float64            `json:"load5"`
+	Load15               float64            `json:"load15"`
+	MemoryTotalBytes     uint64             `json:"memory_total_bytes"`
+	MemoryAvailableBytes uint64             `json:"memory_available_bytes"`
+	Disks                []shared.DiskUsage `json:"disks,omitempty"`
+	ReportedAt           time.Time          `json:"reported_at,omitempty"`
 }
 
 var ErrNodeNotFound = errors.New("node not found")
@@ -90,6 +131,27 @@ func (r *NodeRegistry) Register(node NodeEntry) error {
 	if node.CredSyncStatus == "" {
 		node.CredSyncStatus = CredentialSyncStatusUnknown
 	}
+	// A reconnecting node keeps what it last reported until it reports
+	// again, and stays in its maintenance state.
+	if node.Labels == nil || node.Projects == nil || node.Lifecycle == "" || node.Inventory == nil {
+		if known, err := r.GetNode(node.ID); err == nil {
+			if node.Labels == nil {
+				node.Labels = known.Labels
+			}
+			if node.Projects == nil {
+				node.Projects = known.Projects
+			}
+			if node.Lifecycle == "" {
+				node.Lifecycle = known.Lifecycle
+			}
+			if node.Inventory == nil {
+				node.Inventory = known.Inventory
+			}
+		}
+	}
+	if node.Lifecycle == "" {
+		node.Lifecycle = NodeLifecycleActive
+	}
 
 	if err := r.upsertNode(node); err != nil {
 		return fmt.Errorf("register node %s: %w", node.ID, err)
@@ -203,6 +265,134 @@ func (r *NodeRegistry) UpdateAuthState(nodeID string, states map[string]NodeAuth
 	return nil
 }
 
+// SetLifecycle moves a node to a maintenance state.
+func (r *NodeRegistry) SetLifecycle(nodeID string, lifecycle NodeLifecycle) (NodeEntry, error) {
+	node, err := r.GetNode(nodeID)
+	if err != nil {
+		return NodeEntry{}, err
+	}
+
+	node.Lifecycle = lifecycle
+	if err := r.upsertNode(node); err != nil {
+		return NodeEntry{}, fmt.Errorf("set lifecycle %s: %w", nodeID, err)
+	}
+
+	r.mu.Lock()
+	r.nodes[nodeID] = node
+	r.mu.Unlock()
+
+	return node, nil
+}
+
+// Remove forgets a node. Its stored row is marked removed rather than
+// deleted so the sessions that ran on it keep their history.
+func (r *NodeRegistry) Remove(nodeID string) error {
+	res, err := r.db.Exec(`UPDATE nodes SET lifecycle = ?, status = ? WHERE id = ? AND lifecycle != ?`,
+		string(nodeLifecycleRemoved), string(NodeStatusOffline), nodeID, string(nodeLifecycleRemoved))
+	if err != nil {
+		return fmt.Errorf("remove node %s: %w", nodeID, err)
+	}
+
+	r.mu.Lock()
+	_, known := r.nodes[nodeID]
+	delete(r.nodes, nodeID)
+	r.mu.Unlock()
+
+	if n, _ := res.RowsAffected(); n == 0 && !known {
+		return ErrNodeNotFound
+	}
+	return nil
+}
+
+// UpdateNodeInfo records the hostname, projects, labels and inventory a node
+// reports in its register message. An empty hostname or a missing inventory
+// keeps the current one.
+func (r *NodeRegistry) UpdateNodeInfo(nodeID string, info shared.NodeInfo) error {
+	node, err := r.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	if info.Hostname != "" {
+		node.Hostname = info.Hostname
+	}
+	node.Projects = append([]string(nil), info.Projects...)
+	node.Labels = make(map[string]string, len(info.Labels))
+	for key, value := range info.Labels {
+		node.Labels[key] = value
+	}
+	if info.Inventory != nil {
+		node.Inventory = info.Inventory
+	}
+
+	if err := r.upsertNode(node); err != nil {
+		return fmt.Errorf("update node info %s: %w", nodeID, err)
+	}
+
+	r.mu.Lock()
+	r.nodes[nodeID] = node
+	r.mu.Unlock()
+
+	return nil
+}
+
+// UpdateInventory records the inventory reported by a node's heartbeat. It
+// is stored only when it differs from the one already known.
+func (r *NodeRegistry) UpdateInventory(nodeID string, inventory shared.NodeInventory) error {
+	node, err := r.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+	if node.Inventory != nil && reflect.DeepEqual(*node.Inventory, inventory) {
+		return nil
+	}
+
+	node.Inventory = &inventory
+	if err := r.upsertNode(node); err != nil {
+		return fmt.Errorf("update inventory %s: %w", nodeID, err)
+	}
+
+	r.mu.Lock()
+	if current, ok := r.nodes[nodeID]; ok {
+		current.Inventory = &inventory
+		r.nodes[nodeID] = current
+	} else {
+		r.nodes[nodeID] = node
+	}
+	r.mu.Unlock()
+
+	return nil
+}
+
+// UpdateLoad records the resource usage reported by a node's heartbeat.
+func (r *NodeRegistry) UpdateLoad(nodeID string, load NodeLoad) error {
+	r.mu.Lock()
+	node, ok := r.nodes[nodeID]
+	r.mu.Unlock()
+
+	if !ok {
+		fromDB, err := r.readNode(nodeID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNodeNotFound
+			}
+			return fmt.Errorf("update load %s: %w", nodeID, err)
+		}
+		node = fromDB
+	}
+
+	if load.ReportedAt.IsZero() {
+		load.ReportedAt = time.Now().UTC()
+	}
+	node.Load = load
+
+	r.mu.Lock()
+	r.nodes[nodeID] = node
+	r.mu.Unlock()
+
+	return nil
+}
+
 func (r *NodeRegistry) GetAuthState(nodeID string) map[string]NodeAuthState {
 	r.mu.RLock()
 	node, ok := r.nodes[nodeID]
@@ -258,10 +448,7 @@ func (r *NodeRegistry) LoadNodesFromDB() error {
 		return fmt.Errorf("load nodes: mark offline: %w", err)
 	}
 
-	rows, err := r.db.Query(`
-		SELECT id, hostname, status, last_heartbeat, connected_at
-		FROM nodes
-	`)
+	rows, err := r.db.Query(`SELECT `+nodeColumns+` FROM nodes WHERE lifecycle != ?`, string(nodeLifecycleRemoved))
 	if err != nil {
 		return fmt.Errorf("load nodes: query rows: %w", err)
 	}
@@ -269,12 +456,16 @@ func (r *NodeRegistry) LoadNodesFromDB() error {
 
 	nodes := make(map[string]NodeEntry)
 	for rows.Next() {
-		entry, rowErr := scanNodeRow(rows)
+		entry, rowErr := scanNode(rows)
 		if rowErr != nil {
 			r.incrementRecoveryError("load nodes: corrupted row", rowErr)
 			continue
 		}
 		entry.Status = NodeStatusOffline
+		// A drain does not outlive the supervisor; the node stays unschedulable.
+		if entry.Lifecycle == NodeLifecycleDraining {
+			entry.Lifecycle = NodeLifecycleCordoned
+		}
 		nodes[entry.ID] = entry
 	}
 
@@ -294,20 +485,47 @@ func (r *NodeRegistry) RecoveryErrorCount() uint64 {
 }
 
 func (r *NodeRegistry) upsertNode(node NodeEntry) error {
+	lifecycle := node.Lifecycle
+	if lifecycle == "" {
+		lifecycle = NodeLifecycleActive
+	}
+	labels := []byte("{}")
+	if len(node.Labels) > 0 {
+		encoded, err := json.Marshal(node.Labels)
+		if err != nil {
+			return fmt.Errorf("encode labels for node %s: %w", node.ID, err)
+		}
+		labels = encoded
+	}
+	inventory := []byte("{}")
+	if node.Inventory != nil {
+		encoded, err := json.Marshal(node.Inventory)
+		if err != nil {
+			return fmt.Errorf("encode inventory for node %s: %w", node.ID, err)
+		}
+		inventory = encoded
+	}
+
 	_, err := r.db.Exec(`
-		INSERT INTO nodes (id, hostname, status, last_heartbeat, connected_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO nodes (id, hostname, status, last_heartbeat, connected_at, labels, lifecycle, inventory)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			hostname = excluded.hostname,
 			status = excluded.status,
 			last_heartbeat = excluded.last_heartbeat,
-			connected_at = excluded.connected_at
+			connected_at = excluded.connected_at,
+			labels = excluded.labels,
+			lifecycle = excluded.lifecycle,
+			inventory = excluded.inventory
 	`,
 		node.ID,
 		node.Hostname,
 		string(node.Status),
 		node.LastHeartbeat.UTC().Format(time.RFC3339Nano),
 		node.ConnectedAt.UTC().Format(time.RFC3339Nano),
+		string(labels),
+		string(lifecycle),
+		string(inventory),
 	)
 	if err != nil {
 		return fmt.Errorf("upsert node %s: %w", node.ID, err)
@@ -317,13 +535,8 @@ func (r *NodeRegistry) upsertNode(node NodeEntry) error {
 }
 
 func (r *NodeRegistry) readNode(nodeID string) (NodeEntry, error) {
-	row := r.db.QueryRow(`
-		SELECT id, hostname, status, last_heartbeat, connected_at
-		FROM nodes
-		WHERE id = ?
-	`, nodeID)
-
-	return scanNodeSingleRow(row)
+	row := r.db.QueryRow(`SELECT `+nodeColumns+` FROM nodes WHERE id = ? AND lifecycle != ?`, nodeID, string(nodeLifecycleRemoved))
+	return scanNode(row)
 }
 
 func (r *NodeRegistry) incrementRecoveryError(msg string, err error) {
@@ -331,16 +544,22 @@ func (r *NodeRegistry) incrementRecoveryError(msg string, err error) {
 	r.logger.Warn(msg, zap.Error(err))
 }
 
-func scanNodeRow(rows *sql.Rows) (NodeEntry, error) {
+// nodeColumns lists the stored node columns in the order scanNode reads them.
+const nodeColumns = `id, hostname, status, last_heartbeat, connected_at, labels, lifecycle, inventory`
+
+func scanNode(row rowScanner) (NodeEntry, error) {
 	var (
 		id            string
 		hostname      string
 		statusRaw     string
 		lastHeartbeat sql.NullString
 		connectedAt   sql.NullString
+		labelsJSON    string
+		lifecycle     string
+		inventoryJSON string
 	)
 
-	if err := rows.Scan(&id, &hostname, &statusRaw, &lastHeartbeat, &connectedAt); err != nil {
+	if err := row.Scan(&id, &hostname, &statusRaw, &lastHeartbeat, &connectedAt, &labelsJSON, &lifecycle, &inventoryJSON); err != nil {
 		return NodeEntry{}, fmt.Errorf("scan node row: %w", err)
 	}
 
@@ -349,6 +568,7 @@ func scanNodeRow(rows *sql.Rows) (NodeEntry, error) {
 		Hostname:       hostname,
 		Status:         NodeStatus(statusRaw),
 		CredSyncStatus: CredentialSyncStatusUnknown,
+		Lifecycle:      NodeLifecycle(lifecycle),
 	}
 
 	if lastHeartbeat.Valid {
@@ -367,43 +587,18 @@ func scanNodeRow(rows *sql.Rows) (NodeEntry, error) {
 		entry.ConnectedAt = parsed
 	}
 
-	return entry, nil
-}
-
-func scanNodeSingleRow(row *sql.Row) (NodeEntry, error) {
-	var (
-		id            string
-		hostname      string
-		statusRaw     string
-		lastHeartbeat sql.NullString
-		connectedAt   sql.NullString
-	)
-
-	if err := row.Scan(&id, &hostname, &statusRaw, &lastHeartbeat, &connectedAt); err != nil {
-		return NodeEntry{}, err
-	}
-
-	entry := NodeEntry{
-		ID:             id,
-		Hostname:       hostname,
-		Status:         NodeStatus(statusRaw),
-		CredSyncStatus: CredentialSyncStatusUnknown,
-	}
-
-	if lastHeartbeat.Valid {
-		parsed, err := parseSQLiteTimestamp(lastHeartbeat.String)
-		if err != nil {
-			return NodeEntry{}, fmt.Errorf("parse last_heartbeat for node %s: %w", id, err)
+	if labelsJSON != "" && labelsJSON != "{}" {
+		if err := json.Unmarshal([]byte(labelsJSON), &entry.Labels); err != nil {
+			return NodeEntry{}, fmt.Errorf("parse labels for node %s: %w", id, err)
 		}
-		entry.LastHeartbeat = parsed
 	}
 
-	if connectedAt.Valid {
-		parsed, err := parseSQLiteTimestamp(connectedAt.String)
-		if err != nil {
-			return NodeEntry{}, fmt.Errorf("parse connected_at for node %s: %w", id, err)
+	if inventoryJSON != "" && inventoryJSON != "{}" {
+		var inventory shared.NodeInventory
+		if err := json.Unmarshal([]byte(inventoryJSON), &inventory); err != nil {
+			return NodeEntry{}, fmt.Errorf("parse inventory for node %s: %w", id, err)
 		}
-		entry.ConnectedAt = parsed
+		entry.Inventory = &inventory
 	}
 
 	return entry, nil
@@ -29,6 +29,11 @@ type Server struct {
 	httpShutdown func(ctx context.Context) error
 	wsShutdown   func(ctx context.Context) error
 	costs        *CostAggregator
+	budgets      *BudgetEnforcer
+	tasks        *TaskQueue
+	maintenance  *NodeMaintenance
+	enrollment   *NodeEnrollment
+	nodeCA       *NodeCA
 	audit        *AuditLogger
 	tlsConfig    *tls.Config
 }
@@ -133,10 +138,18 @@ func (s *Server) Start() error {
 	if s.costs != nil {
 		s.costs.Start(s.ctx)
 	}
+	if s.budgets != nil {
+		s.budgets.Start()
+	}
+	if s.tasks != nil {
+		s.tasks.Start()
+	}
 
 	wsAddr := fmt.Sprintf(":%d", s.cfg.Server.Port)
 	wsMux := http.NewServeMux()
 	wsMux.HandleFunc("/", s.hub.ServeWS)
+	wsMux.HandleFunc("POST /enroll", s.hub.ServeEnroll)
+	wsMux.HandleFunc("POST /renew", s.hub.ServeRenew)
 	wsSrv := &http.Server{
 		Addr:         wsAddr,
 		Handler:      wsMux,
@@ -144,12 +157,20 @@ func (s *Server) Start() error {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// The node CA's listener config takes precedence on the WebSocket port so
+	// agents can verify the supervisor against the CA they pin.
+	switch {
+	case s.nodeCA != nil:
+		wsSrv.TLSConfig = s.nodeCA.ServerTLSConfig()
+	case s.tlsConfig != nil:
+		wsSrv.TLSConfig = s.tlsConfig
+	}
 
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.logger.Info("websocket server starting", zap.String("addr", wsAddr))
-		if err := wsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("websocket server starting", zap.String("addr", wsAddr), zap.Bool("tls", wsSrv.TLSConfig != nil))
+		if err := listenAndServe(wsSrv); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("websocket server error", zap.Error(err))
 		}
 	}()
@@ -163,12 +184,13 @@ func (s *Server) Start() error {
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
+			TLSConfig:    s.tlsConfig,
 		}
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
-			s.logger.Info("http api server starting", zap.String("addr", addr))
-			if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Info("http api server starting", zap.String("addr", addr), zap.Bool("tls", httpSrv.TLSConfig != nil))
+			if err := listenAndServe(httpSrv); err != nil && err != http.ErrServerClosed {
 				s.logger.Error("http api server error", zap.Error(err))
 			}
 		}()
@@ -178,6 +200,15 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// listenAndServe serves TLS when the server has a TLS config; its
+// certificates come from the config rather than files.
+func listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
 // Stop gracefully shuts down the supervisor server.
 // It cancels the context and waits for all goroutines to finish.
 func (s *Server) Stop() error {
@@ -208,6 +239,15 @@ func (s *Server) Stop() error {
 
 	// Cancel context to signal goroutines to exit
 	s.cancel()
+	if s.tasks != nil {
+		s.tasks.Stop()
+	}
+	if s.maintenance != nil {
+		s.maintenance.Stop()
+	}
+	if s.budgets != nil {
+		s.budgets.Stop()
+	}
 	if s.costs != nil {
 		s.costs.Stop()
 	}
@@ -311,6 +351,21 @@ func (s *Server) SetHTTPAPI(api *HTTPAPI) {
 	if s.costs != nil {
 		s.httpAPI.SetCostAggregator(s.costs)
 	}
+	if s.budgets != nil {
+		s.httpAPI.SetBudgetEnforcer(s.budgets)
+	}
+	if s.tasks != nil {
+		s.httpAPI.SetTaskQueue(s.tasks)
+	}
+	if s.maintenance != nil {
+		s.httpAPI.SetNodeMaintenance(s.maintenance)
+	}
+	if s.enrollment != nil {
+		s.httpAPI.SetNodeEnrollment(s.enrollment)
+	}
+	if s.nodeCA != nil {
+		s.httpAPI.SetNodeCA(s.nodeCA)
+	}
 	hc := NewHealthChecker(nil, s.hub, nil, s.costs)
 	s.httpAPI.SetHealthChecker(hc)
 }
@@ -321,3 +376,48 @@ func (s *Server) SetCostAggregator(aggregator *CostAggregator) {
 		s.httpAPI.SetCostAggregator(aggregator)
 	}
 }
+
+func (s *Server) SetBudgetEnforcer(enforcer *BudgetEnforcer) {
+	s.budgets = enforcer
+	if s.httpAPI != nil {
+		s.httpAPI.SetBudgetEnforcer(enforcer)
+	}
+}
+
+func (s *Server) SetTaskQueue(queue *TaskQueue) {
+	s.tasks = queue
+	if s.httpAPI != nil {
+		s.httpAPI.SetTaskQueue(queue)
+	}
+}
+
+func (s *Server) SetNodeMaintenance(maintenance *NodeMaintenance) {
+	s.maintenance = maintenance
+	if s.httpAPI != nil {
+		s.httpAPI.SetNodeMaintenance(maintenance)
+	}
+}
+
+// SetNodeEnrollment makes the hub authenticate agents by their per-node
+// credential and enables enrollment and revocation.
+func (s *Server) SetNodeEnrollment(enrollment *NodeEnrollment) {
+	s.enrollment = enrollment
+	if s.hub != nil {
+		s.hub.ConfigureNodeEnrollment(enrollment)
+	}
+	if s.httpAPI != nil {
+		s.httpAPI.SetNodeEnrollment(enrollment)
+	}
+}
+
+// SetNodeCA serves the WebSocket port with certificates from ca, requires
+// agents to present a node certificate and issues them at enrollment.
+func (s *Server) SetNodeCA(ca *NodeCA) {
+	s.nodeCA = ca
+	if s.hub != nil {
+		s.hub.ConfigureNodeCA(ca)
+	}
+	if s.httpAPI != nil {
+		s.httpAPI.SetNodeCA(ca)
+	}
+}
@@ -0,0 +1,185 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TransitionCause says what moved a session to a new status.
+type TransitionCause string
+
+const (
+	TransitionCauseEvent   TransitionCause = "event"
+	TransitionCausePolicy  TransitionCause = "policy"
+	TransitionCauseCommand TransitionCause = "command"
+	TransitionCauseActor   TransitionCause = "actor"
+	TransitionCauseSystem  TransitionCause = "system"
+)
+
+// TransitionReason is recorded with a status change: the cause and a detail
+// such as the event type, policy name, command type or actor.
+type TransitionReason struct {
+	Cause  TransitionCause
+	Detail string
+}
+
+var defaultTransitionReason = TransitionReason{Cause: TransitionCauseSystem}
+
+// SessionTransition is one recorded status change.
+type SessionTransition struct {
+	ID        int64           `json:"id"`
+	SessionID string          `json:"session_id"`
+	From      SessionStatus   `json:"from,omitempty"`
+	To        SessionStatus   `json:"to"`
+	Cause     TransitionCause `json:"cause"`
+	Detail    string          `json:"detail,omitempty"`
+	At        time.Time       `json:"at"`
+}
+
+// SessionHistory is a session's lineage, root first, and the status changes
+// of every session in it, oldest first.
+type SessionHistory struct {
+	SessionID   string
+	Chain       []TrackedSession
+	Transitions []SessionTransition
+}
+
+// maxLineageDepth bounds lineage walks in case of a parent cycle.
+const maxLineageDepth = 256
+
+// recordTransition stores a status change. History is bookkeeping, so
+// failures are logged rather than failing the update.
+func (t *SessionTracker) recordTransition(sessionID string, from, to SessionStatus, reason TransitionReason) {
+	if reason.Cause == "" {
+		reason = defaultTransitionReason
+	}
+	if _, err := t.db.Exec(`
+		INSERT INTO session_transitions (session_id, from_status, to_status, cause, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sessionID, string(from), string(to), string(reason.Cause), reason.Detail, time.Now().UTC()); err != nil {
+		t.logger.Warn("record session transition failed",
+			zap.String("session_id", sessionID),
+			zap.String("to", string(to)),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordBulkTransition records a change to status for every live stored
+// session matching where that is not already in that status.
+func (t *SessionTracker) recordBulkTransition(where string, args []interface{}, to SessionStatus, reason TransitionReason) {
+	query := `
+		INSERT INTO session_transitions (session_id, from_status, to_status, cause, detail, created_at)
+		SELECT id, status, ?, ?, ?, ? FROM sessions WHERE status != ? AND ` + liveSessionsSQL + ` AND ` + where
+	params := append([]interface{}{string(to), string(reason.Cause), reason.Detail, time.Now().UTC(), string(to)}, args...)
+	if _, err := t.db.Exec(query, params...); err != nil {
+		t.logger.Warn("record session transitions failed", zap.String("to", string(to)), zap.Error(err))
+	}
+}
+
+// History returns the lineage of a session, from the session it ultimately
+// replaced through every session that replaced it, with their transitions.
+func (t *SessionTracker) History(sessionID string) (SessionHistory, error) {
+	session, err := t.GetSession(sessionID)
+	if err != nil {
+		return SessionHistory{}, err
+	}
+
+	root := session
+	for depth := 0; root.ParentSessionID != "" && depth < maxLineageDepth; depth++ {
+		parent, err := t.GetSession(root.ParentSessionID)
+		if err != nil {
+			break
+		}
+		root = parent
+	}
+
+	chain := []TrackedSession{root}
+	seen := map[string]bool{root.SessionID: true}
+	for i := 0; i < len(chain) && len(chain) < maxLineageDepth; i++ {
+		children, err := t.childSessionIDs(chain[i].SessionID)
+		if err != nil {
+			return SessionHistory{}, err
+		}
+		for _, childID := range children {
+			if seen[childID] {
+				continue
+			}
+			seen[childID] = true
+			child, err := t.GetSession(childID)
+			if err != nil {
+				continue
+			}
+			chain = append(chain, child)
+		}
+	}
+
+	transitions, err := t.transitionsFor(chain)
+	if err != nil {
+		return SessionHistory{}, err
+	}
+	return SessionHistory{SessionID: sessionID, Chain: chain, Transitions: transitions}, nil
+}
+
+func (t *SessionTracker) childSessionIDs(sessionID string) ([]string, error) {
+	rows, err := t.db.Query(`SELECT id FROM sessions WHERE parent_session_id = ? ORDER BY started_at, id`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query child sessions of %s: %w", sessionID, err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan child session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (t *SessionTracker) transitionsFor(chain []TrackedSession) ([]SessionTransition, error) {
+	placeholders := make([]string, len(chain))
+	args := make([]interface{}, len(chain))
+	for i, session := range chain {
+		placeholders[i] = "?"
+		args[i] = session.SessionID
+	}
+
+	rows, err := t.db.Query(`
+		SELECT id, session_id, from_status, to_status, cause, detail, created_at
+		FROM session_transitions
+		WHERE session_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query session transitions: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := make([]SessionTransition, 0)
+	for rows.Next() {
+		var (
+			tr        SessionTransition
+			from, to  string
+			cause     string
+			createdAt time.Time
+		)
+		if err := rows.Scan(&tr.ID, &tr.SessionID, &from, &to, &cause, &tr.Detail, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan session transition: %w", err)
+		}
+		tr.From = SessionStatus(from)
+		tr.To = SessionStatus(to)
+		tr.Cause = TransitionCause(cause)
+		tr.At = createdAt.UTC()
+		transitions = append(transitions, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate session transitions: %w", err)
+	}
+	return transitions, nil
+}
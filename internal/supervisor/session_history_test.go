@@ -0,0 +1,155 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSessionTrackerRecordsTransitions(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES ('n-1', 'n-1', 'online', ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.ApplyEvent("session.idle", "s-1"); err != nil {
+		t.Fatalf("apply event: %v", err)
+	}
+	// Updates that keep the status are not transitions.
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"cost": 1.5}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	if err := tracker.UpdateSessionWithReason("s-1", map[string]interface{}{"status": string(SessionStatusRunning)},
+		TransitionReason{Cause: TransitionCauseActor, Detail: "alice"}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	if err := tracker.MarkUnreachable("n-1"); err != nil {
+		t.Fatalf("mark unreachable: %v", err)
+	}
+
+	history, err := tracker.History("s-1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := []SessionTransition{
+		{From: "", To: SessionStatusRunning, Cause: TransitionCauseSystem},
+		{From: SessionStatusRunning, To: SessionStatusIdle, Cause: TransitionCauseEvent, Detail: "session.idle"},
+		{From: SessionStatusIdle, To: SessionStatusRunning, Cause: TransitionCauseActor, Detail: "alice"},
+		{From: SessionStatusRunning, To: SessionStatusUnreachable, Cause: TransitionCauseEvent, Detail: "node.offline"},
+	}
+	if len(history.Transitions) != len(want) {
+		t.Fatalf("expected %d transitions, got %+v", len(want), history.Transitions)
+	}
+	for i, tr := range history.Transitions {
+		if tr.SessionID != "s-1" || tr.From != want[i].From || tr.To != want[i].To || tr.Cause != want[i].Cause || tr.Detail != want[i].Detail {
+			t.Fatalf("transition %d: expected %+v, got %+v", i, want[i], tr)
+		}
+	}
+}
+
+func TestRestartRecordsSessionLineage(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1"}); err != nil {
+		t.Fatalf("register node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Model: "claude-4", CurrentTask: "Wire HAL"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	outputs := map[string]string{"s-1": "s-2", "s-2": "s-3"}
+	var dispatcher *CommandDispatcher
+	transport := &mockCommandTransport{}
+	transport.onSend = func(_ string, cmd Command) {
+		output := outputs[cmd.Args["session_id"].(string)]
+		go dispatcher.HandleCommandResult(CommandResult{CommandID: cmd.CommandID, Status: CommandStatusSuccess, Output: output})
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+
+	restart := func(sessionID string, args map[string]interface{}) {
+		t.Helper()
+		args["session_id"] = sessionID
+		result, err := dispatcher.DispatchCommand(context.Background(), Command{
+			Type:   CommandTypeRestartSession,
+			Target: CommandTarget{Project: "proj-a"},
+			Args:   args,
+		})
+		if err != nil || result.Status != CommandStatusSuccess {
+			t.Fatalf("restart %s: %+v %v", sessionID, result, err)
+		}
+	}
+	restart("s-1", map[string]interface{}{})
+	restart("s-2", map[string]interface{}{"policy": "restart_on_compaction"})
+
+	replacement, err := tracker.GetSession("s-2")
+	if err != nil {
+		t.Fatalf("get replacement: %v", err)
+	}
+	if replacement.ParentSessionID != "s-1" || replacement.Model != "claude-4" || replacement.CurrentTask != "Wire HAL" {
+		t.Fatalf("unexpected replacement %+v", replacement)
+	}
+
+	// The whole chain is reachable from any session in it.
+	for _, id := range []string{"s-1", "s-2", "s-3"} {
+		history, err := tracker.History(id)
+		if err != nil {
+			t.Fatalf("History(%s): %v", id, err)
+		}
+		if len(history.Chain) != 3 || history.Chain[0].SessionID != "s-1" || history.Chain[2].SessionID != "s-3" {
+			t.Fatalf("History(%s): unexpected chain %+v", id, history.Chain)
+		}
+	}
+
+	history, _ := tracker.History("s-3")
+	last := history.Transitions[len(history.Transitions)-1]
+	if last.SessionID != "s-3" || last.Cause != TransitionCausePolicy || last.Detail != "restart_on_compaction" {
+		t.Fatalf("expected policy-caused transition for s-3, got %+v", last)
+	}
+	if history.Transitions[1].Cause != TransitionCauseCommand || history.Transitions[1].Detail != string(CommandTypeRestartSession) {
+		t.Fatalf("expected command-caused transition for s-2, got %+v", history.Transitions[1])
+	}
+}
+
+func TestHTTPAPISessionHistory(t *testing.T) {
+	api, registry, tracker := setupHTTPAPI(t)
+	seedNode(t, registry, "n-1", "host-1")
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-2", NodeID: "n-1", Project: "proj-a", ParentSessionID: "s-1"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/sessions/s-2/history", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data sessionHistoryJSON `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Data.SessionID != "s-2" || len(resp.Data.Chain) != 2 || resp.Data.Chain[1].ParentID != "s-1" || len(resp.Data.Transitions) != 2 {
+		t.Fatalf("unexpected history %+v", resp.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/sessions/missing/history", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
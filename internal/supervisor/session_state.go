@@ -0,0 +1,105 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSessionTransition is returned when an update would move a session
+// to a status its current status cannot reach.
+var ErrInvalidSessionTransition = errors.New("invalid session transition")
+
+// sessionTransitions lists the statuses each status may move to (spec §7.1).
+// COMPLETED and KILLED are terminal. HANDOVER and RESTARTING fall back to an
+// active status when the handover or restart fails.
+var sessionTransitions = map[SessionStatus][]SessionStatus{
+	SessionStatusRunning: {
+		SessionStatusIdle, SessionStatusHandover, SessionStatusRestarting,
+		SessionStatusCompleted, SessionStatusKilled, SessionStatusError, SessionStatusUnreachable,
+	},
+	SessionStatusIdle: {
+		SessionStatusRunning, SessionStatusHandover, SessionStatusRestarting,
+		SessionStatusCompleted, SessionStatusKilled, SessionStatusError, SessionStatusUnreachable,
+	},
+	SessionStatusHandover: {
+		SessionStatusRunning, SessionStatusIdle,
+		SessionStatusCompleted, SessionStatusKilled, SessionStatusError, SessionStatusUnreachable,
+	},
+	SessionStatusRestarting: {
+		SessionStatusRunning, SessionStatusIdle,
+		SessionStatusKilled, SessionStatusError, SessionStatusUnreachable,
+	},
+	SessionStatusError: {
+		SessionStatusRunning, SessionStatusIdle, SessionStatusRestarting,
+		SessionStatusCompleted, SessionStatusKilled, SessionStatusUnreachable,
+	},
+	SessionStatusUnreachable: {
+		SessionStatusRunning, SessionStatusIdle, SessionStatusRestarting,
+		SessionStatusCompleted, SessionStatusKilled, SessionStatusError,
+	},
+	SessionStatusCompleted: {},
+	SessionStatusKilled:    {},
+}
+
+// Valid reports whether s is a known status.
+func (s SessionStatus) Valid() bool {
+	_, ok := sessionTransitions[s]
+	return ok
+}
+
+// Terminal reports whether the session has ended for good.
+func (s SessionStatus) Terminal() bool {
+	return s == SessionStatusCompleted || s == SessionStatusKilled
+}
+
+// Active reports whether the session is working, or is being handed over or
+// restarted and so still occupies its project.
+func (s SessionStatus) Active() bool {
+	switch s {
+	case SessionStatusRunning, SessionStatusIdle, SessionStatusHandover, SessionStatusRestarting:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a session in status s may move to next.
+// Staying in the same status is always allowed.
+func (s SessionStatus) CanTransitionTo(next SessionStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range sessionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCommand reports whether a command may act on a session in status s.
+// Ended sessions only answer status queries, and a session mid-handover or
+// mid-restart can only be queried or killed.
+func (s SessionStatus) AllowsCommand(cmd CommandType) bool {
+	if cmd == CommandTypeSessionStatus {
+		return true
+	}
+	switch s {
+	case SessionStatusCompleted, SessionStatusKilled:
+		return false
+	case SessionStatusHandover, SessionStatusRestarting:
+		return cmd == CommandTypeKillSession
+	}
+	return true
+}
+
+// validateSessionTransition checks a status change; an empty from is a new
+// session, which may start in any known status.
+func validateSessionTransition(from, to SessionStatus) error {
+	if !to.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidSessionTransition, to)
+	}
+	if from == "" || from.CanTransitionTo(to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidSessionTransition, from, to)
+}
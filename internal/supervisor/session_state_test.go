@@ -0,0 +1,204 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestSessionStatusTransitions(t *testing.T) {
+	cases := []struct {
+		from, to SessionStatus
+		allowed  bool
+	}{
+		{SessionStatusRunning, SessionStatusIdle, true},
+		{SessionStatusIdle, SessionStatusHandover, true},
+		{SessionStatusHandover, SessionStatusCompleted, true},
+		{SessionStatusRestarting, SessionStatusKilled, true},
+		{SessionStatusRestarting, SessionStatusRunning, true},
+		{SessionStatusUnreachable, SessionStatusRunning, true},
+		{SessionStatusKilled, SessionStatusKilled, true},
+		{SessionStatusRestarting, SessionStatusHandover, false},
+		{SessionStatusRestarting, SessionStatusCompleted, false},
+		{SessionStatusCompleted, SessionStatusRunning, false},
+		{SessionStatusKilled, SessionStatusUnreachable, false},
+	}
+	for _, tc := range cases {
+		if got := tc.from.CanTransitionTo(tc.to); got != tc.allowed {
+			t.Errorf("%s -> %s: expected allowed=%v", tc.from, tc.to, tc.allowed)
+		}
+	}
+
+	if SessionStatusHandover.AllowsCommand(CommandTypePromptSession) || !SessionStatusHandover.AllowsCommand(CommandTypeKillSession) {
+		t.Fatal("handover sessions should only accept kill and status commands")
+	}
+	if SessionStatusKilled.AllowsCommand(CommandTypeKillSession) || !SessionStatusKilled.AllowsCommand(CommandTypeSessionStatus) {
+		t.Fatal("ended sessions should only accept status commands")
+	}
+	if !SessionStatusIdle.AllowsCommand(CommandTypePromptSession) {
+		t.Fatal("idle sessions should accept prompts")
+	}
+}
+
+func TestSessionTrackerEnforcesStateMachine(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES ('n-1', 'n-1', 'online', ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	for _, id := range []string{"s-1", "s-2"} {
+		if err := tracker.AddSession(TrackedSession{SessionID: id, NodeID: "n-1", Project: "proj-a"}); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-3", NodeID: "n-1", Project: "proj-a", Status: "paused"}); !errors.Is(err, ErrInvalidSessionTransition) {
+		t.Fatalf("expected unknown status rejected, got %v", err)
+	}
+
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusKilled)}); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusRunning)})
+	if !errors.Is(err, ErrInvalidSessionTransition) {
+		t.Fatalf("expected killed -> running rejected, got %v", err)
+	}
+	if err := tracker.ApplyEvent("session.idle", "s-1"); !errors.Is(err, ErrInvalidSessionTransition) {
+		t.Fatalf("expected late idle event rejected, got %v", err)
+	}
+
+	// A snapshot re-adding the session cannot revive it.
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("re-add: %v", err)
+	}
+	if session, _ := tracker.GetSession("s-1"); session.Status != SessionStatusKilled {
+		t.Fatalf("expected s-1 to stay killed, got %s", session.Status)
+	}
+
+	// Ended sessions keep their status when the node drops or the
+	// supervisor restarts.
+	if err := tracker.MarkUnreachable("n-1"); err != nil {
+		t.Fatalf("mark unreachable: %v", err)
+	}
+	reloaded := NewSessionTracker(db, zap.NewNop())
+	if err := reloaded.LoadSessionsFromDB(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for id, want := range map[string]SessionStatus{"s-1": SessionStatusKilled, "s-2": SessionStatusUnreachable} {
+		for _, tr := range []*SessionTracker{tracker, reloaded} {
+			if session, _ := tr.GetSession(id); session.Status != want {
+				t.Fatalf("%s: expected %s, got %s", id, want, session.Status)
+			}
+		}
+	}
+
+	// A session purged from memory is checked against its stored status.
+	if _, err := tracker.PurgeNode("n-1"); err != nil {
+		t.Fatalf("purge node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-2", NodeID: "n-1", Project: "proj-a", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("re-add purged: %v", err)
+	}
+	if session, _ := tracker.GetSession("s-2"); session.Status != SessionStatusKilled {
+		t.Fatalf("expected purged s-2 to stay killed, got %s", session.Status)
+	}
+	history, err := tracker.History("s-2")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	for _, tr := range history.Transitions[1:] {
+		if tr.From == "" {
+			t.Fatalf("expected no transition from an empty status after the first, got %+v", history.Transitions)
+		}
+	}
+}
+
+func TestDispatcherDrivesSessionStates(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	registry := NewNodeRegistry(db, logger)
+	tracker := NewSessionTracker(db, logger)
+	if err := registry.Register(NodeEntry{ID: "n-1", Hostname: "n-1"}); err != nil {
+		t.Fatalf("register node: %v", err)
+	}
+	for _, session := range []TrackedSession{
+		{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Status: SessionStatusIdle},
+		{SessionID: "s-2", NodeID: "n-1", Project: "proj-a", Status: SessionStatusHandover},
+	} {
+		if err := tracker.AddSession(session); err != nil {
+			t.Fatalf("add session: %v", err)
+		}
+	}
+
+	var (
+		dispatcher *CommandDispatcher
+		seen       []SessionStatus
+		status     = CommandStatusFailure
+	)
+	transport := &mockCommandTransport{}
+	transport.onSend = func(_ string, cmd Command) {
+		session, _ := tracker.GetSession(cmd.Args["session_id"].(string))
+		seen = append(seen, session.Status)
+		go dispatcher.HandleCommandResult(CommandResult{CommandID: cmd.CommandID, Status: status, Output: "s-9"})
+	}
+	dispatcher = NewCommandDispatcherWithTransport(db, registry, tracker, transport, logger)
+
+	dispatch := func(cmdType CommandType, sessionID string) *CommandResult {
+		t.Helper()
+		result, err := dispatcher.DispatchCommand(context.Background(), Command{
+			Type:   cmdType,
+			Target: CommandTarget{Project: "proj-a"},
+			Args:   map[string]interface{}{"session_id": sessionID},
+		})
+		if err != nil {
+			t.Fatalf("dispatch %s: %v", cmdType, err)
+		}
+		return result
+	}
+
+	// No resume while a handover is in progress.
+	if result := dispatch(CommandTypePromptSession, "s-2"); result.Status != CommandStatusFailure || transport.CallCount() != 0 {
+		t.Fatalf("expected prompt refused during handover, got %+v", result)
+	}
+
+	// A failed restart returns the session to where it was.
+	dispatch(CommandTypeRestartSession, "s-1")
+	if session, _ := tracker.GetSession("s-1"); session.Status != SessionStatusIdle || seen[0] != SessionStatusRestarting {
+		t.Fatalf("expected restarting during the command and idle after failure, got %v then %s", seen, session.Status)
+	}
+
+	status = CommandStatusSuccess
+	dispatch(CommandTypeRestartSession, "s-1")
+	if session, _ := tracker.GetSession("s-1"); session.Status != SessionStatusKilled {
+		t.Fatalf("expected restarted session killed, got %s", session.Status)
+	}
+	if session, err := tracker.GetSession("s-9"); err != nil || session.Status != SessionStatusRunning {
+		t.Fatalf("expected replacement running, got %+v (%v)", session, err)
+	}
+	if result := dispatch(CommandTypeRestartSession, "s-1"); result.Status != CommandStatusFailure {
+		t.Fatalf("expected restart of a killed session refused, got %+v", result)
+	}
+}
+
+func TestPolicyEngineRespectsSessionState(t *testing.T) {
+	stale := time.Now().UTC().Add(-time.Hour)
+	tracker := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "handing-over", Status: SessionStatusHandover, LastActivity: stale},
+		{SessionID: "done", Status: SessionStatusCompleted, LastActivity: stale},
+		{SessionID: "idle", Status: SessionStatusIdle, LastActivity: stale},
+	}}
+	dispatcher := &policyTestDispatcher{}
+	engine := NewPolicyEngine(config.PolicyConfig{
+		ResumeOnIdle: config.IdlePolicyConfig{Enabled: true, IdleThresholdSec: 1, MaxRetries: 3, RetryResetSeconds: 10},
+	}, tracker, dispatcher, &policyTestEvents{})
+	defer engine.Stop()
+
+	engine.runChecks(time.Now().UTC())
+	if dispatcher.callCount() != 1 || dispatcher.calls[0].Args["session_id"] != "idle" {
+		t.Fatalf("expected only the idle session resumed, got %+v", dispatcher.calls)
+	}
+}
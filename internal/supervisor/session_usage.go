@@ -0,0 +1,92 @@
+package supervisor
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SessionDayUsage is the token usage a session reported on one UTC day,
+// with the session's project, node and model.
+type SessionDayUsage struct {
+	SessionID string
+	Project   string
+	NodeID    string
+	Model     string
+	Date      string
+	Usage     TokenUsage
+
+	// SessionCost and SessionTokens are the cost and tokens the session
+	// reported over its life, for sharing its cost out across days.
+	SessionCost   float64
+	SessionTokens int
+}
+
+// reportedCostShare is the part of the session's reported cost that this
+// day's tokens account for.
+func (u SessionDayUsage) reportedCostShare() float64 {
+	if u.SessionTokens <= 0 {
+		return 0
+	}
+	return u.SessionCost * float64(u.Usage.Total) / float64(u.SessionTokens)
+}
+
+// recordUsage adds delta to the session's usage on the UTC day containing
+// at. Usage bookkeeping never fails the session update that triggered it.
+func (t *SessionTracker) recordUsage(sessionID string, at time.Time, delta TokenUsage) {
+	if delta == (TokenUsage{}) {
+		return
+	}
+	if _, err := t.db.Exec(`
+		INSERT INTO session_usage (
+			session_id, date, tokens,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session_id, date) DO UPDATE SET
+			tokens = tokens + excluded.tokens,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cache_read_tokens = cache_read_tokens + excluded.cache_read_tokens,
+			cache_write_tokens = cache_write_tokens + excluded.cache_write_tokens
+	`, sessionID, at.UTC().Format("2006-01-02"), delta.Total,
+		delta.Prompt, delta.Completion, delta.CacheRead, delta.CacheWrite); err != nil {
+		t.logger.Warn("record session usage failed",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+	}
+}
+
+// DailyUsage returns the usage sessions reported on each UTC day from start
+// up to, but not including, end, ordered by day and session.
+func (t *SessionTracker) DailyUsage(start, end time.Time) ([]SessionDayUsage, error) {
+	rows, err := t.db.Query(`
+		SELECT u.session_id, s.project, s.node_id, COALESCE(s.model, ''), u.date, u.tokens,
+			u.input_tokens, u.output_tokens, u.cache_read_tokens, u.cache_write_tokens,
+			COALESCE(s.cost, 0), COALESCE(s.tokens, 0)
+		FROM session_usage u
+		JOIN sessions s ON s.id = u.session_id
+		WHERE u.date >= ? AND u.date < ?
+		ORDER BY u.date ASC, u.session_id ASC
+	`, start.UTC().Format("2006-01-02"), end.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("query session usage: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]SessionDayUsage, 0)
+	for rows.Next() {
+		var day SessionDayUsage
+		if err := rows.Scan(
+			&day.SessionID, &day.Project, &day.NodeID, &day.Model, &day.Date, &day.Usage.Total,
+			&day.Usage.Prompt, &day.Usage.Completion, &day.Usage.CacheRead, &day.Usage.CacheWrite,
+			&day.SessionCost, &day.SessionTokens,
+		); err != nil {
+			return nil, fmt.Errorf("scan session usage: %w", err)
+		}
+		out = append(out, day)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,578 @@
+package supervisor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusQueued     TaskStatus = "queued"
+	TaskStatusDispatched TaskStatus = "dispatched"
+	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+	// TaskStatusExpired marks a task whose deadline passed before it could
+	// be dispatched.
+	TaskStatusExpired TaskStatus = "expired"
+)
+
+const (
+	// maxTaskAttempts is how many failed dispatches a task gets before it is
+	// marked failed.
+	maxTaskAttempts = 3
+
+	// taskDispatchGrace is how long a project stays busy after a dispatch
+	// while the agent has not reported the new session yet.
+	taskDispatchGrace = 2 * time.Minute
+)
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrTaskNotQueued = errors.New("task is not queued")
+)
+
+// QueuedTask is a unit of work waiting for its project to become free.
+// Position is the 1-based place of a queued task within its project's queue.
+type QueuedTask struct {
+	ID           string     `json:"id"`
+	Project      string     `json:"project"`
+	Prompt       string     `json:"prompt"`
+	Priority     int        `json:"priority"`
+	Deadline     *time.Time `json:"deadline,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	Status       TaskStatus `json:"status"`
+	Position     int        `json:"position,omitempty"`
+	NodeID       string     `json:"node_id,omitempty"`
+	SessionID    string     `json:"session_id,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Attempts     int        `json:"attempts"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty"`
+}
+
+type pendingDispatch struct {
+	sessionID string
+	at        time.Time
+}
+
+// TaskQueue persists tasks and starts them one project at a time. Tasks are
+// taken in priority order (highest first), then by earliest deadline, then
+// by age. A project's next task is dispatched only once the project has no
+// running or idle session and a node serving it has spare capacity.
+type TaskQueue struct {
+	cfg        config.TaskQueueConfig
+	db         *sql.DB
+	tracker    *SessionTracker
+	registry   *NodeRegistry
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	}
+	logger *zap.Logger
+	now    func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+
+	dispatchMu sync.Mutex
+	pending    map[string]pendingDispatch
+}
+
+func NewTaskQueue(
+	cfg config.TaskQueueConfig,
+	db *sql.DB,
+	tracker *SessionTracker,
+	registry *NodeRegistry,
+	dispatcher interface {
+		DispatchCommand(ctx context.Context, cmd Command) (*CommandResult, error)
+	},
+	logger *zap.Logger,
+) *TaskQueue {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.PollIntervalSec <= 0 {
+		cfg.PollIntervalSec = 15
+	}
+	if cfg.MaxSessionsPerNode <= 0 {
+		cfg.MaxSessionsPerNode = 4
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &TaskQueue{
+		cfg:        cfg,
+		db:         db,
+		tracker:    tracker,
+		registry:   registry,
+		dispatcher: dispatcher,
+		logger:     logger,
+		now:        func() time.Time { return time.Now().UTC() },
+		ctx:        ctx,
+		cancel:     cancel,
+		wake:       make(chan struct{}, 1),
+		pending:    make(map[string]pendingDispatch),
+	}
+}
+
+func (q *TaskQueue) Start() {
+	q.startOnce.Do(func() {
+		ticker := time.NewTicker(time.Duration(q.cfg.PollIntervalSec) * time.Second)
+
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			defer ticker.Stop()
+
+			q.dispatchLogged()
+			for {
+				select {
+				case <-q.ctx.Done():
+					return
+				case <-ticker.C:
+					q.dispatchLogged()
+				case <-q.wake:
+					q.dispatchLogged()
+				}
+			}
+		}()
+	})
+}
+
+func (q *TaskQueue) Stop() {
+	q.stopOnce.Do(func() {
+		q.cancel()
+
+		done := make(chan struct{})
+		go func() {
+			q.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(250 * time.Millisecond):
+		}
+	})
+}
+
+func (q *TaskQueue) dispatchLogged() {
+	if _, err := q.Dispatch(); err != nil {
+		q.logger.Warn("task queue dispatch failed", zap.Error(err))
+	}
+}
+
+// Enqueue validates and stores a task and returns it with its queue
+// position. A running queue is woken to consider the task right away.
+func (q *TaskQueue) Enqueue(task QueuedTask) (QueuedTask, error) {
+	task.Project = strings.TrimSpace(task.Project)
+	task.Prompt = strings.TrimSpace(task.Prompt)
+	task.Model = strings.TrimSpace(task.Model)
+	if task.Project == "" {
+		return QueuedTask{}, fmt.Errorf("project is required")
+	}
+	if task.Prompt == "" {
+		return QueuedTask{}, fmt.Errorf("prompt is required")
+	}
+	if task.Model != "" {
+		provider, model, ok := strings.Cut(task.Model, "/")
+		if !ok || provider == "" || model == "" {
+			return QueuedTask{}, fmt.Errorf("model %q must be provider/model", task.Model)
+		}
+	}
+
+	task.ID = uuid.NewString()
+	task.Status = TaskStatusQueued
+	task.CreatedAt = q.now()
+	task.NodeID, task.SessionID, task.Error = "", "", ""
+	task.Attempts = 0
+	task.DispatchedAt = nil
+
+	var deadline interface{}
+	if task.Deadline != nil {
+		utc := task.Deadline.UTC()
+		task.Deadline = &utc
+		deadline = utc
+	}
+
+	_, err := q.db.Exec(`
+		INSERT INTO task_queue (id, project, prompt, priority, deadline, model, status, attempts, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)
+	`, task.ID, task.Project, task.Prompt, task.Priority, deadline, task.Model, string(task.Status), task.CreatedAt)
+	if err != nil {
+		return QueuedTask{}, fmt.Errorf("store task: %w", err)
+	}
+
+	q.logger.Info("task queued",
+		zap.String("task_id", task.ID),
+		zap.String("project", task.Project),
+		zap.Int("priority", task.Priority),
+	)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return q.Get(task.ID)
+}
+
+// List returns tasks in dispatch order. An empty status returns every task;
+// queued tasks carry their position within their project's queue.
+func (q *TaskQueue) List(project string, status TaskStatus) ([]QueuedTask, error) {
+	query := `SELECT id, project, prompt, priority, deadline, model, status,
+		COALESCE(node_id, ''), COALESCE(session_id, ''), COALESCE(error, ''),
+		attempts, created_at, dispatched_at
+		FROM task_queue WHERE 1=1`
+	args := make([]interface{}, 0, 2)
+	if project != "" {
+		query += ` AND project = ?`
+		args = append(args, project)
+	}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	query += ` ORDER BY priority DESC, deadline IS NULL, deadline ASC, created_at ASC, id ASC`
+
+	rows, err := q.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]QueuedTask, 0)
+	positions := make(map[string]int)
+	for rows.Next() {
+		task, err := scanQueuedTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		if task.Status == TaskStatusQueued {
+			positions[task.Project]++
+			task.Position = positions[task.Project]
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// Get returns a single task, with its queue position when it is queued.
+func (q *TaskQueue) Get(id string) (QueuedTask, error) {
+	var project string
+	if err := q.db.QueryRow(`SELECT project FROM task_queue WHERE id = ?`, id).Scan(&project); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return QueuedTask{}, ErrTaskNotFound
+		}
+		return QueuedTask{}, fmt.Errorf("get task: %w", err)
+	}
+
+	tasks, err := q.List(project, "")
+	if err != nil {
+		return QueuedTask{}, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+	return QueuedTask{}, ErrTaskNotFound
+}
+
+// Cancel removes a queued task from the queue. Tasks that were already
+// dispatched are left alone.
+func (q *TaskQueue) Cancel(id string) (QueuedTask, error) {
+	task, err := q.Get(id)
+	if err != nil {
+		return QueuedTask{}, err
+	}
+	if task.Status != TaskStatusQueued {
+		return task, ErrTaskNotQueued
+	}
+
+	res, err := q.db.Exec(`UPDATE task_queue SET status = ? WHERE id = ? AND status = ?`,
+		string(TaskStatusCancelled), id, string(TaskStatusQueued))
+	if err != nil {
+		return QueuedTask{}, fmt.Errorf("cancel task: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return task, ErrTaskNotQueued
+	}
+
+	task.Status = TaskStatusCancelled
+	task.Position = 0
+	return task, nil
+}
+
+// Dispatch runs one scheduling pass and returns the tasks it started.
+func (q *TaskQueue) Dispatch() ([]QueuedTask, error) {
+	q.dispatchMu.Lock()
+	defer q.dispatchMu.Unlock()
+
+	queued, err := q.List("", TaskStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	busy, load := q.projectLoad()
+	started := make([]QueuedTask, 0)
+	for _, task := range queued {
+		if task.Deadline != nil && !q.now().Before(*task.Deadline) {
+			q.expire(task)
+			continue
+		}
+		if busy[task.Project] {
+			continue
+		}
+		// A budget refusal or a capacity shortage leaves the task queued
+		// without spending an attempt; it starts once either clears.
+		if !q.withinBudget(task) {
+			continue
+		}
+		nodeID, placement := q.placeTask(task, load)
+		if nodeID == "" {
+			continue
+		}
+		// Later tasks for the project wait for this one, even if it fails.
+		busy[task.Project] = true
+
+		sessionID, err := q.dispatchTask(task, nodeID)
+		if err != nil {
+			q.recordFailure(task, err)
+			continue
+		}
+
+		now := q.now()
+		if _, err := q.db.Exec(`
+			UPDATE task_queue SET status = ?, node_id = ?, session_id = ?, error = NULL,
+				attempts = attempts + 1, dispatched_at = ?
+			WHERE id = ?
+		`, string(TaskStatusDispatched), nodeID, sessionID, now, task.ID); err != nil {
+			return started, fmt.Errorf("mark task dispatched: %w", err)
+		}
+		q.pending[task.Project] = pendingDispatch{sessionID: sessionID, at: now}
+		load[nodeID]++
+
+		task.Status = TaskStatusDispatched
+		task.Position = 0
+		task.NodeID = nodeID
+		task.SessionID = sessionID
+		task.Attempts++
+		task.DispatchedAt = &now
+		started = append(started, task)
+
+		q.logger.Info("task dispatched",
+			zap.String("task_id", task.ID),
+			zap.String("project", task.Project),
+			zap.String("node_id", nodeID),
+			zap.String("placement", placement),
+			zap.String("session_id", sessionID),
+		)
+	}
+	return started, nil
+}
+
+// projectLoad reports which projects have an active or just-dispatched
+// session and how many active sessions each node runs.
+func (q *TaskQueue) projectLoad() (map[string]bool, map[string]int) {
+	busy := make(map[string]bool)
+	load := make(map[string]int)
+	tracked := make(map[string]bool)
+
+	if q.tracker != nil {
+		for _, session := range q.tracker.GetAllSessions() {
+			tracked[session.SessionID] = true
+			if !session.Status.Active() {
+				continue
+			}
+			busy[session.Project] = true
+			load[session.NodeID]++
+		}
+	}
+
+	now := q.now()
+	for project, pending := range q.pending {
+		if tracked[pending.sessionID] || now.Sub(pending.at) > taskDispatchGrace {
+			delete(q.pending, project)
+			continue
+		}
+		busy[project] = true
+	}
+	return busy, load
+}
+
+// taskBudgetGate reports whether the budget lets a project start a session.
+type taskBudgetGate interface {
+	AllowCreateSession(project string) error
+}
+
+// withinBudget reports whether the dispatcher's budget gate, when it has
+// one, lets the task's project start a session now.
+func (q *TaskQueue) withinBudget(task QueuedTask) bool {
+	gate, ok := q.dispatcher.(taskBudgetGate)
+	if !ok {
+		return true
+	}
+	if err := gate.AllowCreateSession(task.Project); err != nil {
+		q.logger.Debug("task waiting for budget",
+			zap.String("task_id", task.ID),
+			zap.String("project", task.Project),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
+
+// taskPlacer places a create_session with the dispatcher's placement
+// strategy, among the nodes under a session limit.
+type taskPlacer interface {
+	PlaceWithCapacity(cmd Command, load map[string]int, maxSessions int) (nodeID, reason string, err error)
+}
+
+// placeTask picks the node a task starts on and says why, or returns an
+// empty node ID when no node serving the project has spare capacity. The
+// dispatcher's placement strategy decides when it offers one; otherwise the
+// first node with capacity is taken.
+func (q *TaskQueue) placeTask(task QueuedTask, load map[string]int) (string, string) {
+	placer, ok := q.dispatcher.(taskPlacer)
+	if !ok {
+		return q.nodeWithCapacity(task.Project, load), "first node with capacity"
+	}
+	cmd := Command{Type: CommandTypeCreateSession, Target: CommandTarget{Project: task.Project}}
+	nodeID, reason, err := placer.PlaceWithCapacity(cmd, load, q.cfg.MaxSessionsPerNode)
+	if err != nil {
+		q.logger.Debug("task waiting for placement",
+			zap.String("task_id", task.ID),
+			zap.String("project", task.Project),
+			zap.Error(err),
+		)
+		return "", ""
+	}
+	return nodeID, reason
+}
+
+func (q *TaskQueue) nodeWithCapacity(project string, load map[string]int) string {
+	if q.registry == nil {
+		return ""
+	}
+	for _, node := range q.registry.ListNodes() {
+		if node.Status != NodeStatusOnline || !node.Schedulable() || load[node.ID] >= q.cfg.MaxSessionsPerNode {
+			continue
+		}
+		for _, p := range node.Projects {
+			if p == project {
+				return node.ID
+			}
+		}
+	}
+	return ""
+}
+
+func (q *TaskQueue) dispatchTask(task QueuedTask, nodeID string) (string, error) {
+	if q.dispatcher == nil {
+		return "", fmt.Errorf("command dispatcher is not configured")
+	}
+
+	args := map[string]interface{}{"prompt": task.Prompt}
+	if task.Model != "" {
+		args["model"] = task.Model
+	}
+
+	ctx, cancel := context.WithTimeout(q.ctx, DefaultCommandTimeout)
+	defer cancel()
+
+	result, err := q.dispatcher.DispatchCommand(ctx, Command{
+		Type:   CommandTypeCreateSession,
+		Target: CommandTarget{Project: task.Project, NodeID: nodeID},
+		Args:   args,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", fmt.Errorf("create_session returned no result")
+	}
+	if result.Status != CommandStatusSuccess {
+		return "", fmt.Errorf("create_session %s: %s", result.Status, result.Error)
+	}
+	return result.Output, nil
+}
+
+// expire takes a task whose deadline has passed out of the queue, so work
+// that is no longer wanted is not started late.
+func (q *TaskQueue) expire(task QueuedTask) {
+	reason := fmt.Sprintf("deadline %s passed before dispatch", task.Deadline.Format(time.RFC3339))
+	q.logger.Info("task expired",
+		zap.String("task_id", task.ID),
+		zap.String("project", task.Project),
+		zap.Time("deadline", *task.Deadline),
+	)
+	if _, err := q.db.Exec(`UPDATE task_queue SET status = ?, error = ? WHERE id = ? AND status = ?`,
+		string(TaskStatusExpired), reason, task.ID, string(TaskStatusQueued)); err != nil {
+		q.logger.Error("record task expiry", zap.String("task_id", task.ID), zap.Error(err))
+	}
+}
+
+func (q *TaskQueue) recordFailure(task QueuedTask, cause error) {
+	status := TaskStatusQueued
+	if task.Attempts+1 >= maxTaskAttempts {
+		status = TaskStatusFailed
+	}
+
+	q.logger.Warn("task dispatch failed",
+		zap.String("task_id", task.ID),
+		zap.String("project", task.Project),
+		zap.Int("attempt", task.Attempts+1),
+		zap.Error(cause),
+	)
+
+	if _, err := q.db.Exec(`UPDATE task_queue SET status = ?, error = ?, attempts = attempts + 1 WHERE id = ?`,
+		string(status), cause.Error(), task.ID); err != nil {
+		q.logger.Error("record task failure", zap.String("task_id", task.ID), zap.Error(err))
+	}
+}
+
+func scanQueuedTask(rows *sql.Rows) (QueuedTask, error) {
+	var (
+		task         QueuedTask
+		status       string
+		deadline     sql.NullTime
+		dispatchedAt sql.NullTime
+	)
+	if err := rows.Scan(&task.ID, &task.Project, &task.Prompt, &task.Priority, &deadline, &task.Model, &status,
+		&task.NodeID, &task.SessionID, &task.Error, &task.Attempts, &task.CreatedAt, &dispatchedAt); err != nil {
+		return QueuedTask{}, fmt.Errorf("scan task: %w", err)
+	}
+	task.Status = TaskStatus(status)
+	if deadline.Valid {
+		t := deadline.Time.UTC()
+		task.Deadline = &t
+	}
+	if dispatchedAt.Valid {
+		t := dispatchedAt.Time.UTC()
+		task.DispatchedAt = &t
+	}
+	task.CreatedAt = task.CreatedAt.UTC()
+	return task, nil
+}
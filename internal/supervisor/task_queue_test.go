@@ -0,0 +1,372 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+func newTestTaskQueue(t *testing.T, dispatcher *policyTestDispatcher, maxSessions int) (*TaskQueue, *SessionTracker, *NodeRegistry) {
+	t.Helper()
+	db := setupSupervisorTestDB(t)
+	registry := NewNodeRegistry(db, zap.NewNop())
+	tracker := NewSessionTracker(db, zap.NewNop())
+	if err := registry.Register(NodeEntry{ID: "node-1", Hostname: "host-1", Projects: []string{"alpha", "beta"}}); err != nil {
+		t.Fatalf("register node: %v", err)
+	}
+	queue := NewTaskQueue(config.TaskQueueConfig{MaxSessionsPerNode: maxSessions}, db, tracker, registry, dispatcher, zap.NewNop())
+	return queue, tracker, registry
+}
+
+func TestTaskQueueOrderingAndPositions(t *testing.T) {
+	queue, _, _ := newTestTaskQueue(t, &policyTestDispatcher{}, 4)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	queue.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Second)
+	}
+	soon := base.Add(time.Hour)
+	later := base.Add(2 * time.Hour)
+
+	low, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "low"})
+	noDeadline, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "urgent, no deadline", Priority: 5})
+	lateDeadline, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "urgent, later", Priority: 5, Deadline: &later})
+	soonDeadline, err := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "urgent, soon", Priority: 5, Deadline: &soon})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if soonDeadline.Position != 1 || soonDeadline.Status != TaskStatusQueued {
+		t.Fatalf("expected soonest deadline first, got %+v", soonDeadline)
+	}
+	other, _ := queue.Enqueue(QueuedTask{Project: "beta", Prompt: "beta work"})
+	if other.Position != 1 {
+		t.Fatalf("positions are per project, got %d", other.Position)
+	}
+
+	tasks, err := queue.List("alpha", TaskStatusQueued)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{soonDeadline.ID, lateDeadline.ID, noDeadline.ID, low.ID}
+	if len(tasks) != len(want) {
+		t.Fatalf("expected %d tasks, got %d", len(want), len(tasks))
+	}
+	for i, task := range tasks {
+		if task.ID != want[i] || task.Position != i+1 {
+			t.Fatalf("unexpected task at %d: %+v", i, task)
+		}
+	}
+
+	if _, err := queue.Enqueue(QueuedTask{Project: "alpha"}); err == nil {
+		t.Fatal("expected error for missing prompt")
+	}
+	if _, err := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "x", Model: "sonnet"}); err == nil {
+		t.Fatal("expected error for model without provider")
+	}
+
+	cancelled, err := queue.Cancel(low.ID)
+	if err != nil || cancelled.Status != TaskStatusCancelled {
+		t.Fatalf("Cancel: %+v %v", cancelled, err)
+	}
+	if _, err := queue.Cancel(low.ID); !errors.Is(err, ErrTaskNotQueued) {
+		t.Fatalf("expected ErrTaskNotQueued, got %v", err)
+	}
+	if _, err := queue.Get("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestTaskQueueDispatchRespectsActiveSessionsAndCapacity(t *testing.T) {
+	dispatcher := &policyTestDispatcher{results: []*CommandResult{{Status: CommandStatusSuccess, Output: "ses-beta"}}}
+	queue, tracker, _ := newTestTaskQueue(t, dispatcher, 2)
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "ses-alpha", NodeID: "node-1", Project: "alpha", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	first, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "alpha task"})
+	beta, _ := queue.Enqueue(QueuedTask{Project: "beta", Prompt: "beta task", Model: "anthropic/claude-sonnet-4"})
+	queue.Enqueue(QueuedTask{Project: "beta", Prompt: "second beta task"})
+
+	started, err := queue.Dispatch()
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(started) != 1 || started[0].ID != beta.ID || started[0].SessionID != "ses-beta" {
+		t.Fatalf("expected only the first beta task dispatched, got %+v", started)
+	}
+	cmd := dispatcher.calls[0]
+	if cmd.Type != CommandTypeCreateSession || cmd.Target.NodeID != "node-1" || cmd.Args["model"] != "anthropic/claude-sonnet-4" {
+		t.Fatalf("unexpected command %+v", cmd)
+	}
+
+	// beta stays busy until its session shows up and ends; alpha's session
+	// is still running.
+	if started, _ := queue.Dispatch(); len(started) != 0 {
+		t.Fatalf("expected nothing dispatched, got %+v", started)
+	}
+
+	if err := tracker.UpdateSession("ses-alpha", map[string]interface{}{"status": string(SessionStatusError)}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "ses-beta", NodeID: "node-1", Project: "beta", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "ses-gamma", NodeID: "node-1", Project: "gamma", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	// alpha is free now, but node-1 is at its limit of 2 active sessions.
+	if started, _ := queue.Dispatch(); len(started) != 0 {
+		t.Fatalf("expected node capacity to block dispatch, got %+v", started)
+	}
+
+	if err := tracker.UpdateSession("ses-gamma", map[string]interface{}{"status": string(SessionStatusError)}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	started, _ = queue.Dispatch()
+	if len(started) != 1 || started[0].ID != first.ID {
+		t.Fatalf("expected alpha task dispatched, got %+v", started)
+	}
+}
+
+func TestTaskQueueDispatchFailures(t *testing.T) {
+	dispatcher := &policyTestDispatcher{results: []*CommandResult{
+		{Status: CommandStatusFailure, Error: "boom"},
+		{Status: CommandStatusFailure, Error: "boom"},
+		{Status: CommandStatusFailure, Error: "boom"},
+	}}
+	queue, _, _ := newTestTaskQueue(t, dispatcher, 4)
+	task, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "flaky"})
+
+	for i := 0; i < maxTaskAttempts; i++ {
+		if started, _ := queue.Dispatch(); len(started) != 0 {
+			t.Fatalf("expected failed dispatch, got %+v", started)
+		}
+	}
+
+	got, err := queue.Get(task.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != TaskStatusFailed || got.Attempts != maxTaskAttempts || got.Error == "" {
+		t.Fatalf("expected task failed after %d attempts, got %+v", maxTaskAttempts, got)
+	}
+	if started, _ := queue.Dispatch(); len(started) != 0 || dispatcher.callCount() != maxTaskAttempts {
+		t.Fatalf("failed tasks must not be retried, got %d calls", dispatcher.callCount())
+	}
+}
+
+func TestTaskQueueDispatchUsesPlacement(t *testing.T) {
+	dispatcher, registry, tracker := setupPlacementDispatcher(t)
+	dispatcher.SetPlacement(config.PlacementConfig{Strategy: config.PlacementPinned, Pins: map[string]string{"proj-a": "n-3"}})
+	queue := NewTaskQueue(config.TaskQueueConfig{MaxSessionsPerNode: 1}, dispatcher.db, tracker, registry, dispatcher, zap.NewNop())
+
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-3", Project: "proj-c", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	task, _ := queue.Enqueue(QueuedTask{Project: "proj-a", Prompt: "pinned work"})
+
+	// The pinned node is full; the task waits rather than going to n-1.
+	if started, err := queue.Dispatch(); err != nil || len(started) != 0 {
+		t.Fatalf("expected the task to wait for n-3, got %+v (%v)", started, err)
+	}
+
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusKilled)}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	started, err := queue.Dispatch()
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(started) != 1 || started[0].ID != task.ID || started[0].NodeID != "n-3" {
+		t.Fatalf("expected the task placed on pinned n-3, got %+v", started)
+	}
+}
+
+func TestTaskQueueWaitsForBudget(t *testing.T) {
+	dispatcher, registry, tracker := setupPlacementDispatcher(t)
+	spend := &policyTestTracker{sessions: []TrackedSession{
+		{SessionID: "s-old", Project: "proj-a", SessionCost: 2, StartedAt: time.Now().UTC()},
+	}}
+	dispatcher.SetBudgetGate(NewBudgetEnforcer(config.BudgetConfig{
+		Enabled:       true,
+		BlockOnExceed: true,
+		Projects:      map[string]config.BudgetLimits{"proj-a": {DailyUSD: 1}},
+	}, nil, spend, nil, zap.NewNop()))
+	queue := NewTaskQueue(config.TaskQueueConfig{}, dispatcher.db, tracker, registry, dispatcher, zap.NewNop())
+
+	task, _ := queue.Enqueue(QueuedTask{Project: "proj-a", Prompt: "over budget"})
+	for i := 0; i < maxTaskAttempts+1; i++ {
+		if started, err := queue.Dispatch(); err != nil || len(started) != 0 {
+			t.Fatalf("expected the task to wait for budget, got %+v (%v)", started, err)
+		}
+	}
+	got, err := queue.Get(task.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != TaskStatusQueued || got.Attempts != 0 {
+		t.Fatalf("expected the task still queued without attempts, got %+v", got)
+	}
+
+	spend.mu.Lock()
+	spend.sessions[0].SessionCost = 0
+	spend.mu.Unlock()
+	started, err := queue.Dispatch()
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(started) != 1 || started[0].ID != task.ID {
+		t.Fatalf("expected the task started once within budget, got %+v", started)
+	}
+}
+
+func TestTaskQueueExpiresPastDeadline(t *testing.T) {
+	dispatcher := &policyTestDispatcher{results: []*CommandResult{{Status: CommandStatusSuccess, Output: "ses-1"}}}
+	queue, _, _ := newTestTaskQueue(t, dispatcher, 4)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	queue.now = func() time.Time { return now }
+
+	passed := now.Add(-time.Minute)
+	stale, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "too late", Priority: 5, Deadline: &passed})
+	next, _ := queue.Enqueue(QueuedTask{Project: "alpha", Prompt: "still wanted"})
+
+	started, err := queue.Dispatch()
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(started) != 1 || started[0].ID != next.ID || dispatcher.callCount() != 1 {
+		t.Fatalf("expected only the task without a deadline dispatched, got %+v", started)
+	}
+
+	got, err := queue.Get(stale.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != TaskStatusExpired || got.Error == "" || got.Attempts != 0 {
+		t.Fatalf("expected the task expired without an attempt, got %+v", got)
+	}
+	if expired, _ := queue.List("alpha", TaskStatusExpired); len(expired) != 1 || expired[0].ID != stale.ID {
+		t.Fatalf("expected the expired task listed by status, got %+v", expired)
+	}
+	if _, err := queue.Cancel(stale.ID); !errors.Is(err, ErrTaskNotQueued) {
+		t.Fatalf("expected an expired task not to be cancellable, got %v", err)
+	}
+}
+
+func TestHTTPAPITasks(t *testing.T) {
+	api, registry, tracker := setupHTTPAPI(t)
+	handler := api.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/tasks", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without task queue, got %d", rec.Code)
+	}
+
+	api.SetTaskQueue(NewTaskQueue(config.TaskQueueConfig{}, tracker.db, tracker, registry, &policyTestDispatcher{}, zap.NewNop()))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/tasks", `{"project":"alpha","prompt":"write tests","priority":3,"deadline":"2026-01-02T15:04:05Z"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data QueuedTask `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.Data.ID == "" || created.Data.Position != 1 || created.Data.Priority != 3 || created.Data.Deadline == nil {
+		t.Fatalf("unexpected task %+v", created.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodPost, "/api/v1/tasks", `{"project":"alpha"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for missing prompt, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/tasks?project=alpha&status=queued", ""))
+	var listed struct {
+		Data []QueuedTask `json:"data"`
+		Meta apiMeta      `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if listed.Meta.Total != 1 || listed.Data[0].ID != created.Data.ID {
+		t.Fatalf("unexpected list %+v", listed)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/tasks?status=bogus", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid status, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodDelete, "/api/v1/tasks/"+created.Data.ID, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on cancel, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodDelete, "/api/v1/tasks/"+created.Data.ID, ""))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on second cancel, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/tasks/missing", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestDiscordQueueCommand(t *testing.T) {
+	bot, mock, _ := newTestDiscordBot(t)
+
+	simulateInteraction(bot, "queue", nil)
+	if embed := mock.lastFollowupEmbed(); embed == nil || embed.Title != "Queue Unavailable" {
+		t.Fatalf("expected unavailable embed, got %+v", embed)
+	}
+
+	bot.SetTaskQueue(NewTaskQueue(config.TaskQueueConfig{}, bot.tracker.db, bot.tracker, nil, &policyTestDispatcher{}, zap.NewNop()))
+
+	simulateInteraction(bot, "queue", []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "project", Type: discordgo.ApplicationCommandOptionString, Value: "myproject"},
+		{Name: "prompt", Type: discordgo.ApplicationCommandOptionString, Value: "add retries"},
+		{Name: "priority", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(2)},
+	})
+	embed := mock.lastFollowupEmbed()
+	if embed == nil || embed.Title != "Queued: myproject" || embed.Fields[2].Value != "1" {
+		t.Fatalf("unexpected queue embed %+v", embed)
+	}
+
+	simulateInteraction(bot, "queue", nil)
+	embed = mock.lastFollowupEmbed()
+	if embed == nil || len(embed.Fields) != 1 || embed.Fields[0].Name != "myproject #1 (priority 2)" {
+		t.Fatalf("unexpected queue listing %+v", embed)
+	}
+
+	passed := time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC)
+	if _, err := bot.tasks.Enqueue(QueuedTask{Project: "myproject", Prompt: "stale work", Deadline: &passed}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	bot.tasks.Dispatch()
+	simulateInteraction(bot, "queue", nil)
+	embed = mock.lastFollowupEmbed()
+	if embed == nil || embed.Description != "1 queued task(s), 1 expired" || embed.Fields[len(embed.Fields)-1].Name != "myproject expired (deadline 2026-01-01 09:30)" {
+		t.Fatalf("expected the expired task listed, got %+v", embed)
+	}
+}
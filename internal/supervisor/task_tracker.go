@@ -0,0 +1,350 @@
+package supervisor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type TaskState string
+
+const (
+	TaskStateOpen      TaskState = "open"
+	TaskStateCompleted TaskState = "completed"
+)
+
+// Task is a piece of work that may outlive any single session: restarts,
+// compactions and handovers start new sessions on the same task. Sessions are
+// linked to the task their project reported as current, and their tokens,
+// cost and active time roll up into the task.
+type Task struct {
+	ID                 string     `json:"id"`
+	Project            string     `json:"project"`
+	Title              string     `json:"title"`
+	AcceptanceCriteria string     `json:"acceptance_criteria,omitempty"`
+	State              TaskState  `json:"state"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+
+	// Outcome is the recorded outcome of a finished task. For open tasks it
+	// is derived from the linked sessions: pending, in_progress, or the
+	// status of the most recent session.
+	Outcome          string   `json:"outcome"`
+	SessionIDs       []string `json:"session_ids"`
+	Tokens           int      `json:"tokens"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Cost             float64  `json:"cost"`
+	DurationSec      int64    `json:"duration_sec"`
+}
+
+// TaskTracker stores tasks and the sessions that worked on them.
+type TaskTracker struct {
+	db       *sql.DB
+	sessions *SessionTracker
+	logger   *zap.Logger
+	now      func() time.Time
+
+	// createMu serialises find-or-create so concurrent reports of the same
+	// task do not create duplicates.
+	createMu sync.Mutex
+}
+
+func NewTaskTracker(db *sql.DB, sessions *SessionTracker, logger *zap.Logger) *TaskTracker {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &TaskTracker{
+		db:       db,
+		sessions: sessions,
+		logger:   logger,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// AttachSession links a session to the project's open task with the given
+// title, creating the task if there is none.
+func (t *TaskTracker) AttachSession(project, title, sessionID string) (Task, error) {
+	if sessionID == "" {
+		return Task{}, fmt.Errorf("attach session: missing session_id")
+	}
+	id, err := t.ensureOpenTask(project, title)
+	if err != nil {
+		return Task{}, err
+	}
+
+	now := t.now()
+	if _, err := t.db.Exec(`
+		INSERT INTO task_sessions (task_id, session_id, linked_at) VALUES (?, ?, ?)
+		ON CONFLICT(task_id, session_id) DO NOTHING
+	`, id, sessionID, now); err != nil {
+		return Task{}, fmt.Errorf("link session %s to task %s: %w", sessionID, id, err)
+	}
+	if _, err := t.db.Exec(`UPDATE tasks SET updated_at = ? WHERE id = ?`, now, id); err != nil {
+		return Task{}, fmt.Errorf("touch task %s: %w", id, err)
+	}
+
+	return t.Get(id)
+}
+
+// SetAcceptanceCriteria records the acceptance criteria of the project's open
+// task with the given title, creating the task if there is none.
+func (t *TaskTracker) SetAcceptanceCriteria(project, title, criteria string) error {
+	id, err := t.ensureOpenTask(project, title)
+	if err != nil {
+		return err
+	}
+	if _, err := t.db.Exec(`UPDATE tasks SET acceptance_criteria = ?, updated_at = ? WHERE id = ?`,
+		strings.TrimSpace(criteria), t.now(), id); err != nil {
+		return fmt.Errorf("set acceptance criteria for task %s: %w", id, err)
+	}
+	return nil
+}
+
+// Complete marks the project's open task with the given title as completed.
+// It reports false when there is no such task.
+func (t *TaskTracker) Complete(project, title, outcome string) (bool, error) {
+	if outcome == "" {
+		outcome = string(TaskStateCompleted)
+	}
+	now := t.now()
+	res, err := t.db.Exec(`
+		UPDATE tasks SET state = ?, outcome = ?, completed_at = ?, updated_at = ?
+		WHERE project = ? AND title = ? AND state = ?
+	`, string(TaskStateCompleted), outcome, now, now, project, strings.TrimSpace(title), string(TaskStateOpen))
+	if err != nil {
+		return false, fmt.Errorf("complete task %q: %w", title, err)
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		t.logger.Info("task completed", zap.String("project", project), zap.String("task", title))
+	}
+	return n > 0, nil
+}
+
+// Get returns a task with its session roll-up.
+func (t *TaskTracker) Get(id string) (Task, error) {
+	row := t.db.QueryRow(`
+		SELECT id, project, title, acceptance_criteria, state, outcome, created_at, updated_at, completed_at
+		FROM tasks WHERE id = ?
+	`, id)
+	task, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, ErrTaskNotFound
+		}
+		return Task{}, fmt.Errorf("get task %s: %w", id, err)
+	}
+	if err := t.rollUp(&task); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// List returns tasks, newest first, optionally for one project.
+func (t *TaskTracker) List(project string) ([]Task, error) {
+	query := `SELECT id, project, title, acceptance_criteria, state, outcome, created_at, updated_at, completed_at FROM tasks`
+	args := make([]interface{}, 0, 1)
+	if project != "" {
+		query += ` WHERE project = ?`
+		args = append(args, project)
+	}
+	query += ` ORDER BY created_at DESC, id`
+
+	rows, err := t.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tasks: %w", err)
+	}
+
+	for i := range tasks {
+		if err := t.rollUp(&tasks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// ForSession returns the task the session was most recently linked to.
+func (t *TaskTracker) ForSession(sessionID string) (Task, error) {
+	id, err := t.taskIDForSession(sessionID)
+	if err != nil {
+		return Task{}, err
+	}
+	return t.Get(id)
+}
+
+func (t *TaskTracker) taskIDForSession(sessionID string) (string, error) {
+	var id string
+	err := t.db.QueryRow(`
+		SELECT task_id FROM task_sessions WHERE session_id = ?
+		ORDER BY linked_at DESC, rowid DESC LIMIT 1
+	`, sessionID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTaskNotFound
+		}
+		return "", fmt.Errorf("get task for session %s: %w", sessionID, err)
+	}
+	return id, nil
+}
+
+func (t *TaskTracker) ensureOpenTask(project, title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if project == "" {
+		return "", fmt.Errorf("task: missing project")
+	}
+	if title == "" {
+		return "", fmt.Errorf("task: missing title")
+	}
+
+	t.createMu.Lock()
+	defer t.createMu.Unlock()
+
+	var id string
+	err := t.db.QueryRow(`
+		SELECT id FROM tasks WHERE project = ? AND title = ? AND state = ?
+		ORDER BY created_at DESC LIMIT 1
+	`, project, title, string(TaskStateOpen)).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("find task %q: %w", title, err)
+	}
+
+	id = uuid.NewString()
+	now := t.now()
+	if _, err := t.db.Exec(`
+		INSERT INTO tasks (id, project, title, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, project, title, string(TaskStateOpen), now, now); err != nil {
+		return "", fmt.Errorf("create task %q: %w", title, err)
+	}
+	t.logger.Info("task created", zap.String("task_id", id), zap.String("project", project), zap.String("task", title))
+	return id, nil
+}
+
+// rollUp fills the task's session totals and derived outcome.
+func (t *TaskTracker) rollUp(task *Task) error {
+	rows, err := t.db.Query(`SELECT session_id FROM task_sessions WHERE task_id = ? ORDER BY linked_at, session_id`, task.ID)
+	if err != nil {
+		return fmt.Errorf("query task sessions: %w", err)
+	}
+	task.SessionIDs = make([]string, 0)
+	for rows.Next() {
+		var sessionID string
+		if err := rows.Scan(&sessionID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan task session: %w", err)
+		}
+		task.SessionIDs = append(task.SessionIDs, sessionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate task sessions: %w", err)
+	}
+
+	var (
+		duration time.Duration
+		active   bool
+		latest   *TrackedSession
+	)
+	now := t.now()
+	for _, sessionID := range task.SessionIDs {
+		if t.sessions == nil {
+			break
+		}
+		session, err := t.sessions.GetSession(sessionID)
+		if err != nil {
+			continue
+		}
+		task.Tokens += session.TokenUsage.Total
+		task.PromptTokens += session.TokenUsage.Prompt
+		task.CompletionTokens += session.TokenUsage.Completion
+		task.Cost += session.SessionCost
+
+		end := session.LastActivity
+		if session.Status.Active() {
+			active = true
+			end = now
+		}
+		if end.After(session.StartedAt) {
+			duration += end.Sub(session.StartedAt)
+		}
+		if latest == nil || session.StartedAt.After(latest.StartedAt) {
+			s := session
+			latest = &s
+		}
+	}
+	task.DurationSec = int64(duration / time.Second)
+
+	switch {
+	case task.State != TaskStateOpen:
+		if task.Outcome == "" {
+			task.Outcome = string(task.State)
+		}
+	case active:
+		task.Outcome = "in_progress"
+	case latest != nil:
+		task.Outcome = string(latest.Status)
+	default:
+		task.Outcome = "pending"
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner) (Task, error) {
+	var (
+		task        Task
+		state       string
+		completedAt sql.NullTime
+	)
+	if err := row.Scan(&task.ID, &task.Project, &task.Title, &task.AcceptanceCriteria, &state, &task.Outcome,
+		&task.CreatedAt, &task.UpdatedAt, &completedAt); err != nil {
+		return Task{}, err
+	}
+	task.State = TaskState(state)
+	task.CreatedAt = task.CreatedAt.UTC()
+	task.UpdatedAt = task.UpdatedAt.UTC()
+	if completedAt.Valid {
+		at := completedAt.Time.UTC()
+		task.CompletedAt = &at
+	}
+	return task, nil
+}
+
+// formatTaskProgress summarises a task's roll-up for chat, e.g.
+// "3 sessions · 120,000 tokens · $1.20 · 2h10m0s".
+func formatTaskProgress(task Task) string {
+	parts := []string{
+		fmt.Sprintf("%d sessions", len(task.SessionIDs)),
+		formatThousands(task.Tokens) + " tokens",
+		fmt.Sprintf("$%.2f", task.Cost),
+		(time.Duration(task.DurationSec) * time.Second).String(),
+	}
+	return strings.Join(parts, " · ")
+}
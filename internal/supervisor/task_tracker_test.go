@@ -0,0 +1,186 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+func seedTaskNode(t *testing.T, tracker *SessionTracker) {
+	t.Helper()
+	if _, err := tracker.db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES ('n-1', 'n-1', 'online', ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+}
+
+func TestTaskTrackerRollsUpAcrossSessions(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	tasks := NewTaskTracker(db, tracker, zap.NewNop())
+	tracker.SetTaskTracker(tasks)
+	seedTaskNode(t, tracker)
+
+	start := time.Now().UTC().Add(-2 * time.Hour)
+	if err := tracker.AddSession(TrackedSession{
+		SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Status: SessionStatusError, CurrentTask: "Wire HAL",
+		TokenUsage: TokenUsage{Prompt: 800, Completion: 200, Total: 1000}, SessionCost: 0.50,
+		StartedAt: start, LastActivity: start.Add(30 * time.Minute),
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	first, err := tasks.ForSession("s-1")
+	if err != nil {
+		t.Fatalf("ForSession: %v", err)
+	}
+	if first.Title != "Wire HAL" || first.State != TaskStateOpen || first.Outcome != string(SessionStatusError) {
+		t.Fatalf("unexpected task %+v", first)
+	}
+
+	// A handover session picks up the same task.
+	if err := tracker.AddSession(TrackedSession{
+		SessionID: "s-2", NodeID: "n-1", Project: "proj-a", Status: SessionStatusRunning, CurrentTask: "Wire HAL",
+		TokenUsage: TokenUsage{Prompt: 1500, Completion: 500, Total: 2000}, SessionCost: 1.25,
+		StartedAt: time.Now().UTC().Add(-time.Hour),
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	task, err := tasks.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(task.SessionIDs) != 2 || task.Tokens != 3000 || task.PromptTokens != 2300 || task.CompletionTokens != 700 {
+		t.Fatalf("unexpected roll-up %+v", task)
+	}
+	if task.Cost != 1.75 || task.Outcome != "in_progress" {
+		t.Fatalf("unexpected cost/outcome %+v", task)
+	}
+	if task.DurationSec < int64((90*time.Minute)/time.Second) {
+		t.Fatalf("expected at least 90m of session time, got %ds", task.DurationSec)
+	}
+
+	completed, err := tasks.Complete("proj-a", "Wire HAL", "")
+	if err != nil || !completed {
+		t.Fatalf("Complete: %v %v", completed, err)
+	}
+	task, _ = tasks.Get(first.ID)
+	if task.State != TaskStateCompleted || task.Outcome != "completed" || task.CompletedAt == nil {
+		t.Fatalf("expected completed task, got %+v", task)
+	}
+
+	// Reporting the title again after completion starts a new task.
+	if err := tracker.UpdateSession("s-2", map[string]interface{}{"current_task": "Wire HAL v2"}); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+	listed, err := tasks.List("proj-a")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(listed))
+	}
+	if current, _ := tasks.ForSession("s-2"); current.Title != "Wire HAL v2" || len(current.SessionIDs) != 1 {
+		t.Fatalf("expected s-2 linked to the new task, got %+v", current)
+	}
+
+	if _, err := tasks.Get("missing"); err != ErrTaskNotFound {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestHubProgressEventsUpdateTasks(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+	tasks := NewTaskTracker(db, tracker, zap.NewNop())
+	tracker.SetTaskTracker(tasks)
+	seedTaskNode(t, tracker)
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Status: SessionStatusRunning}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureSessionTracker(tracker)
+	hub.ConfigureTaskTracker(tasks)
+
+	hub.ingestSessionEvent([]byte(`{"type":"task.updated","payload":{"project":"proj-a","task":"Define HAL","acceptance_criteria":"- Compiles"}}`))
+	task, err := tasks.ForSession("s-1")
+	if err != nil {
+		t.Fatalf("ForSession: %v", err)
+	}
+	if task.Title != "Define HAL" || task.AcceptanceCriteria != "- Compiles" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	hub.ingestSessionEvent([]byte(`{"type":"task.completed","payload":{"project":"proj-a","task":"Define HAL","current_task":"Publish AIDL"}}`))
+	done, err := tasks.Get(task.ID)
+	if err != nil || done.State != TaskStateCompleted {
+		t.Fatalf("expected completed task, got %+v (%v)", done, err)
+	}
+	if next, _ := tasks.ForSession("s-1"); next.Title != "Publish AIDL" || next.State != TaskStateOpen {
+		t.Fatalf("expected session moved to the next task, got %+v", next)
+	}
+}
+
+func TestHTTPAPISessionsIncludeTask(t *testing.T) {
+	api, _, tracker := setupHTTPAPI(t)
+	tasks := NewTaskTracker(tracker.db, tracker, zap.NewNop())
+	tracker.SetTaskTracker(tasks)
+	api.SetTaskTracker(tasks)
+	seedTaskNode(t, tracker)
+	if err := tracker.AddSession(TrackedSession{
+		SessionID: "s-1", NodeID: "n-1", Project: "proj-a", Status: SessionStatusRunning,
+		CurrentTask: "Wire HAL", TokenUsage: TokenUsage{Total: 1200},
+	}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	api.Handler().ServeHTTP(rec, authRequest(http.MethodGet, "/api/v1/sessions/s-1", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data sessionJSON `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Data.CurrentTask != "Wire HAL" || resp.Data.Task == nil || resp.Data.Task.Tokens != 1200 {
+		t.Fatalf("unexpected session task %+v", resp.Data)
+	}
+}
+
+func TestDiscordStatusShowsTask(t *testing.T) {
+	bot, mock, _ := newTestDiscordBot(t)
+	tasks := NewTaskTracker(bot.tracker.db, bot.tracker, zap.NewNop())
+	if _, err := tasks.AttachSession("myproject", "implement feature", "sess-1"); err != nil {
+		t.Fatalf("AttachSession: %v", err)
+	}
+	bot.SetTaskTracker(tasks)
+
+	simulateInteraction(bot, "status", []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "project", Type: discordgo.ApplicationCommandOptionString, Value: "myproject"},
+	})
+	embed := mock.lastFollowupEmbed()
+	if embed == nil {
+		t.Fatal("expected followup embed")
+	}
+	fields := map[string]string{}
+	for _, field := range embed.Fields {
+		fields[field.Name] = field.Value
+	}
+	if fields["Task"] != "implement feature (in_progress)" {
+		t.Fatalf("unexpected task field %q", fields["Task"])
+	}
+	if !strings.HasPrefix(fields["Task Progress"], "1 sessions · 5,000 tokens · $1.50") {
+		t.Fatalf("unexpected task progress %q", fields["Task Progress"])
+	}
+}
@@ -0,0 +1,147 @@
+package supervisor
+
+import (
+	"encoding/json"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+)
+
+// tokenUsageReport is the per-message usage carried by an agent event.
+type tokenUsageReport struct {
+	SessionID string
+	MessageID string
+	Model     string
+	Usage     TokenUsage
+}
+
+func (u TokenUsage) splitTotal() int {
+	return u.Prompt + u.Completion + u.CacheRead + u.CacheWrite
+}
+
+func (u TokenUsage) plus(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		Prompt:     u.Prompt + other.Prompt,
+		Completion: u.Completion + other.Completion,
+		CacheRead:  u.CacheRead + other.CacheRead,
+		CacheWrite: u.CacheWrite + other.CacheWrite,
+		Total:      u.Total + other.Total,
+	}
+}
+
+func (u TokenUsage) minus(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		Prompt:     u.Prompt - other.Prompt,
+		Completion: u.Completion - other.Completion,
+		CacheRead:  u.CacheRead - other.CacheRead,
+		CacheWrite: u.CacheWrite - other.CacheWrite,
+		Total:      u.Total - other.Total,
+	}
+}
+
+// priceTokenUsage returns the USD cost of usage at the per-1K-token rate.
+// Each token class uses its own rate; cache classes without a configured rate
+// fall back to the input rate. Tokens only known as part of Total (agents
+// that do not report a split) are priced at the blended input/output rate.
+func priceTokenUsage(rate config.ModelRate, usage TokenUsage) float64 {
+	cacheRead := rate.CacheRead
+	if cacheRead == 0 {
+		cacheRead = rate.Input
+	}
+	cacheWrite := rate.CacheWrite
+	if cacheWrite == 0 {
+		cacheWrite = rate.Input
+	}
+
+	cost := float64(usage.Prompt)*rate.Input +
+		float64(usage.Completion)*rate.Output +
+		float64(usage.CacheRead)*cacheRead +
+		float64(usage.CacheWrite)*cacheWrite
+
+	if unsplit := usage.Total - usage.splitTotal(); unsplit > 0 {
+		cost += float64(unsplit) * blendedRate(rate)
+	}
+
+	return cost / 1000
+}
+
+func blendedRate(rate config.ModelRate) float64 {
+	switch {
+	case rate.Input > 0 && rate.Output > 0:
+		return (rate.Input + rate.Output) / 2
+	case rate.Output > 0:
+		return rate.Output
+	default:
+		return rate.Input
+	}
+}
+
+// parseTokenUsageEvent extracts message token usage from an agent event
+// payload. It understands opencode message events, where usage sits under
+// properties.info.tokens, as well as Anthropic-style usage objects.
+func parseTokenUsageEvent(raw []byte) (tokenUsageReport, bool) {
+	var envelope struct {
+		SessionID string          `json:"session_id"`
+		Payload   json.RawMessage `json:"payload"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return tokenUsageReport{}, false
+	}
+
+	body := envelope.Payload
+	if len(body) == 0 {
+		body = envelope.Data
+	}
+	if len(body) == 0 {
+		return tokenUsageReport{}, false
+	}
+
+	var inner map[string]interface{}
+	if err := json.Unmarshal(body, &inner); err != nil {
+		return tokenUsageReport{}, false
+	}
+
+	info := inner
+	if properties, ok := inner["properties"].(map[string]interface{}); ok {
+		info = properties
+	}
+	if nested, ok := info["info"].(map[string]interface{}); ok {
+		info = nested
+	}
+
+	var usage TokenUsage
+	if tokens, ok := info["tokens"].(map[string]interface{}); ok {
+		usage.Prompt = int(asInt64(tokens["input"]))
+		usage.Completion = int(asInt64(tokens["output"]) + asInt64(tokens["reasoning"]))
+		if cache, ok := tokens["cache"].(map[string]interface{}); ok {
+			usage.CacheRead = int(asInt64(cache["read"]))
+			usage.CacheWrite = int(asInt64(cache["write"]))
+		}
+	} else if fields, ok := info["usage"].(map[string]interface{}); ok {
+		usage.Prompt = int(asInt64(fields["input_tokens"]))
+		usage.Completion = int(asInt64(fields["output_tokens"]))
+		usage.CacheRead = int(asInt64(fields["cache_read_input_tokens"]))
+		usage.CacheWrite = int(asInt64(fields["cache_creation_input_tokens"]))
+	} else {
+		return tokenUsageReport{}, false
+	}
+	usage.Total = usage.splitTotal()
+	if usage.Total == 0 {
+		return tokenUsageReport{}, false
+	}
+
+	sessionID := envelope.SessionID
+	if sessionID == "" {
+		sessionID = asString(info["sessionID"], info["session_id"])
+	}
+	if sessionID == "" {
+		return tokenUsageReport{}, false
+	}
+
+	return tokenUsageReport{
+		SessionID: sessionID,
+		MessageID: asString(info["id"], info["message_id"]),
+		Model:     asString(info["modelID"], info["model"]),
+		Usage:     usage,
+	}, true
+}
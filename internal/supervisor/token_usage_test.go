@@ -0,0 +1,179 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bldg-7/hal-o-swarm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestPriceTokenUsage(t *testing.T) {
+	rate := config.ModelRate{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}
+	usage := TokenUsage{Prompt: 1000, Completion: 1000, CacheRead: 1000, CacheWrite: 1000, Total: 4000}
+	if got := priceTokenUsage(rate, usage); math.Abs(got-22.05) > 1e-9 {
+		t.Fatalf("expected 22.05, got %f", got)
+	}
+
+	// Cache classes without their own rate use the input rate.
+	noCacheRates := config.ModelRate{Input: 3, Output: 15}
+	if got := priceTokenUsage(noCacheRates, TokenUsage{CacheRead: 1000, CacheWrite: 1000, Total: 2000}); math.Abs(got-6) > 1e-9 {
+		t.Fatalf("expected cache tokens at input rate (6), got %f", got)
+	}
+
+	// Tokens reported only as a total are priced at the blended rate.
+	if got := priceTokenUsage(noCacheRates, TokenUsage{Prompt: 1000, Total: 3000}); math.Abs(got-21) > 1e-9 {
+		t.Fatalf("expected 3 + 2K unsplit at 9 = 21, got %f", got)
+	}
+}
+
+func TestParseTokenUsageEvent(t *testing.T) {
+	opencode := []byte(`{
+		"type": "message.updated",
+		"session_id": "s-1",
+		"payload": {
+			"type": "message.updated",
+			"properties": {
+				"info": {
+					"id": "msg-1",
+					"sessionID": "s-1",
+					"modelID": "claude-sonnet-4",
+					"tokens": {"input": 100, "output": 40, "reasoning": 10, "cache": {"read": 900, "write": 50}}
+				}
+			}
+		}
+	}`)
+	report, ok := parseTokenUsageEvent(opencode)
+	if !ok {
+		t.Fatal("expected usage from opencode message event")
+	}
+	want := TokenUsage{Prompt: 100, Completion: 50, CacheRead: 900, CacheWrite: 50, Total: 1100}
+	if report.SessionID != "s-1" || report.MessageID != "msg-1" || report.Model != "claude-sonnet-4" || report.Usage != want {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	anthropic := []byte(`{"data": {"session_id": "s-2", "id": "msg-2", "usage": {"input_tokens": 10, "output_tokens": 20, "cache_read_input_tokens": 30, "cache_creation_input_tokens": 40}}}`)
+	report, ok = parseTokenUsageEvent(anthropic)
+	if !ok || report.SessionID != "s-2" || report.Usage.CacheWrite != 40 || report.Usage.Total != 100 {
+		t.Fatalf("unexpected anthropic-style report %+v ok=%v", report, ok)
+	}
+
+	if _, ok := parseTokenUsageEvent([]byte(`{"session_id": "s-1", "payload": {"type": "session.idle"}}`)); ok {
+		t.Fatal("expected events without usage to be ignored")
+	}
+}
+
+func TestSessionTrackerRecordMessageUsage(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	// A streaming message re-reports its growing usage; only the latest counts.
+	if err := tracker.RecordMessageUsage("s-1", "msg-1", "claude-sonnet-4", TokenUsage{Prompt: 100, Completion: 10}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-1", "msg-1", "", TokenUsage{Prompt: 100, Completion: 40, CacheRead: 500}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+	if err := tracker.RecordMessageUsage("s-1", "msg-2", "", TokenUsage{Prompt: 20, Completion: 5, CacheWrite: 30}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+
+	want := TokenUsage{Prompt: 120, Completion: 45, CacheRead: 500, CacheWrite: 30, Total: 695}
+	session, err := tracker.GetSession("s-1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if session.TokenUsage != want || session.Model != "claude-sonnet-4" {
+		t.Fatalf("unexpected session usage %+v model %q", session.TokenUsage, session.Model)
+	}
+
+	persisted, err := tracker.readSession("s-1")
+	if err != nil {
+		t.Fatalf("read session: %v", err)
+	}
+	if persisted.TokenUsage != want {
+		t.Fatalf("expected token split persisted, got %+v", persisted.TokenUsage)
+	}
+
+	if err := tracker.RecordMessageUsage("missing", "msg-1", "", TokenUsage{Prompt: 1}); err == nil {
+		t.Fatal("expected error for unknown session")
+	}
+}
+
+func TestSessionTrackerRecordMessageUsageConcurrent(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	const reports = 20
+	var wg sync.WaitGroup
+	for i := 0; i < reports; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := tracker.RecordMessageUsage("s-1", fmt.Sprintf("msg-%d", i), "", TokenUsage{Prompt: 10}); err != nil {
+				t.Errorf("record usage: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	session, err := tracker.GetSession("s-1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if session.TokenUsage.Prompt != reports*10 {
+		t.Fatalf("expected every report counted once, got %+v", session.TokenUsage)
+	}
+
+	if err := tracker.UpdateSession("s-1", map[string]interface{}{"status": string(SessionStatusCompleted)}); err != nil {
+		t.Fatalf("complete session: %v", err)
+	}
+	tracker.mu.RLock()
+	remaining := len(tracker.messageUsage["s-1"])
+	tracker.mu.RUnlock()
+	if remaining != 0 {
+		t.Fatalf("expected message usage dropped for ended session, got %d entries", remaining)
+	}
+}
+
+func TestHubIngestSessionEventUpdatesTracker(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	tracker := NewSessionTracker(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO nodes (id, hostname, status, last_heartbeat) VALUES (?, ?, ?, ?)`, "n-1", "node-1", "online", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	if err := tracker.AddSession(TrackedSession{SessionID: "s-1", NodeID: "n-1", Project: "proj-a"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	hub := NewHub(context.Background(), "test-token", nil, 30*time.Second, 3, zap.NewNop())
+	hub.ConfigureSessionTracker(tracker)
+	hub.ingestSessionEvent([]byte(`{"session_id":"s-1","payload":{"properties":{"info":{"id":"m","tokens":{"input":7,"output":3,"cache":{"read":0,"write":0}}}}}}`))
+
+	session, err := tracker.GetSession("s-1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if session.TokenUsage.Prompt != 7 || session.TokenUsage.Completion != 3 || session.TokenUsage.Total != 10 {
+		t.Fatalf("unexpected usage after ingest %+v", session.TokenUsage)
+	}
+}
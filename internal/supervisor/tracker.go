@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,18 +12,29 @@ import (
 	"go.uber.org/zap"
 )
 
+// SessionStatus is a session's state in the lifecycle described in
+// session_state.go.
 type SessionStatus string
 
 const (
 	SessionStatusRunning     SessionStatus = "running"
 	SessionStatusIdle        SessionStatus = "idle"
+	SessionStatusHandover    SessionStatus = "handover"
+	SessionStatusRestarting  SessionStatus = "restarting"
+	SessionStatusCompleted   SessionStatus = "completed"
+	SessionStatusKilled      SessionStatus = "killed"
 	SessionStatusError       SessionStatus = "error"
 	SessionStatusUnreachable SessionStatus = "unreachable"
 )
 
+// TokenUsage splits a session's tokens by how they are billed. Prompt counts
+// uncached input only; prompt-cache reads and writes are tracked separately.
+// Total may exceed the sum of the split when an agent only reports totals.
 type TokenUsage struct {
 	Prompt     int
 	Completion int
+	CacheRead  int
+	CacheWrite int
 	Total      int
 }
 
@@ -38,6 +50,17 @@ type TrackedSession struct {
 	SessionCost     float64
 	Model           string
 	StartedAt       time.Time
+	// ParentSessionID is the session this one replaced through a restart or
+	// handover, if any.
+	ParentSessionID string
+
+	// ContextTokens is the size of the session's most recent request;
+	// ContextUsed falls back to TokenUsage.Total when it is unknown.
+	ContextTokens int
+	// ContextWindow and CompactionHeadroom come from the model catalog when
+	// the session is read from the tracker; zero means the model is unknown.
+	ContextWindow      int
+	CompactionHeadroom int
 }
 
 var ErrSessionNotFound = errors.New("session not found")
@@ -48,6 +71,16 @@ type SessionTracker struct {
 
 	mu       sync.RWMutex
 	sessions map[string]TrackedSession
+	// messageUsage holds the last usage reported per message so repeated
+	// reports for a streaming message replace rather than add. Entries are
+	// dropped once the session ends.
+	messageUsage map[string]map[string]TokenUsage
+	models       *ModelCatalog
+	// projectTasks holds the current task each project's agent reported
+	// from CURRENT_TASK.md, applied to sessions added later.
+	projectTasks map[string]string
+	// tasks, when set, links sessions to the task named by CurrentTask.
+	tasks *TaskTracker
 
 	recoveryErrors atomic.Uint64
 }
@@ -58,13 +91,64 @@ func NewSessionTracker(db *sql.DB, logger *zap.Logger) *SessionTracker {
 	}
 
 	return &SessionTracker{
-		db:       db,
-		logger:   logger,
-		sessions: make(map[string]TrackedSession),
+		db:           db,
+		logger:       logger,
+		sessions:     make(map[string]TrackedSession),
+		messageUsage: make(map[string]map[string]TokenUsage),
+		models:       NewModelCatalog(nil),
+		projectTasks: make(map[string]string),
 	}
 }
 
+// SetModelCatalog replaces the catalog used to resolve session context
+// windows.
+func (t *SessionTracker) SetModelCatalog(catalog *ModelCatalog) {
+	t.mu.Lock()
+	t.models = catalog
+	t.mu.Unlock()
+}
+
+// SetTaskTracker links sessions to first-class tasks from now on.
+func (t *SessionTracker) SetTaskTracker(tasks *TaskTracker) {
+	t.mu.Lock()
+	t.tasks = tasks
+	t.mu.Unlock()
+}
+
+// linkTask attaches the session to the task named by its CurrentTask. Task
+// bookkeeping never fails the session update that triggered it.
+func (t *SessionTracker) linkTask(session TrackedSession) {
+	t.mu.RLock()
+	tasks := t.tasks
+	t.mu.RUnlock()
+	if tasks == nil || session.CurrentTask == "" {
+		return
+	}
+	if _, err := tasks.AttachSession(session.Project, session.CurrentTask, session.SessionID); err != nil {
+		t.logger.Warn("link session to task failed",
+			zap.String("session_id", session.SessionID),
+			zap.String("task", session.CurrentTask),
+			zap.Error(err),
+		)
+	}
+}
+
+// withModelInfo fills the session's context window from the model catalog.
+// Callers must hold t.mu.
+func (t *SessionTracker) withModelInfo(session TrackedSession) TrackedSession {
+	info, _ := t.models.Lookup(session.Model)
+	session.ContextWindow = info.ContextWindow
+	session.CompactionHeadroom = info.CompactionHeadroom
+	return session
+}
+
 func (t *SessionTracker) AddSession(session TrackedSession) error {
+	return t.AddSessionWithReason(session, defaultTransitionReason)
+}
+
+// AddSessionWithReason adds or replaces a session, recording why its status
+// was set.
+func (t *SessionTracker) AddSessionWithReason(session TrackedSession, reason TransitionReason) error {
 	if session.SessionID == "" {
 		return fmt.Errorf("add session: missing session_id")
 	}
@@ -84,117 +168,257 @@ func (t *SessionTracker) AddSession(session TrackedSession) error {
 	if session.Status == "" {
 		session.Status = SessionStatusRunning
 	}
+	if session.CurrentTask == "" {
+		t.mu.RLock()
+		session.CurrentTask = t.projectTasks[session.Project]
+		t.mu.RUnlock()
+	}
 
-	if err := t.upsertSession(session); err != nil {
-		return fmt.Errorf("add session %s: %w", session.SessionID, err)
+	if !session.Status.Valid() {
+		return fmt.Errorf("add session %s: %w", session.SessionID, validateSessionTransition("", session.Status))
 	}
 
 	t.mu.Lock()
-	t.sessions[session.SessionID] = session
+	session, previous, existed, err := t.addSessionLocked(session)
 	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("add session %s: %w", session.SessionID, err)
+	}
 
+	if !existed || previous.Status != session.Status {
+		t.recordTransition(session.SessionID, previous.Status, session.Status, reason)
+	}
+	t.linkTask(session)
 	return nil
 }
 
+// addSessionLocked stores session and returns it with the session it
+// replaced, read from memory or, after a purge or restart, the database.
+// Callers must hold t.mu for writing, so concurrent adds check the state
+// machine against the status the other just stored.
+func (t *SessionTracker) addSessionLocked(session TrackedSession) (TrackedSession, TrackedSession, bool, error) {
+	previous, err := t.sessionLocked(session.SessionID)
+	existed := err == nil
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		t.logger.Warn("read stored session failed",
+			zap.String("session_id", session.SessionID),
+			zap.Error(err),
+		)
+	}
+	if existed && !previous.Status.CanTransitionTo(session.Status) {
+		// Re-adding (e.g. from an agent snapshot) must not revive an ended
+		// session or skip the state machine; keep the known status.
+		t.logger.Warn("ignoring illegal session transition",
+			zap.String("session_id", session.SessionID),
+			zap.String("from", string(previous.Status)),
+			zap.String("to", string(session.Status)),
+		)
+		session.Status = previous.Status
+	}
+
+	if err := t.upsertSession(session); err != nil {
+		return session, previous, existed, err
+	}
+	// Usage a new session arrives with is attributed to its start day; growth
+	// of a known session's usage to today.
+	usageAt := time.Now().UTC()
+	if !existed {
+		usageAt = session.StartedAt
+	}
+	t.recordUsage(session.SessionID, usageAt, session.TokenUsage.minus(previous.TokenUsage))
+
+	t.sessions[session.SessionID] = session
+	if session.Status.Terminal() {
+		delete(t.messageUsage, session.SessionID)
+	}
+	return session, previous, existed, nil
+}
+
 func (t *SessionTracker) UpdateSession(sessionID string, updates map[string]interface{}) error {
-	session, err := t.GetSession(sessionID)
+	return t.UpdateSessionWithReason(sessionID, updates, defaultTransitionReason)
+}
+
+// UpdateSessionWithReason applies updates to a session, recording reason
+// when they change its status.
+func (t *SessionTracker) UpdateSessionWithReason(sessionID string, updates map[string]interface{}, reason TransitionReason) error {
+	err := t.updateSession(sessionID, reason, func(session *TrackedSession) error {
+		return applySessionUpdates(session, updates)
+	})
 	if err != nil {
 		return fmt.Errorf("update session %s: %w", sessionID, err)
 	}
+	return nil
+}
+
+// updateSession reads the session, applies mutate and stores the result
+// under one hold of t.mu, so concurrent updates never overwrite each other.
+// A session that reaches a terminal status forgets its per-message usage.
+func (t *SessionTracker) updateSession(sessionID string, reason TransitionReason, mutate func(session *TrackedSession) error) error {
+	t.mu.Lock()
+	session, previous, err := t.updateSessionLocked(sessionID, mutate)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if session.Status != previous.Status {
+		t.recordTransition(sessionID, previous.Status, session.Status, reason)
+	}
+	if session.CurrentTask != previous.CurrentTask || session.Project != previous.Project {
+		t.linkTask(session)
+	}
+	return nil
+}
+
+// updateSessionLocked does the work of updateSession and returns the session
+// before and after mutate. Callers must hold t.mu for writing.
+func (t *SessionTracker) updateSessionLocked(sessionID string, mutate func(session *TrackedSession) error) (TrackedSession, TrackedSession, error) {
+	session, err := t.sessionLocked(sessionID)
+	if err != nil {
+		return TrackedSession{}, TrackedSession{}, err
+	}
+	previous := session
 
+	if err := mutate(&session); err != nil {
+		return TrackedSession{}, TrackedSession{}, err
+	}
+	if session.Status != previous.Status {
+		if err := validateSessionTransition(previous.Status, session.Status); err != nil {
+			return TrackedSession{}, TrackedSession{}, err
+		}
+	}
+
+	if err := t.upsertSession(session); err != nil {
+		return TrackedSession{}, TrackedSession{}, err
+	}
+	t.recordUsage(sessionID, time.Now().UTC(), session.TokenUsage.minus(previous.TokenUsage))
+
+	t.sessions[session.SessionID] = session
+	if session.Status.Terminal() {
+		delete(t.messageUsage, sessionID)
+	}
+	return session, previous, nil
+}
+
+// applySessionUpdates sets the fields named in updates on session.
+func applySessionUpdates(session *TrackedSession, updates map[string]interface{}) error {
 	for key, value := range updates {
 		switch key {
 		case "node_id":
 			nodeID, ok := value.(string)
 			if !ok {
-				return fmt.Errorf("update session %s: node_id must be string", sessionID)
+				return errors.New("node_id must be string")
 			}
 			session.NodeID = nodeID
 		case "project":
 			project, ok := value.(string)
 			if !ok {
-				return fmt.Errorf("update session %s: project must be string", sessionID)
+				return errors.New("project must be string")
 			}
 			session.Project = project
 		case "status":
 			status, ok := value.(string)
 			if !ok {
-				return fmt.Errorf("update session %s: status must be string", sessionID)
+				return errors.New("status must be string")
 			}
 			session.Status = SessionStatus(status)
 		case "tokens":
 			tokens, ok := value.(int)
 			if !ok {
-				return fmt.Errorf("update session %s: tokens must be int", sessionID)
+				return errors.New("tokens must be int")
 			}
 			session.TokenUsage.Total = tokens
 		case "cost":
 			cost, ok := value.(float64)
 			if !ok {
-				return fmt.Errorf("update session %s: cost must be float64", sessionID)
+				return errors.New("cost must be float64")
 			}
 			session.SessionCost = cost
 		case "started_at":
 			startedAt, ok := value.(time.Time)
 			if !ok {
-				return fmt.Errorf("update session %s: started_at must be time.Time", sessionID)
+				return errors.New("started_at must be time.Time")
 			}
 			session.StartedAt = startedAt.UTC()
 		case "token_usage":
 			tokenUsage, ok := value.(TokenUsage)
 			if !ok {
-				return fmt.Errorf("update session %s: token_usage must be TokenUsage", sessionID)
+				return errors.New("token_usage must be TokenUsage")
 			}
 			session.TokenUsage = tokenUsage
 		case "session_cost":
 			sessionCost, ok := value.(float64)
 			if !ok {
-				return fmt.Errorf("update session %s: session_cost must be float64", sessionID)
+				return errors.New("session_cost must be float64")
 			}
 			session.SessionCost = sessionCost
 		case "last_activity":
 			lastActivity, ok := value.(time.Time)
 			if !ok {
-				return fmt.Errorf("update session %s: last_activity must be time.Time", sessionID)
+				return errors.New("last_activity must be time.Time")
 			}
 			session.LastActivity = lastActivity.UTC()
 		case "current_task":
 			currentTask, ok := value.(string)
 			if !ok {
-				return fmt.Errorf("update session %s: current_task must be string", sessionID)
+				return errors.New("current_task must be string")
 			}
 			session.CurrentTask = currentTask
 		case "model":
 			model, ok := value.(string)
 			if !ok {
-				return fmt.Errorf("update session %s: model must be string", sessionID)
+				return errors.New("model must be string")
 			}
 			session.Model = model
+		case "context_tokens":
+			contextTokens, ok := value.(int)
+			if !ok {
+				return errors.New("context_tokens must be int")
+			}
+			session.ContextTokens = contextTokens
 		case "compaction_count":
 			compactionCount, ok := value.(int)
 			if !ok {
-				return fmt.Errorf("update session %s: compaction_count must be int", sessionID)
+				return errors.New("compaction_count must be int")
 			}
 			session.CompactionCount = compactionCount
+		case "parent_session_id":
+			parentSessionID, ok := value.(string)
+			if !ok {
+				return errors.New("parent_session_id must be string")
+			}
+			session.ParentSessionID = parentSessionID
 		default:
-			return fmt.Errorf("update session %s: unsupported field %q", sessionID, key)
+			return fmt.Errorf("unsupported field %q", key)
 		}
 	}
 
-	if err := t.upsertSession(session); err != nil {
-		return fmt.Errorf("update session %s: %w", sessionID, err)
-	}
+	return nil
+}
 
-	t.mu.Lock()
+// sessionLocked returns the session from memory, loading it from the
+// database when it is not cached. Callers must hold t.mu for writing.
+func (t *SessionTracker) sessionLocked(sessionID string) (TrackedSession, error) {
+	if session, ok := t.sessions[sessionID]; ok {
+		return session, nil
+	}
+	session, err := t.readSession(sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TrackedSession{}, ErrSessionNotFound
+		}
+		return TrackedSession{}, fmt.Errorf("get session %s: %w", sessionID, err)
+	}
 	t.sessions[session.SessionID] = session
-	t.mu.Unlock()
-
-	return nil
+	return session, nil
 }
 
 func (t *SessionTracker) GetSession(sessionID string) (TrackedSession, error) {
 	t.mu.RLock()
 	session, ok := t.sessions[sessionID]
+	if ok {
+		session = t.withModelInfo(session)
+	}
 	t.mu.RUnlock()
 	if ok {
 		return session, nil
@@ -210,6 +434,7 @@ func (t *SessionTracker) GetSession(sessionID string) (TrackedSession, error) {
 
 	t.mu.Lock()
 	t.sessions[session.SessionID] = session
+	session = t.withModelInfo(session)
 	t.mu.Unlock()
 
 	return session, nil
@@ -221,7 +446,7 @@ func (t *SessionTracker) GetAllSessions() []TrackedSession {
 
 	out := make([]TrackedSession, 0, len(t.sessions))
 	for _, session := range t.sessions {
-		out = append(out, session)
+		out = append(out, t.withModelInfo(session))
 	}
 	return out
 }
@@ -233,20 +458,26 @@ func (t *SessionTracker) GetSessionsByProject(project string) []TrackedSession {
 	out := make([]TrackedSession, 0)
 	for _, session := range t.sessions {
 		if session.Project == project {
-			out = append(out, session)
+			out = append(out, t.withModelInfo(session))
 		}
 	}
 	return out
 }
 
+// liveSessionsSQL matches sessions that have not ended; ended sessions keep
+// their final status when nodes or the supervisor go away.
+const liveSessionsSQL = `status NOT IN ('completed', 'killed')`
+
 func (t *SessionTracker) MarkUnreachable(nodeID string) error {
-	if _, err := t.db.Exec(`UPDATE sessions SET status = ? WHERE node_id = ?`, string(SessionStatusUnreachable), nodeID); err != nil {
+	t.recordBulkTransition(`node_id = ?`, []interface{}{nodeID}, SessionStatusUnreachable,
+		TransitionReason{Cause: TransitionCauseEvent, Detail: "node.offline"})
+	if _, err := t.db.Exec(`UPDATE sessions SET status = ? WHERE node_id = ? AND `+liveSessionsSQL, string(SessionStatusUnreachable), nodeID); err != nil {
 		return fmt.Errorf("mark sessions unreachable for node %s: %w", nodeID, err)
 	}
 
 	t.mu.Lock()
 	for sessionID, session := range t.sessions {
-		if session.NodeID != nodeID {
+		if session.NodeID != nodeID || session.Status.Terminal() {
 			continue
 		}
 		session.Status = SessionStatusUnreachable
@@ -257,15 +488,40 @@ func (t *SessionTracker) MarkUnreachable(nodeID string) error {
 	return nil
 }
 
+// PurgeNode drops the sessions that ran on a removed node from the live
+// view and returns their IDs. Sessions still live are marked killed; stored
+// sessions, their events, transitions, task links and lineage are kept so
+// handover history and cost roll-ups survive the node.
+func (t *SessionTracker) PurgeNode(nodeID string) ([]string, error) {
+	t.recordBulkTransition(`node_id = ?`, []interface{}{nodeID}, SessionStatusKilled,
+		TransitionReason{Cause: TransitionCauseSystem, Detail: "node.removed"})
+	if _, err := t.db.Exec(`UPDATE sessions SET status = ? WHERE node_id = ? AND `+liveSessionsSQL, string(SessionStatusKilled), nodeID); err != nil {
+		return nil, fmt.Errorf("purge sessions for node %s: %w", nodeID, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	purged := make([]string, 0)
+	for sessionID, session := range t.sessions {
+		if session.NodeID != nodeID {
+			continue
+		}
+		delete(t.sessions, sessionID)
+		delete(t.messageUsage, sessionID)
+		purged = append(purged, sessionID)
+	}
+	sort.Strings(purged)
+	return purged, nil
+}
+
 func (t *SessionTracker) LoadSessionsFromDB() error {
-	if _, err := t.db.Exec(`UPDATE sessions SET status = ?`, string(SessionStatusUnreachable)); err != nil {
+	t.recordBulkTransition(`1 = 1`, nil, SessionStatusUnreachable,
+		TransitionReason{Cause: TransitionCauseSystem, Detail: "supervisor.restart"})
+	if _, err := t.db.Exec(`UPDATE sessions SET status = ? WHERE `+liveSessionsSQL, string(SessionStatusUnreachable)); err != nil {
 		return fmt.Errorf("load sessions: mark unreachable: %w", err)
 	}
 
-	rows, err := t.db.Query(`
-		SELECT id, node_id, project, status, tokens, cost, started_at
-		FROM sessions
-	`)
+	rows, err := t.db.Query(`SELECT ` + sessionColumns + ` FROM sessions`)
 	if err != nil {
 		return fmt.Errorf("load sessions: query rows: %w", err)
 	}
@@ -278,7 +534,9 @@ func (t *SessionTracker) LoadSessionsFromDB() error {
 			t.incrementRecoveryError("load sessions: corrupted row", rowErr)
 			continue
 		}
-		session.Status = SessionStatusUnreachable
+		if !session.Status.Terminal() {
+			session.Status = SessionStatusUnreachable
+		}
 		sessions[session.SessionID] = session
 	}
 
@@ -305,10 +563,68 @@ func (t *SessionTracker) ApplyEvent(eventType string, sessionID string) error {
 		return fmt.Errorf("apply event: unsupported event type %q", eventType)
 	}
 
-	return t.UpdateSession(sessionID, map[string]interface{}{
+	return t.UpdateSessionWithReason(sessionID, map[string]interface{}{
 		"status":        string(status),
 		"last_activity": time.Now().UTC(),
+	}, TransitionReason{Cause: TransitionCauseEvent, Detail: eventType})
+}
+
+// RecordMessageUsage applies the token usage an agent reported for one message
+// of a session. Usage without a message ID is added as a delta. The message's
+// own size becomes the session's context size, and the model is recorded when
+// the session does not have one yet.
+func (t *SessionTracker) RecordMessageUsage(sessionID, messageID, model string, usage TokenUsage) error {
+	if usage.Total == 0 {
+		usage.Total = usage.splitTotal()
+	}
+
+	err := t.updateSession(sessionID, defaultTransitionReason, func(session *TrackedSession) error {
+		var previous TokenUsage
+		if messageID != "" {
+			if t.messageUsage[sessionID] == nil {
+				t.messageUsage[sessionID] = make(map[string]TokenUsage)
+			}
+			previous = t.messageUsage[sessionID][messageID]
+			t.messageUsage[sessionID][messageID] = usage
+		}
+
+		session.TokenUsage = session.TokenUsage.plus(usage).minus(previous)
+		session.ContextTokens = usage.splitTotal()
+		session.LastActivity = time.Now().UTC()
+		if session.Model == "" && model != "" {
+			session.Model = model
+		}
+		return nil
 	})
+	if err != nil {
+		return fmt.Errorf("record usage for session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// SetProjectTask records the project's current task and applies it to the
+// project's running and idle sessions.
+func (t *SessionTracker) SetProjectTask(project, task string) error {
+	if project == "" {
+		return fmt.Errorf("set project task: missing project")
+	}
+
+	t.mu.Lock()
+	t.projectTasks[project] = task
+	t.mu.Unlock()
+
+	for _, session := range t.GetSessionsByProject(project) {
+		if session.CurrentTask == task {
+			continue
+		}
+		if session.Status != SessionStatusRunning && session.Status != SessionStatusIdle {
+			continue
+		}
+		if err := t.UpdateSession(session.SessionID, map[string]interface{}{"current_task": task}); err != nil {
+			return fmt.Errorf("set project task: %w", err)
+		}
+	}
+	return nil
 }
 
 func (t *SessionTracker) RestoreFromSnapshot(nodeID string, sessions []TrackedSession) error {
@@ -317,7 +633,7 @@ func (t *SessionTracker) RestoreFromSnapshot(nodeID string, sessions []TrackedSe
 		if session.Status == "" {
 			session.Status = SessionStatusRunning
 		}
-		if err := t.AddSession(session); err != nil {
+		if err := t.AddSessionWithReason(session, TransitionReason{Cause: TransitionCauseEvent, Detail: "agent.snapshot"}); err != nil {
 			return fmt.Errorf("restore snapshot session %s: %w", session.SessionID, err)
 		}
 	}
@@ -329,25 +645,57 @@ func (t *SessionTracker) RecoveryErrorCount() uint64 {
 	return t.recoveryErrors.Load()
 }
 
+// sessionColumns lists the sessions table columns in the order
+// scanSessionRow reads them.
+const sessionColumns = `id, node_id, project, status, tokens,
+	input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+	cost, started_at, parent_session_id,
+	model, current_task, compaction_count, context_tokens, last_activity`
+
 func (t *SessionTracker) upsertSession(session TrackedSession) error {
+	var lastActivity interface{}
+	if !session.LastActivity.IsZero() {
+		lastActivity = session.LastActivity.UTC().Format(time.RFC3339Nano)
+	}
+
 	_, err := t.db.Exec(`
-		INSERT INTO sessions (id, node_id, project, status, tokens, cost, started_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO sessions (`+sessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			node_id = excluded.node_id,
 			project = excluded.project,
 			status = excluded.status,
 			tokens = excluded.tokens,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			cache_read_tokens = excluded.cache_read_tokens,
+			cache_write_tokens = excluded.cache_write_tokens,
 			cost = excluded.cost,
-			started_at = excluded.started_at
+			started_at = excluded.started_at,
+			parent_session_id = excluded.parent_session_id,
+			model = excluded.model,
+			current_task = excluded.current_task,
+			compaction_count = excluded.compaction_count,
+			context_tokens = excluded.context_tokens,
+			last_activity = excluded.last_activity
 	`,
 		session.SessionID,
 		session.NodeID,
 		session.Project,
 		string(session.Status),
 		session.TokenUsage.Total,
+		session.TokenUsage.Prompt,
+		session.TokenUsage.Completion,
+		session.TokenUsage.CacheRead,
+		session.TokenUsage.CacheWrite,
 		session.SessionCost,
 		session.StartedAt.UTC().Format(time.RFC3339Nano),
+		session.ParentSessionID,
+		session.Model,
+		session.CurrentTask,
+		session.CompactionCount,
+		session.ContextTokens,
+		lastActivity,
 	)
 	if err != nil {
 		return fmt.Errorf("upsert session %s: %w", session.SessionID, err)
@@ -357,13 +705,8 @@ func (t *SessionTracker) upsertSession(session TrackedSession) error {
 }
 
 func (t *SessionTracker) readSession(sessionID string) (TrackedSession, error) {
-	row := t.db.QueryRow(`
-		SELECT id, node_id, project, status, tokens, cost, started_at
-		FROM sessions
-		WHERE id = ?
-	`, sessionID)
-
-	return scanSessionSingleRow(row)
+	row := t.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, sessionID)
+	return scanSessionRow(row)
 }
 
 func (t *SessionTracker) incrementRecoveryError(msg string, err error) {
@@ -371,64 +714,37 @@ func (t *SessionTracker) incrementRecoveryError(msg string, err error) {
 	t.logger.Warn(msg, zap.Error(err))
 }
 
-func scanSessionRow(rows *sql.Rows) (TrackedSession, error) {
+func scanSessionRow(row rowScanner) (TrackedSession, error) {
 	var (
-		sessionID string
-		nodeID    string
-		project   string
-		statusRaw string
-		tokens    int
-		cost      float64
-		startedAt string
+		session      TrackedSession
+		statusRaw    string
+		startedAt    string
+		lastActivity sql.NullString
 	)
 
-	if err := rows.Scan(&sessionID, &nodeID, &project, &statusRaw, &tokens, &cost, &startedAt); err != nil {
+	if err := row.Scan(
+		&session.SessionID, &session.NodeID, &session.Project, &statusRaw, &session.TokenUsage.Total,
+		&session.TokenUsage.Prompt, &session.TokenUsage.Completion, &session.TokenUsage.CacheRead, &session.TokenUsage.CacheWrite,
+		&session.SessionCost, &startedAt, &session.ParentSessionID,
+		&session.Model, &session.CurrentTask, &session.CompactionCount, &session.ContextTokens, &lastActivity,
+	); err != nil {
 		return TrackedSession{}, fmt.Errorf("scan session row: %w", err)
 	}
+	session.Status = SessionStatus(statusRaw)
 
 	startedAtTime, err := parseSQLiteTimestamp(startedAt)
 	if err != nil {
-		return TrackedSession{}, fmt.Errorf("parse started_at for session %s: %w", sessionID, err)
+		return TrackedSession{}, fmt.Errorf("parse started_at for session %s: %w", session.SessionID, err)
 	}
+	session.StartedAt = startedAtTime
 
-	return TrackedSession{
-		SessionID:   sessionID,
-		NodeID:      nodeID,
-		Project:     project,
-		Status:      SessionStatus(statusRaw),
-		TokenUsage:  TokenUsage{Total: tokens},
-		SessionCost: cost,
-		StartedAt:   startedAtTime,
-	}, nil
-}
-
-func scanSessionSingleRow(row *sql.Row) (TrackedSession, error) {
-	var (
-		sessionID string
-		nodeID    string
-		project   string
-		statusRaw string
-		tokens    int
-		cost      float64
-		startedAt string
-	)
-
-	if err := row.Scan(&sessionID, &nodeID, &project, &statusRaw, &tokens, &cost, &startedAt); err != nil {
-		return TrackedSession{}, err
+	if lastActivity.Valid && lastActivity.String != "" {
+		lastActivityTime, err := parseSQLiteTimestamp(lastActivity.String)
+		if err != nil {
+			return TrackedSession{}, fmt.Errorf("parse last_activity for session %s: %w", session.SessionID, err)
+		}
+		session.LastActivity = lastActivityTime
 	}
 
-	startedAtTime, err := parseSQLiteTimestamp(startedAt)
-	if err != nil {
-		return TrackedSession{}, fmt.Errorf("parse started_at for session %s: %w", sessionID, err)
-	}
-
-	return TrackedSession{
-		SessionID:   sessionID,
-		NodeID:      nodeID,
-		Project:     project,
-		Status:      SessionStatus(statusRaw),
-		TokenUsage:  TokenUsage{Total: tokens},
-		SessionCost: cost,
-		StartedAt:   startedAtTime,
-	}, nil
+	return session, nil
 }
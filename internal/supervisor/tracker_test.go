@@ -67,6 +67,51 @@ func TestTrackerPersistReload(t *testing.T) {
 	}
 }
 
+func TestTrackerReloadRestoresFullState(t *testing.T) {
+	db := setupSupervisorTestDB(t)
+	logger := zap.NewNop()
+	if err := NewNodeRegistry(db, logger).Register(NodeEntry{ID: "node-1", Hostname: "host-1"}); err != nil {
+		t.Fatalf("register node failed: %v", err)
+	}
+
+	startedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	want := TrackedSession{
+		SessionID:       "sess-full",
+		NodeID:          "node-1",
+		Project:         "proj-a",
+		Status:          SessionStatusIdle,
+		TokenUsage:      TokenUsage{Prompt: 1200, Completion: 300, CacheRead: 50, CacheWrite: 25, Total: 1575},
+		CompactionCount: 2,
+		CurrentTask:     "Wire HAL",
+		LastActivity:    startedAt.Add(42*time.Minute + 123*time.Millisecond),
+		SessionCost:     0.42,
+		Model:           "claude-4",
+		StartedAt:       startedAt,
+		ParentSessionID: "sess-prev",
+		ContextTokens:   900,
+	}
+	tracker := NewSessionTracker(db, logger)
+	if err := tracker.AddSession(want); err != nil {
+		t.Fatalf("add session failed: %v", err)
+	}
+
+	reloaded := NewSessionTracker(db, logger)
+	if err := reloaded.LoadSessionsFromDB(); err != nil {
+		t.Fatalf("load sessions failed: %v", err)
+	}
+	got, err := reloaded.GetSession("sess-full")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+
+	// Only the status changes: the session is unreachable until its agent
+	// reports in again.
+	want.Status = SessionStatusUnreachable
+	if got != want {
+		t.Fatalf("reloaded session differs:\n got %+v\nwant %+v", got, want)
+	}
+}
+
 func TestTrackerCorruptedRow(t *testing.T) {
 	db := setupSupervisorTestDB(t)
 	logger := zap.NewNop()